@@ -18,25 +18,57 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
 
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/adminapi"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/auth"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/logging"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/server"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/signals"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/watch"
 )
 
+// checkSubcommand validates the kubeconfig and the RBAC permissions, and exits, e.g., `control-plane check`.
+const checkSubcommand = "check"
+
+// watchSubcommand connects to an xDS management server and prints resource changes, e.g., `control-plane watch`.
+const watchSubcommand = "watch"
+
 func Run(ctx context.Context, flagset *flag.FlagSet, args []string) error {
+	subcommand := ""
+	if len(args) > 0 && (args[0] == checkSubcommand || args[0] == watchSubcommand) {
+		subcommand, args = args[0], args[1:]
+	}
 	ctx = signals.SetupSignalHandler(ctx)
 	logging.InitFlags(flagset)
+	if subcommand == watchSubcommand {
+		return runWatch(ctx, flagset, args)
+	}
+	adminapi.InitFlags(flagset)
 	informers.InitFlags(flagset)
+	metrics.InitFlags(flagset)
+	server.InitFlags(flagset)
 	if err := flagset.Parse(args); err != nil {
 		return fmt.Errorf("could not parse command line flags args=%+v: %w", args, err)
 	}
+	if err := logging.ApplyFlags(flagset); err != nil {
+		return fmt.Errorf("invalid logging flags: %w", err)
+	}
 	logger := logging.NewLogger()
 	logging.SetGRPCLogger(logger)
 	ctx = logging.NewContext(ctx, logger)
+	kubecontexts, err := config.Kubecontexts(logger)
+	if err != nil {
+		return fmt.Errorf("could not initialize informer configuration: %w", err)
+	}
+	if subcommand == checkSubcommand {
+		return runCheck(ctx, logger, kubecontexts)
+	}
 	auth.RegisterAll(ctx, logger)
 	servingPort, err := config.ServingPort()
 	if err != nil {
@@ -46,10 +78,6 @@ func Run(ctx context.Context, flagset *flag.FlagSet, args []string) error {
 	if err != nil {
 		return fmt.Errorf("could not configure management server health checking port: %w", err)
 	}
-	kubecontexts, err := config.Kubecontexts(logger)
-	if err != nil {
-		return fmt.Errorf("could not initialize informer configuration: %w", err)
-	}
 	xdsFeatures, err := config.XDSFeatures(logger)
 	if err != nil {
 		return fmt.Errorf("could not initialize xDS feature flags: %w", err)
@@ -63,3 +91,40 @@ func Run(ctx context.Context, flagset *flag.FlagSet, args []string) error {
 	}
 	return server.Run(ctx, servingPort, healthPort, kubecontexts, xdsFeatures, authority)
 }
+
+// runWatch streams xDS resources from a management server and prints changes to stdout,
+// without loading the informer configuration.
+func runWatch(ctx context.Context, flagset *flag.FlagSet, args []string) error {
+	watch.InitFlags(flagset)
+	if err := flagset.Parse(args); err != nil {
+		return fmt.Errorf("could not parse command line flags args=%+v: %w", args, err)
+	}
+	if err := logging.ApplyFlags(flagset); err != nil {
+		return fmt.Errorf("invalid logging flags: %w", err)
+	}
+	logger := logging.NewLogger()
+	logging.SetGRPCLogger(logger)
+	ctx = logging.NewContext(ctx, logger)
+	if err := watch.Run(ctx, logger, os.Stdout); err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+	return nil
+}
+
+// runCheck checks connectivity and RBAC permissions for the kubecontexts in the informer configuration,
+// without starting the snapshot cache, the informers, or the gRPC servers.
+func runCheck(ctx context.Context, logger logr.Logger, kubecontexts []informers.Kubecontext) error {
+	xdsFeatures, err := config.XDSFeatures(logger)
+	if err != nil {
+		return fmt.Errorf("could not initialize xDS feature flags: %w", err)
+	}
+	serverPermissions, err := server.RequiredPermissions(logger)
+	if err != nil {
+		return fmt.Errorf("check failed: %w", err)
+	}
+	if err := informers.Check(ctx, logger, kubecontexts, xdsFeatures.DataPlaneTLSSecret, serverPermissions); err != nil {
+		return fmt.Errorf("check failed: %w", err)
+	}
+	logger.V(1).Info("All checks passed")
+	return nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminapi
+
+import (
+	"flag"
+)
+
+var (
+	adminAddr  string
+	adminToken string
+)
+
+// InitFlags initializes flags for the admin HTTP server.
+func InitFlags(flagset *flag.FlagSet) {
+	if flagset == nil {
+		flagset = flag.CommandLine
+	}
+	flagset.StringVar(&adminAddr, "admin-addr", "", "(optional) address for the admin HTTP server, e.g., localhost:8080, disabled if empty")
+	flagset.StringVar(&adminToken, "admin-token", "", "(optional) bearer token required for non-GET requests to the admin HTTP server, non-GET requests are rejected if empty")
+}
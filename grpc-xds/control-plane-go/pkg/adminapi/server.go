@@ -0,0 +1,170 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	snapshotPathPrefix      = "/snapshot/"
+	maxRequestBodyBytes     = 10 << 20
+	serverReadHeaderTimeout = 5 * time.Second
+	serverShutdownTimeout   = 5 * time.Second
+)
+
+// StartServer starts the admin HTTP server, if the `admin-addr` flag is set.
+// The server provides runtime inspection and manual override of xDS resource snapshots,
+// keyed by node hash:
+//
+//   - `GET /snapshot/{node-hash}` returns the current snapshot as JSON.
+//   - `POST /snapshot/{node-hash}` replaces the snapshot with the snapshot in the JSON request body.
+//     Informer updates and the periodic resync do not replace it until the next `DELETE`.
+//   - `DELETE /snapshot/{node-hash}` removes the snapshot, and the control plane manages the
+//     snapshot for the node hash again.
+//
+// `POST` and `DELETE` requests require the bearer token from the `admin-token` flag.
+// The server shuts down when the provided context is done.
+func StartServer(ctx context.Context, logger logr.Logger, xdsCache *xds.SnapshotCache) error {
+	if adminAddr == "" {
+		logger.V(2).Info("Not serving the admin API, as the admin address is not set")
+		return nil
+	}
+	listener, err := net.Listen("tcp", adminAddr)
+	if err != nil {
+		return fmt.Errorf("could not create TCP listener for admin server on address=%s: %w", adminAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(snapshotPathPrefix, &snapshotHandler{
+		ctx:      ctx,
+		logger:   logger,
+		xdsCache: xdsCache,
+		token:    adminToken,
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "Could not gracefully shut down the admin server")
+		}
+	}()
+	go func() {
+		logger.V(1).Info("Admin server listening", "address", adminAddr)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err, "Admin server stopped unexpectedly")
+		}
+	}()
+	return nil
+}
+
+// snapshotHandler handles requests to `/snapshot/{node-hash}`.
+type snapshotHandler struct {
+	ctx      context.Context
+	logger   logr.Logger
+	xdsCache *xds.SnapshotCache
+	token    string
+}
+
+func (h *snapshotHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	nodeHash := strings.TrimPrefix(r.URL.Path, snapshotPathPrefix)
+	// Node hashes that are scoped to a namespace contain a `/`, e.g., `us-central1-a/foo`.
+	if nodeHash == "" {
+		http.Error(w, "expected path /snapshot/{node-hash}", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet && !h.authorized(r) {
+		http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+	logger := h.logger.WithValues("method", r.Method, "nodeHash", nodeHash)
+	switch r.Method {
+	case http.MethodGet:
+		h.getSnapshot(logger, w, nodeHash)
+	case http.MethodPost:
+		h.setSnapshot(logger, w, r, nodeHash)
+	case http.MethodDelete:
+		logger.Info("Removing xDS resource snapshot via admin API")
+		h.xdsCache.ClearSnapshot(nodeHash)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPost, http.MethodDelete}, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized returns true if the request has the expected bearer token.
+// Requests are never authorized if no token is configured.
+func (h *snapshotHandler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return false
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *snapshotHandler) getSnapshot(logger logr.Logger, w http.ResponseWriter, nodeHash string) {
+	snapshot, err := h.xdsCache.GetSnapshot(nodeHash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	snapshotJSONBytes, err := marshalSnapshot(snapshot)
+	if err != nil {
+		logger.Error(err, "Could not marshal xDS resource snapshot to JSON")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(snapshotJSONBytes); err != nil {
+		logger.Error(err, "Could not write response")
+	}
+}
+
+func (h *snapshotHandler) setSnapshot(logger logr.Logger, w http.ResponseWriter, r *http.Request, nodeHash string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snapshot, err := unmarshalSnapshot(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger.Info("Replacing xDS resource snapshot via admin API")
+	if err := h.xdsCache.SetSnapshot(h.ctx, nodeHash, snapshot); err != nil {
+		logger.Error(err, "Could not set xDS resource snapshot")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
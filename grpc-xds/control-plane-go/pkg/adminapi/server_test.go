@@ -0,0 +1,207 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	testNodeHash = "zone-a"
+	testToken    = "s3cr3t"
+)
+
+func newTestSnapshot(t *testing.T, version string) *cachev3.Snapshot {
+	t.Helper()
+	snapshot, err := cachev3.NewSnapshot(version, map[string][]types.Resource{
+		resource.ClusterType: {&clusterv3.Cluster{Name: "greeter"}},
+	})
+	if err != nil {
+		t.Fatalf("NewSnapshot() error = %v", err)
+	}
+	return snapshot
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	postBody, err := marshalSnapshot(newTestSnapshot(t, "2"))
+	if err != nil {
+		t.Fatalf("marshalSnapshot() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		// noAdminToken means the `admin-token` flag is not set.
+		noAdminToken bool
+		method       string
+		path         string
+		token        string
+		body         string
+		wantStatus   int
+		// wantVersion is the Cluster version of the snapshot for `testNodeHash` after the request,
+		// or empty if there should be no snapshot.
+		wantVersion string
+	}{
+		{
+			name:        "get returns the snapshot",
+			method:      http.MethodGet,
+			path:        "/snapshot/" + testNodeHash,
+			wantStatus:  http.StatusOK,
+			wantVersion: "1",
+		},
+		{
+			name:        "get unknown node hash",
+			method:      http.MethodGet,
+			path:        "/snapshot/zone-b",
+			wantStatus:  http.StatusNotFound,
+			wantVersion: "1",
+		},
+		{
+			name:        "get without node hash",
+			method:      http.MethodGet,
+			path:        "/snapshot/",
+			wantStatus:  http.StatusNotFound,
+			wantVersion: "1",
+		},
+		{
+			name:        "post replaces the snapshot",
+			method:      http.MethodPost,
+			path:        "/snapshot/" + testNodeHash,
+			token:       testToken,
+			body:        string(postBody),
+			wantStatus:  http.StatusNoContent,
+			wantVersion: "2",
+		},
+		{
+			name:        "post without bearer token",
+			method:      http.MethodPost,
+			path:        "/snapshot/" + testNodeHash,
+			body:        string(postBody),
+			wantStatus:  http.StatusUnauthorized,
+			wantVersion: "1",
+		},
+		{
+			name:        "post with invalid bearer token",
+			method:      http.MethodPost,
+			path:        "/snapshot/" + testNodeHash,
+			token:       "invalid",
+			body:        string(postBody),
+			wantStatus:  http.StatusUnauthorized,
+			wantVersion: "1",
+		},
+		{
+			name:         "post is never authorized without configured token",
+			noAdminToken: true,
+			method:       http.MethodPost,
+			path:         "/snapshot/" + testNodeHash,
+			body:         string(postBody),
+			wantStatus:   http.StatusUnauthorized,
+			wantVersion:  "1",
+		},
+		{
+			name:        "post with invalid body",
+			method:      http.MethodPost,
+			path:        "/snapshot/" + testNodeHash,
+			token:       testToken,
+			body:        `{"version":`,
+			wantStatus:  http.StatusBadRequest,
+			wantVersion: "1",
+		},
+		{
+			name:       "delete removes the snapshot",
+			method:     http.MethodDelete,
+			path:       "/snapshot/" + testNodeHash,
+			token:      testToken,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:        "delete without bearer token",
+			method:      http.MethodDelete,
+			path:        "/snapshot/" + testNodeHash,
+			wantStatus:  http.StatusUnauthorized,
+			wantVersion: "1",
+		},
+		{
+			name:        "unknown method",
+			method:      http.MethodPut,
+			path:        "/snapshot/" + testNodeHash,
+			token:       testToken,
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantVersion: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			xdsCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+			if err := xdsCache.SetSnapshot(ctx, testNodeHash, newTestSnapshot(t, "1")); err != nil {
+				t.Fatalf("SetSnapshot() error = %v", err)
+			}
+			token := testToken
+			if tt.noAdminToken {
+				token = ""
+			}
+			handler := &snapshotHandler{ctx: ctx, logger: logr.Discard(), xdsCache: xdsCache, token: token}
+
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %q", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", got)
+				}
+				snapshot, err := unmarshalSnapshot(recorder.Body.Bytes())
+				if err != nil {
+					t.Fatalf("unmarshalSnapshot() error = %v", err)
+				}
+				if got := snapshot.GetVersion(resource.ClusterType); got != "1" {
+					t.Errorf("version of response snapshot = %q, want 1", got)
+				}
+			}
+			if tt.wantStatus == http.StatusMethodNotAllowed {
+				if got := recorder.Header().Get("Allow"); got != "GET, POST, DELETE" {
+					t.Errorf("Allow = %q, want GET, POST, DELETE", got)
+				}
+			}
+			snapshot, err := xdsCache.GetSnapshot(testNodeHash)
+			switch {
+			case tt.wantVersion == "" && err == nil:
+				t.Errorf("GetSnapshot() found snapshot, want none")
+			case tt.wantVersion != "" && err != nil:
+				t.Errorf("GetSnapshot() error = %v", err)
+			case tt.wantVersion != "" && snapshot.GetVersion(resource.ClusterType) != tt.wantVersion:
+				t.Errorf("version of snapshot = %q, want %q", snapshot.GetVersion(resource.ClusterType), tt.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var errUnknownResourceType = errors.New("unknown xDS resource type")
+
+// resourceTypes are the xDS resource types served by this control plane.
+var resourceTypes = []string{
+	resource.ListenerType,
+	resource.RouteType,
+	resource.ClusterType,
+	resource.EndpointType,
+}
+
+// snapshotJSON is the JSON representation of a snapshot in the admin API, keyed by resource type URL.
+type snapshotJSON map[string]resourcesJSON
+
+// resourcesJSON contains the version and the resources of one resource type.
+type resourcesJSON struct {
+	Version   string            `json:"version"`
+	Resources []json.RawMessage `json:"resources"`
+}
+
+func marshalSnapshot(snapshot cachev3.ResourceSnapshot) ([]byte, error) {
+	marshalOptions := protojson.MarshalOptions{
+		UseProtoNames: true,
+	}
+	snapshotJSONValue := snapshotJSON{}
+	for _, typeURL := range resourceTypes {
+		resources := snapshot.GetResources(typeURL)
+		resourcesJSONValue := resourcesJSON{
+			Version:   snapshot.GetVersion(typeURL),
+			Resources: make([]json.RawMessage, 0, len(resources)),
+		}
+		for name, res := range resources {
+			resourceJSONBytes, err := marshalOptions.Marshal(res)
+			if err != nil {
+				return nil, fmt.Errorf("could not marshal resource type=%s name=%s to JSON: %w", typeURL, name, err)
+			}
+			resourcesJSONValue.Resources = append(resourcesJSONValue.Resources, resourceJSONBytes)
+		}
+		snapshotJSONValue[typeURL] = resourcesJSONValue
+	}
+	return json.MarshalIndent(snapshotJSONValue, "", "  ")
+}
+
+func unmarshalSnapshot(snapshotJSONBytes []byte) (*cachev3.Snapshot, error) {
+	var snapshotJSONValue snapshotJSON
+	if err := json.Unmarshal(snapshotJSONBytes, &snapshotJSONValue); err != nil {
+		return nil, fmt.Errorf("could not unmarshal snapshot JSON: %w", err)
+	}
+	snapshot := &cachev3.Snapshot{}
+	for typeURL, resourcesJSONValue := range snapshotJSONValue {
+		responseType := cachev3.GetResponseType(typeURL)
+		if responseType == types.UnknownType {
+			return nil, fmt.Errorf("%w: %s", errUnknownResourceType, typeURL)
+		}
+		resources := make([]types.Resource, 0, len(resourcesJSONValue.Resources))
+		for _, resourceJSONBytes := range resourcesJSONValue.Resources {
+			res, err := unmarshalResource(typeURL, resourceJSONBytes)
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, res)
+		}
+		snapshot.Resources[responseType] = cachev3.NewResources(resourcesJSONValue.Version, resources)
+	}
+	if err := snapshot.Consistent(); err != nil {
+		return nil, fmt.Errorf("inconsistent snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+func unmarshalResource(typeURL string, resourceJSONBytes []byte) (types.Resource, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not find message type for type=%s: %w", typeURL, err)
+	}
+	message := messageType.New().Interface()
+	if err := protojson.Unmarshal(resourceJSONBytes, message); err != nil {
+		return nil, fmt.Errorf("could not unmarshal resource of type=%s from JSON: %w", typeURL, err)
+	}
+	return message, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSnapshotJSONRoundTrip(t *testing.T) {
+	cluster := &clusterv3.Cluster{
+		Name:                 "greeter",
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		EdsClusterConfig: &clusterv3.Cluster_EdsClusterConfig{
+			EdsConfig:   &corev3.ConfigSource{ConfigSourceSpecifier: &corev3.ConfigSource_Ads{Ads: &corev3.AggregatedConfigSource{}}},
+			ServiceName: "greeter",
+		},
+	}
+	clusterLoadAssignment := &endpointv3.ClusterLoadAssignment{ClusterName: "greeter"}
+	snapshot, err := cachev3.NewSnapshot("1", map[string][]types.Resource{
+		resource.ClusterType:  {cluster},
+		resource.EndpointType: {clusterLoadAssignment},
+	})
+	if err != nil {
+		t.Fatalf("NewSnapshot() error = %v", err)
+	}
+	snapshotJSONBytes, err := marshalSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("marshalSnapshot() error = %v", err)
+	}
+	got, err := unmarshalSnapshot(snapshotJSONBytes)
+	if err != nil {
+		t.Fatalf("unmarshalSnapshot() error = %v", err)
+	}
+	for _, typeURL := range resourceTypes {
+		if got.GetVersion(typeURL) != snapshot.GetVersion(typeURL) {
+			t.Errorf("version of %s = %q, want %q", typeURL, got.GetVersion(typeURL), snapshot.GetVersion(typeURL))
+		}
+		gotResources, wantResources := got.GetResources(typeURL), snapshot.GetResources(typeURL)
+		if len(gotResources) != len(wantResources) {
+			t.Errorf("number of %s resources = %d, want %d", typeURL, len(gotResources), len(wantResources))
+			continue
+		}
+		for name, want := range wantResources {
+			if !proto.Equal(gotResources[name], want) {
+				t.Errorf("resource %s of type %s = %v, want %v", name, typeURL, gotResources[name], want)
+			}
+		}
+	}
+}
+
+func TestUnmarshalSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		json       string
+		wantErr    error
+		wantPrefix string
+	}{
+		{
+			name:    "unknown resource type",
+			json:    `{"type.googleapis.com/unknown.Type": {"version": "1", "resources": []}}`,
+			wantErr: errUnknownResourceType,
+		},
+		{
+			name: "inconsistent snapshot",
+			json: `{"` + resource.ClusterType + `": {"version": "1", "resources": [
+				{"name": "greeter", "type": "EDS", "eds_cluster_config": {"eds_config": {"ads": {}}}}
+			]}, "` + resource.EndpointType + `": {"version": "1", "resources": []}}`,
+			wantPrefix: "inconsistent snapshot",
+		},
+		{
+			name:       "malformed JSON",
+			json:       `{`,
+			wantPrefix: "could not unmarshal snapshot JSON",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := unmarshalSnapshot([]byte(tt.json))
+			if err == nil {
+				t.Fatal("unmarshalSnapshot() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("unmarshalSnapshot() error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantPrefix) {
+				t.Errorf("unmarshalSnapshot() error = %v, want prefix %q", err, tt.wantPrefix)
+			}
+		})
+	}
+}
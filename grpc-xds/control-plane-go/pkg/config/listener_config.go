@@ -0,0 +1,86 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	// maxRequestHeadersKB is the maximum value allowed by Envoy.
+	maxRequestHeadersKB = 8192
+	// minHTTP2WindowSize and maxHTTP2WindowSize are the limits from the HTTP/2 specification.
+	minHTTP2WindowSize = 65535
+	maxHTTP2WindowSize = 2147483647
+)
+
+var (
+	errNegativeTimeout           = errors.New("timeouts must not be negative")
+	errMaxRequestHeadersKB       = fmt.Errorf("maxRequestHeadersKb must not exceed %d", maxRequestHeadersKB)
+	errHTTP2WindowSizeOutOfRange = fmt.Errorf("HTTP/2 window sizes must be between %d and %d", minHTTP2WindowSize, maxHTTP2WindowSize)
+)
+
+// ListenerConfig loads the LDS API listener configuration from the YAML file at the provided path.
+// If the path is empty, the compiled-in defaults are used, i.e., an empty configuration.
+func ListenerConfig(logger logr.Logger, listenerConfigFilePath string) (*xds.ListenerConfig, error) {
+	if listenerConfigFilePath == "" {
+		return &xds.ListenerConfig{}, nil
+	}
+	logger.V(4).Info("Loading listener configuration", "filepath", listenerConfigFilePath)
+	yamlBytes, err := os.ReadFile(listenerConfigFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read listener configuration from file %s: %w", listenerConfigFilePath, err)
+	}
+	var listenerConfig xds.ListenerConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(yamlBytes))
+	// Catch typos in field names, instead of silently using the defaults.
+	decoder.KnownFields(true)
+	// An empty file is valid, and results in the defaults.
+	if err := decoder.Decode(&listenerConfig); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not unmarshal listener configuration YAML file contents [%s]: %w", yamlBytes, err)
+	}
+	if err := validateListenerConfig(listenerConfig); err != nil {
+		return nil, fmt.Errorf("listener configuration validation failed: %w", err)
+	}
+	logger.V(2).Info("Listener", "configuration", listenerConfig)
+	return &listenerConfig, nil
+}
+
+func validateListenerConfig(listenerConfig xds.ListenerConfig) error {
+	if listenerConfig.StreamIdleTimeout < 0 || listenerConfig.RequestTimeout < 0 {
+		return errNegativeTimeout
+	}
+	if listenerConfig.MaxRequestHeadersKB > maxRequestHeadersKB {
+		return fmt.Errorf("%w: maxRequestHeadersKb=%d", errMaxRequestHeadersKB, listenerConfig.MaxRequestHeadersKB)
+	}
+	for name, windowSize := range map[string]uint32{
+		"initialStreamWindowSize":     listenerConfig.HTTP2ProtocolOptions.InitialStreamWindowSize,
+		"initialConnectionWindowSize": listenerConfig.HTTP2ProtocolOptions.InitialConnectionWindowSize,
+	} {
+		if windowSize != 0 && (windowSize < minHTTP2WindowSize || windowSize > maxHTTP2WindowSize) {
+			return fmt.Errorf("%w: %s=%d", errHTTP2WindowSizeOutOfRange, name, windowSize)
+		}
+	}
+	return nil
+}
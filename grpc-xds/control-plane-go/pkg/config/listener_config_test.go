@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestListenerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    xds.ListenerConfig
+		wantErr error
+	}{
+		{
+			name: "empty file",
+			yaml: "",
+			want: xds.ListenerConfig{},
+		},
+		{
+			name: "all fields",
+			yaml: `http2ProtocolOptions:
+  maxConcurrentStreams: 100
+  initialStreamWindowSize: 65536
+  initialConnectionWindowSize: 1048576
+streamIdleTimeout: 5m
+requestTimeout: 30s
+maxRequestHeadersKb: 96
+accessLogFormat: "%START_TIME% %RESPONSE_CODE%\n"
+`,
+			want: xds.ListenerConfig{
+				HTTP2ProtocolOptions: xds.HTTP2ProtocolOptions{
+					MaxConcurrentStreams:        100,
+					InitialStreamWindowSize:     65536,
+					InitialConnectionWindowSize: 1048576,
+				},
+				StreamIdleTimeout:   5 * time.Minute,
+				RequestTimeout:      30 * time.Second,
+				MaxRequestHeadersKB: 96,
+				AccessLogFormat:     "%START_TIME% %RESPONSE_CODE%\n",
+			},
+		},
+		{
+			name:    "negative timeout",
+			yaml:    "requestTimeout: -1s\n",
+			wantErr: errNegativeTimeout,
+		},
+		{
+			name:    "max request headers too large",
+			yaml:    "maxRequestHeadersKb: 8193\n",
+			wantErr: errMaxRequestHeadersKB,
+		},
+		{
+			name:    "HTTP/2 window size too small",
+			yaml:    "http2ProtocolOptions:\n  initialStreamWindowSize: 1024\n",
+			wantErr: errHTTP2WindowSizeOutOfRange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "listener-config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatalf("could not write listener configuration file: %v", err)
+			}
+			got, err := ListenerConfig(logr.Discard(), path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListenerConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && *got != tt.want {
+				t.Errorf("ListenerConfig() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenerConfigRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "listener-config.yaml")
+	if err := os.WriteFile(path, []byte("requestTimeOut: 30s\n"), 0o600); err != nil {
+		t.Fatalf("could not write listener configuration file: %v", err)
+	}
+	if _, err := ListenerConfig(logr.Discard(), path); err == nil {
+		t.Error("ListenerConfig() error = nil, want an error for an unknown field")
+	}
+}
+
+func TestListenerConfigWithoutFile(t *testing.T) {
+	got, err := ListenerConfig(logr.Discard(), "")
+	if err != nil {
+		t.Fatalf("ListenerConfig() error = %v", err)
+	}
+	if *got != (xds.ListenerConfig{}) {
+		t.Errorf("ListenerConfig() = %+v, want the defaults", *got)
+	}
+}
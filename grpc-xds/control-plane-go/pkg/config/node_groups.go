@@ -0,0 +1,55 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+// NodeGroups loads the node groups for additional xDS management server listeners from the YAML file
+// at the provided path, and validates them, including conflicts between node selectors.
+// If the path is empty, there are no node groups.
+func NodeGroups(logger logr.Logger, xdsListenersFilePath string) ([]xds.NodeGroup, error) {
+	if xdsListenersFilePath == "" {
+		return nil, nil
+	}
+	logger.V(4).Info("Loading xDS listener configuration", "filepath", xdsListenersFilePath)
+	yamlBytes, err := os.ReadFile(xdsListenersFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read xDS listener configuration from file %s: %w", xdsListenersFilePath, err)
+	}
+	var nodeGroups []xds.NodeGroup
+	decoder := yaml.NewDecoder(bytes.NewReader(yamlBytes))
+	// Catch typos in field names, instead of silently ignoring node selectors or namespaces.
+	decoder.KnownFields(true)
+	// An empty file is valid, and results in no node groups.
+	if err := decoder.Decode(&nodeGroups); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not unmarshal xDS listener configuration YAML file contents [%s]: %w", yamlBytes, err)
+	}
+	if err := xds.ValidateNodeGroups(nodeGroups); err != nil {
+		return nil, fmt.Errorf("xDS listener configuration validation failed: %w", err)
+	}
+	logger.V(2).Info("xDS listener", "nodeGroups", nodeGroups)
+	return nodeGroups, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestNodeGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name: "empty file",
+			yaml: "",
+		},
+		{
+			name: "node groups",
+			yaml: `- name: gateway
+  port: 50052
+  nodeSelector:
+    role: gateway
+  namespaces: [foo]
+- name: egress
+  port: 50053
+  nodeSelector:
+    role: egress
+`,
+			wantNames: []string{"gateway", "egress"},
+		},
+		{
+			name:    "unknown field",
+			yaml:    "- name: gateway\n  port: 50052\n  nodeSelectors:\n    role: gateway\n",
+			wantErr: true,
+		},
+		{
+			name:    "conflicting selectors",
+			yaml:    "- name: a\n  port: 50052\n  nodeSelector: {role: gateway}\n- name: b\n  port: 50053\n  nodeSelector: {tier: edge}\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "xds-listeners.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatalf("could not write xDS listener configuration file: %v", err)
+			}
+			nodeGroups, err := NodeGroups(logr.Discard(), path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NodeGroups() error = %v, want error %t", err, tt.wantErr)
+			}
+			names := make([]string, 0, len(nodeGroups))
+			for _, group := range nodeGroups {
+				names = append(names, group.Name)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("NodeGroups() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestNodeGroupsWithoutFile(t *testing.T) {
+	nodeGroups, err := NodeGroups(logr.Discard(), "")
+	if err != nil || nodeGroups != nil {
+		t.Errorf("NodeGroups() = (%v, %v), want (nil, nil)", nodeGroups, err)
+	}
+}
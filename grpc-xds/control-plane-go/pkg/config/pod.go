@@ -0,0 +1,58 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+const (
+	// Environment variables set using the downward API, as the pod IP is not available in downward API volumes.
+	podIPEnvVar   = "POD_IP"
+	podNameEnvVar = "POD_NAME"
+)
+
+var errInvalidPodIP = errors.New("invalid pod IP address")
+
+// PodIP returns the IP address of this pod from the `POD_IP` environment variable,
+// set from the `status.podIP` field using the downward API.
+func PodIP() (string, error) {
+	podIP, exists := os.LookupEnv(podIPEnvVar)
+	if !exists {
+		return "", fmt.Errorf("%w: the environment variable %s is not set", errInvalidPodIP, podIPEnvVar)
+	}
+	if net.ParseIP(podIP) == nil {
+		return "", fmt.Errorf("%w: %s=%q", errInvalidPodIP, podIPEnvVar, podIP)
+	}
+	return podIP, nil
+}
+
+// PodName returns the name of this pod from the `POD_NAME` environment variable,
+// set from the `metadata.name` field using the downward API.
+// If the environment variable is not set, this function returns the hostname,
+// which is the pod name unless the pod spec sets `hostname`.
+func PodName() (string, error) {
+	if podName, exists := os.LookupEnv(podNameEnvVar); exists && podName != "" {
+		return podName, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the pod name from the environment variable %s or the hostname: %w", podNameEnvVar, err)
+	}
+	return hostname, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestPodIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   *string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "not set",
+			value:   nil,
+			wantErr: errInvalidPodIP,
+		},
+		{
+			name:  "IPv4",
+			value: ptr("10.0.0.1"),
+			want:  "10.0.0.1",
+		},
+		{
+			name:  "IPv6",
+			value: ptr("fd00::1"),
+			want:  "fd00::1",
+		},
+		{
+			name:    "not an IP address",
+			value:   ptr("control-plane"),
+			wantErr: errInvalidPodIP,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOrUnsetEnv(t, podIPEnvVar, tt.value)
+			got, err := PodIP()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PodIP() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PodIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodName(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("could not get hostname: %v", err)
+	}
+	tests := []struct {
+		name  string
+		value *string
+		want  string
+	}{
+		{name: "set", value: ptr("control-plane-0"), want: "control-plane-0"},
+		{name: "empty uses the hostname", value: ptr(""), want: hostname},
+		{name: "not set uses the hostname", value: nil, want: hostname},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOrUnsetEnv(t, podNameEnvVar, tt.value)
+			got, err := PodName()
+			if err != nil {
+				t.Fatalf("PodName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PodName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// setOrUnsetEnv sets the environment variable to the value, or unsets it if the value is nil,
+// and restores the previous value when the test finishes.
+func setOrUnsetEnv(t *testing.T, key string, value *string) {
+	t.Helper()
+	// Register the restore of the previous value.
+	t.Setenv(key, "")
+	if value == nil {
+		if err := os.Unsetenv(key); err != nil {
+			t.Fatalf("could not unset %s: %v", key, err)
+		}
+		return
+	}
+	t.Setenv(key, *value)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
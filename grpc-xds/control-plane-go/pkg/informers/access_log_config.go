@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var errInvalidAccessLogConfig = errors.New("invalid AccessLogConfig")
+
+// accessLogConfigSpec is the `spec` of `AccessLogConfig` custom resources,
+// see `k8s/control-plane/base/crd-access-log-configs.yaml`.
+type accessLogConfigSpec struct {
+	Sink   string `json:"sink,omitempty"`
+	Format struct {
+		Text string            `json:"text,omitempty"`
+		JSON map[string]string `json:"json,omitempty"`
+	} `json:"format,omitempty"`
+	GRPCService struct {
+		ClusterName string `json:"clusterName,omitempty"`
+		Address     string `json:"address,omitempty"`
+		LogName     string `json:"logName,omitempty"`
+	} `json:"grpcService,omitempty"`
+}
+
+func (m *Manager) handleAccessLogConfigs(ctx context.Context, logger logr.Logger, namespace string, objs []*unstructured.Unstructured) {
+	var accessLogConfigs []xds.AccessLogConfig
+	for _, obj := range objs {
+		accessLogConfig, err := accessLogConfigFromUnstructured(obj)
+		if err != nil {
+			logger.Error(err, "Skipping AccessLogConfig", "name", obj.GetName())
+			continue
+		}
+		accessLogConfigs = append(accessLogConfigs, accessLogConfig)
+	}
+	// Sort for a stable order of access logs in the HTTP connection managers.
+	slices.SortFunc(accessLogConfigs, func(a xds.AccessLogConfig, b xds.AccessLogConfig) int {
+		return a.Compare(b)
+	})
+	logger.V(2).Info("Informer resource update", "accessLogConfigs", accessLogConfigs)
+	if err := m.xdsCache.UpdateAccessLogConfigs(ctx, logger, m.kubecontext, namespace, accessLogConfigs); err != nil {
+		logger.Error(err, "Could not update the xDS resource cache with access log configurations", "accessLogConfigs", accessLogConfigs)
+	}
+}
+
+func accessLogConfigFromUnstructured(obj *unstructured.Unstructured) (xds.AccessLogConfig, error) {
+	var spec accessLogConfigSpec
+	if err := specFromUnstructured(obj, &spec); err != nil {
+		return xds.AccessLogConfig{}, fmt.Errorf("%w: %w", errInvalidAccessLogConfig, err)
+	}
+	sink := spec.Sink
+	if sink == "" {
+		sink = xds.AccessLogSinkStdout
+	}
+	accessLogConfig := xds.AccessLogConfig{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Sink:      sink,
+	}
+	switch sink {
+	case xds.AccessLogSinkStdout:
+		if spec.Format.Text != "" && len(spec.Format.JSON) > 0 {
+			return xds.AccessLogConfig{}, fmt.Errorf("%w: format must have either text or json, not both", errInvalidAccessLogConfig)
+		}
+		accessLogConfig.TextFormat = spec.Format.Text
+		for key, value := range spec.Format.JSON {
+			accessLogConfig.JSONFormat = append(accessLogConfig.JSONFormat, xds.AccessLogJSONField{
+				Key:   key,
+				Value: value,
+			})
+		}
+		slices.SortFunc(accessLogConfig.JSONFormat, func(a xds.AccessLogJSONField, b xds.AccessLogJSONField) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+	case xds.AccessLogSinkGRPC:
+		if (spec.GRPCService.ClusterName == "") == (spec.GRPCService.Address == "") {
+			return xds.AccessLogConfig{}, fmt.Errorf("%w: grpcService must have either clusterName or address", errInvalidAccessLogConfig)
+		}
+		if spec.Format.Text != "" || len(spec.Format.JSON) > 0 {
+			return xds.AccessLogConfig{}, fmt.Errorf("%w: format is not supported for the grpc sink", errInvalidAccessLogConfig)
+		}
+		accessLogConfig.GRPCClusterName = spec.GRPCService.ClusterName
+		accessLogConfig.GRPCAddress = spec.GRPCService.Address
+		accessLogConfig.LogName = spec.GRPCService.LogName
+	default:
+		return xds.AccessLogConfig{}, fmt.Errorf("%w: sink=%s, must be %s or %s", errInvalidAccessLogConfig, spec.Sink, xds.AccessLogSinkStdout, xds.AccessLogSinkGRPC)
+	}
+	return accessLogConfig, nil
+}
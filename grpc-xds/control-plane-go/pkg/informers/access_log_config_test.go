@@ -0,0 +1,127 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestAccessLogConfigFromUnstructured(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    xds.AccessLogConfig
+		wantErr error
+	}{
+		{
+			name: "no spec defaults to stdout with the default format",
+			spec: nil,
+			want: xds.AccessLogConfig{Namespace: "default", Name: "logs", Sink: xds.AccessLogSinkStdout},
+		},
+		{
+			name: "stdout with sorted JSON format",
+			spec: map[string]interface{}{
+				"format": map[string]interface{}{
+					"json": map[string]interface{}{
+						"path":   "%REQ(:PATH)%",
+						"status": "%GRPC_STATUS%",
+						"method": "%REQ(:METHOD)%",
+					},
+				},
+			},
+			want: xds.AccessLogConfig{
+				Namespace: "default",
+				Name:      "logs",
+				Sink:      xds.AccessLogSinkStdout,
+				JSONFormat: []xds.AccessLogJSONField{
+					{Key: "method", Value: "%REQ(:METHOD)%"},
+					{Key: "path", Value: "%REQ(:PATH)%"},
+					{Key: "status", Value: "%GRPC_STATUS%"},
+				},
+			},
+		},
+		{
+			name: "stdout with both text and JSON format",
+			spec: map[string]interface{}{
+				"format": map[string]interface{}{
+					"text": "%REQ(:PATH)%\n",
+					"json": map[string]interface{}{"path": "%REQ(:PATH)%"},
+				},
+			},
+			wantErr: errInvalidAccessLogConfig,
+		},
+		{
+			name: "grpc with cluster name",
+			spec: map[string]interface{}{
+				"sink": "grpc",
+				"grpcService": map[string]interface{}{
+					"clusterName": "als",
+					"logName":     "greeter",
+				},
+			},
+			want: xds.AccessLogConfig{
+				Namespace:       "default",
+				Name:            "logs",
+				Sink:            xds.AccessLogSinkGRPC,
+				GRPCClusterName: "als",
+				LogName:         "greeter",
+			},
+		},
+		{
+			name: "grpc with both cluster name and address",
+			spec: map[string]interface{}{
+				"sink": "grpc",
+				"grpcService": map[string]interface{}{
+					"clusterName": "als",
+					"address":     "als.xds.svc.cluster.local:9000",
+				},
+			},
+			wantErr: errInvalidAccessLogConfig,
+		},
+		{
+			name:    "grpc without cluster name or address",
+			spec:    map[string]interface{}{"sink": "grpc"},
+			wantErr: errInvalidAccessLogConfig,
+		},
+		{
+			name: "grpc with format",
+			spec: map[string]interface{}{
+				"sink":        "grpc",
+				"grpcService": map[string]interface{}{"clusterName": "als"},
+				"format":      map[string]interface{}{"text": "%REQ(:PATH)%\n"},
+			},
+			wantErr: errInvalidAccessLogConfig,
+		},
+		{
+			name:    "unknown sink",
+			spec:    map[string]interface{}{"sink": "file"},
+			wantErr: errInvalidAccessLogConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := accessLogConfigFromUnstructured(newTestCustomResource("AccessLogConfig", "logs", tt.spec))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("accessLogConfigFromUnstructured() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Compare(tt.want) != 0 {
+				t.Errorf("accessLogConfigFromUnstructured() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
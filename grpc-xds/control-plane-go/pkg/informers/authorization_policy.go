@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var errInvalidAuthorizationPolicyAction = errors.New("invalid AuthorizationPolicy action, must be ALLOW or DENY")
+
+// authorizationPolicySpec is the `spec` of `AuthorizationPolicy` custom resources,
+// see `k8s/control-plane/base/crd-authorization-policies.yaml`.
+type authorizationPolicySpec struct {
+	Action string                    `json:"action,omitempty"`
+	Rules  []authorizationPolicyRule `json:"rules,omitempty"`
+}
+
+type authorizationPolicyRule struct {
+	From struct {
+		IPBlocks        []string `json:"ipBlocks,omitempty"`
+		ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	} `json:"from,omitempty"`
+	To struct {
+		Methods      []string `json:"methods,omitempty"`
+		PathPrefixes []string `json:"pathPrefixes,omitempty"`
+	} `json:"to,omitempty"`
+}
+
+func (m *Manager) handleAuthorizationPolicies(ctx context.Context, logger logr.Logger, namespace string, objs []*unstructured.Unstructured) {
+	var policies []xds.AuthorizationPolicy
+	for _, obj := range objs {
+		policy, err := authorizationPolicyFromUnstructured(obj)
+		if err != nil {
+			logger.Error(err, "Skipping AuthorizationPolicy", "name", obj.GetName())
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	logger.V(2).Info("Informer resource update", "authorizationPolicies", policies)
+	if err := m.xdsCache.UpdateAuthorizationPolicies(ctx, logger, m.kubecontext, namespace, policies); err != nil {
+		logger.Error(err, "Could not update the xDS resource cache with authorization policies", "authorizationPolicies", policies)
+	}
+}
+
+func authorizationPolicyFromUnstructured(obj *unstructured.Unstructured) (xds.AuthorizationPolicy, error) {
+	var spec authorizationPolicySpec
+	if err := specFromUnstructured(obj, &spec); err != nil {
+		return xds.AuthorizationPolicy{}, err
+	}
+	action := spec.Action
+	if action == "" {
+		action = xds.AuthorizationPolicyActionAllow
+	}
+	if action != xds.AuthorizationPolicyActionAllow && action != xds.AuthorizationPolicyActionDeny {
+		return xds.AuthorizationPolicy{}, fmt.Errorf("%w: action=%s", errInvalidAuthorizationPolicyAction, spec.Action)
+	}
+	rules := make([]xds.AuthorizationRule, len(spec.Rules))
+	for i, rule := range spec.Rules {
+		for _, ipBlock := range rule.From.IPBlocks {
+			if _, _, err := net.ParseCIDR(ipBlock); err != nil {
+				return xds.AuthorizationPolicy{}, fmt.Errorf("invalid ipBlock in rule %d: %w", i, err)
+			}
+		}
+		rules[i] = xds.AuthorizationRule{
+			IPBlocks:        rule.From.IPBlocks,
+			ServiceAccounts: rule.From.ServiceAccounts,
+			Methods:         rule.To.Methods,
+			PathPrefixes:    rule.To.PathPrefixes,
+		}
+	}
+	return xds.AuthorizationPolicy{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Action:    action,
+		Rules:     rules,
+	}, nil
+}
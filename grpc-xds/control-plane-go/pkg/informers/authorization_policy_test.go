@@ -0,0 +1,128 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+// newTestCustomResource returns a custom resource in the `default` namespace with the provided spec.
+func newTestCustomResource(kind string, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": customResourceGroupVersion.String(),
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"namespace": "default",
+			"name":      name,
+		},
+	}}
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	return u
+}
+
+func TestAuthorizationPolicyFromUnstructured(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    xds.AuthorizationPolicy
+		wantErr error
+	}{
+		{
+			name: "no spec defaults to ALLOW without rules",
+			spec: nil,
+			want: xds.AuthorizationPolicy{
+				Namespace: "default",
+				Name:      "policy",
+				Action:    xds.AuthorizationPolicyActionAllow,
+				Rules:     []xds.AuthorizationRule{},
+			},
+		},
+		{
+			name: "DENY with rules",
+			spec: map[string]interface{}{
+				"action": "DENY",
+				"rules": []interface{}{
+					map[string]interface{}{
+						"from": map[string]interface{}{
+							"ipBlocks":        []interface{}{"10.0.0.0/8"},
+							"serviceAccounts": []interface{}{"spiffe://example.com/ns/default/sa/client"},
+						},
+						"to": map[string]interface{}{
+							"methods":      []interface{}{"POST"},
+							"pathPrefixes": []interface{}{"/helloworld.Greeter/"},
+						},
+					},
+				},
+			},
+			want: xds.AuthorizationPolicy{
+				Namespace: "default",
+				Name:      "policy",
+				Action:    xds.AuthorizationPolicyActionDeny,
+				Rules: []xds.AuthorizationRule{{
+					IPBlocks:        []string{"10.0.0.0/8"},
+					ServiceAccounts: []string{"spiffe://example.com/ns/default/sa/client"},
+					Methods:         []string{"POST"},
+					PathPrefixes:    []string{"/helloworld.Greeter/"},
+				}},
+			},
+		},
+		{
+			name:    "invalid action",
+			spec:    map[string]interface{}{"action": "AUDIT"},
+			wantErr: errInvalidAuthorizationPolicyAction,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authorizationPolicyFromUnstructured(newTestCustomResource("AuthorizationPolicy", "policy", tt.spec))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authorizationPolicyFromUnstructured() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.Compare(tt.want) != 0 {
+				t.Errorf("authorizationPolicyFromUnstructured() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizationPolicyFromUnstructuredRejectsInvalidIPBlocks(t *testing.T) {
+	spec := map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"from": map[string]interface{}{
+					"ipBlocks": []interface{}{"10.0.0.0"},
+				},
+			},
+		},
+	}
+	if _, err := authorizationPolicyFromUnstructured(newTestCustomResource("AuthorizationPolicy", "policy", spec)); err == nil {
+		t.Error("authorizationPolicyFromUnstructured() error = nil, want an error for an IP address without prefix length")
+	}
+}
+
+func TestSpecFromUnstructuredRejectsUnexpectedTypes(t *testing.T) {
+	var spec authorizationPolicySpec
+	u := newTestCustomResource("AuthorizationPolicy", "policy", map[string]interface{}{"rules": "all"})
+	if err := specFromUnstructured(u, &spec); err == nil {
+		t.Errorf("specFromUnstructured() error = nil, spec = %+v, want an error", spec)
+	}
+}
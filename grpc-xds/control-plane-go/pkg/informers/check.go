@@ -0,0 +1,225 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	errMissingPermissions = errors.New("missing Kubernetes RBAC permissions")
+	errAPIServerCheck     = errors.New("could not connect to the Kubernetes API server")
+)
+
+// readVerbs are the verbs required by informers.
+var readVerbs = []string{"get", "list", "watch"}
+
+// Permission is a Kubernetes RBAC permission that the control plane requires, see `Check()`.
+type Permission struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verbs       []string
+	// Namespace is the namespace where the permission is required. If empty, the permission
+	// is required in the namespaces of the informer configuration.
+	Namespace     string
+	ClusterScoped bool
+	// RequiredBy is the flag or feature that requires the permission, if it is optional.
+	RequiredBy string
+}
+
+// kubecontextCheck is the permissions to check for a kubecontext.
+type kubecontextCheck struct {
+	context     string
+	permissions []Permission
+	// namespaces of the informer configuration. The empty namespace means all namespaces.
+	namespaces []string
+}
+
+// Check verifies that the control plane can connect to the Kubernetes API server of each kubecontext,
+// and that it has the RBAC permissions required by the informers in the namespaces of the informer
+// configuration, using `SelfSubjectAccessReview`s. It returns an error that lists all missing permissions.
+// It does not start any informers.
+//
+// The informers of the first kubecontext also watch the `dataPlaneTLSSecret`, if it is not empty.
+// The `serverPermissions` are checked for the kubecontext of the cluster where the control plane runs,
+// i.e., the empty kubecontext name.
+func Check(ctx context.Context, logger logr.Logger, kubecontexts []Kubecontext, dataPlaneTLSSecret string, serverPermissions []Permission) error {
+	checks := make([]kubecontextCheck, 0, len(kubecontexts)+1)
+	for i, kubecontext := range kubecontexts {
+		permissions, err := informerPermissions(i == 0, dataPlaneTLSSecret)
+		if err != nil {
+			return err
+		}
+		var namespaces []string
+		for _, config := range ScopeToNamespaces(kubecontext.Informers, WatchNamespaces()) {
+			namespaces = appendMissing(namespaces, config.Namespace)
+		}
+		checks = append(checks, kubecontextCheck{context: kubecontext.Context, permissions: permissions, namespaces: namespaces})
+	}
+	if len(serverPermissions) > 0 {
+		i := slices.IndexFunc(checks, func(check kubecontextCheck) bool { return check.context == "" })
+		if i < 0 {
+			checks = append(checks, kubecontextCheck{})
+			i = len(checks) - 1
+		}
+		checks[i].permissions = append(checks[i].permissions, serverPermissions...)
+	}
+	var errs []error
+	for _, check := range checks {
+		if err := checkKubecontext(ctx, logger.WithValues("kubecontext", check.context), check); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkKubecontext(ctx context.Context, logger logr.Logger, check kubecontextCheck) error {
+	clientset, err := NewClientSet(ctx, check.context)
+	if err != nil {
+		return fmt.Errorf("%w: kubecontext=%s: %w", errAPIServerCheck, check.context, err)
+	}
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("%w: kubecontext=%s, check the kubeconfig file and the network connectivity: %w", errAPIServerCheck, check.context, err)
+	}
+	logger.V(2).Info("Connected to the Kubernetes API server", "version", serverVersion.GitVersion)
+	missing, err := missingPermissions(ctx, clientset, check.permissions, check.namespaces)
+	if err != nil {
+		return fmt.Errorf("could not check permissions for kubecontext=%s: %w", check.context, err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w for kubecontext=%s, grant them to the Kubernetes service account of the control plane, "+
+			"see the ClusterRole and Roles in k8s/control-plane/base, or with -watch-namespaces, the Role in k8s/control-plane/components/watch-namespaces:\n  %s",
+			errMissingPermissions, check.context, strings.Join(missing, "\n  "))
+	}
+	logger.V(2).Info("All required permissions are granted", "namespaces", check.namespaces)
+	return nil
+}
+
+// informerPermissions returns the permissions required by the informers enabled via flags.
+// If `watchTLSSecret` is true and `dataPlaneTLSSecret` is not empty, the permissions include
+// read access to the Secret.
+func informerPermissions(watchTLSSecret bool, dataPlaneTLSSecret string) ([]Permission, error) {
+	permissions := []Permission{
+		{Group: "", Resource: "services", Verbs: readVerbs},
+		// For the gRPC service config status annotation.
+		{Group: "", Resource: "services", Verbs: []string{"patch"}},
+		{Group: "", Resource: "endpoints", Verbs: readVerbs},
+		{Group: "discovery.k8s.io", Resource: "endpointslices", Verbs: readVerbs},
+		{Group: "", Resource: "pods", Verbs: readVerbs},
+	}
+	if localityLB {
+		permissions = append(permissions, Permission{Group: "", Resource: "nodes", Verbs: readVerbs, ClusterScoped: true, RequiredBy: "-" + localityLBFlag})
+	}
+	customResources := []struct {
+		enabled  bool
+		resource string
+		flag     string
+	}{
+		{enabled: watchAuthorizationPolicies, resource: "authorizationpolicies", flag: watchAuthorizationPoliciesFlag},
+		{enabled: watchGRPCRoutes, resource: grpcRouteResource, flag: watchGRPCRoutesFlag},
+		{enabled: watchAccessLogConfigs, resource: "accesslogconfigs", flag: watchAccessLogConfigsFlag},
+		{enabled: watchExtAuthzPolicies, resource: "extauthzpolicies", flag: watchExtAuthzPoliciesFlag},
+		{enabled: watchRateLimitPolicies, resource: "ratelimitpolicies", flag: watchRateLimitPoliciesFlag},
+	}
+	for _, customResource := range customResources {
+		if customResource.enabled {
+			permissions = append(permissions, Permission{Group: customResourceGroupVersion.Group, Resource: customResource.resource, Verbs: readVerbs, RequiredBy: "-" + customResource.flag})
+		}
+	}
+	if watchGRPCRoutes {
+		permissions = append(permissions, Permission{Group: customResourceGroupVersion.Group, Resource: grpcRouteResource, Subresource: "status", Verbs: []string{"update"}, RequiredBy: "-" + watchGRPCRoutesFlag})
+	}
+	if watchTLSSecret && dataPlaneTLSSecret != "" {
+		namespace, _, err := splitTLSSecretName(dataPlaneTLSSecret)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, Permission{Group: "", Resource: "secrets", Verbs: readVerbs, Namespace: namespace, RequiredBy: "the dataPlaneTlsSecret xDS feature"})
+	}
+	return permissions, nil
+}
+
+// missingPermissions returns a description of each missing permission. The empty namespace means all namespaces.
+func missingPermissions(ctx context.Context, clientset kubernetes.Interface, permissions []Permission, namespaces []string) ([]string, error) {
+	var missing []string
+	for _, permission := range permissions {
+		permissionNamespaces := namespaces
+		switch {
+		case permission.ClusterScoped:
+			permissionNamespaces = []string{""}
+		case permission.Namespace != "":
+			permissionNamespaces = []string{permission.Namespace}
+		}
+		for _, namespace := range permissionNamespaces {
+			for _, verb := range permission.Verbs {
+				review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+					Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+						ResourceAttributes: &authorizationv1.ResourceAttributes{
+							Namespace:   namespace,
+							Verb:        verb,
+							Group:       permission.Group,
+							Resource:    permission.Resource,
+							Subresource: permission.Subresource,
+						},
+					},
+				}, metav1.CreateOptions{})
+				if err != nil {
+					return nil, fmt.Errorf("could not create SelfSubjectAccessReview for verb=%s resource=%s namespace=%s: %w", verb, permission.Resource, namespace, err)
+				}
+				if !review.Status.Allowed {
+					missing = append(missing, describeMissingPermission(permission, namespace, verb, review.Status.Reason))
+				}
+			}
+		}
+	}
+	return missing, nil
+}
+
+func describeMissingPermission(permission Permission, namespace string, verb string, reason string) string {
+	group := permission.Group
+	if group == "" {
+		group = `""`
+	}
+	resource := permission.Resource
+	if permission.Subresource != "" {
+		resource += "/" + permission.Subresource
+	}
+	scope := "in all namespaces"
+	if namespace != "" {
+		scope = "in namespace " + namespace
+	}
+	if permission.ClusterScoped {
+		scope = "(cluster-scoped)"
+	}
+	description := fmt.Sprintf("apiGroups: [%s], resources: [%s], verbs: [%s] %s", group, resource, verb, scope)
+	if permission.RequiredBy != "" {
+		description += ", required by " + permission.RequiredBy
+	}
+	if reason != "" {
+		description += ", reason: " + reason
+	}
+	return description
+}
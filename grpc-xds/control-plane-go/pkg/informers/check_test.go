@@ -0,0 +1,211 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDescribeMissingPermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission Permission
+		namespace  string
+		reason     string
+		want       string
+	}{
+		{
+			name:       "core group in a namespace",
+			permission: Permission{Group: "", Resource: "services"},
+			namespace:  "default",
+			want:       `apiGroups: [""], resources: [services], verbs: [watch] in namespace default`,
+		},
+		{
+			name:       "named group in all namespaces",
+			permission: Permission{Group: "discovery.k8s.io", Resource: "endpointslices"},
+			namespace:  "",
+			want:       `apiGroups: [discovery.k8s.io], resources: [endpointslices], verbs: [watch] in all namespaces`,
+		},
+		{
+			name:       "cluster-scoped with reason",
+			permission: Permission{Group: "", Resource: "nodes", ClusterScoped: true},
+			namespace:  "",
+			reason:     "no RBAC policy matched",
+			want:       `apiGroups: [""], resources: [nodes], verbs: [watch] (cluster-scoped), reason: no RBAC policy matched`,
+		},
+		{
+			name:       "subresource required by a flag",
+			permission: Permission{Group: "xds.example.com", Resource: "grpcroutes", Subresource: "status", RequiredBy: "-watch-grpc-routes"},
+			namespace:  "default",
+			want:       `apiGroups: [xds.example.com], resources: [grpcroutes/status], verbs: [watch] in namespace default, required by -watch-grpc-routes`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeMissingPermission(tt.permission, tt.namespace, "watch", tt.reason); got != tt.want {
+				t.Errorf("describeMissingPermission() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInformerPermissions(t *testing.T) {
+	previousLocalityLB, previousWatchGRPCRoutes := localityLB, watchGRPCRoutes
+	t.Cleanup(func() {
+		localityLB, watchGRPCRoutes = previousLocalityLB, previousWatchGRPCRoutes
+	})
+	tests := []struct {
+		name               string
+		localityLB         bool
+		watchGRPCRoutes    bool
+		watchTLSSecret     bool
+		dataPlaneTLSSecret string
+		want               []string
+		wantAbsent         []string
+	}{
+		{
+			name:       "defaults",
+			localityLB: true,
+			want:       []string{"services", "services:patch", "endpointslices", "nodes"},
+			wantAbsent: []string{"grpcroutes", "grpcroutes/status", "secrets"},
+		},
+		{
+			name:       "nodes are not required without locality load balancing",
+			localityLB: false,
+			wantAbsent: []string{"nodes"},
+		},
+		{
+			name:            "custom resources and status subresource",
+			watchGRPCRoutes: true,
+			want:            []string{"grpcroutes", "grpcroutes/status:update"},
+		},
+		{
+			name:               "data plane TLS Secret in the first kubecontext",
+			watchTLSSecret:     true,
+			dataPlaneTLSSecret: "tls-ns/data-plane-tls",
+			want:               []string{"secrets@tls-ns"},
+		},
+		{
+			name:               "data plane TLS Secret in other kubecontexts",
+			watchTLSSecret:     false,
+			dataPlaneTLSSecret: "tls-ns/data-plane-tls",
+			wantAbsent:         []string{"secrets"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localityLB, watchGRPCRoutes = tt.localityLB, tt.watchGRPCRoutes
+			permissions, err := informerPermissions(tt.watchTLSSecret, tt.dataPlaneTLSSecret)
+			if err != nil {
+				t.Fatalf("informerPermissions() error = %v", err)
+			}
+			// Describe each permission as `resource[/subresource][:verb][@namespace]`, with and without details.
+			var got []string
+			for _, permission := range permissions {
+				resource := permission.Resource
+				if permission.Subresource != "" {
+					resource += "/" + permission.Subresource
+				}
+				got = append(got, resource)
+				for _, verb := range permission.Verbs {
+					got = append(got, resource+":"+verb)
+				}
+				if permission.Namespace != "" {
+					got = append(got, resource+"@"+permission.Namespace)
+				}
+			}
+			for _, want := range tt.want {
+				if !slices.Contains(got, want) {
+					t.Errorf("informerPermissions() = %v, want %s", got, want)
+				}
+			}
+			for _, absent := range tt.wantAbsent {
+				if slices.Contains(got, absent) {
+					t.Errorf("informerPermissions() = %v, want no %s", got, absent)
+				}
+			}
+		})
+	}
+}
+
+func TestMissingPermissions(t *testing.T) {
+	permissions := []Permission{
+		{Group: "", Resource: "services", Verbs: []string{"get", "patch"}},
+		{Group: "", Resource: "nodes", Verbs: []string{"list"}, ClusterScoped: true},
+		{Group: "xds.example.com", Resource: "grpcroutes", Subresource: "status", Verbs: []string{"update"}, RequiredBy: "-watch-grpc-routes"},
+		{Group: "", Resource: "secrets", Verbs: []string{"get"}, Namespace: "tls-ns"},
+	}
+	tests := []struct {
+		name string
+		// denied returns true if the SelfSubjectAccessReview is not allowed.
+		denied      func(attributes *authorizationv1.ResourceAttributes) bool
+		wantMissing []string
+	}{
+		{
+			name:        "all granted",
+			denied:      func(_ *authorizationv1.ResourceAttributes) bool { return false },
+			wantMissing: nil,
+		},
+		{
+			name: "partially granted",
+			denied: func(attributes *authorizationv1.ResourceAttributes) bool {
+				return attributes.Verb == "patch" ||
+					attributes.Subresource == "status" ||
+					(attributes.Resource == "services" && attributes.Namespace == "team-b")
+			},
+			wantMissing: []string{
+				`apiGroups: [""], resources: [services], verbs: [patch] in namespace team-a, reason: denied by test`,
+				`apiGroups: [""], resources: [services], verbs: [get] in namespace team-b, reason: denied by test`,
+				`apiGroups: [""], resources: [services], verbs: [patch] in namespace team-b, reason: denied by test`,
+				`apiGroups: [xds.example.com], resources: [grpcroutes/status], verbs: [update] in namespace team-a, required by -watch-grpc-routes, reason: denied by test`,
+				`apiGroups: [xds.example.com], resources: [grpcroutes/status], verbs: [update] in namespace team-b, required by -watch-grpc-routes, reason: denied by test`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			var reviewed []string
+			clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).DeepCopy()
+				attributes := review.Spec.ResourceAttributes
+				reviewed = append(reviewed, attributes.Resource+"@"+attributes.Namespace)
+				review.Status.Allowed = !tt.denied(attributes)
+				if !review.Status.Allowed {
+					review.Status.Reason = "denied by test"
+				}
+				return true, review, nil
+			})
+			missing, err := missingPermissions(context.Background(), clientset, permissions, []string{"team-a", "team-b"})
+			if err != nil {
+				t.Fatalf("missingPermissions() error = %v", err)
+			}
+			if !slices.Equal(missing, tt.wantMissing) {
+				t.Errorf("missingPermissions() =\n  %v\nwant\n  %v", missing, tt.wantMissing)
+			}
+			wantReviewed := []string{"services@team-a", "services@team-a", "services@team-b", "services@team-b", "nodes@", "grpcroutes@team-a", "grpcroutes@team-b", "secrets@tls-ns"}
+			if !slices.Equal(reviewed, wantReviewed) {
+				t.Errorf("SelfSubjectAccessReviews = %v, want %v", reviewed, wantReviewed)
+			}
+		})
+	}
+}
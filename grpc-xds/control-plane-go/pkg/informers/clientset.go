@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"github.com/go-logr/logr"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -39,6 +40,20 @@ func NewClientSet(ctx context.Context, kubecontextName string) (*kubernetes.Clie
 	return clientset, nil
 }
 
+// NewDynamicClient creates a client for custom resources, using the same config as `NewClientSet()`.
+func NewDynamicClient(ctx context.Context, kubecontextName string) (*dynamic.DynamicClient, error) {
+	logger := logging.FromContext(ctx)
+	config, err := clientConfig(logger, kubecontextName)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Kubernetes config for context=%s: %w", kubecontextName, err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Kubernetes dynamic client for context=%s and config=%+v: %w", kubecontextName, config, err)
+	}
+	return dynamicClient, nil
+}
+
 // clientConfig uses in-cluster config if the values of the kubeconfig flag
 // and KUBECONFIG environment variable are empty. Otherwise, the specified
 // kubeconfig files are parsed, and the provided kubecontextName is selected
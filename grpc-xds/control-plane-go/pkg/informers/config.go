@@ -14,12 +14,57 @@
 
 package informers
 
+import (
+	"slices"
+)
+
 // Config represents a collection of Kubernetes services in a namespace.
 type Config struct {
 	Namespace string   `yaml:"namespace"`
 	Services  []string `yaml:"services"`
 }
 
+// ScopeToNamespaces returns the informer configs for the provided namespaces only.
+// A config with an empty namespace, which means all namespaces, is expanded into one config
+// per provided namespace, and its services are merged with any existing config for that namespace.
+// If the provided list of namespaces is empty, the configs are returned unchanged.
+func ScopeToNamespaces(configs []Config, namespaces []string) []Config {
+	if len(namespaces) == 0 {
+		return configs
+	}
+	servicesByNamespace := map[string][]string{}
+	for _, config := range configs {
+		if config.Namespace == "" {
+			for _, namespace := range namespaces {
+				servicesByNamespace[namespace] = appendMissing(servicesByNamespace[namespace], config.Services...)
+			}
+			continue
+		}
+		if slices.Contains(namespaces, config.Namespace) {
+			servicesByNamespace[config.Namespace] = appendMissing(servicesByNamespace[config.Namespace], config.Services...)
+		}
+	}
+	scopedConfigs := make([]Config, 0, len(servicesByNamespace))
+	for _, namespace := range namespaces {
+		if services, exists := servicesByNamespace[namespace]; exists {
+			scopedConfigs = append(scopedConfigs, Config{
+				Namespace: namespace,
+				Services:  services,
+			})
+		}
+	}
+	return scopedConfigs
+}
+
+func appendMissing(values []string, newValues ...string) []string {
+	for _, value := range newValues {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // Kubecontext represents a kubeconfig context,
 // containing a list of `informer.Config`s.
 type Kubecontext struct {
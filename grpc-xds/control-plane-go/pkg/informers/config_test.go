@@ -0,0 +1,167 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/stream/v3"
+	"github.com/go-logr/logr"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestScopeToNamespaces(t *testing.T) {
+	configs := []Config{
+		{Namespace: "", Services: []string{"greeter-leaf"}},
+		{Namespace: "team-a", Services: []string{"greeter-intermediary", "greeter-leaf"}},
+		{Namespace: "team-b", Services: []string{"other"}},
+	}
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       []Config
+	}{
+		{
+			name:       "no namespaces returns the configs unchanged",
+			namespaces: nil,
+			want:       configs,
+		},
+		{
+			name:       "all namespaces config is expanded and merged without duplicates",
+			namespaces: []string{"team-a"},
+			want: []Config{
+				{Namespace: "team-a", Services: []string{"greeter-leaf", "greeter-intermediary"}},
+			},
+		},
+		{
+			name:       "configs are returned in the order of the namespaces",
+			namespaces: []string{"team-c", "team-b"},
+			want: []Config{
+				{Namespace: "team-c", Services: []string{"greeter-leaf"}},
+				{Namespace: "team-b", Services: []string{"greeter-leaf", "other"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScopeToNamespaces(configs, tt.namespaces)
+			if !slices.EqualFunc(got, tt.want, func(a Config, b Config) bool {
+				return a.Namespace == b.Namespace && slices.Equal(a.Services, b.Services)
+			}) {
+				t.Errorf("ScopeToNamespaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeToNamespacesSkipsUnlistedNamespaces(t *testing.T) {
+	configs := []Config{{Namespace: "team-a", Services: []string{"greeter-leaf"}}}
+	if got := ScopeToNamespaces(configs, []string{"team-b"}); len(got) != 0 {
+		t.Errorf("ScopeToNamespaces() = %+v, want no configs", got)
+	}
+}
+
+func TestWatchNamespaces(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "", want: nil},
+		{value: "team-a", want: []string{"team-a"}},
+		{value: " team-a, ,team-b ", want: []string{"team-a", "team-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			previous := watchNamespaces
+			watchNamespaces = tt.value
+			t.Cleanup(func() { watchNamespaces = previous })
+			if got := WatchNamespaces(); !slices.Equal(got, tt.want) {
+				t.Errorf("WatchNamespaces() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestScopedInformersSkipUnwatchedNamespaces verifies that, with `-watch-namespaces`,
+// EndpointSlices in namespaces that are not watched never reach an xDS resource snapshot,
+// even if the services config lists them for all namespaces.
+func TestScopedInformersSkipUnwatchedNamespaces(t *testing.T) {
+	previous := localityLB
+	localityLB = false
+	t.Cleanup(func() { localityLB = previous })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clientset := fake.NewSimpleClientset(
+		testEndpointSlice("foo", "greeter-foo", "10.0.0.1"),
+		testEndpointSlice("bar", "greeter-bar", "10.0.1.1"),
+	)
+	xdsCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+	node := &corev3.Node{Id: "test-node", Locality: &corev3.Locality{Zone: "zone-a"}}
+	cancelWatch := xdsCache.CreateWatch(&cachev3.Request{Node: node, TypeUrl: resource.ClusterType}, stream.NewStreamState(false, nil), make(chan cachev3.Response, 10))
+	t.Cleanup(cancelWatch)
+	m := &Manager{kubecontext: "test", clientset: clientset, xdsCache: xdsCache}
+
+	configs := []Config{{Namespace: "", Services: []string{"greeter-foo", "greeter-bar"}}}
+	for _, config := range ScopeToNamespaces(configs, []string{"foo"}) {
+		if err := m.AddEndpointSliceInformer(ctx, logr.Discard(), config); err != nil {
+			t.Fatalf("AddEndpointSliceInformer(%+v) error = %v", config, err)
+		}
+	}
+	if !m.WaitForCacheSync(ctx) {
+		t.Fatal("WaitForCacheSync() = false, want true")
+	}
+	m.Flush()
+
+	if _, found := xdsCache.GetGRPCApplication("test", "foo", "greeter-foo"); !found {
+		t.Error("GetGRPCApplication(foo, greeter-foo) not found, want found")
+	}
+	if _, found := xdsCache.GetGRPCApplication("test", "bar", "greeter-bar"); found {
+		t.Error("GetGRPCApplication(bar, greeter-bar) found, want not found")
+	}
+	snapshot, err := xdsCache.GetSnapshot(xds.ZoneHash{}.ID(node))
+	if err != nil {
+		t.Fatalf("GetSnapshot() error = %v", err)
+	}
+	clusters := snapshot.GetResources(resource.ClusterType)
+	if _, found := clusters["greeter-foo"]; !found {
+		t.Errorf("snapshot clusters = %v, want greeter-foo", clusters)
+	}
+	if _, found := clusters["greeter-bar"]; found {
+		t.Errorf("snapshot clusters = %v, want no greeter-bar", clusters)
+	}
+}
+
+func testEndpointSlice(namespace string, service string, address string) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      service + "-abcde",
+			Namespace: namespace,
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{address}}},
+		Ports:       []discoveryv1.EndpointPort{{Name: ptr("grpc"), Port: ptr(int32(50051))}},
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	informercache "k8s.io/client-go/tools/cache"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
+)
+
+// customResourceGroupVersion of the custom resources watched by the control plane.
+var customResourceGroupVersion = schema.GroupVersion{Group: "xds.example.com", Version: "v1alpha1"}
+
+// AddCustomResourceInformers creates informers for the custom resources enabled via flags,
+// in the namespace of the provided config.
+func (m *Manager) AddCustomResourceInformers(ctx context.Context, logger logr.Logger, config Config) error {
+	logger = logger.WithValues("component", "informers", "kubecontext", m.kubecontext, "namespace", config.Namespace)
+	if watchAuthorizationPolicies {
+		if err := m.addCustomResourceInformer(ctx, logger, config, "AuthorizationPolicy", "authorizationpolicies", m.handleAuthorizationPolicies); err != nil {
+			return err
+		}
+	}
+	if watchGRPCRoutes {
+		statusWriter := newCustomResourceStatusWriter(logger.WithValues("kind", "GRPCRoute"), grpcRouteResource, func(ctx context.Context, obj *unstructured.Unstructured) error {
+			_, err := m.dynamicClient.Resource(customResourceGroupVersion.WithResource(grpcRouteResource)).Namespace(obj.GetNamespace()).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+		handleGRPCRoutes := func(ctx context.Context, logger logr.Logger, _ string, objs []*unstructured.Unstructured) {
+			m.handleGRPCRoutes(ctx, logger, config, statusWriter, objs)
+		}
+		if err := m.addCustomResourceInformer(ctx, logger, config, "GRPCRoute", grpcRouteResource, handleGRPCRoutes); err != nil {
+			return err
+		}
+		go statusWriter.run(ctx)
+	}
+	if watchAccessLogConfigs {
+		if err := m.addCustomResourceInformer(ctx, logger, config, "AccessLogConfig", "accesslogconfigs", m.handleAccessLogConfigs); err != nil {
+			return err
+		}
+	}
+	if watchExtAuthzPolicies {
+		if err := m.addCustomResourceInformer(ctx, logger, config, "ExtAuthzPolicy", "extauthzpolicies", m.handleExtAuthzPolicies); err != nil {
+			return err
+		}
+	}
+	if watchRateLimitPolicies {
+		if err := m.addCustomResourceInformer(ctx, logger, config, "RateLimitPolicy", "ratelimitpolicies", m.handleRateLimitPolicies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addCustomResourceInformer creates and starts an informer for the custom resources of the provided kind.
+// After each event, `handle` is called with all custom resources in the informer cache.
+func (m *Manager) addCustomResourceInformer(ctx context.Context, logger logr.Logger, config Config, kind string, resource string, handle func(ctx context.Context, logger logr.Logger, namespace string, objs []*unstructured.Unstructured)) error {
+	logger = logger.WithValues("kind", kind)
+	logger.V(2).Info("Creating informer for custom resources")
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(m.dynamicClient, 0, config.Namespace, nil)
+	informer := factory.ForResource(customResourceGroupVersion.WithResource(resource)).Informer()
+	// Coalesce bursts of events into a single xDS resource update.
+	eventDebouncer := NewDebouncer(debounceWindow(), debounceMaxWindow(), func(events []string) {
+		logger := logger.WithValues("events", len(events))
+		handle(ctx, logger, config.Namespace, listUnstructured(logger, informer))
+	})
+	m.debouncers = append(m.debouncers, eventDebouncer)
+	handleEvent := func(eventType string) {
+		metrics.K8sWatchEvent(kind, eventType)
+		eventDebouncer.Add(eventType)
+	}
+	registration, err := informer.AddEventHandler(informercache.ResourceEventHandlerFuncs{
+		AddFunc: func(_ interface{}) {
+			handleEvent("add")
+		},
+		UpdateFunc: func(_, _ interface{}) {
+			handleEvent("update")
+		},
+		DeleteFunc: func(_ interface{}) {
+			handleEvent("delete")
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not add informer event handler for kind=%s kubecontext=%s namespace=%s: %w", kind, m.kubecontext, config.Namespace, err)
+	}
+	m.informers = append(m.informers, informer)
+	m.handlersSynced = append(m.handlersSynced, registration.HasSynced)
+	go func() {
+		logger.V(2).Info("Starting informer for custom resources")
+		informer.Run(ctx.Done())
+	}()
+	return nil
+}
+
+func listUnstructured(logger logr.Logger, informer informercache.SharedIndexInformer) []*unstructured.Unstructured {
+	var objs []*unstructured.Unstructured
+	for _, obj := range informer.GetIndexer().List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			logger.Error(fmt.Errorf("%w: expected *unstructured.Unstructured, got %T", errUnexpectedType, obj), "Skipping custom resource")
+			continue
+		}
+		objs = append(objs, u)
+	}
+	return objs
+}
+
+// specFromUnstructured converts the `spec` field of the custom resource to the provided type.
+func specFromUnstructured(u *unstructured.Unstructured, spec interface{}) error {
+	specMap, found, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil {
+		return fmt.Errorf("invalid spec in %s %s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	if !found {
+		return nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specMap, spec); err != nil {
+		return fmt.Errorf("could not convert spec of %s %s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	informercache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// customResourceStatusMaxAttempts is the number of attempts to update the status of a custom resource.
+const customResourceStatusMaxAttempts = 5
+
+// customResourceStatusWriter writes `status.conditions` of custom resources from a work queue,
+// so that computing xDS resources from informer events does not wait for requests to the API server.
+// The work queue holds each custom resource at most once, and only the latest pending status of
+// each custom resource is written.
+type customResourceStatusWriter struct {
+	logger   logr.Logger
+	resource string
+	queue    workqueue.RateLimitingInterface
+	// mu guards pending.
+	mu sync.Mutex
+	// pending has the custom resources with updated status conditions, keyed by namespace/name.
+	pending map[string]*unstructured.Unstructured
+	// updateStatus writes the status of the custom resource.
+	updateStatus func(ctx context.Context, obj *unstructured.Unstructured) error
+}
+
+func newCustomResourceStatusWriter(logger logr.Logger, resource string, updateStatus func(ctx context.Context, obj *unstructured.Unstructured) error) *customResourceStatusWriter {
+	return &customResourceStatusWriter{
+		logger:       logger,
+		resource:     resource,
+		pending:      map[string]*unstructured.Unstructured{},
+		updateStatus: updateStatus,
+		queue: workqueue.NewRateLimitingQueueWithConfig(
+			workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{Name: resource + "-status"},
+		),
+	}
+}
+
+// enqueue schedules a status update of the custom resource with the condition, unless the
+// custom resource already has the same condition.
+func (w *customResourceStatusWriter) enqueue(obj *unstructured.Unstructured, condition metav1.Condition) {
+	key, err := informercache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		w.logger.Error(err, "Could not create key for custom resource", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return
+	}
+	updated, changed, err := withStatusCondition(w.logger, obj, condition)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil || !changed {
+		// The custom resource in the informer cache is newer than any pending status update.
+		delete(w.pending, key)
+		if err != nil {
+			w.logger.Error(err, "Could not set status condition", "name", obj.GetName(), "condition", condition)
+		}
+		return
+	}
+	w.pending[key] = updated
+	w.queue.Add(key)
+}
+
+// run processes the queued custom resources until the context is done.
+func (w *customResourceStatusWriter) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		w.queue.ShutDown()
+	}()
+	for w.processNext(ctx) {
+	}
+}
+
+func (w *customResourceStatusWriter) processNext(ctx context.Context) bool {
+	item, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(item)
+	key, ok := item.(string)
+	if !ok {
+		w.queue.Forget(item)
+		return true
+	}
+	w.mu.Lock()
+	updated, found := w.pending[key]
+	w.mu.Unlock()
+	if !found {
+		w.queue.Forget(item)
+		return true
+	}
+	if err := w.updateStatus(ctx, updated); err != nil {
+		if attempt := w.queue.NumRequeues(item) + 1; attempt < customResourceStatusMaxAttempts {
+			w.logger.V(1).Info("Warning: retrying update of status conditions", "resource", w.resource, "name", key, "attempt", attempt, "error", err.Error())
+			w.queue.AddRateLimited(item)
+			return true
+		}
+		w.logger.Error(err, "Could not update status conditions", "resource", w.resource, "name", key)
+	} else {
+		w.logger.V(2).Info("Updated status conditions", "resource", w.resource, "name", key)
+	}
+	w.mu.Lock()
+	if w.pending[key] == updated {
+		delete(w.pending, key)
+	}
+	w.mu.Unlock()
+	w.queue.Forget(item)
+	return true
+}
+
+// withStatusCondition returns a copy of the custom resource with the condition in `status.conditions`,
+// and false if the custom resource already has the same condition.
+func withStatusCondition(logger logr.Logger, obj *unstructured.Unstructured, condition metav1.Condition) (*unstructured.Unstructured, bool, error) {
+	conditionsSlice, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		logger.Error(err, "Ignoring invalid status conditions", "name", obj.GetName())
+	}
+	conditions := make([]metav1.Condition, 0, len(conditionsSlice)+1)
+	for _, conditionObj := range conditionsSlice {
+		conditionMap, ok := conditionObj.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var existing metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(conditionMap, &existing); err != nil {
+			continue
+		}
+		conditions = append(conditions, existing)
+	}
+	if existing := meta.FindStatusCondition(conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status &&
+		existing.Reason == condition.Reason &&
+		existing.Message == condition.Message &&
+		existing.ObservedGeneration == condition.ObservedGeneration {
+		return obj, false, nil
+	}
+	meta.SetStatusCondition(&conditions, condition)
+	conditionsSlice = make([]interface{}, len(conditions))
+	for i := range conditions {
+		conditionMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&conditions[i])
+		if err != nil {
+			return nil, false, fmt.Errorf("could not convert status condition %+v: %w", conditions[i], err)
+		}
+		conditionsSlice[i] = conditionMap
+	}
+	updated := obj.DeepCopy()
+	if err := unstructured.SetNestedSlice(updated.Object, conditionsSlice, "status", "conditions"); err != nil {
+		return nil, false, fmt.Errorf("could not set status conditions: %w", err)
+	}
+	return updated, true, nil
+}
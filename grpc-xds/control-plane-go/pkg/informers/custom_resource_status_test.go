@@ -0,0 +1,120 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCustomResourceStatusWriter(t *testing.T) {
+	obj := newTestCustomResource("GRPCRoute", "route", nil)
+	obj.SetGeneration(3)
+	var updates []*unstructured.Unstructured
+	w := newCustomResourceStatusWriter(logr.Discard(), grpcRouteResource, func(_ context.Context, obj *unstructured.Unstructured) error {
+		updates = append(updates, obj)
+		return nil
+	})
+	t.Cleanup(w.queue.ShutDown)
+
+	accepted := grpcRouteCondition(obj, nil)
+	w.enqueue(obj, accepted)
+	w.enqueue(obj, accepted)
+	if got := w.queue.Len(); got != 1 {
+		t.Fatalf("queue length after enqueueing the same custom resource twice = %d, want 1", got)
+	}
+	if !w.processNext(context.Background()) {
+		t.Fatal("processNext() = false, want true")
+	}
+	if len(updates) != 1 {
+		t.Fatalf("number of status updates = %d, want 1", len(updates))
+	}
+	assertStatusCondition(t, updates[0], accepted)
+
+	// The update event of the custom resource with the new status must not lead to another update.
+	w.enqueue(updates[0], accepted)
+	if got := w.queue.Len(); got != 0 {
+		t.Errorf("queue length after enqueueing a custom resource with an up-to-date status = %d, want 0", got)
+	}
+
+	// Only the latest pending status is written.
+	conflict := grpcRouteCondition(obj, errGRPCRouteConflict)
+	invalid := grpcRouteCondition(obj, errInvalidGRPCRoute)
+	w.enqueue(updates[0], conflict)
+	w.enqueue(updates[0], invalid)
+	if !w.processNext(context.Background()) {
+		t.Fatal("processNext() = false, want true")
+	}
+	if len(updates) != 2 {
+		t.Fatalf("number of status updates = %d, want 2", len(updates))
+	}
+	assertStatusCondition(t, updates[1], invalid)
+	if got := w.queue.Len(); got != 0 {
+		t.Errorf("queue length after writing the latest status = %d, want 0", got)
+	}
+}
+
+func TestCustomResourceStatusWriterRetries(t *testing.T) {
+	obj := newTestCustomResource("GRPCRoute", "route", nil)
+	attempts := 0
+	w := newCustomResourceStatusWriter(logr.Discard(), grpcRouteResource, func(_ context.Context, _ *unstructured.Unstructured) error {
+		attempts++
+		return errors.New("conflict")
+	})
+	t.Cleanup(w.queue.ShutDown)
+
+	w.enqueue(obj, grpcRouteCondition(obj, nil))
+	for i := 0; i < customResourceStatusMaxAttempts; i++ {
+		if !w.processNext(context.Background()) {
+			t.Fatal("processNext() = false, want true")
+		}
+	}
+	if attempts != customResourceStatusMaxAttempts {
+		t.Errorf("number of attempts = %d, want %d", attempts, customResourceStatusMaxAttempts)
+	}
+	if got := w.queue.NumRequeues("default/route"); got != 0 {
+		t.Errorf("NumRequeues() after the last attempt = %d, want 0", got)
+	}
+}
+
+func assertStatusCondition(t *testing.T, obj *unstructured.Unstructured, want metav1.Condition) {
+	t.Helper()
+	conditionsSlice, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		t.Fatalf("invalid status conditions: %v", err)
+	}
+	var conditions []metav1.Condition
+	for _, conditionObj := range conditionsSlice {
+		var condition metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(conditionObj.(map[string]interface{}), &condition); err != nil {
+			t.Fatalf("could not convert status condition: %v", err)
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) != 1 {
+		t.Fatalf("status conditions = %+v, want one condition", conditions)
+	}
+	got := meta.FindStatusCondition(conditions, want.Type)
+	if got == nil || got.Status != want.Status || got.Reason != want.Reason || got.Message != want.Message || got.ObservedGeneration != want.ObservedGeneration {
+		t.Errorf("status condition = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer coalesces values added within a time window into a single call of its handler.
+//
+// The window starts with the first value after the previous call, and each value added within
+// the window extends it, so that a burst of values, e.g., informer events during a rolling
+// deployment, results in a single call after the burst ends. To guarantee that a continuous
+// stream of values does not delay the call indefinitely, the handler is always called no later
+// than the max window after the first value.
+//
+// The handler receives the values in the order they were added. Handler calls are serialized,
+// also with calls from `Flush()`, so a handler that takes longer than the window delays the next
+// call instead of overlapping with it. The handler must not call `Flush()`.
+type Debouncer[T any] struct {
+	// handleMu serializes handler calls. It is acquired before mu.
+	handleMu  sync.Mutex
+	mu        sync.Mutex
+	window    time.Duration
+	maxWindow time.Duration
+	handle    func(values []T)
+	values    []T
+	timer     *time.Timer
+	// deadline is the end of the max window of the pending values.
+	deadline time.Time
+	// generation identifies the current timer, so that stopped timers that already fired are ignored.
+	generation uint64
+}
+
+// NewDebouncer creates a debouncer that calls `handle` with the values added within the window.
+// If the window is zero or negative, `handle` is called immediately for each value.
+// A max window shorter than the window is increased to the window.
+func NewDebouncer[T any](window time.Duration, maxWindow time.Duration, handle func(values []T)) *Debouncer[T] {
+	return &Debouncer[T]{
+		window:    window,
+		maxWindow: max(window, maxWindow),
+		handle:    handle,
+	}
+}
+
+// Add adds the value to the current window, and restarts the window, unless that would end it
+// after the max window.
+func (d *Debouncer[T]) Add(value T) {
+	if d.window <= 0 {
+		d.handleMu.Lock()
+		defer d.handleMu.Unlock()
+		d.handle([]T{value})
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if len(d.values) == 0 {
+		d.deadline = now.Add(d.maxWindow)
+	}
+	d.values = append(d.values, value)
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.generation++
+	generation := d.generation
+	d.timer = time.AfterFunc(min(d.window, d.deadline.Sub(now)), func() {
+		d.fire(generation)
+	})
+}
+
+func (d *Debouncer[T]) fire(generation uint64) {
+	d.handleMu.Lock()
+	defer d.handleMu.Unlock()
+	d.mu.Lock()
+	if generation != d.generation {
+		d.mu.Unlock()
+		return
+	}
+	values := d.take()
+	d.mu.Unlock()
+	if len(values) > 0 {
+		d.handle(values)
+	}
+}
+
+// Flush calls the handler with the pending values immediately, if there are any,
+// instead of waiting for the end of the current window.
+// If a handler call is in progress, Flush waits for it to return first.
+func (d *Debouncer[T]) Flush() {
+	d.handleMu.Lock()
+	defer d.handleMu.Unlock()
+	d.mu.Lock()
+	values := d.take()
+	d.mu.Unlock()
+	if len(values) > 0 {
+		d.handle(values)
+	}
+}
+
+// take returns and removes the pending values, and stops the timer. Requires the lock.
+func (d *Debouncer[T]) take() []T {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.generation++
+	values := d.values
+	d.values = nil
+	return values
+}
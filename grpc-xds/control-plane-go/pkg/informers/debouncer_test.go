@@ -0,0 +1,159 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDebouncerZeroWindowCallsHandlerImmediately(t *testing.T) {
+	var calls [][]int
+	d := NewDebouncer(0, time.Second, func(values []int) {
+		calls = append(calls, values)
+	})
+	d.Add(1)
+	d.Add(2)
+	if len(calls) != 2 || !slices.Equal(calls[0], []int{1}) || !slices.Equal(calls[1], []int{2}) {
+		t.Errorf("handler calls = %v, want [[1] [2]]", calls)
+	}
+}
+
+func TestDebouncerCoalescesValues(t *testing.T) {
+	calls := make(chan []int, 10)
+	d := NewDebouncer(50*time.Millisecond, time.Minute, func(values []int) {
+		calls <- values
+	})
+	for i := 1; i <= 3; i++ {
+		d.Add(i)
+	}
+	select {
+	case values := <-calls:
+		if want := []int{1, 2, 3}; !slices.Equal(values, want) {
+			t.Errorf("handler values = %v, want %v", values, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not called")
+	}
+	select {
+	case values := <-calls:
+		t.Errorf("unexpected second handler call with values %v", values)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDebouncerMaxWindow(t *testing.T) {
+	calls := make(chan []int, 10)
+	const maxWindow = 200 * time.Millisecond
+	d := NewDebouncer(100*time.Millisecond, maxWindow, func(values []int) {
+		calls <- values
+	})
+	start := time.Now()
+	stop := time.After(2 * time.Second)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	// Adding values more often than the window would delay the handler indefinitely without the max window.
+	for i := 0; ; i++ {
+		select {
+		case values := <-calls:
+			if elapsed := time.Since(start); elapsed < maxWindow {
+				t.Errorf("handler called after %v, before the max window %v", elapsed, maxWindow)
+			}
+			if len(values) == 0 || values[0] != 0 {
+				t.Errorf("handler values = %v, want values starting with 0", values)
+			}
+			return
+		case <-stop:
+			t.Fatal("handler was not called within the max window")
+		case <-ticker.C:
+			d.Add(i)
+		}
+	}
+}
+
+func TestDebouncerFlush(t *testing.T) {
+	var calls [][]int
+	d := NewDebouncer(time.Hour, time.Hour, func(values []int) {
+		calls = append(calls, values)
+	})
+	d.Flush()
+	if len(calls) != 0 {
+		t.Fatalf("handler calls after Flush() without values = %v, want none", calls)
+	}
+	d.Add(1)
+	d.Add(2)
+	d.Flush()
+	if len(calls) != 1 || !slices.Equal(calls[0], []int{1, 2}) {
+		t.Errorf("handler calls after Flush() = %v, want [[1 2]]", calls)
+	}
+}
+
+func TestDebouncerSerializesHandlerCalls(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int
+	inFlight, maxInFlight := 0, 0
+	started := make(chan struct{}, 10)
+	d := NewDebouncer(10*time.Millisecond, time.Minute, func(values []int) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		started <- struct{}{}
+		// A slow handler, e.g., waiting for the API server.
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		handled = append(handled, values...)
+		inFlight--
+		mu.Unlock()
+	})
+	d.Add(1)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not called")
+	}
+	// The window of the second value ends while the first handler call is in progress.
+	d.Add(2)
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		time.Sleep(20 * time.Millisecond)
+		d.Add(3)
+		d.Flush()
+	}()
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush() did not return")
+	}
+	d.Flush()
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInFlight != 1 {
+		t.Errorf("max concurrent handler calls = %d, want 1", maxInFlight)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(handled, want) {
+		t.Errorf("handled values = %v, want %v", handled, want)
+	}
+}
+
+func TestNewDebouncerIncreasesMaxWindow(t *testing.T) {
+	d := NewDebouncer(time.Second, time.Millisecond, func([]int) {})
+	if d.maxWindow != time.Second {
+		t.Errorf("maxWindow = %v, want %v", d.maxWindow, time.Second)
+	}
+}
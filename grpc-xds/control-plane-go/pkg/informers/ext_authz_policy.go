@@ -0,0 +1,90 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var errInvalidExtAuthzPolicy = errors.New("invalid ExtAuthzPolicy")
+
+// extAuthzPolicySpec is the `spec` of `ExtAuthzPolicy` custom resources,
+// see `k8s/control-plane/base/crd-ext-authz-policies.yaml`.
+type extAuthzPolicySpec struct {
+	GRPCService struct {
+		ClusterName string `json:"clusterName,omitempty"`
+		Authority   string `json:"authority,omitempty"`
+	} `json:"grpcService,omitempty"`
+	Timeout          string `json:"timeout,omitempty"`
+	FailureModeAllow bool   `json:"failureModeAllow,omitempty"`
+	Selector         struct {
+		MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	} `json:"selector,omitempty"`
+}
+
+func (m *Manager) handleExtAuthzPolicies(ctx context.Context, logger logr.Logger, namespace string, objs []*unstructured.Unstructured) {
+	var policies []xds.ExtAuthzPolicy
+	for _, obj := range objs {
+		policy, err := extAuthzPolicyFromUnstructured(obj)
+		if err != nil {
+			logger.Error(err, "Skipping ExtAuthzPolicy", "name", obj.GetName())
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	logger.V(2).Info("Informer resource update", "extAuthzPolicies", policies)
+	if err := m.xdsCache.UpdateExtAuthzPolicies(ctx, logger, m.kubecontext, namespace, policies); err != nil {
+		logger.Error(err, "Could not update the xDS resource cache with ext_authz policies", "extAuthzPolicies", policies)
+	}
+}
+
+func extAuthzPolicyFromUnstructured(obj *unstructured.Unstructured) (xds.ExtAuthzPolicy, error) {
+	var spec extAuthzPolicySpec
+	if err := specFromUnstructured(obj, &spec); err != nil {
+		return xds.ExtAuthzPolicy{}, fmt.Errorf("%w: %w", errInvalidExtAuthzPolicy, err)
+	}
+	if spec.GRPCService.ClusterName == "" {
+		return xds.ExtAuthzPolicy{}, fmt.Errorf("%w: grpcService.clusterName is required", errInvalidExtAuthzPolicy)
+	}
+	timeout := xds.DefaultExtAuthzTimeout
+	if spec.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return xds.ExtAuthzPolicy{}, fmt.Errorf("%w: timeout=%q: %w", errInvalidExtAuthzPolicy, spec.Timeout, err)
+		}
+		if timeout <= 0 {
+			return xds.ExtAuthzPolicy{}, fmt.Errorf("%w: timeout=%q must be positive", errInvalidExtAuthzPolicy, spec.Timeout)
+		}
+	}
+	return xds.ExtAuthzPolicy{
+		Namespace:        obj.GetNamespace(),
+		Name:             obj.GetName(),
+		ClusterName:      spec.GRPCService.ClusterName,
+		Authority:        spec.GRPCService.Authority,
+		Timeout:          timeout,
+		FailureModeAllow: spec.FailureModeAllow,
+		MatchLabels:      maps.Clone(spec.Selector.MatchLabels),
+	}, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestExtAuthzPolicyFromUnstructured(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    xds.ExtAuthzPolicy
+		wantErr error
+	}{
+		{
+			name: "defaults",
+			spec: map[string]interface{}{
+				"grpcService": map[string]interface{}{"clusterName": "authz"},
+			},
+			want: xds.ExtAuthzPolicy{
+				Namespace:   "default",
+				Name:        "policy",
+				ClusterName: "authz",
+				Timeout:     xds.DefaultExtAuthzTimeout,
+			},
+		},
+		{
+			name: "all fields",
+			spec: map[string]interface{}{
+				"grpcService":      map[string]interface{}{"clusterName": "authz", "authority": "authz.example.com"},
+				"timeout":          "1s",
+				"failureModeAllow": true,
+				"selector":         map[string]interface{}{"matchLabels": map[string]interface{}{"team": "a"}},
+			},
+			want: xds.ExtAuthzPolicy{
+				Namespace:        "default",
+				Name:             "policy",
+				ClusterName:      "authz",
+				Authority:        "authz.example.com",
+				Timeout:          time.Second,
+				FailureModeAllow: true,
+				MatchLabels:      map[string]string{"team": "a"},
+			},
+		},
+		{
+			name:    "no cluster name",
+			spec:    map[string]interface{}{"timeout": "1s"},
+			wantErr: errInvalidExtAuthzPolicy,
+		},
+		{
+			name: "invalid timeout",
+			spec: map[string]interface{}{
+				"grpcService": map[string]interface{}{"clusterName": "authz"},
+				"timeout":     "soon",
+			},
+			wantErr: errInvalidExtAuthzPolicy,
+		},
+		{
+			name: "zero timeout",
+			spec: map[string]interface{}{
+				"grpcService": map[string]interface{}{"clusterName": "authz"},
+				"timeout":     "0s",
+			},
+			wantErr: errInvalidExtAuthzPolicy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extAuthzPolicyFromUnstructured(newTestCustomResource("ExtAuthzPolicy", "policy", tt.spec))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("extAuthzPolicyFromUnstructured() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Compare(tt.want) != 0 {
+				t.Errorf("extAuthzPolicyFromUnstructured() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
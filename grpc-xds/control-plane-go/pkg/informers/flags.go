@@ -18,6 +18,8 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
@@ -26,6 +28,44 @@ import (
 const (
 	configPathFlagUsage = "absolute path to the kubeconfig file(s), colon-separated if multiple files"
 
+	debounceWindowFlag      = "debounce-window"
+	debounceWindowFlagUsage = "(optional) quiet period after the last informer event before the coalesced events trigger a single xDS resource update, 0 to disable"
+	defaultDebounceWindow   = 200 * time.Millisecond
+
+	debounceMaxFlag      = "debounce-max"
+	debounceMaxFlagUsage = "(optional) maximum time from the first informer event to the xDS resource update, so that a continuous stream of events does not delay updates indefinitely"
+	defaultDebounceMax   = 2 * time.Second
+
+	edsDebounceFlag      = "eds-debounce-ms"
+	edsDebounceFlagUsage = "(deprecated) use -debounce-window instead, overrides -debounce-window if not negative"
+
+	watchNamespacesFlag      = "watch-namespaces"
+	watchNamespacesFlagUsage = "(optional) comma-separated list of namespaces to watch, all namespaces in the informer configuration are watched if empty"
+
+	localityLBFlag      = "locality-lb"
+	localityLBFlagUsage = "(optional) group EDS endpoints into localities by the zone of the Kubernetes node of each endpoint"
+
+	ipv6Flag      = "ipv6"
+	ipv6FlagUsage = "(optional) prefer IPv6 EndpointSlices over IPv4 EndpointSlices for EDS endpoints, falling back to IPv4 for Services without IPv6 endpoints"
+
+	dualStackFlag      = "dual-stack"
+	dualStackFlagUsage = "(optional) include the endpoints of both IPv4 and IPv6 EndpointSlices of each Service in EDS, takes precedence over -ipv6"
+
+	watchAuthorizationPoliciesFlag      = "watch-authorization-policies"
+	watchAuthorizationPoliciesFlagUsage = "(optional) watch AuthorizationPolicy custom resources, and add them as RBAC HTTP filters to server listeners, requires the CustomResourceDefinition"
+
+	watchGRPCRoutesFlag      = "watch-grpc-routes"
+	watchGRPCRoutesFlagUsage = "(optional) watch GRPCRoute custom resources, and add routes with header matchers to RDS route configurations, requires the CustomResourceDefinition"
+
+	watchAccessLogConfigsFlag      = "watch-access-log-configs"
+	watchAccessLogConfigsFlagUsage = "(optional) watch AccessLogConfig custom resources, and add access logs to LDS API listeners, requires the CustomResourceDefinition"
+
+	watchExtAuthzPoliciesFlag      = "watch-ext-authz-policies"
+	watchExtAuthzPoliciesFlagUsage = "(optional) watch ExtAuthzPolicy custom resources, and add ext_authz HTTP filters to the LDS API listeners of the selected Services, requires the CustomResourceDefinition"
+
+	watchRateLimitPoliciesFlag      = "watch-rate-limit-policies"
+	watchRateLimitPoliciesFlagUsage = "(optional) watch RateLimitPolicy custom resources, and add ratelimit HTTP filters to the LDS API listeners and rate limit actions to the RDS routes of the selected Services, requires the CustomResourceDefinition"
+
 	// Do not change the values below from their recommended values in clientcmd:.
 	configPathEnvVar = clientcmd.RecommendedConfigPathEnvVar
 	configPathFlag   = clientcmd.RecommendedConfigPathFlag
@@ -34,8 +74,20 @@ const (
 )
 
 var (
-	kubeconfig  string
-	commandLine flag.FlagSet
+	kubeconfig                 string
+	debounceWindowValue        time.Duration
+	debounceMax                time.Duration
+	edsDebounceMillis          int
+	watchNamespaces            string
+	localityLB                 bool
+	preferIPv6                 bool
+	dualStack                  bool
+	watchAuthorizationPolicies bool
+	watchGRPCRoutes            bool
+	watchAccessLogConfigs      bool
+	watchExtAuthzPolicies      bool
+	watchRateLimitPolicies     bool
+	commandLine                flag.FlagSet
 )
 
 func init() {
@@ -55,6 +107,43 @@ func init() {
 	} else {
 		commandLine.StringVar(&kubeconfig, configPathFlag, "", usage)
 	}
+	commandLine.DurationVar(&debounceWindowValue, debounceWindowFlag, defaultDebounceWindow, debounceWindowFlagUsage)
+	commandLine.DurationVar(&debounceMax, debounceMaxFlag, defaultDebounceMax, debounceMaxFlagUsage)
+	commandLine.IntVar(&edsDebounceMillis, edsDebounceFlag, -1, edsDebounceFlagUsage)
+	commandLine.StringVar(&watchNamespaces, watchNamespacesFlag, "", watchNamespacesFlagUsage)
+	commandLine.BoolVar(&localityLB, localityLBFlag, true, localityLBFlagUsage)
+	commandLine.BoolVar(&preferIPv6, ipv6Flag, false, ipv6FlagUsage)
+	commandLine.BoolVar(&dualStack, dualStackFlag, false, dualStackFlagUsage)
+	commandLine.BoolVar(&watchAuthorizationPolicies, watchAuthorizationPoliciesFlag, false, watchAuthorizationPoliciesFlagUsage)
+	commandLine.BoolVar(&watchGRPCRoutes, watchGRPCRoutesFlag, false, watchGRPCRoutesFlagUsage)
+	commandLine.BoolVar(&watchAccessLogConfigs, watchAccessLogConfigsFlag, false, watchAccessLogConfigsFlagUsage)
+	commandLine.BoolVar(&watchExtAuthzPolicies, watchExtAuthzPoliciesFlag, false, watchExtAuthzPoliciesFlagUsage)
+	commandLine.BoolVar(&watchRateLimitPolicies, watchRateLimitPoliciesFlag, false, watchRateLimitPoliciesFlagUsage)
+}
+
+// WatchNamespaces returns the namespaces from the `watch-namespaces` flag,
+// or nil if the flag value is empty.
+func WatchNamespaces() []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// debounceWindow returns the debounce window from the `debounce-window` flag,
+// or from the deprecated `eds-debounce-ms` flag if it is set.
+func debounceWindow() time.Duration {
+	if edsDebounceMillis >= 0 {
+		return time.Duration(edsDebounceMillis) * time.Millisecond
+	}
+	return debounceWindowValue
+}
+
+func debounceMaxWindow() time.Duration {
+	return debounceMax
 }
 
 // InitFlags initializes flags for the Kubernetes client.
@@ -0,0 +1,196 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	grpcRouteResource = "grpcroutes"
+
+	// grpcRouteConditionAccepted is the type of the status condition of GRPCRoutes.
+	grpcRouteConditionAccepted = "Accepted"
+	grpcRouteReasonAccepted    = "Accepted"
+	grpcRouteReasonInvalid     = "Invalid"
+	grpcRouteReasonConflict    = "Conflict"
+)
+
+var (
+	errInvalidGRPCRoute  = errors.New("invalid GRPCRoute")
+	errGRPCRouteConflict = errors.New("conflicting GRPCRoute rule")
+)
+
+// grpcRouteSpec is the `spec` of `GRPCRoute` custom resources,
+// see `k8s/control-plane/base/crd-grpc-routes.yaml`.
+type grpcRouteSpec struct {
+	Service string          `json:"service,omitempty"`
+	Rules   []grpcRouteRule `json:"rules,omitempty"`
+}
+
+type grpcRouteRule struct {
+	HeaderMatchers []grpcRouteHeaderMatcher `json:"headerMatchers,omitempty"`
+	ClusterRef     struct {
+		Name string `json:"name,omitempty"`
+	} `json:"clusterRef,omitempty"`
+}
+
+type grpcRouteHeaderMatcher struct {
+	Name   string `json:"name,omitempty"`
+	Exact  string `json:"exact,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// grpcRouteClaim records which GRPCRoute first used a set of header matchers for a Service.
+type grpcRouteClaim struct {
+	name        string
+	clusterName string
+}
+
+// handleGRPCRoutes updates the xDS resource cache with the valid GRPCRoutes in the namespace, and
+// queues writes of the `Accepted` status condition of each GRPCRoute. If rules of different GRPCRoutes
+// have the same header matchers for the same Service, but different clusters, the oldest GRPCRoute is
+// accepted, and the others are rejected with the reason `Conflict`.
+func (m *Manager) handleGRPCRoutes(ctx context.Context, logger logr.Logger, config Config, statusWriter *customResourceStatusWriter, objs []*unstructured.Unstructured) {
+	slices.SortFunc(objs, func(a *unstructured.Unstructured, b *unstructured.Unstructured) int {
+		if c := a.GetCreationTimestamp().Time.Compare(b.GetCreationTimestamp().Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	claims := map[string]grpcRouteClaim{}
+	var routes []xds.GRPCRoute
+	for _, obj := range objs {
+		route, err := grpcRouteFromUnstructured(obj, config.Services)
+		if err == nil {
+			err = claimGRPCRouteRules(claims, route)
+		}
+		if err != nil {
+			logger.Error(err, "Skipping GRPCRoute", "name", obj.GetName())
+		} else {
+			routes = append(routes, route)
+		}
+		statusWriter.enqueue(obj, grpcRouteCondition(obj, err))
+	}
+	logger.V(2).Info("Informer resource update", "grpcRoutes", routes)
+	if err := m.xdsCache.UpdateGRPCRoutes(ctx, logger, m.kubecontext, config.Namespace, routes); err != nil {
+		logger.Error(err, "Could not update the xDS resource cache with GRPCRoutes", "grpcRoutes", routes)
+	}
+}
+
+func grpcRouteFromUnstructured(obj *unstructured.Unstructured, knownServiceNames []string) (xds.GRPCRoute, error) {
+	var spec grpcRouteSpec
+	if err := specFromUnstructured(obj, &spec); err != nil {
+		return xds.GRPCRoute{}, fmt.Errorf("%w: %w", errInvalidGRPCRoute, err)
+	}
+	if !slices.Contains(knownServiceNames, spec.Service) {
+		return xds.GRPCRoute{}, fmt.Errorf("%w: service=%q is not in the informer configuration", errInvalidGRPCRoute, spec.Service)
+	}
+	if len(spec.Rules) == 0 {
+		return xds.GRPCRoute{}, fmt.Errorf("%w: no rules", errInvalidGRPCRoute)
+	}
+	rules := make([]xds.GRPCRouteRule, len(spec.Rules))
+	for i, rule := range spec.Rules {
+		if !slices.Contains(knownServiceNames, rule.ClusterRef.Name) {
+			return xds.GRPCRoute{}, fmt.Errorf("%w: clusterRef name=%q in rule %d is not in the informer configuration", errInvalidGRPCRoute, rule.ClusterRef.Name, i)
+		}
+		if len(rule.HeaderMatchers) == 0 {
+			return xds.GRPCRoute{}, fmt.Errorf("%w: no headerMatchers in rule %d", errInvalidGRPCRoute, i)
+		}
+		headerMatchers := make([]xds.HeaderMatcher, len(rule.HeaderMatchers))
+		for j, headerMatcher := range rule.HeaderMatchers {
+			// HTTP/2 header names are lowercase.
+			name := strings.ToLower(headerMatcher.Name)
+			if name == "" || strings.HasPrefix(name, ":") || strings.HasSuffix(name, "-bin") {
+				return xds.GRPCRoute{}, fmt.Errorf("%w: header name=%q in rule %d must not be empty, a pseudo-header, or a binary header", errInvalidGRPCRoute, headerMatcher.Name, i)
+			}
+			if headerMatcher.Exact != "" && headerMatcher.Prefix != "" {
+				return xds.GRPCRoute{}, fmt.Errorf("%w: header name=%q in rule %d has both exact and prefix", errInvalidGRPCRoute, headerMatcher.Name, i)
+			}
+			headerMatchers[j] = xds.HeaderMatcher{
+				Name:   name,
+				Exact:  headerMatcher.Exact,
+				Prefix: headerMatcher.Prefix,
+			}
+		}
+		slices.SortFunc(headerMatchers, func(a xds.HeaderMatcher, b xds.HeaderMatcher) int {
+			return a.Compare(b)
+		})
+		rules[i] = xds.GRPCRouteRule{
+			HeaderMatchers: headerMatchers,
+			ClusterName:    rule.ClusterRef.Name,
+		}
+	}
+	return xds.GRPCRoute{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Service:   spec.Service,
+		Rules:     rules,
+	}, nil
+}
+
+// claimGRPCRouteRules adds the header matchers of the rules of the route to the claims,
+// unless a rule conflicts with an existing claim, or with another rule of the route.
+// Rules with the same header matchers and the same cluster do not conflict.
+func claimGRPCRouteRules(claims map[string]grpcRouteClaim, route xds.GRPCRoute) error {
+	routeClaims := map[string]grpcRouteClaim{}
+	for i, rule := range route.Rules {
+		key := fmt.Sprintf("%s %+v", route.Service, rule.HeaderMatchers)
+		for _, existingClaims := range []map[string]grpcRouteClaim{claims, routeClaims} {
+			if claim, exists := existingClaims[key]; exists && claim.clusterName != rule.ClusterName {
+				return fmt.Errorf("%w: rule %d has the same header matchers as a rule in GRPCRoute %s with clusterRef name=%s", errGRPCRouteConflict, i, claim.name, claim.clusterName)
+			}
+		}
+		routeClaims[key] = grpcRouteClaim{
+			name:        route.Name,
+			clusterName: rule.ClusterName,
+		}
+	}
+	for key, claim := range routeClaims {
+		if _, exists := claims[key]; !exists {
+			claims[key] = claim
+		}
+	}
+	return nil
+}
+
+func grpcRouteCondition(obj *unstructured.Unstructured, err error) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               grpcRouteConditionAccepted,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: obj.GetGeneration(),
+		Reason:             grpcRouteReasonAccepted,
+		Message:            "The rules were added to the route configuration",
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = grpcRouteReasonInvalid
+		if errors.Is(err, errGRPCRouteConflict) {
+			condition.Reason = grpcRouteReasonConflict
+		}
+		condition.Message = err.Error()
+	}
+	return condition
+}
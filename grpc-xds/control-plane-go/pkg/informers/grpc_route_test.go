@@ -0,0 +1,221 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestGRPCRouteFromUnstructured(t *testing.T) {
+	knownServiceNames := []string{"greeter", "greeter-canary"}
+	rule := func(clusterName string, headerMatchers ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"headerMatchers": headerMatchers,
+			"clusterRef":     map[string]interface{}{"name": clusterName},
+		}
+	}
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    xds.GRPCRoute
+		wantErr error
+	}{
+		{
+			name: "valid with sorted lowercase header matchers",
+			spec: map[string]interface{}{
+				"service": "greeter",
+				"rules": []interface{}{
+					rule("greeter-canary",
+						map[string]interface{}{"name": "X-Version", "exact": "v2"},
+						map[string]interface{}{"name": "x-user", "prefix": "test-"},
+						map[string]interface{}{"name": "x-debug"},
+					),
+				},
+			},
+			want: xds.GRPCRoute{
+				Namespace: "default",
+				Name:      "route",
+				Service:   "greeter",
+				Rules: []xds.GRPCRouteRule{{
+					HeaderMatchers: []xds.HeaderMatcher{
+						{Name: "x-debug"},
+						{Name: "x-user", Prefix: "test-"},
+						{Name: "x-version", Exact: "v2"},
+					},
+					ClusterName: "greeter-canary",
+				}},
+			},
+		},
+		{
+			name: "unknown service",
+			spec: map[string]interface{}{
+				"service": "unknown",
+				"rules":   []interface{}{rule("greeter", map[string]interface{}{"name": "x-version"})},
+			},
+			wantErr: errInvalidGRPCRoute,
+		},
+		{
+			name:    "no rules",
+			spec:    map[string]interface{}{"service": "greeter"},
+			wantErr: errInvalidGRPCRoute,
+		},
+		{
+			name: "unknown cluster",
+			spec: map[string]interface{}{
+				"service": "greeter",
+				"rules":   []interface{}{rule("unknown", map[string]interface{}{"name": "x-version"})},
+			},
+			wantErr: errInvalidGRPCRoute,
+		},
+		{
+			name: "no header matchers",
+			spec: map[string]interface{}{
+				"service": "greeter",
+				"rules":   []interface{}{rule("greeter-canary")},
+			},
+			wantErr: errInvalidGRPCRoute,
+		},
+		{
+			name: "pseudo-header",
+			spec: map[string]interface{}{
+				"service": "greeter",
+				"rules":   []interface{}{rule("greeter-canary", map[string]interface{}{"name": ":authority"})},
+			},
+			wantErr: errInvalidGRPCRoute,
+		},
+		{
+			name: "binary header",
+			spec: map[string]interface{}{
+				"service": "greeter",
+				"rules":   []interface{}{rule("greeter-canary", map[string]interface{}{"name": "x-trace-bin"})},
+			},
+			wantErr: errInvalidGRPCRoute,
+		},
+		{
+			name: "both exact and prefix",
+			spec: map[string]interface{}{
+				"service": "greeter",
+				"rules": []interface{}{rule("greeter-canary",
+					map[string]interface{}{"name": "x-version", "exact": "v2", "prefix": "v"},
+				)},
+			},
+			wantErr: errInvalidGRPCRoute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := grpcRouteFromUnstructured(newTestCustomResource("GRPCRoute", "route", tt.spec), knownServiceNames)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("grpcRouteFromUnstructured() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Compare(tt.want) != 0 {
+				t.Errorf("grpcRouteFromUnstructured() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimGRPCRouteRules(t *testing.T) {
+	headerMatchers := []xds.HeaderMatcher{{Name: "x-version", Exact: "v2"}}
+	route := func(name string, clusterNames ...string) xds.GRPCRoute {
+		rules := make([]xds.GRPCRouteRule, len(clusterNames))
+		for i, clusterName := range clusterNames {
+			rules[i] = xds.GRPCRouteRule{HeaderMatchers: headerMatchers, ClusterName: clusterName}
+		}
+		return xds.GRPCRoute{Namespace: "default", Name: name, Service: "greeter", Rules: rules}
+	}
+	tests := []struct {
+		name    string
+		routes  []xds.GRPCRoute
+		wantErr []error
+	}{
+		{
+			name:    "same header matchers and same cluster",
+			routes:  []xds.GRPCRoute{route("a", "greeter-canary"), route("b", "greeter-canary")},
+			wantErr: []error{nil, nil},
+		},
+		{
+			name:    "same header matchers and different clusters",
+			routes:  []xds.GRPCRoute{route("a", "greeter-canary"), route("b", "greeter")},
+			wantErr: []error{nil, errGRPCRouteConflict},
+		},
+		{
+			name:    "conflicting rules in the same route",
+			routes:  []xds.GRPCRoute{route("a", "greeter-canary", "greeter")},
+			wantErr: []error{errGRPCRouteConflict},
+		},
+		{
+			name: "rejected route does not claim its rules",
+			routes: []xds.GRPCRoute{
+				route("a", "greeter-canary", "greeter"),
+				route("b", "greeter"),
+			},
+			wantErr: []error{errGRPCRouteConflict, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]grpcRouteClaim{}
+			for i, route := range tt.routes {
+				if err := claimGRPCRouteRules(claims, route); !errors.Is(err, tt.wantErr[i]) {
+					t.Errorf("claimGRPCRouteRules(%s) error = %v, want %v", route.Name, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCRouteCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "accepted",
+			err:        nil,
+			wantStatus: metav1.ConditionTrue,
+			wantReason: grpcRouteReasonAccepted,
+		},
+		{
+			name:       "invalid",
+			err:        errInvalidGRPCRoute,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: grpcRouteReasonInvalid,
+		},
+		{
+			name:       "conflict",
+			err:        errGRPCRouteConflict,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: grpcRouteReasonConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := newTestCustomResource("GRPCRoute", "route", nil)
+			obj.SetGeneration(3)
+			got := grpcRouteCondition(obj, tt.err)
+			if got.Type != grpcRouteConditionAccepted || got.Status != tt.wantStatus || got.Reason != tt.wantReason || got.ObservedGeneration != 3 {
+				t.Errorf("grpcRouteCondition() = %+v, want type=%s status=%s reason=%s observedGeneration=3", got, grpcRouteConditionAccepted, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
@@ -23,13 +23,17 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
 	"k8s.io/client-go/kubernetes"
 	informercache "k8s.io/client-go/tools/cache"
 
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
 )
 
@@ -43,9 +47,16 @@ var (
 
 // Manager manages a collection of informers.
 type Manager struct {
-	kubecontext string
-	clientset   *kubernetes.Clientset
-	xdsCache    *xds.SnapshotCache
+	kubecontext   string
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+	xdsCache      *xds.SnapshotCache
+	informers     []informercache.SharedIndexInformer
+	nodeInformer  informercache.SharedIndexInformer
+	// handlersSynced report whether the event handlers have received the initial list of objects.
+	handlersSynced []informercache.InformerSynced
+	// debouncers are the event debouncers of the informers, see `Flush()` and `Resync()`.
+	debouncers []*Debouncer[string]
 }
 
 // NewManager creates an instance that manages a collection of informers
@@ -55,15 +66,23 @@ func NewManager(ctx context.Context, kubecontextName string, xdsCache *xds.Snaps
 	if err != nil {
 		return nil, err
 	}
+	dynamicClient, err := NewDynamicClient(ctx, kubecontextName)
+	if err != nil {
+		return nil, err
+	}
 	return &Manager{
-		kubecontext: kubecontextName,
-		clientset:   clientset,
-		xdsCache:    xdsCache,
+		kubecontext:   kubecontextName,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		xdsCache:      xdsCache,
 	}, nil
 }
 
+// AddEndpointSliceInformer creates informers for the EndpointSlices and Services listed in the config.
+// EndpointSlices provide the endpoints of the gRPC applications, and annotations on the Services
+// provide additional xDS configuration.
 func (m *Manager) AddEndpointSliceInformer(ctx context.Context, logger logr.Logger, config Config) error {
-	logger = logger.WithValues("kubecontext", m.kubecontext, "namespace", config.Namespace)
+	logger = logger.WithValues("component", "informers", "kubecontext", m.kubecontext, "namespace", config.Namespace)
 	if config.Services == nil {
 		config.Services = make([]string, 0)
 	}
@@ -77,7 +96,7 @@ func (m *Manager) AddEndpointSliceInformer(ctx context.Context, logger logr.Logg
 		close(stop)
 	}()
 
-	factory := informers.NewSharedInformerFactory(m.clientset, 0)
+	factory := informers.NewSharedInformerFactoryWithOptions(m.clientset, 0, informers.WithNamespace(config.Namespace))
 	informer := factory.InformerFor(&discoveryv1.EndpointSlice{}, func(clientSet kubernetes.Interface, resyncPeriod time.Duration) informercache.SharedIndexInformer {
 		indexers := informercache.Indexers{informercache.NamespaceIndex: informercache.MetaNamespaceIndexFunc}
 		return discoveryinformers.NewFilteredEndpointSliceInformer(clientSet, config.Namespace, resyncPeriod, indexers, func(listOptions *metav1.ListOptions) {
@@ -85,36 +104,108 @@ func (m *Manager) AddEndpointSliceInformer(ctx context.Context, logger logr.Logg
 		})
 	})
 
-	_, err := informer.AddEventHandler(informercache.ResourceEventHandlerFuncs{
+	serviceInformer := factory.Core().V1().Services().Informer()
+	nodeInformer := m.getOrCreateNodeInformer(ctx, logger)
+	statusWriter := newServiceStatusWriter(logger, serviceInformer, func(ctx context.Context, namespace string, name string, patch []byte) error {
+		_, err := m.clientset.CoreV1().Services(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	// Coalesce bursts of events, e.g., during rolling updates, into a single xDS resource update.
+	eventDebouncer := NewDebouncer(debounceWindow(), debounceMaxWindow(), func(events []string) {
+		logger := logger.WithValues("events", len(events))
+		apps := m.getAppsForInformer(logger, informer, serviceInformer, nodeInformer, statusWriter, config.Services)
+		m.handleEndpointSliceEvent(ctx, logger, config.Namespace, apps)
+	})
+	m.debouncers = append(m.debouncers, eventDebouncer)
+
+	registration, err := informer.AddEventHandler(informercache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			logger := logger.WithValues("event", "add")
-			logEndpointSlice(logger, obj)
-			apps := getAppsForInformer(logger, informer)
-			m.handleEndpointSliceEvent(ctx, logger, config.Namespace, apps)
+			metrics.K8sWatchEvent("EndpointSlice", "add")
+			logEndpointSlice(logger.WithValues("event", "add"), obj)
+			eventDebouncer.Add("EndpointSlice/add")
 		},
 		UpdateFunc: func(_, obj interface{}) {
-			logger := logger.WithValues("event", "update")
-			logEndpointSlice(logger, obj)
-			apps := getAppsForInformer(logger, informer)
-			m.handleEndpointSliceEvent(ctx, logger, config.Namespace, apps)
+			metrics.K8sWatchEvent("EndpointSlice", "update")
+			logEndpointSlice(logger.WithValues("event", "update"), obj)
+			eventDebouncer.Add("EndpointSlice/update")
 		},
 		DeleteFunc: func(obj interface{}) {
-			logger := logger.WithValues("event", "delete")
-			logEndpointSlice(logger, obj)
-			apps := getAppsForInformer(logger, informer)
-			m.handleEndpointSliceEvent(ctx, logger, config.Namespace, apps)
+			metrics.K8sWatchEvent("EndpointSlice", "delete")
+			logEndpointSlice(logger.WithValues("event", "delete"), obj)
+			eventDebouncer.Add("EndpointSlice/delete")
 		},
 	})
 	if err != nil {
 		return fmt.Errorf("could not add informer event handler for kubecontext=%s namespace=%s services=%+v: %w", m.kubecontext, config.Namespace, config.Services, err)
 	}
+	m.handlersSynced = append(m.handlersSynced, registration.HasSynced)
+	if err := m.addServiceEventHandler(config, serviceInformer, eventDebouncer); err != nil {
+		return err
+	}
+	m.informers = append(m.informers, informer, serviceInformer)
 	go func() {
 		logger.V(2).Info("Starting informer", "services", config.Services)
 		informer.Run(stop)
 	}()
+	go func() {
+		logger.V(2).Info("Starting Service informer", "services", config.Services)
+		serviceInformer.Run(stop)
+	}()
+	go statusWriter.run(ctx)
 	return nil
 }
 
+// getOrCreateNodeInformer returns the informer for Kubernetes Nodes, which provides the zones of
+// endpoints when the EndpointSlices do not include them. Returns nil if locality load balancing is disabled.
+// The informer is shared by all EndpointSlice informers of this instance. Node events do not trigger
+// xDS resource updates, as the zone label of a Node does not change during its lifetime.
+func (m *Manager) getOrCreateNodeInformer(ctx context.Context, logger logr.Logger) informercache.SharedIndexInformer {
+	if !localityLB {
+		return nil
+	}
+	if m.nodeInformer != nil {
+		return m.nodeInformer
+	}
+	factory := informers.NewSharedInformerFactory(m.clientset, 0)
+	m.nodeInformer = factory.Core().V1().Nodes().Informer()
+	m.informers = append(m.informers, m.nodeInformer)
+	go func() {
+		logger.V(2).Info("Starting Node informer")
+		m.nodeInformer.Run(ctx.Done())
+	}()
+	return m.nodeInformer
+}
+
+// WaitForCacheSync blocks until the caches of all informers managed by this instance
+// have synced, and their event handlers have received the initial list of objects,
+// or until the context is done. Returns true if all caches synced.
+func (m *Manager) WaitForCacheSync(ctx context.Context) bool {
+	hasSyncedFuncs := make([]informercache.InformerSynced, 0, len(m.informers)+len(m.handlersSynced))
+	for _, informer := range m.informers {
+		hasSyncedFuncs = append(hasSyncedFuncs, informer.HasSynced)
+	}
+	hasSyncedFuncs = append(hasSyncedFuncs, m.handlersSynced...)
+	return informercache.WaitForCacheSync(ctx.Done(), hasSyncedFuncs...)
+}
+
+// Flush handles pending debounced informer events immediately, instead of
+// waiting for the end of the debounce window.
+func (m *Manager) Flush() {
+	for _, eventDebouncer := range m.debouncers {
+		eventDebouncer.Flush()
+	}
+}
+
+// Resync handles a synthetic event for each informer, so that the xDS resource cache is updated
+// from the current contents of the informer caches, in case events were missed.
+// The xDS resource cache ignores updates that do not change its configuration.
+func (m *Manager) Resync() {
+	for _, eventDebouncer := range m.debouncers {
+		eventDebouncer.Add("resync")
+		eventDebouncer.Flush()
+	}
+}
+
 func logEndpointSlice(logger logr.Logger, obj interface{}) {
 	if logger.V(4).Enabled() {
 		jsonBytes, err := json.MarshalIndent(obj, "", "  ")
@@ -133,43 +224,121 @@ func (m *Manager) handleEndpointSliceEvent(ctx context.Context, logger logr.Logg
 	}
 }
 
-func getAppsForInformer(logger logr.Logger, informer informercache.SharedIndexInformer) []xds.GRPCApplication {
-	var apps []xds.GRPCApplication
+func (m *Manager) getAppsForInformer(logger logr.Logger, informer informercache.SharedIndexInformer, serviceInformer informercache.SharedIndexInformer, nodeInformer informercache.SharedIndexInformer, statusWriter *serviceStatusWriter, services []string) []xds.GRPCApplication {
+	var endpointSlices []*discoveryv1.EndpointSlice
 	for _, eps := range informer.GetIndexer().List() {
 		endpointSlice, err := validateEndpointSlice(eps)
 		if err != nil {
 			logger.Error(err, "Skipping EndpointSlice")
 			continue
 		}
+		endpointSlices = append(endpointSlices, endpointSlice)
+	}
+	var apps []xds.GRPCApplication
+	for _, endpointSlice := range selectEndpointSlicesByAddressType(logger, endpointSlices) {
 		k8sServiceName := endpointSlice.GetObjectMeta().GetLabels()[discoveryv1.LabelServiceName]
 		namespace := endpointSlice.GetObjectMeta().GetNamespace()
 		// TODO: Handle more than one port?
 		port := uint32(*endpointSlice.Ports[0].Port)
-		appEndpoints := getApplicationEndpoints(endpointSlice)
+		appEndpoints := getApplicationEndpoints(logger, endpointSlice, nodeInformer)
 		app := xds.NewGRPCApplication(namespace, k8sServiceName, port, appEndpoints)
+		app.AppProtocol = xds.AppProtocolFromServicePort(logger, endpointSlice.Ports[0].AppProtocol)
+		if service := getService(logger, serviceInformer, namespace, k8sServiceName); service != nil {
+			previous, found := m.xdsCache.GetGRPCApplication(m.kubecontext, namespace, k8sServiceName)
+			if !found {
+				previous = app
+			}
+			applyServiceAnnotations(logger, &app, service, services, previous)
+			statusWriter.enqueue(service)
+		}
 		apps = append(apps, app)
 	}
-	return apps
+	return append(apps, m.getExternalNameApps(logger, serviceInformer, statusWriter, services)...)
+}
+
+// selectEndpointSlicesByAddressType returns the EndpointSlices with the address types to use for EDS.
+// A dual-stack Service has separate EndpointSlices for IPv4 and IPv6 addresses.
+// By default, the IPv4 EndpointSlices of each Service are used, or the IPv6 EndpointSlices
+// if the Service has no IPv4 EndpointSlices. The `-ipv6` flag reverses this preference.
+// With the `-dual-stack` flag, both address types are used, and the endpoints of a Pod
+// end up as separate `LbEndpoint`s in the same locality.
+// EndpointSlices with the FQDN address type are always skipped.
+func selectEndpointSlicesByAddressType(logger logr.Logger, endpointSlices []*discoveryv1.EndpointSlice) []*discoveryv1.EndpointSlice {
+	preferred := discoveryv1.AddressTypeIPv4
+	if preferIPv6 {
+		preferred = discoveryv1.AddressTypeIPv6
+	}
+	hasPreferred := map[string]bool{}
+	for _, endpointSlice := range endpointSlices {
+		if endpointSlice.AddressType == preferred {
+			hasPreferred[endpointSliceServiceKey(endpointSlice)] = true
+		}
+	}
+	var selected []*discoveryv1.EndpointSlice
+	for _, endpointSlice := range endpointSlices {
+		switch endpointSlice.AddressType {
+		case discoveryv1.AddressTypeIPv4, discoveryv1.AddressTypeIPv6:
+		default:
+			logger.V(4).Info("Skipping EndpointSlice with unsupported address type", "name", endpointSlice.GetName(), "namespace", endpointSlice.GetNamespace(), "addressType", endpointSlice.AddressType)
+			continue
+		}
+		if !dualStack && endpointSlice.AddressType != preferred && hasPreferred[endpointSliceServiceKey(endpointSlice)] {
+			logger.V(4).Info("Skipping EndpointSlice with non-preferred address type", "name", endpointSlice.GetName(), "namespace", endpointSlice.GetNamespace(), "addressType", endpointSlice.AddressType)
+			continue
+		}
+		selected = append(selected, endpointSlice)
+	}
+	return selected
+}
+
+// endpointSliceServiceKey returns the namespace and name of the Service that owns the EndpointSlice.
+func endpointSliceServiceKey(endpointSlice *discoveryv1.EndpointSlice) string {
+	return endpointSlice.GetNamespace() + "/" + endpointSlice.GetLabels()[discoveryv1.LabelServiceName]
 }
 
 // getApplicationEndpoints returns the endpoints as `GRPCApplicationEndpoints`.
-func getApplicationEndpoints(endpointSlice *discoveryv1.EndpointSlice) []xds.GRPCApplicationEndpoints {
+// Endpoints of Pods that are not ready are included, with an unhealthy or draining status,
+// so that xDS clients stop sending requests to them.
+// If locality load balancing is disabled, the zones of all endpoints are empty,
+// so that they end up in a single EDS locality.
+func getApplicationEndpoints(logger logr.Logger, endpointSlice *discoveryv1.EndpointSlice, nodeInformer informercache.SharedIndexInformer) []xds.GRPCApplicationEndpoints {
 	var appEndpoints []xds.GRPCApplicationEndpoints
 	for _, endpoint := range endpointSlice.Endpoints {
-		if endpoint.Conditions.Ready != nil && *endpoint.Conditions.Ready {
-			var k8sNode, zone string
-			if endpoint.NodeName != nil {
-				k8sNode = *endpoint.NodeName
-			}
+		var k8sNode, zone string
+		if endpoint.NodeName != nil {
+			k8sNode = *endpoint.NodeName
+		}
+		if localityLB {
 			if endpoint.Zone != nil {
 				zone = *endpoint.Zone
+			} else {
+				zone = getNodeZone(logger, nodeInformer, k8sNode)
 			}
-			appEndpoints = append(appEndpoints, xds.NewGRPCApplicationEndpoints(k8sNode, zone, endpoint.Addresses, xds.EndpointStatusFromConditions(endpoint.Conditions)))
 		}
+		appEndpoints = append(appEndpoints, xds.NewGRPCApplicationEndpoints(k8sNode, zone, endpoint.Addresses, xds.EndpointStatusFromConditions(endpoint.Conditions)))
 	}
 	return appEndpoints
 }
 
+// getNodeZone returns the value of the `topology.kubernetes.io/zone` label of the Node,
+// or the empty string if the Node or the label is not found.
+func getNodeZone(logger logr.Logger, nodeInformer informercache.SharedIndexInformer, nodeName string) string {
+	if nodeInformer == nil || nodeName == "" {
+		return ""
+	}
+	obj, exists, err := nodeInformer.GetIndexer().GetByKey(nodeName)
+	if err != nil || !exists {
+		logger.V(4).Info("Node not found in informer cache", "node", nodeName)
+		return ""
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		logger.Error(fmt.Errorf("%w: expected *corev1.Node, got %T", errUnexpectedType, obj), "Skipping Node", "node", nodeName)
+		return ""
+	}
+	return node.GetLabels()[corev1.LabelTopologyZone]
+}
+
 // validateEndpointSlice ensures that the EndpointSlice contains the fields
 // required to turn it into a `xds.GRPCApplication` instance.
 func validateEndpointSlice(eps interface{}) (*discoveryv1.EndpointSlice, error) {
@@ -0,0 +1,243 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	informercache "k8s.io/client-go/tools/cache"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestValidateEndpointSlice(t *testing.T) {
+	validMeta := metav1.ObjectMeta{
+		Name:      "greeter-abcde",
+		Namespace: "default",
+		Labels:    map[string]string{discoveryv1.LabelServiceName: "greeter"},
+	}
+	validPorts := []discoveryv1.EndpointPort{{Name: ptr("grpc"), Port: ptr(int32(50051))}}
+	tests := []struct {
+		name    string
+		obj     interface{}
+		wantErr error
+	}{
+		{
+			name:    "nil",
+			obj:     nil,
+			wantErr: errNilEndpointSlice,
+		},
+		{
+			name:    "unexpected type",
+			obj:     &corev1.Service{},
+			wantErr: errUnexpectedType,
+		},
+		{
+			name: "missing namespace",
+			obj: &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: "greeter-abcde", Labels: validMeta.Labels},
+				Ports:      validPorts,
+			},
+			wantErr: errMissingMetadata,
+		},
+		{
+			name: "missing service label",
+			obj: &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: "greeter-abcde", Namespace: "default"},
+				Ports:      validPorts,
+			},
+			wantErr: errMissingLabel,
+		},
+		{
+			name:    "no ports",
+			obj:     &discoveryv1.EndpointSlice{ObjectMeta: validMeta},
+			wantErr: errNoPortsInEndpointSlice,
+		},
+		{
+			name:    "valid",
+			obj:     &discoveryv1.EndpointSlice{ObjectMeta: validMeta, Ports: validPorts},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateEndpointSlice(tt.obj)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateEndpointSlice() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetApplicationEndpoints(t *testing.T) {
+	nodeInformer := informercache.NewSharedIndexInformer(&informercache.ListWatch{}, &corev1.Node{}, 0, informercache.Indexers{})
+	if err := nodeInformer.GetIndexer().Add(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{corev1.LabelTopologyZone: "zone-from-node"},
+		},
+	}); err != nil {
+		t.Fatalf("could not add Node to indexer: %v", err)
+	}
+	endpointSlice := &discoveryv1.EndpointSlice{
+		Endpoints: []discoveryv1.Endpoint{
+			{
+				Addresses: []string{"10.0.0.2", "10.0.0.1"},
+				NodeName:  ptr("node-1"),
+				Zone:      ptr("zone-from-slice"),
+			},
+			{
+				Addresses:  []string{"10.0.0.3"},
+				NodeName:   ptr("node-1"),
+				Conditions: discoveryv1.EndpointConditions{Ready: ptr(false)},
+			},
+			{
+				Addresses: []string{"10.0.0.4"},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		localityLB bool
+		want       []xds.GRPCApplicationEndpoints
+	}{
+		{
+			name:       "locality LB uses the EndpointSlice zone, then the Node zone",
+			localityLB: true,
+			want: []xds.GRPCApplicationEndpoints{
+				xds.NewGRPCApplicationEndpoints("node-1", "zone-from-slice", []string{"10.0.0.1", "10.0.0.2"}, xds.Healthy),
+				xds.NewGRPCApplicationEndpoints("node-1", "zone-from-node", []string{"10.0.0.3"}, xds.Unhealthy),
+				xds.NewGRPCApplicationEndpoints("", "", []string{"10.0.0.4"}, xds.Healthy),
+			},
+		},
+		{
+			name:       "without locality LB the zone is empty",
+			localityLB: false,
+			want: []xds.GRPCApplicationEndpoints{
+				xds.NewGRPCApplicationEndpoints("node-1", "", []string{"10.0.0.1", "10.0.0.2"}, xds.Healthy),
+				xds.NewGRPCApplicationEndpoints("node-1", "", []string{"10.0.0.3"}, xds.Unhealthy),
+				xds.NewGRPCApplicationEndpoints("", "", []string{"10.0.0.4"}, xds.Healthy),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := localityLB
+			localityLB = tt.localityLB
+			t.Cleanup(func() { localityLB = previous })
+			got := getApplicationEndpoints(logr.Discard(), endpointSlice, nodeInformer)
+			if !slices.EqualFunc(got, tt.want, xds.GRPCApplicationEndpoints.Equal) {
+				t.Errorf("getApplicationEndpoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNodeZone(t *testing.T) {
+	nodeInformer := informercache.NewSharedIndexInformer(&informercache.ListWatch{}, &corev1.Node{}, 0, informercache.Indexers{})
+	for _, node := range []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-with-zone", Labels: map[string]string{corev1.LabelTopologyZone: "zone-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-without-zone"}},
+	} {
+		if err := nodeInformer.GetIndexer().Add(node); err != nil {
+			t.Fatalf("could not add Node to indexer: %v", err)
+		}
+	}
+	tests := []struct {
+		name         string
+		nodeInformer informercache.SharedIndexInformer
+		nodeName     string
+		want         string
+	}{
+		{name: "node with zone label", nodeInformer: nodeInformer, nodeName: "node-with-zone", want: "zone-a"},
+		{name: "node without zone label", nodeInformer: nodeInformer, nodeName: "node-without-zone", want: ""},
+		{name: "unknown node", nodeInformer: nodeInformer, nodeName: "unknown", want: ""},
+		{name: "no node name", nodeInformer: nodeInformer, nodeName: "", want: ""},
+		{name: "no node informer", nodeInformer: nil, nodeName: "node-with-zone", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getNodeZone(logr.Discard(), tt.nodeInformer, tt.nodeName); got != tt.want {
+				t.Errorf("getNodeZone() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectEndpointSlicesByAddressType(t *testing.T) {
+	endpointSlice := func(name string, service string, addressType discoveryv1.AddressType) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+			},
+			AddressType: addressType,
+		}
+	}
+	endpointSlices := []*discoveryv1.EndpointSlice{
+		endpointSlice("dual-stack-ipv4", "dual-stack", discoveryv1.AddressTypeIPv4),
+		endpointSlice("dual-stack-ipv6", "dual-stack", discoveryv1.AddressTypeIPv6),
+		endpointSlice("ipv4-only", "ipv4-only", discoveryv1.AddressTypeIPv4),
+		endpointSlice("ipv6-only", "ipv6-only", discoveryv1.AddressTypeIPv6),
+		endpointSlice("fqdn", "fqdn", discoveryv1.AddressTypeFQDN),
+	}
+	tests := []struct {
+		name       string
+		preferIPv6 bool
+		dualStack  bool
+		want       []string
+	}{
+		{
+			name: "prefer IPv4",
+			want: []string{"dual-stack-ipv4", "ipv4-only", "ipv6-only"},
+		},
+		{
+			name:       "prefer IPv6",
+			preferIPv6: true,
+			want:       []string{"dual-stack-ipv6", "ipv4-only", "ipv6-only"},
+		},
+		{
+			name:       "dual-stack takes precedence over preferring IPv6",
+			preferIPv6: true,
+			dualStack:  true,
+			want:       []string{"dual-stack-ipv4", "dual-stack-ipv6", "ipv4-only", "ipv6-only"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousPreferIPv6, previousDualStack := preferIPv6, dualStack
+			preferIPv6, dualStack = tt.preferIPv6, tt.dualStack
+			t.Cleanup(func() { preferIPv6, dualStack = previousPreferIPv6, previousDualStack })
+			var got []string
+			for _, selected := range selectEndpointSlicesByAddressType(logr.Discard(), endpointSlices) {
+				got = append(got, selected.GetName())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectEndpointSlicesByAddressType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var errInvalidRateLimitPolicy = errors.New("invalid RateLimitPolicy")
+
+// rateLimitPolicySpec is the `spec` of `RateLimitPolicy` custom resources,
+// see `k8s/control-plane/base/crd-rate-limit-policies.yaml`.
+type rateLimitPolicySpec struct {
+	RateLimitServiceCluster string                  `json:"rateLimitServiceCluster,omitempty"`
+	Domain                  string                  `json:"domain,omitempty"`
+	Timeout                 string                  `json:"timeout,omitempty"`
+	FailureModeDeny         bool                    `json:"failureModeDeny,omitempty"`
+	Actions                 []rateLimitPolicyAction `json:"actions,omitempty"`
+	Selector                struct {
+		MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	} `json:"selector,omitempty"`
+}
+
+type rateLimitPolicyAction struct {
+	DescriptorValue string `json:"descriptorValue,omitempty"`
+	// ExpectMatch is a pointer, as it defaults to true.
+	ExpectMatch    *bool                    `json:"expectMatch,omitempty"`
+	HeaderMatchers []grpcRouteHeaderMatcher `json:"headerMatchers,omitempty"`
+}
+
+func (m *Manager) handleRateLimitPolicies(ctx context.Context, logger logr.Logger, namespace string, objs []*unstructured.Unstructured) {
+	var policies []xds.RateLimitPolicy
+	for _, obj := range objs {
+		policy, err := rateLimitPolicyFromUnstructured(obj)
+		if err != nil {
+			logger.Error(err, "Skipping RateLimitPolicy", "name", obj.GetName())
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	logger.V(2).Info("Informer resource update", "rateLimitPolicies", policies)
+	if err := m.xdsCache.UpdateRateLimitPolicies(ctx, logger, m.kubecontext, namespace, policies); err != nil {
+		logger.Error(err, "Could not update the xDS resource cache with rate limit policies", "rateLimitPolicies", policies)
+	}
+}
+
+func rateLimitPolicyFromUnstructured(obj *unstructured.Unstructured) (xds.RateLimitPolicy, error) {
+	var spec rateLimitPolicySpec
+	if err := specFromUnstructured(obj, &spec); err != nil {
+		return xds.RateLimitPolicy{}, fmt.Errorf("%w: %w", errInvalidRateLimitPolicy, err)
+	}
+	if spec.RateLimitServiceCluster == "" {
+		return xds.RateLimitPolicy{}, fmt.Errorf("%w: rateLimitServiceCluster is required", errInvalidRateLimitPolicy)
+	}
+	if spec.Domain == "" {
+		return xds.RateLimitPolicy{}, fmt.Errorf("%w: domain is required", errInvalidRateLimitPolicy)
+	}
+	timeout := xds.DefaultRateLimitTimeout
+	if spec.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return xds.RateLimitPolicy{}, fmt.Errorf("%w: timeout=%q: %w", errInvalidRateLimitPolicy, spec.Timeout, err)
+		}
+		if timeout <= 0 {
+			return xds.RateLimitPolicy{}, fmt.Errorf("%w: timeout=%q must be positive", errInvalidRateLimitPolicy, spec.Timeout)
+		}
+	}
+	if len(spec.Actions) == 0 {
+		return xds.RateLimitPolicy{}, fmt.Errorf("%w: no actions", errInvalidRateLimitPolicy)
+	}
+	actions := make([]xds.RateLimitAction, len(spec.Actions))
+	for i, action := range spec.Actions {
+		if action.DescriptorValue == "" {
+			return xds.RateLimitPolicy{}, fmt.Errorf("%w: descriptorValue is required in action %d", errInvalidRateLimitPolicy, i)
+		}
+		if len(action.HeaderMatchers) == 0 {
+			return xds.RateLimitPolicy{}, fmt.Errorf("%w: no headerMatchers in action %d", errInvalidRateLimitPolicy, i)
+		}
+		headerMatchers := make([]xds.HeaderMatcher, len(action.HeaderMatchers))
+		for j, headerMatcher := range action.HeaderMatchers {
+			// HTTP/2 header names are lowercase.
+			name := strings.ToLower(headerMatcher.Name)
+			if name == "" {
+				return xds.RateLimitPolicy{}, fmt.Errorf("%w: header name must not be empty in action %d", errInvalidRateLimitPolicy, i)
+			}
+			if headerMatcher.Exact != "" && headerMatcher.Prefix != "" {
+				return xds.RateLimitPolicy{}, fmt.Errorf("%w: header name=%q in action %d has both exact and prefix", errInvalidRateLimitPolicy, headerMatcher.Name, i)
+			}
+			headerMatchers[j] = xds.HeaderMatcher{
+				Name:   name,
+				Exact:  headerMatcher.Exact,
+				Prefix: headerMatcher.Prefix,
+			}
+		}
+		slices.SortFunc(headerMatchers, func(a xds.HeaderMatcher, b xds.HeaderMatcher) int {
+			return a.Compare(b)
+		})
+		actions[i] = xds.RateLimitAction{
+			DescriptorValue: action.DescriptorValue,
+			ExpectMatch:     action.ExpectMatch == nil || *action.ExpectMatch,
+			HeaderMatchers:  headerMatchers,
+		}
+	}
+	return xds.RateLimitPolicy{
+		Namespace:               obj.GetNamespace(),
+		Name:                    obj.GetName(),
+		RateLimitServiceCluster: spec.RateLimitServiceCluster,
+		Domain:                  spec.Domain,
+		Timeout:                 timeout,
+		FailureModeDeny:         spec.FailureModeDeny,
+		Actions:                 actions,
+		MatchLabels:             maps.Clone(spec.Selector.MatchLabels),
+	}, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestRateLimitPolicyFromUnstructured(t *testing.T) {
+	spec := func(actions ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"rateLimitServiceCluster": "ratelimit",
+			"domain":                  "greeter",
+			"actions":                 actions,
+		}
+	}
+	action := func(descriptorValue string, headerMatchers ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"descriptorValue": descriptorValue,
+			"headerMatchers":  headerMatchers,
+		}
+	}
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    xds.RateLimitPolicy
+		wantErr error
+	}{
+		{
+			name: "defaults with sorted lowercase header matchers",
+			spec: spec(action("users",
+				map[string]interface{}{"name": "X-User", "prefix": "test-"},
+				map[string]interface{}{"name": "x-debug"},
+			)),
+			want: xds.RateLimitPolicy{
+				Namespace:               "default",
+				Name:                    "policy",
+				RateLimitServiceCluster: "ratelimit",
+				Domain:                  "greeter",
+				Timeout:                 xds.DefaultRateLimitTimeout,
+				Actions: []xds.RateLimitAction{{
+					DescriptorValue: "users",
+					ExpectMatch:     true,
+					HeaderMatchers: []xds.HeaderMatcher{
+						{Name: "x-debug"},
+						{Name: "x-user", Prefix: "test-"},
+					},
+				}},
+			},
+		},
+		{
+			name: "all fields",
+			spec: map[string]interface{}{
+				"rateLimitServiceCluster": "ratelimit",
+				"domain":                  "greeter",
+				"timeout":                 "100ms",
+				"failureModeDeny":         true,
+				"actions": []interface{}{map[string]interface{}{
+					"descriptorValue": "not-debug",
+					"expectMatch":     false,
+					"headerMatchers":  []interface{}{map[string]interface{}{"name": "x-debug", "exact": "true"}},
+				}},
+				"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": "a"}},
+			},
+			want: xds.RateLimitPolicy{
+				Namespace:               "default",
+				Name:                    "policy",
+				RateLimitServiceCluster: "ratelimit",
+				Domain:                  "greeter",
+				Timeout:                 100 * time.Millisecond,
+				FailureModeDeny:         true,
+				Actions: []xds.RateLimitAction{{
+					DescriptorValue: "not-debug",
+					HeaderMatchers:  []xds.HeaderMatcher{{Name: "x-debug", Exact: "true"}},
+				}},
+				MatchLabels: map[string]string{"team": "a"},
+			},
+		},
+		{
+			name:    "no rate limit service cluster",
+			spec:    map[string]interface{}{"domain": "greeter", "actions": []interface{}{action("a", map[string]interface{}{"name": "x"})}},
+			wantErr: errInvalidRateLimitPolicy,
+		},
+		{
+			name:    "no domain",
+			spec:    map[string]interface{}{"rateLimitServiceCluster": "ratelimit", "actions": []interface{}{action("a", map[string]interface{}{"name": "x"})}},
+			wantErr: errInvalidRateLimitPolicy,
+		},
+		{
+			name:    "no actions",
+			spec:    spec(),
+			wantErr: errInvalidRateLimitPolicy,
+		},
+		{
+			name:    "no descriptor value",
+			spec:    spec(action("", map[string]interface{}{"name": "x"})),
+			wantErr: errInvalidRateLimitPolicy,
+		},
+		{
+			name:    "no header matchers",
+			spec:    spec(action("a")),
+			wantErr: errInvalidRateLimitPolicy,
+		},
+		{
+			name:    "exact and prefix",
+			spec:    spec(action("a", map[string]interface{}{"name": "x", "exact": "1", "prefix": "1"})),
+			wantErr: errInvalidRateLimitPolicy,
+		},
+		{
+			name: "negative timeout",
+			spec: map[string]interface{}{
+				"rateLimitServiceCluster": "ratelimit",
+				"domain":                  "greeter",
+				"timeout":                 "-1s",
+				"actions":                 []interface{}{action("a", map[string]interface{}{"name": "x"})},
+			},
+			wantErr: errInvalidRateLimitPolicy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rateLimitPolicyFromUnstructured(newTestCustomResource("RateLimitPolicy", "policy", tt.spec))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("rateLimitPolicyFromUnstructured() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Compare(tt.want) != 0 {
+				t.Errorf("rateLimitPolicyFromUnstructured() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
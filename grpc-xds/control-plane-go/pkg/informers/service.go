@@ -0,0 +1,165 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	informercache "k8s.io/client-go/tools/cache"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+// addServiceEventHandler regenerates the gRPC application configuration when
+// a Service listed in the config is added, updated, or deleted, using the
+// debouncer of the EndpointSlice informer.
+func (m *Manager) addServiceEventHandler(config Config, serviceInformer informercache.SharedIndexInformer, eventDebouncer *Debouncer[string]) error {
+	handleServiceEvent := func(eventType string, obj interface{}) {
+		if !isListedService(obj, config.Services) {
+			return
+		}
+		metrics.K8sWatchEvent("Service", eventType)
+		eventDebouncer.Add("Service/" + eventType)
+	}
+	registration, err := serviceInformer.AddEventHandler(informercache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handleServiceEvent("add", obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			handleServiceEvent("update", obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			handleServiceEvent("delete", obj)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not add Service informer event handler for kubecontext=%s namespace=%s services=%+v: %w", m.kubecontext, config.Namespace, config.Services, err)
+	}
+	m.handlersSynced = append(m.handlersSynced, registration.HasSynced)
+	return nil
+}
+
+// isListedService returns true if the object is a Service, or a tombstone for a Service,
+// with a name in the provided list of Service names.
+func isListedService(obj interface{}, services []string) bool {
+	key, err := informercache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return false
+	}
+	_, name, err := informercache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(services, name)
+}
+
+// getService returns the Service with the provided namespace and name from the informer cache,
+// or nil if it does not exist.
+func getService(logger logr.Logger, serviceInformer informercache.SharedIndexInformer, namespace string, name string) *corev1.Service {
+	obj, exists, err := serviceInformer.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil {
+		logger.Error(err, "Could not look up Service in informer cache", "namespace", namespace, "name", name)
+		return nil
+	}
+	if !exists {
+		return nil
+	}
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		logger.Error(errUnexpectedType, "Expected *corev1.Service", "type", fmt.Sprintf("%T", obj))
+		return nil
+	}
+	return service
+}
+
+// getExternalNameApps returns the gRPC applications for the listed Services of type `ExternalName`.
+// These Services have no EndpointSlices, so the applications come from the Service informer cache.
+// The port is the first port of the Service, and Services without ports are skipped.
+func (m *Manager) getExternalNameApps(logger logr.Logger, serviceInformer informercache.SharedIndexInformer, statusWriter *serviceStatusWriter, services []string) []xds.GRPCApplication {
+	var apps []xds.GRPCApplication
+	for _, obj := range serviceInformer.GetIndexer().List() {
+		service, ok := obj.(*corev1.Service)
+		if !ok || service.Spec.Type != corev1.ServiceTypeExternalName || !slices.Contains(services, service.GetName()) {
+			continue
+		}
+		if service.Spec.ExternalName == "" || len(service.Spec.Ports) == 0 {
+			logger.V(1).Info("Warning: skipping ExternalName Service without an external name or a port", "namespace", service.GetNamespace(), "service", service.GetName())
+			continue
+		}
+		port := uint32(service.Spec.Ports[0].Port)
+		app := xds.NewExternalNameGRPCApplication(service.GetNamespace(), service.GetName(), service.Spec.ExternalName, port)
+		app.AppProtocol = xds.AppProtocolFromServicePort(logger, service.Spec.Ports[0].AppProtocol)
+		previous, found := m.xdsCache.GetGRPCApplication(m.kubecontext, service.GetNamespace(), service.GetName())
+		if !found {
+			previous = app
+		}
+		applyServiceAnnotations(logger, &app, service, services, previous)
+		app.UpstreamTLS = xds.UpstreamTLSFromAnnotations(logger, service.GetAnnotations(), port)
+		statusWriter.enqueue(service)
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+// applyServiceAnnotations configures the gRPC application using annotations on the Service.
+// Invalid annotation values are logged and ignored. For some annotations, invalid values keep
+// the configuration from the previous version of the gRPC application instead.
+func applyServiceAnnotations(logger logr.Logger, app *xds.GRPCApplication, service *corev1.Service, services []string, previous xds.GRPCApplication) {
+	logger = logger.WithValues("service", service.GetName())
+	annotations := service.GetAnnotations()
+	trafficSplit, err := xds.TrafficSplitFromAnnotations(annotations, services)
+	if err != nil {
+		logger.Error(err, "Ignoring invalid traffic split annotation")
+	} else {
+		app.TrafficSplit = trafficSplit
+	}
+	app.OutlierDetection = xds.OutlierDetectionFromAnnotations(logger, annotations)
+	app.CircuitBreakers = xds.CircuitBreakersFromAnnotations(logger, annotations)
+	app.RetryPolicy = xds.RetryPolicyFromAnnotations(logger, annotations)
+	app.GRPCTimeouts = xds.GRPCTimeoutsFromAnnotations(logger, annotations)
+	lbPolicy, err := xds.LBPolicyFromAnnotations(logger, annotations)
+	if err != nil {
+		logger.Error(err, "Invalid load balancing policy annotations, keeping the previous load balancing policy", "lbPolicy", previous.LBPolicy)
+		lbPolicy = previous.LBPolicy
+	}
+	app.LBPolicy = lbPolicy
+	app.ConnectionOptions = xds.ConnectionOptionsFromAnnotations(logger, annotations)
+	healthCheck, err := xds.HealthCheckFromAnnotations(logger, annotations)
+	if err != nil {
+		logger.Error(err, "Invalid health check annotations, keeping the previous health check", "healthCheck", previous.HealthCheck)
+		healthCheck = previous.HealthCheck
+	}
+	app.HealthCheck = healthCheck
+	app.FaultInjection = xds.FaultInjectionFromAnnotations(logger, annotations)
+	app.HTTP2ProtocolOptions = xds.HTTP2ProtocolOptionsFromAnnotations(logger, annotations)
+	upstreamProtocol, err := xds.UpstreamProtocolFromAnnotations(annotations)
+	if err != nil {
+		logger.Error(err, "Invalid upstream protocol annotation, keeping the previous upstream protocol", "upstreamProtocol", previous.UpstreamProtocol)
+		upstreamProtocol = previous.UpstreamProtocol
+	}
+	app.UpstreamProtocol = upstreamProtocol
+	app.Labels = maps.Clone(service.GetLabels())
+	grpcServiceConfig, err := xds.GRPCServiceConfigFromAnnotations(annotations)
+	if err != nil {
+		logger.Error(err, "Invalid gRPC service config annotation, keeping the previous service config")
+		grpcServiceConfig = previous.GRPCServiceConfig
+	}
+	app.GRPCServiceConfig = grpcServiceConfig
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	informercache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	// grpcServiceConfigStatusAnnotation has the result of validating the gRPC service config annotation.
+	grpcServiceConfigStatusAnnotation = "xds.example.com/grpc-service-config-status"
+	grpcServiceConfigStatusAccepted   = "Accepted"
+	grpcServiceConfigStatusInvalid    = "Invalid"
+
+	// serviceStatusMaxAttempts is the number of attempts to patch the status annotation of a Service.
+	serviceStatusMaxAttempts = 5
+)
+
+// serviceStatusWriter writes the gRPC service config status annotation of Services from a work queue,
+// so that computing xDS resources from informer events does not wait for requests to the API server.
+// The work queue holds each Service at most once, so pending patches of the same Service are coalesced.
+type serviceStatusWriter struct {
+	logger          logr.Logger
+	serviceInformer informercache.SharedIndexInformer
+	queue           workqueue.RateLimitingInterface
+	// patchService applies the JSON merge patch to the Service.
+	patchService func(ctx context.Context, namespace string, name string, patch []byte) error
+}
+
+func newServiceStatusWriter(logger logr.Logger, serviceInformer informercache.SharedIndexInformer, patchService func(ctx context.Context, namespace string, name string, patch []byte) error) *serviceStatusWriter {
+	return &serviceStatusWriter{
+		logger:          logger,
+		serviceInformer: serviceInformer,
+		patchService:    patchService,
+		queue: workqueue.NewRateLimitingQueueWithConfig(
+			workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{Name: "grpc-service-config-status"},
+		),
+	}
+}
+
+// enqueue schedules a patch of the status annotation of the Service, if the status annotation
+// does not match the result of validating the gRPC service config annotation.
+func (w *serviceStatusWriter) enqueue(service *corev1.Service) {
+	if grpcServiceConfigStatus(service.GetAnnotations()) == service.GetAnnotations()[grpcServiceConfigStatusAnnotation] {
+		return
+	}
+	key, err := informercache.MetaNamespaceKeyFunc(service)
+	if err != nil {
+		w.logger.Error(err, "Could not create key for Service", "namespace", service.GetNamespace(), "service", service.GetName())
+		return
+	}
+	w.queue.Add(key)
+}
+
+// run processes the queued Services until the context is done.
+func (w *serviceStatusWriter) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		w.queue.ShutDown()
+	}()
+	for w.processNext(ctx) {
+	}
+}
+
+func (w *serviceStatusWriter) processNext(ctx context.Context) bool {
+	item, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(item)
+	key, ok := item.(string)
+	if !ok {
+		w.queue.Forget(item)
+		return true
+	}
+	if err := w.setGRPCServiceConfigStatus(ctx, key); err != nil {
+		if attempt := w.queue.NumRequeues(item) + 1; attempt < serviceStatusMaxAttempts {
+			w.logger.V(1).Info("Warning: retrying write of the gRPC service config status annotation", "service", key, "attempt", attempt, "error", err.Error())
+			w.queue.AddRateLimited(item)
+			return true
+		}
+		w.logger.Error(err, "Could not write the gRPC service config status annotation", "service", key)
+	}
+	w.queue.Forget(item)
+	return true
+}
+
+// setGRPCServiceConfigStatus writes the result of validating the gRPC service config annotation of the
+// Service to the status annotation, or removes the status annotation if there is no service config annotation.
+// The Service is read from the informer cache when the patch is made, and only patched if the status changed,
+// so the resulting Service update event does not lead to another patch.
+func (w *serviceStatusWriter) setGRPCServiceConfigStatus(ctx context.Context, key string) error {
+	obj, exists, err := w.serviceInformer.GetIndexer().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		return fmt.Errorf("%w: expected *corev1.Service, got %T", errUnexpectedType, obj)
+	}
+	annotations := service.GetAnnotations()
+	status := grpcServiceConfigStatus(annotations)
+	if status == annotations[grpcServiceConfigStatusAnnotation] {
+		return nil
+	}
+	// A null value in a JSON merge patch removes the annotation.
+	var statusValue interface{}
+	if status != "" {
+		statusValue = status
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				grpcServiceConfigStatusAnnotation: statusValue,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not create patch for the gRPC service config status annotation: %w", err)
+	}
+	if err := w.patchService(ctx, service.GetNamespace(), service.GetName(), patch); err != nil {
+		return fmt.Errorf("could not patch Service %s with status %q: %w", key, status, err)
+	}
+	w.logger.V(2).Info("Wrote the gRPC service config status annotation", "service", key, "status", status)
+	return nil
+}
+
+// grpcServiceConfigStatus returns the value of the status annotation for the gRPC service config
+// annotation, or the empty string if there is no gRPC service config annotation.
+func grpcServiceConfigStatus(annotations map[string]string) string {
+	grpcServiceConfig, err := xds.GRPCServiceConfigFromAnnotations(annotations)
+	switch {
+	case err != nil:
+		return grpcServiceConfigStatusInvalid + ": " + err.Error()
+	case grpcServiceConfig != "":
+		return grpcServiceConfigStatusAccepted
+	default:
+		return ""
+	}
+}
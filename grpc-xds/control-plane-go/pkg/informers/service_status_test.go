@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	informercache "k8s.io/client-go/tools/cache"
+)
+
+const testGRPCServiceConfigAnnotation = "xds.example.com/grpc-service-config"
+
+func TestGRPCServiceConfigStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantPrefix  string
+	}{
+		{
+			name:        "no annotation",
+			annotations: nil,
+			wantPrefix:  "",
+		},
+		{
+			name:        "valid service config",
+			annotations: map[string]string{testGRPCServiceConfigAnnotation: `{"loadBalancingConfig": [{"round_robin": {}}]}`},
+			wantPrefix:  grpcServiceConfigStatusAccepted,
+		},
+		{
+			name:        "malformed JSON",
+			annotations: map[string]string{testGRPCServiceConfigAnnotation: `{"loadBalancingConfig": `},
+			wantPrefix:  grpcServiceConfigStatusInvalid + ": ",
+		},
+		{
+			name:        "unknown field",
+			annotations: map[string]string{testGRPCServiceConfigAnnotation: `{"unknownField": true}`},
+			wantPrefix:  grpcServiceConfigStatusInvalid + ": ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := grpcServiceConfigStatus(tt.annotations)
+			if !strings.HasPrefix(got, tt.wantPrefix) || (tt.wantPrefix == "" && got != "") {
+				t.Errorf("grpcServiceConfigStatus() = %q, want prefix %q", got, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestServiceStatusWriter(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "greeter",
+			Namespace:   "default",
+			Annotations: map[string]string{testGRPCServiceConfigAnnotation: `{"loadBalancingConfig": [{"round_robin": {}}]}`},
+		},
+	}
+	serviceInformer := informercache.NewSharedIndexInformer(&informercache.ListWatch{}, &corev1.Service{}, 0, informercache.Indexers{})
+	if err := serviceInformer.GetIndexer().Add(service); err != nil {
+		t.Fatalf("could not add Service to indexer: %v", err)
+	}
+	var patches []string
+	w := newServiceStatusWriter(logr.Discard(), serviceInformer, func(_ context.Context, namespace string, name string, patch []byte) error {
+		patches = append(patches, namespace+"/"+name+" "+string(patch))
+		return nil
+	})
+	t.Cleanup(w.queue.ShutDown)
+
+	w.enqueue(service)
+	w.enqueue(service)
+	if got := w.queue.Len(); got != 1 {
+		t.Fatalf("queue length after enqueueing the same Service twice = %d, want 1", got)
+	}
+	if !w.processNext(context.Background()) {
+		t.Fatal("processNext() = false, want true")
+	}
+	wantPatch := `default/greeter {"metadata":{"annotations":{"xds.example.com/grpc-service-config-status":"Accepted"}}}`
+	if len(patches) != 1 || patches[0] != wantPatch {
+		t.Fatalf("patches = %q, want [%q]", patches, wantPatch)
+	}
+
+	// The update event of the patched Service must not lead to another patch.
+	patched := service.DeepCopy()
+	patched.Annotations[grpcServiceConfigStatusAnnotation] = grpcServiceConfigStatusAccepted
+	w.enqueue(patched)
+	if got := w.queue.Len(); got != 0 {
+		t.Errorf("queue length after enqueueing a Service with an up-to-date status = %d, want 0", got)
+	}
+
+	// Removing the service config annotation removes the status annotation.
+	removed := patched.DeepCopy()
+	delete(removed.Annotations, testGRPCServiceConfigAnnotation)
+	if err := serviceInformer.GetIndexer().Update(removed); err != nil {
+		t.Fatalf("could not update Service in indexer: %v", err)
+	}
+	w.enqueue(removed)
+	if !w.processNext(context.Background()) {
+		t.Fatal("processNext() = false, want true")
+	}
+	wantPatch = `default/greeter {"metadata":{"annotations":{"xds.example.com/grpc-service-config-status":null}}}`
+	if len(patches) != 2 || patches[1] != wantPatch {
+		t.Errorf("patches = %q, want second patch %q", patches, wantPatch)
+	}
+}
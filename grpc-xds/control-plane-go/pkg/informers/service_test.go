@@ -0,0 +1,61 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	informercache "k8s.io/client-go/tools/cache"
+)
+
+func TestIsListedService(t *testing.T) {
+	services := []string{"greeter-leaf", "greeter-intermediary"}
+	listed := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "greeter-leaf", Namespace: "default"}}
+	tests := []struct {
+		name string
+		obj  interface{}
+		want bool
+	}{
+		{
+			name: "listed Service",
+			obj:  listed,
+			want: true,
+		},
+		{
+			name: "Service that is not listed",
+			obj:  &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}},
+			want: false,
+		},
+		{
+			name: "tombstone of a listed Service",
+			obj:  informercache.DeletedFinalStateUnknown{Key: "default/greeter-leaf", Obj: listed},
+			want: true,
+		},
+		{
+			name: "object that is not a Kubernetes resource",
+			obj:  "greeter-leaf",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isListedService(tt.obj, services); got != tt.want {
+				t.Errorf("isListedService() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
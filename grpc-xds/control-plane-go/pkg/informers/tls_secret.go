@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	informercache "k8s.io/client-go/tools/cache"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+// tlsSecretCACertificatesKey is the key of the optional CA certificates in TLS Secrets, as written by cert-manager.
+const tlsSecretCACertificatesKey = "ca.crt"
+
+var errInvalidTLSSecretName = errors.New("invalid TLS Secret name, expected <namespace>/<name>")
+
+// AddTLSSecretInformer creates an informer for the Kubernetes Secret of type `kubernetes.io/tls`
+// with the provided name, `<namespace>/<name>`, and updates the SDS resources of the xDS resource
+// cache when the Secret changes, e.g., when cert-manager rotates the certificate.
+func (m *Manager) AddTLSSecretInformer(ctx context.Context, logger logr.Logger, secretName string) error {
+	namespace, name, err := splitTLSSecretName(secretName)
+	if err != nil {
+		return err
+	}
+	logger = logger.WithValues("component", "informers", "kubecontext", m.kubecontext, "namespace", namespace, "secret", name)
+	fieldSelector := fields.AndSelectors(
+		fields.OneTermEqualSelector("metadata.name", name),
+		fields.OneTermEqualSelector("type", string(corev1.SecretTypeTLS)),
+	).String()
+	logger.V(2).Info("Creating informer for TLS Secret", "fieldSelector", fieldSelector)
+	factory := informers.NewSharedInformerFactoryWithOptions(m.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fieldSelector
+		}))
+	informer := factory.Core().V1().Secrets().Informer()
+	// Coalesce bursts of events into a single xDS resource update.
+	eventDebouncer := NewDebouncer(debounceWindow(), debounceMaxWindow(), func(events []string) {
+		m.handleTLSSecrets(ctx, logger.WithValues("events", len(events)), namespace, informer)
+	})
+	m.debouncers = append(m.debouncers, eventDebouncer)
+	handleEvent := func(eventType string) {
+		metrics.K8sWatchEvent("Secret", eventType)
+		eventDebouncer.Add(eventType)
+	}
+	registration, err := informer.AddEventHandler(informercache.ResourceEventHandlerFuncs{
+		AddFunc: func(_ interface{}) {
+			handleEvent("add")
+		},
+		UpdateFunc: func(_, _ interface{}) {
+			handleEvent("update")
+		},
+		DeleteFunc: func(_ interface{}) {
+			handleEvent("delete")
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not add informer event handler for TLS Secret %s in kubecontext=%s: %w", secretName, m.kubecontext, err)
+	}
+	m.informers = append(m.informers, informer)
+	m.handlersSynced = append(m.handlersSynced, registration.HasSynced)
+	go func() {
+		logger.V(2).Info("Starting informer for TLS Secret")
+		informer.Run(ctx.Done())
+	}()
+	return nil
+}
+
+// splitTLSSecretName returns the namespace and the name of a TLS Secret name, `<namespace>/<name>`.
+func splitTLSSecretName(secretName string) (string, string, error) {
+	namespace, name, found := strings.Cut(secretName, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("%w: %q", errInvalidTLSSecretName, secretName)
+	}
+	return namespace, name, nil
+}
+
+func (m *Manager) handleTLSSecrets(ctx context.Context, logger logr.Logger, namespace string, informer informercache.SharedIndexInformer) {
+	var tlsSecrets []xds.TLSSecret
+	for _, obj := range informer.GetIndexer().List() {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			logger.Error(fmt.Errorf("%w: expected *corev1.Secret, got %T", errUnexpectedType, obj), "Skipping TLS Secret")
+			continue
+		}
+		certificateChain := secret.Data[corev1.TLSCertKey]
+		privateKey := secret.Data[corev1.TLSPrivateKeyKey]
+		if len(certificateChain) == 0 || len(privateKey) == 0 {
+			logger.V(1).Info("Warning: skipping TLS Secret without certificate chain or private key", "name", secret.GetName())
+			continue
+		}
+		tlsSecrets = append(tlsSecrets, xds.TLSSecret{
+			Namespace:        secret.GetNamespace(),
+			Name:             secret.GetName(),
+			CertificateChain: certificateChain,
+			PrivateKey:       privateKey,
+			CACertificates:   secret.Data[tlsSecretCACertificatesKey],
+		})
+	}
+	logger.V(2).Info("Informer resource update", "tlsSecrets", len(tlsSecrets))
+	if err := m.xdsCache.UpdateTLSSecrets(ctx, logger, m.kubecontext, namespace, tlsSecrets); err != nil {
+		logger.Error(err, "Could not update the xDS resource cache with TLS Secrets")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSplitTLSSecretName(t *testing.T) {
+	tests := []struct {
+		secretName    string
+		wantNamespace string
+		wantName      string
+		wantErr       error
+	}{
+		{secretName: "xds/control-plane-tls", wantNamespace: "xds", wantName: "control-plane-tls"},
+		{secretName: "control-plane-tls", wantErr: errInvalidTLSSecretName},
+		{secretName: "/control-plane-tls", wantErr: errInvalidTLSSecretName},
+		{secretName: "xds/", wantErr: errInvalidTLSSecretName},
+		{secretName: "xds/control-plane/tls", wantErr: errInvalidTLSSecretName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secretName, func(t *testing.T) {
+			namespace, name, err := splitTLSSecretName(tt.secretName)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("splitTLSSecretName(%q) error = %v, want %v", tt.secretName, err, tt.wantErr)
+			}
+			if namespace != tt.wantNamespace || name != tt.wantName {
+				t.Errorf("splitTLSSecretName(%q) = (%q, %q), want (%q, %q)", tt.secretName, namespace, name, tt.wantNamespace, tt.wantName)
+			}
+		})
+	}
+}
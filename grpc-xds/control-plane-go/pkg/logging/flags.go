@@ -15,12 +15,91 @@
 package logging
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
 
 	"k8s.io/klog/v2"
 )
 
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+
+	// Verbosity levels, see
+	// https://github.com/kubernetes/community/blob/1a09f121536ddb84c1429c88fbb3978d6c5e2dd0/contributors/devel/sig-instrumentation/logging.md#what-method-to-use
+	debugVerbosity = 4
+	infoVerbosity  = 2
+	warnVerbosity  = 1
+	errorVerbosity = 0
+)
+
+var (
+	errInvalidLogFormat = errors.New("invalid log format, must be one of text, json")
+	errInvalidLogLevel  = errors.New("invalid log level, must be one of debug, info, warn, error")
+
+	logFormat = logFormatText
+	logLevel  string
+	// verbosity is the klog verbosity from the `v` flag, used by the JSON format without a log level,
+	// so that both formats log the same messages for the same flags.
+	verbosity = errorVerbosity
+
+	verbosityByLogLevel = map[string]int{
+		logLevelDebug: debugVerbosity,
+		logLevelInfo:  infoVerbosity,
+		logLevelWarn:  warnVerbosity,
+		logLevelError: errorVerbosity,
+	}
+)
+
 // InitFlags initializes logging-related flags.
 func InitFlags(flagset *flag.FlagSet) {
 	klog.InitFlags(flagset)
+	flagset.StringVar(&logFormat, "log-format", logFormatText, "(optional) log output format, one of text (human-readable), json")
+	flagset.StringVar(&logLevel, "log-level", "", "(optional) log level, one of debug, info, warn, error; overrides the -v flag")
+}
+
+// ApplyFlags validates the logging flags after parsing.
+// For the text format, the log level is applied by setting the klog verbosity flag.
+func ApplyFlags(flagset *flag.FlagSet) error {
+	if logFormat != logFormatText && logFormat != logFormatJSON {
+		return fmt.Errorf("%w: got %q", errInvalidLogFormat, logFormat)
+	}
+	if logLevel == "" {
+		if v := flagset.Lookup("v"); v != nil {
+			klogVerbosity, err := strconv.Atoi(v.Value.String())
+			if err != nil {
+				return fmt.Errorf("could not parse klog verbosity %q: %w", v.Value.String(), err)
+			}
+			verbosity = klogVerbosity
+		}
+		return nil
+	}
+	levelVerbosity, exists := verbosityByLogLevel[logLevel]
+	if !exists {
+		return fmt.Errorf("%w: got %q", errInvalidLogLevel, logLevel)
+	}
+	verbosity = levelVerbosity
+	if logFormat == logFormatText {
+		if err := flagset.Set("v", strconv.Itoa(levelVerbosity)); err != nil {
+			return fmt.Errorf("could not set klog verbosity for log level %s: %w", logLevel, err)
+		}
+	}
+	return nil
+}
+
+// slogLevel maps the log level flag, or without it, the klog verbosity, to a slog level.
+// logr verbosity `V(n)` maps to slog level `-n`, and errors always use `slog.LevelError`.
+func slogLevel() slog.Level {
+	if logLevel == logLevelError {
+		return slog.LevelError
+	}
+	return slog.Level(-verbosity)
 }
@@ -0,0 +1,109 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"testing"
+)
+
+func TestApplyFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		level         string
+		verbosity     string
+		wantErr       error
+		wantVerbosity string
+		wantSlogLevel slog.Level
+	}{
+		{
+			name:          "defaults",
+			format:        logFormatText,
+			wantVerbosity: "0",
+			wantSlogLevel: slog.LevelInfo,
+		},
+		{
+			name:          "json without level uses klog verbosity",
+			format:        logFormatJSON,
+			verbosity:     "2",
+			wantVerbosity: "2",
+			wantSlogLevel: slog.Level(-2),
+		},
+		{
+			name:          "text debug",
+			format:        logFormatText,
+			level:         logLevelDebug,
+			wantVerbosity: "4",
+			wantSlogLevel: slog.Level(-debugVerbosity),
+		},
+		{
+			name:          "text warn",
+			format:        logFormatText,
+			level:         logLevelWarn,
+			wantVerbosity: "1",
+			wantSlogLevel: slog.Level(-warnVerbosity),
+		},
+		{
+			name:          "json error does not set klog verbosity",
+			format:        logFormatJSON,
+			level:         logLevelError,
+			wantVerbosity: "0",
+			wantSlogLevel: slog.LevelError,
+		},
+		{
+			name:    "invalid format",
+			format:  "xml",
+			wantErr: errInvalidLogFormat,
+		},
+		{
+			name:    "invalid level",
+			format:  logFormatJSON,
+			level:   "trace",
+			wantErr: errInvalidLogLevel,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagset := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			InitFlags(flagset)
+			t.Cleanup(func() {
+				_ = flagset.Set("v", "0")
+				logFormat, logLevel, verbosity = logFormatText, "", errorVerbosity
+			})
+			logFormat, logLevel = tt.format, tt.level
+			if tt.verbosity != "" {
+				if err := flagset.Set("v", tt.verbosity); err != nil {
+					t.Fatalf("could not set klog verbosity: %v", err)
+				}
+			}
+			err := ApplyFlags(flagset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApplyFlags() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := flagset.Lookup("v").Value.String(); got != tt.wantVerbosity {
+				t.Errorf("klog verbosity = %s, want %s", got, tt.wantVerbosity)
+			}
+			if got := slogLevel(); got != tt.wantSlogLevel {
+				t.Errorf("slogLevel() = %v, want %v", got, tt.wantSlogLevel)
+			}
+		})
+	}
+}
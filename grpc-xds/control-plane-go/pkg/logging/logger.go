@@ -15,14 +15,36 @@
 package logging
 
 import (
+	"io"
+	"log/slog"
+	"os"
+
 	"github.com/go-logr/logr"
 	"k8s.io/klog/v2"
 )
 
 // Ref: https://github.com/kubernetes/community/blob/1a09f121536ddb84c1429c88fbb3978d6c5e2dd0/contributors/devel/sig-instrumentation/logging.md
 
+// NewLogger creates a logger that writes human-readable text using klog,
+// or JSON using slog if the `log-format` flag value is `json`.
 func NewLogger() logr.Logger {
-	logger := klog.NewKlogr()
-	logger.WithCallDepth(2).V(1).Info("Creating new logger")
+	return newLogger(os.Stderr)
+}
+
+// newLogger creates a logger that writes JSON to w, if the `log-format` flag value is `json`.
+// Text output uses klog, which writes to its own output.
+func newLogger(w io.Writer) logr.Logger {
+	var logger logr.Logger
+	if logFormat == logFormatJSON {
+		logger = logr.FromSlogHandler(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			AddSource: true,
+			Level:     slogLevel(),
+		}))
+		// Route log output from libraries that use klog, such as client-go, to the JSON logger.
+		klog.SetLogger(logger)
+	} else {
+		logger = klog.NewKlogr()
+	}
+	logger.WithCallDepth(3).V(1).Info("Creating new logger")
 	return logger
 }
@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestNewLoggerJSON(t *testing.T) {
+	t.Cleanup(func() {
+		klog.ClearLogger()
+		logFormat, logLevel, verbosity = logFormatText, "", errorVerbosity
+	})
+	logFormat, logLevel, verbosity = logFormatJSON, logLevelInfo, infoVerbosity
+	var buf bytes.Buffer
+	logger := newLogger(&buf)
+	logger.V(2).Info("Informer resource update", "namespace", "default")
+	logger.V(4).Info("Debug message that is not logged")
+	logger.Error(errors.New("boom"), "Could not update")
+
+	// The first line is the V(1) message from creating the logger.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3: %s", len(lines), buf.String())
+	}
+	lines = lines[1:]
+	for i, want := range []map[string]string{
+		{"level": "DEBUG+2", "msg": "Informer resource update", "namespace": "default"},
+		{"level": "ERROR", "msg": "Could not update", "err": "boom"},
+	} {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("log line %d is not valid JSON: %v: %s", i, err, lines[i])
+		}
+		for _, field := range []string{"time", "source"} {
+			if _, exists := entry[field]; !exists {
+				t.Errorf("log line %d has no field %q: %s", i, field, lines[i])
+			}
+		}
+		for field, value := range want {
+			if entry[field] != value {
+				t.Errorf("log line %d field %q = %v, want %q", i, field, entry[field], value)
+			}
+		}
+	}
+}
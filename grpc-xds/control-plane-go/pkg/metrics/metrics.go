@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	labelEventType    = "event_type"
+	labelKind         = "kind"
+	labelReason       = "reason"
+	labelResourceType = "resource_type"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	xdsClientsConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "xds_clients_connected",
+		Help: "Number of open xDS streams, both state-of-the-world and delta.",
+	})
+	xdsSnapshotUpdatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xds_snapshot_updates_total",
+		Help: "Number of xDS resource snapshot updates, by resource type.",
+	}, []string{labelResourceType})
+	xdsSnapshotUpdateDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "xds_snapshot_update_duration_seconds",
+		Help:    "Time taken to build and set an xDS resource snapshot for a node hash.",
+		Buckets: prometheus.DefBuckets,
+	})
+	xdsSnapshotInconsistenciesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "xds_snapshot_inconsistencies_total",
+		Help: "Number of xDS resource snapshots that were not set because they referenced missing resources.",
+	})
+	xdsSnapshotResyncCorrectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xds_snapshot_resync_corrections_total",
+		Help: "Number of xDS resource snapshots corrected by a periodic resync, by resource type.",
+	}, []string{labelResourceType})
+	xdsNACKTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "xds_nack_total",
+		Help: "Number of xDS responses rejected by xDS clients, by resource type and reason (gRPC status code).",
+	}, []string{labelResourceType, labelReason})
+	k8sWatchEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_watch_events_total",
+		Help: "Number of Kubernetes informer events, by resource kind and event type.",
+	}, []string{labelKind, labelEventType})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		xdsClientsConnected,
+		xdsSnapshotUpdatesTotal,
+		xdsSnapshotUpdateDurationSeconds,
+		xdsSnapshotInconsistenciesTotal,
+		xdsSnapshotResyncCorrectionsTotal,
+		xdsNACKTotal,
+		k8sWatchEventsTotal,
+	)
+}
+
+// XDSClientConnected records that an xDS stream was opened.
+func XDSClientConnected() {
+	xdsClientsConnected.Inc()
+}
+
+// XDSClientDisconnected records that an xDS stream was closed.
+func XDSClientDisconnected() {
+	xdsClientsConnected.Dec()
+}
+
+// XDSSnapshotUpdated records a successful snapshot update that changed the provided resource types,
+// and the time it took to build and set the snapshot.
+func XDSSnapshotUpdated(duration time.Duration, resourceTypes ...string) {
+	for _, resourceType := range resourceTypes {
+		xdsSnapshotUpdatesTotal.WithLabelValues(resourceType).Inc()
+	}
+	xdsSnapshotUpdateDurationSeconds.Observe(duration.Seconds())
+}
+
+// XDSSnapshotInconsistent records that an xDS resource snapshot was not set, because it was inconsistent.
+func XDSSnapshotInconsistent() {
+	xdsSnapshotInconsistenciesTotal.Inc()
+}
+
+// XDSSnapshotResyncCorrected records that a periodic resync found differences in the provided
+// resource types between the current snapshot and the snapshot built from the informer caches.
+func XDSSnapshotResyncCorrected(resourceTypes ...string) {
+	for _, resourceType := range resourceTypes {
+		xdsSnapshotResyncCorrectionsTotal.WithLabelValues(resourceType).Inc()
+	}
+}
+
+// XDSNACK records that an xDS client rejected a response with resources of the provided type.
+// The reason is the name of the gRPC status code in the error detail of the NACK, instead of the
+// error message, to limit the cardinality of the metric.
+func XDSNACK(resourceType string, reason string) {
+	xdsNACKTotal.WithLabelValues(resourceType, reason).Inc()
+}
+
+// K8sWatchEvent records an informer event, e.g., kind=EndpointSlice and eventType=add.
+func K8sWatchEvent(kind string, eventType string) {
+	k8sWatchEventsTotal.WithLabelValues(kind, eventType).Inc()
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegistry(t *testing.T) {
+	// Vectors are only gathered once they have a child, so record one value for each metric.
+	XDSClientConnected()
+	XDSSnapshotUpdated(time.Millisecond, "cds")
+	XDSSnapshotInconsistent()
+	XDSSnapshotResyncCorrected("cds")
+	XDSNACK("cds", "InvalidArgument")
+	K8sWatchEvent("EndpointSlice", "add")
+	t.Cleanup(XDSClientDisconnected)
+
+	names := []string{
+		"xds_clients_connected",
+		"xds_snapshot_updates_total",
+		"xds_snapshot_update_duration_seconds",
+		"xds_snapshot_inconsistencies_total",
+		"xds_snapshot_resync_corrections_total",
+		"xds_nack_total",
+		"k8s_watch_events_total",
+		"go_goroutines",
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("registry.Gather() error = %v", err)
+	}
+	gathered := map[string]bool{}
+	for _, family := range families {
+		gathered[family.GetName()] = true
+	}
+	for _, name := range names {
+		if !gathered[name] {
+			t.Errorf("metric %s is not registered", name)
+		}
+	}
+}
+
+func TestCounters(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func()
+		value  func() float64
+		want   float64
+	}{
+		{
+			name: "xds_clients_connected",
+			record: func() {
+				XDSClientConnected()
+				XDSClientConnected()
+				XDSClientDisconnected()
+			},
+			value: func() float64 { return testutil.ToFloat64(xdsClientsConnected) },
+			want:  1,
+		},
+		{
+			name: "xds_snapshot_updates_total",
+			record: func() {
+				XDSSnapshotUpdated(time.Millisecond, "cds", "eds")
+				XDSSnapshotUpdated(time.Millisecond, "eds")
+			},
+			value: func() float64 { return testutil.ToFloat64(xdsSnapshotUpdatesTotal.WithLabelValues("eds")) },
+			want:  2,
+		},
+		{
+			name: "xds_snapshot_inconsistencies_total",
+			record: func() {
+				XDSSnapshotInconsistent()
+			},
+			value: func() float64 { return testutil.ToFloat64(xdsSnapshotInconsistenciesTotal) },
+			want:  1,
+		},
+		{
+			name: "xds_snapshot_resync_corrections_total",
+			record: func() {
+				XDSSnapshotResyncCorrected("lds", "rds")
+			},
+			value: func() float64 { return testutil.ToFloat64(xdsSnapshotResyncCorrectionsTotal.WithLabelValues("rds")) },
+			want:  1,
+		},
+		{
+			name: "xds_nack_total",
+			record: func() {
+				XDSNACK("lds", "InvalidArgument")
+				XDSNACK("lds", "InvalidArgument")
+				XDSNACK("lds", "Unknown")
+			},
+			value: func() float64 { return testutil.ToFloat64(xdsNACKTotal.WithLabelValues("lds", "InvalidArgument")) },
+			want:  2,
+		},
+		{
+			name: "k8s_watch_events_total",
+			record: func() {
+				K8sWatchEvent("Service", "update")
+				K8sWatchEvent("Service", "delete")
+			},
+			value: func() float64 { return testutil.ToFloat64(k8sWatchEventsTotal.WithLabelValues("Service", "update")) },
+			want:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.value()
+			tt.record()
+			if got := tt.value() - before; got != tt.want {
+				t.Errorf("%s increased by %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsPath             = "/metrics"
+	serverReadHeaderTimeout = 5 * time.Second
+	serverShutdownTimeout   = 5 * time.Second
+)
+
+// StartServer starts an HTTP server that serves Prometheus metrics on the
+// `/metrics` path, if the `metrics-addr` flag is set.
+// The server shuts down when the provided context is done.
+func StartServer(ctx context.Context, logger logr.Logger) error {
+	if metricsAddr == "" {
+		logger.V(2).Info("Not serving metrics, as the metrics address is not set")
+		return nil
+	}
+	listener, err := net.Listen("tcp", metricsAddr)
+	if err != nil {
+		return fmt.Errorf("could not create TCP listener for metrics server on address=%s: %w", metricsAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "Could not gracefully shut down the metrics server")
+		}
+	}()
+	go func() {
+		logger.V(1).Info("Metrics server listening", "address", metricsAddr, "path", metricsPath)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err, "Metrics server stopped unexpectedly")
+		}
+	}()
+	return nil
+}
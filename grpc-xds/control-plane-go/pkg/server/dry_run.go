@@ -0,0 +1,65 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var errDryRunCacheSync = errors.New("stopped waiting for informer caches to sync")
+
+// runDryRun starts the informers and writes the xDS resources computed from the Kubernetes
+// resources to stdout, one JSON object per resource, instead of serving them to xDS clients.
+//
+// With the `dry-run-once` flag, it writes one snapshot after the informer caches have synced,
+// and returns. Otherwise, it writes a new snapshot after every update until the context is done.
+func runDryRun(ctx context.Context, logger logr.Logger, kubecontexts []informers.Kubecontext, xdsFeatures *xds.Features, authority string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger.V(2).Info("Dry-run mode, writing xDS resources to stdout instead of serving them", "once", dryRunOnce)
+	xdsCache := xds.NewSnapshotCache(ctx, true, nodeHash(logger, nil), xds.LocalityPriorityByZone{}, xdsFeatures, authority)
+	if !dryRunOnce {
+		xdsCache.EnableDryRun(os.Stdout)
+	}
+	if err := watchListenerConfig(ctx, logger, xdsCache); err != nil {
+		return fmt.Errorf("could not load listener configuration: %w", err)
+	}
+	informerManagers, err := createInformers(ctx, logger, kubecontexts, xdsCache, xdsFeatures)
+	if err != nil {
+		return fmt.Errorf("could not create Kubernetes informer managers: %w", err)
+	}
+	if !dryRunOnce {
+		<-ctx.Done()
+		return nil
+	}
+	if !waitForCacheSync(ctx, informerManagers) {
+		return errDryRunCacheSync
+	}
+	for _, informerManager := range informerManagers {
+		informerManager.Flush()
+	}
+	if err := xdsCache.WriteSnapshot(os.Stdout, ""); err != nil {
+		return fmt.Errorf("could not write xDS resources: %w", err)
+	}
+	return nil
+}
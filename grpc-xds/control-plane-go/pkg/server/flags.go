@@ -0,0 +1,87 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"time"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var (
+	tlsCertFile string
+	tlsKeyFile  string
+	tlsCAFile   string
+
+	leaderElection                bool
+	leaderElectionNamespace       string
+	leaderElectionName            string
+	leaderElectionReleaseOnCancel time.Duration
+
+	listenerConfigFile string
+
+	drainTimeout time.Duration
+
+	scopeMetadataKey string
+
+	xdsAddr          string
+	xdsSocket        string
+	xdsListenersFile string
+
+	dryRun     bool
+	dryRunOnce bool
+
+	maxReconcileAttempts int
+
+	resyncInterval time.Duration
+
+	nackRetryDelay time.Duration
+
+	cacheConfigMap string
+	cacheMaxAge    time.Duration
+
+	selfRegister        bool
+	selfRegisterService string
+)
+
+// InitFlags initializes flags for the xDS management server.
+func InitFlags(flagset *flag.FlagSet) {
+	if flagset == nil {
+		flagset = flag.CommandLine
+	}
+	flagset.StringVar(&tlsCertFile, "tls-cert", "", "(optional) path to the PEM-encoded server certificate chain file, enables mTLS together with -tls-key and -tls-ca")
+	flagset.StringVar(&tlsKeyFile, "tls-key", "", "(optional) path to the PEM-encoded server private key file, enables mTLS together with -tls-cert and -tls-ca")
+	flagset.StringVar(&tlsCAFile, "tls-ca", "", "(optional) path to the PEM-encoded CA certificates file used to verify client certificates, enables mTLS together with -tls-cert and -tls-key")
+	flagset.BoolVar(&leaderElection, "leader-election", false, "(optional) enable leader election, so that only one replica serves xDS resources at a time")
+	flagset.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "(optional) namespace of the Lease used for leader election, defaults to the namespace of this pod")
+	flagset.StringVar(&leaderElectionName, "leader-election-name", "control-plane", "(optional) name of the Lease used for leader election")
+	flagset.DurationVar(&leaderElectionReleaseOnCancel, "leader-election-release-on-cancel", gracefulStopTimeout, "(optional) maximum time to drain in-flight RPCs after losing leadership, before exiting")
+	flagset.StringVar(&listenerConfigFile, "listener-config", "", "(optional) path to a YAML file with HTTP connection manager settings for LDS API listeners, reloaded on changes and on SIGHUP")
+	flagset.DurationVar(&drainTimeout, "drain-timeout", defaultDrainTimeout, "(optional) maximum time to wait for xDS streams to end on shutdown, before closing them")
+	flagset.StringVar(&scopeMetadataKey, "scope-metadata-key", "", "(optional) name of the xDS node metadata field with the namespace that scopes the snapshot for the node, e.g., NAMESPACE, snapshots are not scoped if empty")
+	flagset.StringVar(&xdsAddr, "xds-addr", "", "(optional) TCP address of the xDS management server, e.g., 127.0.0.1:50051, defaults to all interfaces and the port from the PORT environment variable, mutually exclusive with -xds-socket")
+	flagset.StringVar(&xdsSocket, "xds-socket", "", "(optional) path of a Unix domain socket for the xDS management server, instead of TCP, mutually exclusive with -xds-addr")
+	flagset.StringVar(&xdsListenersFile, "xds-listeners", "", "(optional) path to a YAML file with node groups, each with a port for an additional xDS management server listener, and a selector matched against node metadata")
+	flagset.BoolVar(&dryRun, "dry-run", false, "(optional) write the xDS resources computed from Kubernetes resources to stdout after every update, instead of serving them to xDS clients")
+	flagset.BoolVar(&dryRunOnce, "dry-run-once", false, "(optional) like -dry-run, but write the xDS resources once after the informer caches have synced, and exit")
+	flagset.IntVar(&maxReconcileAttempts, "reconcile-max-attempts", xds.DefaultMaxReconcileAttempts, "(optional) maximum number of attempts to update the xDS resource snapshot for a node hash after a failed update, with exponential back-off between attempts")
+	flagset.DurationVar(&resyncInterval, "resync-interval", defaultResyncInterval, "(optional) interval between full rebuilds of the xDS resource snapshots from the informer caches, to correct missed events, 0 to disable")
+	flagset.DurationVar(&nackRetryDelay, "nack-retry-delay", xds.DefaultNACKRetryDelay, "(optional) delay after an xDS client rejects resources (NACK) before the last accepted resources are set again")
+	flagset.StringVar(&cacheConfigMap, "cache-configmap", "", "(optional) name of a ConfigMap in the namespace of this pod where the xDS resource snapshots are written after updates, and restored from on startup, so that xDS clients receive resources before the informer caches sync")
+	flagset.DurationVar(&cacheMaxAge, "cache-max-age", defaultCacheMaxAge, "(optional) maximum age of the snapshots in the ConfigMap from -cache-configmap to restore them on startup")
+	flagset.BoolVar(&selfRegister, "self-register", false, "(optional) add the IP address of this pod to the Endpoints of the headless Service from -self-register-service while serving, so that xDS clients can discover the control plane by DNS, requires the POD_IP environment variable")
+	flagset.StringVar(&selfRegisterService, "self-register-service", "control-plane-xds", "(optional) name of the headless Service without a selector used for self-registration, created if it does not exist")
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
+)
+
+// Leader election timings, using the defaults from kube-controller-manager.
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+var errLeadershipLost = errors.New("lost leadership")
+
+// runLeaderElection participates in leader election using a `Lease` lock, and calls
+// `onStartedLeading` in a new goroutine after acquiring leadership.
+//
+// When ctx is done, the leader keeps the Lease until serveCtx is done, i.e., until draining
+// finishes, see `addServerStopBehavior()`, so that a new leader does not serve xDS clients
+// while this replica still serves them. Replicas that are not leading stop campaigning when
+// ctx is done.
+//
+// This function blocks until the Lease is released, or until leadership is lost.
+// It returns `errLeadershipLost` if leadership was lost before ctx was done.
+func runLeaderElection(ctx context.Context, serveCtx context.Context, logger logr.Logger, onStartedLeading func(context.Context)) error {
+	namespace := leaderElectionNamespace
+	if namespace == "" {
+		var err error
+		namespace, err = config.Namespace(logger)
+		if err != nil {
+			return fmt.Errorf("could not determine namespace for leader election Lease: %w", err)
+		}
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("could not determine leader election identity from hostname: %w", err)
+	}
+	// Using the kubecontext of the cluster where the control plane runs.
+	clientset, err := informers.NewClientSet(ctx, "")
+	if err != nil {
+		return fmt.Errorf("could not create Kubernetes clientset for leader election: %w", err)
+	}
+	logger = logger.WithValues("lease", leaderElectionName, "namespace", namespace, "identity", identity)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      leaderElectionName,
+				Namespace: namespace,
+			},
+			Client: clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: identity,
+			},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaderElectionName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				if ctx.Err() != nil {
+					logger.V(1).Info("Acquired leadership while draining, not serving")
+					return
+				}
+				onStartedLeading(leaderCtx)
+			},
+			OnStoppedLeading: func() {
+				logger.V(1).Info("Stopped leading")
+			},
+			OnNewLeader: func(leader string) {
+				logger.V(2).Info("Observed new leader", "leader", leader)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not create leader elector: %w", err)
+	}
+	// ReleaseOnCancel releases the Lease when electionCtx is done.
+	electionCtx, cancelElection := context.WithCancel(serveCtx)
+	defer cancelElection()
+	go func() {
+		select {
+		case <-ctx.Done():
+			if !elector.IsLeader() {
+				cancelElection()
+			}
+		case <-electionCtx.Done():
+		}
+	}()
+	logger.V(1).Info("Starting leader election")
+	elector.Run(electionCtx)
+	if ctx.Err() == nil {
+		return errLeadershipLost
+	}
+	return nil
+}
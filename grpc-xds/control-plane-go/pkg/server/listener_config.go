@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+// watchListenerConfig loads the listener configuration file from the `listener-config` flag,
+// and reloads it in a new goroutine when the file changes, or when the process receives SIGHUP.
+// If the flag is not set, the compiled-in defaults are used, and the function returns immediately.
+//
+// If reloading fails, the error is logged, and the previous listener configuration stays in use.
+func watchListenerConfig(ctx context.Context, logger logr.Logger, xdsCache *xds.SnapshotCache) error {
+	if listenerConfigFile == "" {
+		return nil
+	}
+	if err := loadListenerConfig(logger, xdsCache); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create file watcher for listener configuration file %s: %w", listenerConfigFile, err)
+	}
+	// Watch the directory instead of the file, as Kubernetes updates files in ConfigMap volumes
+	// by swapping symlinks, and editors often replace files instead of writing to them.
+	if err := watcher.Add(filepath.Dir(listenerConfigFile)); err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("could not watch directory of listener configuration file %s: %w", listenerConfigFile, err)
+	}
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		defer func() {
+			signal.Stop(sighup)
+			_ = watcher.Close()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sighup:
+				logger.V(2).Info("Received SIGHUP, reloading listener configuration", "filepath", listenerConfigFile)
+				if err := loadListenerConfig(logger, xdsCache); err != nil {
+					logger.Error(err, "Could not reload listener configuration, keeping the previous configuration")
+				}
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) {
+					continue
+				}
+				logger.V(2).Info("Listener configuration directory changed, reloading listener configuration", "filepath", listenerConfigFile, "event", event.String())
+				if err := loadListenerConfig(logger, xdsCache); err != nil {
+					logger.Error(err, "Could not reload listener configuration, keeping the previous configuration")
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error(err, "Listener configuration file watcher error")
+			}
+		}
+	}()
+	return nil
+}
+
+func loadListenerConfig(logger logr.Logger, xdsCache *xds.SnapshotCache) error {
+	listenerConfig, err := config.ListenerConfig(logger, listenerConfigFile)
+	if err != nil {
+		return fmt.Errorf("could not load listener configuration: %w", err)
+	}
+	return xdsCache.SetListenerConfig(logger, listenerConfig)
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+// TestWatchListenerConfig verifies that the listener configuration is reloaded when the file changes,
+// and on SIGHUP, and that the previous listener configuration stays in use if the file is invalid.
+func TestWatchListenerConfig(t *testing.T) {
+	// The flag points to a symlink, so that changes to the target file in another directory
+	// are only picked up on SIGHUP, and not by the file watcher.
+	watchedDir, targetDir := t.TempDir(), t.TempDir()
+	targetFile := filepath.Join(targetDir, "listener-config.yaml")
+	writeListenerConfigFile(t, targetFile, "maxRequestHeadersKb: 60\n")
+	configFile := filepath.Join(watchedDir, "listener-config.yaml")
+	if err := os.Symlink(targetFile, configFile); err != nil {
+		t.Fatalf("could not create symlink: %v", err)
+	}
+	previous := listenerConfigFile
+	listenerConfigFile = configFile
+	t.Cleanup(func() { listenerConfigFile = previous })
+
+	reloadErrors := make(chan string, 10)
+	logger := funcr.New(func(_ string, args string) {
+		if strings.Contains(args, "Could not reload listener configuration") {
+			reloadErrors <- args
+		}
+	}, funcr.Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	xdsCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+	if err := watchListenerConfig(ctx, logger, xdsCache); err != nil {
+		t.Fatalf("watchListenerConfig() error = %v", err)
+	}
+	waitForMaxRequestHeadersKB(t, xdsCache, 60)
+
+	writeListenerConfigFile(t, targetFile, "maxRequestHeadersKb: 70\n")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("could not send SIGHUP: %v", err)
+	}
+	waitForMaxRequestHeadersKB(t, xdsCache, 70)
+
+	// Replace the file, as editors do.
+	replacementFile := filepath.Join(watchedDir, "listener-config.yaml.tmp")
+	writeListenerConfigFile(t, replacementFile, "maxRequestHeadersKb: 80\n")
+	if err := os.Rename(replacementFile, configFile); err != nil {
+		t.Fatalf("could not replace listener configuration file: %v", err)
+	}
+	waitForMaxRequestHeadersKB(t, xdsCache, 80)
+
+	writeListenerConfigFile(t, configFile, "maxRequestHeadersKb: many\n")
+	select {
+	case <-reloadErrors:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reload of the invalid listener configuration file to fail")
+	}
+	if got := xdsCache.ListenerConfig().MaxRequestHeadersKB; got != 80 {
+		t.Errorf("MaxRequestHeadersKB after invalid file = %d, want previous value 80", got)
+	}
+}
+
+func writeListenerConfigFile(t *testing.T, path string, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("could not write listener configuration file: %v", err)
+	}
+}
+
+func waitForMaxRequestHeadersKB(t *testing.T, xdsCache *xds.SnapshotCache, want uint32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		listenerConfig := xdsCache.ListenerConfig()
+		if listenerConfig != nil && listenerConfig.MaxRequestHeadersKB == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("listener configuration = %+v, want MaxRequestHeadersKB %d", listenerConfig, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var errNodeGroupPort = errors.New("node group ports must differ from the serving and health ports")
+
+// nodeGroupGuard restricts xDS streams to the listener of their node group. Nodes that
+// match a node group must connect to the port of the group, and the ports of node groups
+// only accept nodes from their group. A nil nodeGroupGuard accepts all nodes.
+type nodeGroupGuard struct {
+	nodeGroups   []xds.NodeGroup
+	groupsByPort map[int]xds.NodeGroup
+	// streamPorts stores the local port of each open stream, keyed by `streamKey`.
+	streamPorts sync.Map
+}
+
+// streamKey identifies a stream, as state-of-the-world and delta streams have separate stream IDs.
+type streamKey struct {
+	streamID int64
+	delta    bool
+}
+
+func newNodeGroupGuard(nodeGroups []xds.NodeGroup) *nodeGroupGuard {
+	if len(nodeGroups) == 0 {
+		return nil
+	}
+	groupsByPort := make(map[int]xds.NodeGroup, len(nodeGroups))
+	for _, group := range nodeGroups {
+		groupsByPort[group.Port] = group
+	}
+	return &nodeGroupGuard{
+		nodeGroups:   nodeGroups,
+		groupsByPort: groupsByPort,
+	}
+}
+
+// streamOpened records the local port of the listener that accepted the stream.
+func (g *nodeGroupGuard) streamOpened(ctx context.Context, key streamKey) {
+	if g == nil {
+		return
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return
+	}
+	if tcpAddr, ok := p.LocalAddr.(*net.TCPAddr); ok {
+		g.streamPorts.Store(key, tcpAddr.Port)
+	}
+}
+
+func (g *nodeGroupGuard) streamClosed(key streamKey) {
+	if g == nil {
+		return
+	}
+	g.streamPorts.Delete(key)
+}
+
+// checkNode returns a PermissionDenied error if the node connected to the wrong listener for its node group.
+func (g *nodeGroupGuard) checkNode(key streamKey, node *corev3.Node) error {
+	if g == nil {
+		return nil
+	}
+	port := 0
+	if value, ok := g.streamPorts.Load(key); ok {
+		port, _ = value.(int)
+	}
+	listenerGroup, isGroupPort := g.groupsByPort[port]
+	nodeGroup, isGroupNode := xds.FindNodeGroup(g.nodeGroups, node)
+	switch {
+	case isGroupPort && (!isGroupNode || nodeGroup.Name != listenerGroup.Name):
+		return status.Errorf(codes.PermissionDenied, "node %s does not match the node group %s of port %d", node.GetId(), listenerGroup.Name, port)
+	case !isGroupPort && isGroupNode:
+		return status.Errorf(codes.PermissionDenied, "node %s is in node group %s, and must connect to port %d", node.GetId(), nodeGroup.Name, nodeGroup.Port)
+	}
+	return nil
+}
+
+// validateNodeGroupPorts returns an error if a node group uses the serving port or the health port.
+func validateNodeGroupPorts(nodeGroups []xds.NodeGroup, servingPort int, healthPort int) error {
+	for _, group := range nodeGroups {
+		if group.Port == servingPort || group.Port == healthPort {
+			return fmt.Errorf("%w: name=%s port=%d servingPort=%d healthPort=%d", errNodeGroupPort, group.Name, group.Port, servingPort, healthPort)
+		}
+	}
+	return nil
+}
+
+// serveNodeGroups creates a TCP listener for each node group, and starts serving the xDS management
+// server on them in new goroutines. The listeners use the host from the `xds-addr` flag, if set.
+func serveNodeGroups(logger logr.Logger, server *grpc.Server, nodeGroups []xds.NodeGroup) error {
+	host := ""
+	if xdsAddr != "" {
+		var err error
+		host, _, err = net.SplitHostPort(xdsAddr)
+		if err != nil {
+			return fmt.Errorf("could not extract host from xds-addr=%s: %w", xdsAddr, err)
+		}
+	}
+	listeners := make([]net.Listener, 0, len(nodeGroups))
+	for _, group := range nodeGroups {
+		addr := net.JoinHostPort(host, strconv.Itoa(group.Port))
+		tcpListener, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, listener := range listeners {
+				_ = listener.Close()
+			}
+			return fmt.Errorf("could not create TCP listener for node group %s on address=%s: %w", group.Name, addr, err)
+		}
+		logger.V(1).Info("xDS control plane management server listening for node group", "nodeGroup", group.Name, "address", addr)
+		listeners = append(listeners, tcpListener)
+	}
+	for i, listener := range listeners {
+		go func(group xds.NodeGroup, listener net.Listener) {
+			if err := server.Serve(listener); err != nil {
+				logger.Error(err, "xDS management server listener for node group stopped", "nodeGroup", group.Name)
+			}
+		}(nodeGroups[i], listener)
+	}
+	return nil
+}
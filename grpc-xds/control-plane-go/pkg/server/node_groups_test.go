@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestNodeGroupGuardCheckNode(t *testing.T) {
+	guard := newNodeGroupGuard([]xds.NodeGroup{
+		{Name: "gateway", Port: 50052, NodeSelector: map[string]string{"role": "gateway"}},
+	})
+	gatewayNode := &corev3.Node{Id: "gateway", Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+		"role": structpb.NewStringValue("gateway"),
+	}}}
+	sidecarNode := &corev3.Node{Id: "sidecar"}
+	tests := []struct {
+		name     string
+		port     int
+		node     *corev3.Node
+		wantCode codes.Code
+	}{
+		{name: "group node on group port", port: 50052, node: gatewayNode, wantCode: codes.OK},
+		{name: "other node on serving port", port: 50051, node: sidecarNode, wantCode: codes.OK},
+		{name: "group node on serving port", port: 50051, node: gatewayNode, wantCode: codes.PermissionDenied},
+		{name: "other node on group port", port: 50052, node: sidecarNode, wantCode: codes.PermissionDenied},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := streamKey{streamID: int64(i)}
+			ctx := peer.NewContext(context.Background(), &peer.Peer{LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: tt.port}})
+			guard.streamOpened(ctx, key)
+			defer guard.streamClosed(key)
+			if code := status.Code(guard.checkNode(key, tt.node)); code != tt.wantCode {
+				t.Errorf("checkNode() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestNodeGroupGuardWithoutNodeGroups(t *testing.T) {
+	guard := newNodeGroupGuard(nil)
+	if guard != nil {
+		t.Fatalf("newNodeGroupGuard(nil) = %v, want nil", guard)
+	}
+	if err := guard.checkNode(streamKey{}, &corev3.Node{Id: "node"}); err != nil {
+		t.Errorf("checkNode() on nil guard error = %v, want nil", err)
+	}
+}
+
+func TestValidateNodeGroupPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		port    int
+		wantErr error
+	}{
+		{name: "separate port", port: 50052},
+		{name: "serving port", port: 50051, wantErr: errNodeGroupPort},
+		{name: "health port", port: 50055, wantErr: errNodeGroupPort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroups := []xds.NodeGroup{{Name: "gateway", Port: tt.port}}
+			if err := validateNodeGroupPorts(nodeGroups, 50051, 50055); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateNodeGroupPorts() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
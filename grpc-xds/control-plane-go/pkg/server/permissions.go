@@ -0,0 +1,56 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
+)
+
+// RequiredPermissions returns the Kubernetes RBAC permissions in the namespace of this pod that are
+// required by the features enabled via flags, i.e., leader election, self-registration, and the
+// snapshot cache ConfigMap. See `k8s/control-plane/base/role.yaml`.
+func RequiredPermissions(logger logr.Logger) ([]informers.Permission, error) {
+	if !leaderElection && !selfRegister && cacheConfigMap == "" {
+		return nil, nil
+	}
+	namespace, err := config.Namespace(logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine namespace for the permission checks: %w", err)
+	}
+	editVerbs := []string{"create", "get", "update"}
+	var permissions []informers.Permission
+	if leaderElection {
+		leaseNamespace := leaderElectionNamespace
+		if leaseNamespace == "" {
+			leaseNamespace = namespace
+		}
+		permissions = append(permissions, informers.Permission{Group: "coordination.k8s.io", Resource: "leases", Verbs: editVerbs, Namespace: leaseNamespace, RequiredBy: "-leader-election"})
+	}
+	if selfRegister {
+		permissions = append(permissions,
+			informers.Permission{Group: "", Resource: "services", Verbs: []string{"create", "get"}, Namespace: namespace, RequiredBy: "-self-register"},
+			informers.Permission{Group: "", Resource: "endpoints", Verbs: editVerbs, Namespace: namespace, RequiredBy: "-self-register"},
+		)
+	}
+	if cacheConfigMap != "" {
+		permissions = append(permissions, informers.Permission{Group: "", Resource: "configmaps", Verbs: editVerbs, Namespace: namespace, RequiredBy: "-cache-configmap"})
+	}
+	return permissions, nil
+}
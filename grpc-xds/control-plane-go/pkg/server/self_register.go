@@ -0,0 +1,236 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
+)
+
+const (
+	// selfRegisterAnnotation marks Services that were created for self-registration.
+	selfRegisterAnnotation = "xds.example.com/self-register"
+	selfRegisterPortName   = "xds"
+	selfDeregisterTimeout  = 5 * time.Second
+)
+
+var (
+	errSelfRegisterSocket   = errors.New("self-registration is not supported with the -xds-socket flag")
+	errSelfRegisterSelector = errors.New("the self-registration Service must not have a selector, as the endpoints controller would overwrite the Endpoints")
+)
+
+// selfRegistration adds the IP address of this pod to the `Endpoints` of a headless Service without a selector,
+// so that xDS clients can discover the control plane using the cluster DNS name of the Service in their bootstrap
+// configuration, e.g., `control-plane-xds.xds.svc.cluster.local`.
+type selfRegistration struct {
+	clientset   kubernetes.Interface
+	namespace   string
+	serviceName string
+	podName     string
+	podIP       string
+	port        int32
+}
+
+// startSelfRegistration registers this pod in the `Endpoints` of the self-registration Service,
+// and removes the registration when the context is done, e.g., on clean shutdown, or when leadership is lost.
+func startSelfRegistration(ctx context.Context, logger logr.Logger, servingPort int) error {
+	if xdsSocket != "" {
+		return errSelfRegisterSocket
+	}
+	namespace, err := config.Namespace(logger)
+	if err != nil {
+		return fmt.Errorf("could not determine namespace for self-registration: %w", err)
+	}
+	podName, err := config.PodName()
+	if err != nil {
+		return fmt.Errorf("could not determine pod name for self-registration: %w", err)
+	}
+	podIP, err := config.PodIP()
+	if err != nil {
+		return fmt.Errorf("could not determine pod IP address for self-registration: %w", err)
+	}
+	// Using the kubecontext of the cluster where the control plane runs.
+	clientset, err := informers.NewClientSet(ctx, "")
+	if err != nil {
+		return fmt.Errorf("could not create Kubernetes clientset for self-registration: %w", err)
+	}
+	r := &selfRegistration{
+		clientset:   clientset,
+		namespace:   namespace,
+		serviceName: selfRegisterService,
+		podName:     podName,
+		podIP:       podIP,
+		port:        int32(servingPort),
+	}
+	logger = logger.WithValues("service", r.serviceName, "namespace", r.namespace, "pod", r.podName, "podIP", r.podIP)
+	if err := r.register(ctx); err != nil {
+		return err
+	}
+	logger.V(2).Info("Registered the control plane in the Endpoints of the self-registration Service")
+	go func() {
+		<-ctx.Done()
+		// The context is done, so use a new context for the API requests to remove the registration.
+		deregisterCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), selfDeregisterTimeout)
+		defer cancel()
+		if err := r.deregister(deregisterCtx); err != nil {
+			logger.Error(err, "Could not remove the control plane from the Endpoints of the self-registration Service")
+			return
+		}
+		logger.V(2).Info("Removed the control plane from the Endpoints of the self-registration Service")
+	}()
+	return nil
+}
+
+// register creates the Service if it does not exist, and adds the pod to its Endpoints.
+func (r *selfRegistration) register(ctx context.Context) error {
+	if err := r.ensureService(ctx); err != nil {
+		return err
+	}
+	return r.updateEndpoints(ctx, func(addresses []corev1.EndpointAddress) []corev1.EndpointAddress {
+		addresses = r.withoutPod(addresses)
+		return append(addresses, corev1.EndpointAddress{
+			IP:       r.podIP,
+			Hostname: r.podName,
+			TargetRef: &corev1.ObjectReference{
+				Kind:      "Pod",
+				Namespace: r.namespace,
+				Name:      r.podName,
+			},
+		})
+	})
+}
+
+// deregister removes the pod from the Endpoints of the Service, and keeps the Service.
+func (r *selfRegistration) deregister(ctx context.Context) error {
+	return r.updateEndpoints(ctx, r.withoutPod)
+}
+
+func (r *selfRegistration) ensureService(ctx context.Context) error {
+	service, err := r.clientset.CoreV1().Services(r.namespace).Get(ctx, r.serviceName, metav1.GetOptions{})
+	if err == nil {
+		if len(service.Spec.Selector) > 0 {
+			return fmt.Errorf("%w: service=%s namespace=%s", errSelfRegisterSelector, r.serviceName, r.namespace)
+		}
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not get self-registration Service %s/%s: %w", r.namespace, r.serviceName, err)
+	}
+	service = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.serviceName,
+			Namespace: r.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "control-plane",
+			},
+			Annotations: map[string]string{
+				selfRegisterAnnotation: "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			// Headless, so that the cluster DNS name resolves to the pod IP addresses in the Endpoints.
+			ClusterIP: corev1.ClusterIPNone,
+			Ports: []corev1.ServicePort{
+				{
+					Name:     selfRegisterPortName,
+					Port:     r.port,
+					Protocol: corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+	if _, err := r.clientset.CoreV1().Services(r.namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("could not create self-registration Service %s/%s: %w", r.namespace, r.serviceName, err)
+	}
+	return nil
+}
+
+// updateEndpoints applies the update to the addresses of the Endpoints, and creates the Endpoints if they do not exist.
+// The update is retried on conflicts, e.g., when other replicas register at the same time.
+func (r *selfRegistration) updateEndpoints(ctx context.Context, update func([]corev1.EndpointAddress) []corev1.EndpointAddress) error {
+	endpointsClient := r.clientset.CoreV1().Endpoints(r.namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		endpoints, err := endpointsClient.Get(ctx, r.serviceName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			addresses := update(nil)
+			if len(addresses) == 0 {
+				return nil
+			}
+			_, err = endpointsClient.Create(ctx, &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      r.serviceName,
+					Namespace: r.namespace,
+				},
+				Subsets: []corev1.EndpointSubset{r.subset(addresses)},
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Another replica created the Endpoints, retry as an update.
+				return apierrors.NewConflict(corev1.Resource("endpoints"), r.serviceName, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		var addresses []corev1.EndpointAddress
+		for _, subset := range endpoints.Subsets {
+			addresses = append(addresses, subset.Addresses...)
+		}
+		addresses = update(addresses)
+		endpoints.Subsets = nil
+		if len(addresses) > 0 {
+			endpoints.Subsets = []corev1.EndpointSubset{r.subset(addresses)}
+		}
+		_, err = endpointsClient.Update(ctx, endpoints, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not update Endpoints %s/%s for self-registration: %w", r.namespace, r.serviceName, err)
+	}
+	return nil
+}
+
+func (r *selfRegistration) subset(addresses []corev1.EndpointAddress) corev1.EndpointSubset {
+	return corev1.EndpointSubset{
+		Addresses: addresses,
+		Ports: []corev1.EndpointPort{
+			{
+				Name:     selfRegisterPortName,
+				Port:     r.port,
+				Protocol: corev1.ProtocolTCP,
+			},
+		},
+	}
+}
+
+// withoutPod returns the addresses without the address of this pod, matching by IP address or pod name.
+func (r *selfRegistration) withoutPod(addresses []corev1.EndpointAddress) []corev1.EndpointAddress {
+	return slices.DeleteFunc(addresses, func(address corev1.EndpointAddress) bool {
+		return address.IP == r.podIP || (address.TargetRef != nil && address.TargetRef.Name == r.podName)
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testSelfRegisterNamespace = "xds"
+	testSelfRegisterService   = "control-plane-xds"
+)
+
+func newTestSelfRegistration(clientset kubernetes.Interface, podName string, podIP string) *selfRegistration {
+	return &selfRegistration{
+		clientset:   clientset,
+		namespace:   testSelfRegisterNamespace,
+		serviceName: testSelfRegisterService,
+		podName:     podName,
+		podIP:       podIP,
+		port:        50051,
+	}
+}
+
+// endpointIPs returns the IP addresses in the Endpoints of the self-registration Service.
+func endpointIPs(t *testing.T, clientset kubernetes.Interface) []string {
+	t.Helper()
+	endpoints, err := clientset.CoreV1().Endpoints(testSelfRegisterNamespace).Get(context.Background(), testSelfRegisterService, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get Endpoints: %v", err)
+	}
+	var ips []string
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			ips = append(ips, address.IP)
+		}
+	}
+	slices.Sort(ips)
+	return ips
+}
+
+func TestSelfRegistrationWithoutPod(t *testing.T) {
+	r := &selfRegistration{podName: "control-plane-0", podIP: "10.0.0.1"}
+	addresses := []corev1.EndpointAddress{
+		{IP: "10.0.0.1"},
+		{IP: "10.0.0.2", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "control-plane-1"}},
+		// A previous registration of this pod with a different IP address, e.g., after a restart.
+		{IP: "10.0.0.3", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "control-plane-0"}},
+		{IP: "10.0.0.4"},
+	}
+	var gotIPs []string
+	for _, address := range r.withoutPod(addresses) {
+		gotIPs = append(gotIPs, address.IP)
+	}
+	if wantIPs := []string{"10.0.0.2", "10.0.0.4"}; !slices.Equal(gotIPs, wantIPs) {
+		t.Errorf("withoutPod() IPs = %v, want %v", gotIPs, wantIPs)
+	}
+}
+
+func TestSelfRegistrationSubset(t *testing.T) {
+	r := &selfRegistration{port: 50051}
+	addresses := []corev1.EndpointAddress{{IP: "10.0.0.1"}}
+	subset := r.subset(addresses)
+	if len(subset.Addresses) != 1 || subset.Addresses[0].IP != "10.0.0.1" {
+		t.Errorf("subset addresses = %+v, want %+v", subset.Addresses, addresses)
+	}
+	wantPorts := []corev1.EndpointPort{{Name: selfRegisterPortName, Port: 50051, Protocol: corev1.ProtocolTCP}}
+	if !slices.Equal(subset.Ports, wantPorts) {
+		t.Errorf("subset ports = %+v, want %+v", subset.Ports, wantPorts)
+	}
+}
+
+func TestStartSelfRegistrationRejectsSocket(t *testing.T) {
+	setXDSListenerFlags(t, "", filepath.Join(t.TempDir(), "xds.sock"))
+	if err := startSelfRegistration(context.Background(), logr.Discard(), 50051); !errors.Is(err, errSelfRegisterSocket) {
+		t.Errorf("startSelfRegistration() error = %v, want %v", err, errSelfRegisterSocket)
+	}
+}
+
+func TestSelfRegistrationRegisterAndDeregister(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	r0 := newTestSelfRegistration(clientset, "control-plane-0", "10.0.0.1")
+	r1 := newTestSelfRegistration(clientset, "control-plane-1", "10.0.0.2")
+
+	if err := r0.register(ctx); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	service, err := clientset.CoreV1().Services(testSelfRegisterNamespace).Get(ctx, testSelfRegisterService, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get self-registration Service: %v", err)
+	}
+	if service.Spec.ClusterIP != corev1.ClusterIPNone || len(service.Spec.Selector) > 0 || service.Annotations[selfRegisterAnnotation] != "true" {
+		t.Errorf("self-registration Service = %+v, want headless Service without selector and with annotation %s", service, selfRegisterAnnotation)
+	}
+	if got, want := endpointIPs(t, clientset), []string{"10.0.0.1"}; !slices.Equal(got, want) {
+		t.Errorf("Endpoints IPs after first registration = %v, want %v", got, want)
+	}
+
+	if err := r1.register(ctx); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	// Registering again, e.g., after a restart, does not add a duplicate address.
+	if err := r1.register(ctx); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if got, want := endpointIPs(t, clientset), []string{"10.0.0.1", "10.0.0.2"}; !slices.Equal(got, want) {
+		t.Errorf("Endpoints IPs after second registration = %v, want %v", got, want)
+	}
+
+	if err := r0.deregister(ctx); err != nil {
+		t.Fatalf("deregister() error = %v", err)
+	}
+	if got, want := endpointIPs(t, clientset), []string{"10.0.0.2"}; !slices.Equal(got, want) {
+		t.Errorf("Endpoints IPs after first deregistration = %v, want %v", got, want)
+	}
+	if err := r1.deregister(ctx); err != nil {
+		t.Fatalf("deregister() error = %v", err)
+	}
+	if got := endpointIPs(t, clientset); len(got) != 0 {
+		t.Errorf("Endpoints IPs after last deregistration = %v, want none", got)
+	}
+	if _, err := clientset.CoreV1().Services(testSelfRegisterNamespace).Get(ctx, testSelfRegisterService, metav1.GetOptions{}); err != nil {
+		t.Errorf("self-registration Service was removed after deregistration: %v", err)
+	}
+}
+
+func TestSelfRegistrationRetriesCreateConflict(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	created := false
+	// Another replica creates the Endpoints between the Get and the Create of this replica.
+	clientset.PrependReactor("create", "endpoints", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		if created {
+			return false, nil, nil
+		}
+		created = true
+		other := &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: testSelfRegisterService, Namespace: testSelfRegisterNamespace},
+			Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}}}},
+		}
+		if err := clientset.Tracker().Add(other); err != nil {
+			return true, nil, err
+		}
+		return true, nil, apierrors.NewAlreadyExists(corev1.Resource("endpoints"), testSelfRegisterService)
+	})
+	r := newTestSelfRegistration(clientset, "control-plane-0", "10.0.0.1")
+	if err := r.register(context.Background()); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if !created {
+		t.Fatal("the Endpoints create reactor was not called")
+	}
+	if got, want := endpointIPs(t, clientset), []string{"10.0.0.1", "10.0.0.2"}; !slices.Equal(got, want) {
+		t.Errorf("Endpoints IPs = %v, want %v", got, want)
+	}
+}
+
+func TestSelfRegistrationRejectsServiceWithSelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: testSelfRegisterService, Namespace: testSelfRegisterNamespace},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "control-plane"}},
+	})
+	r := newTestSelfRegistration(clientset, "control-plane-0", "10.0.0.1")
+	if err := r.register(context.Background()); !errors.Is(err, errSelfRegisterSelector) {
+		t.Fatalf("register() error = %v, want %v", err, errSelfRegisterSelector)
+	}
+	if _, err := clientset.CoreV1().Endpoints(testSelfRegisterNamespace).Get(context.Background(), testSelfRegisterService, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Endpoints after rejected registration error = %v, want not found", err)
+	}
+}
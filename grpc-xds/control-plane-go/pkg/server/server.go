@@ -16,10 +16,15 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"time"
 
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
 	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
@@ -27,6 +32,7 @@ import (
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
 	runtimev3 "github.com/envoyproxy/go-control-plane/envoy/service/runtime/v3"
 	secretv3 "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
 	"github.com/go-logr/logr"
 	"google.golang.org/grpc"
@@ -41,10 +47,14 @@ import (
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/security/advancedtls"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
 
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/adminapi"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/interceptors"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/logging"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
 )
 
@@ -54,6 +64,26 @@ const (
 	grpcKeepaliveTimeout     = 5 * time.Second
 	grpcKeepaliveMinTime     = 30 * time.Second
 	grpcMaxConcurrentStreams = 1000000
+	gracefulStopTimeout      = 5 * time.Second
+	defaultDrainTimeout      = 15 * time.Second
+	defaultResyncInterval    = 5 * time.Minute
+)
+
+// Health service names. Readiness uses the empty service name, which is the default for
+// Kubernetes gRPC probes, and reflects the overall health of the server.
+const (
+	// healthServiceReadiness is SERVING after the informer caches have synced and the initial
+	// snapshots have been set, see `setReady()`, and NOT_SERVING while draining.
+	healthServiceReadiness = ""
+	// healthServiceLiveness is SERVING from startup until the process exits, so that Kubernetes
+	// does not restart the pod while informers sync or re-list, or while it waits for leadership.
+	healthServiceLiveness = "liveness"
+)
+
+var (
+	errIncompleteTLSFlags = errors.New("all of the flags tls-cert, tls-key, and tls-ca are required for mTLS")
+	errNoCACertificates   = errors.New("no PEM-encoded CA certificates found in file")
+	errXDSAddrAndSocket   = errors.New("the flags xds-addr and xds-socket are mutually exclusive")
 )
 
 type transportCredentials struct {
@@ -71,19 +101,38 @@ func (c *transportCredentials) Close() {
 }
 
 func Run(ctx context.Context, servingPort int, healthPort int, kubecontexts []informers.Kubecontext, xdsFeatures *xds.Features, authority string) error {
-	logger := logging.FromContext(ctx)
+	logger := logging.FromContext(ctx).WithValues("component", "server")
+	if xdsAddr != "" && xdsSocket != "" {
+		return errXDSAddrAndSocket
+	}
+	if dryRun || dryRunOnce {
+		return runDryRun(ctx, logger, kubecontexts, xdsFeatures, authority)
+	}
+	// serveCtx outlives ctx while draining, so that xDS streams and informers keep working until
+	// the drain timeout, see `addServerStopBehavior()`.
+	serveCtx, cancelServe := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelServe()
 	serverCredentials, err := createServerCredentials(logger, xdsFeatures)
 	if err != nil {
 		return fmt.Errorf("could not create server-side transport credentials: %w", err)
 	}
 	defer serverCredentials.Close()
 
+	nodeGroups, err := config.NodeGroups(logger, xdsListenersFile)
+	if err != nil {
+		return fmt.Errorf("could not load xDS listener configuration: %w", err)
+	}
+	if err := validateNodeGroupPorts(nodeGroups, servingPort, healthPort); err != nil {
+		return err
+	}
+
 	grpcOptions := serverOptions(logger, serverCredentials)
 	server := grpc.NewServer(grpcOptions...)
 	healthGRPCServer := grpc.NewServer()
 	healthServer := health.NewServer()
-	addServerStopBehavior(ctx, logger, server, healthGRPCServer, healthServer)
-	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	addServerStopBehavior(ctx, logger, server, healthGRPCServer, healthServer, cancelServe)
+	healthServer.SetServingStatus(healthServiceLiveness, healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(healthServiceReadiness, healthpb.HealthCheckResponse_NOT_SERVING)
 	healthpb.RegisterHealthServer(server, healthServer)
 	healthpb.RegisterHealthServer(healthGRPCServer, healthServer)
 
@@ -96,31 +145,248 @@ func Run(ctx context.Context, servingPort int, healthPort int, kubecontexts []in
 	reflection.Register(server)
 	reflection.Register(healthGRPCServer)
 
-	xdsCache := xds.NewSnapshotCache(ctx, true, xds.ZoneHash{}, xds.LocalityPriorityByZone{}, xdsFeatures, authority)
-	xdsServer := serverv3.NewServer(ctx, xdsCache, xdsServerCallbackFuncs(logger))
+	if err := metrics.StartServer(ctx, logger); err != nil {
+		return fmt.Errorf("could not start metrics server: %w", err)
+	}
+
+	xdsCache := xds.NewSnapshotCache(serveCtx, true, nodeHash(logger, nodeGroups), xds.LocalityPriorityByZone{}, xdsFeatures, authority)
+	xdsCache.SetMaxReconcileAttempts(maxReconcileAttempts)
+	xdsCache.SetNodeGroups(nodeGroups)
+	nackTracker := xds.NewNACKTracker(serveCtx, logger, xdsCache, nackRetryDelay)
+	xdsServer := serverv3.NewServer(serveCtx, xdsCache, xdsServerCallbackFuncs(logger, newNodeGroupGuard(nodeGroups), nackTracker))
 
 	registerXDSServices(server, xdsServer)
 
-	if err := createInformers(ctx, logger, kubecontexts, xdsCache); err != nil {
-		return fmt.Errorf("could not create Kubernetes informer managers: %w", err)
+	if err := watchListenerConfig(ctx, logger, xdsCache); err != nil {
+		return fmt.Errorf("could not load listener configuration: %w", err)
+	}
+
+	if err := adminapi.StartServer(ctx, logger, xdsCache); err != nil {
+		return fmt.Errorf("could not start admin API server: %w", err)
+	}
+
+	var snapshotCache *snapshotConfigMap
+	restored := false
+	if cacheConfigMap != "" {
+		snapshotCache, err = newSnapshotConfigMap(ctx, logger)
+		if err != nil {
+			return err
+		}
+		// Restore before starting the informers, so that informer events do not replace the restored snapshots.
+		restored, err = snapshotCache.restore(ctx, logger, xdsCache)
+		if err != nil {
+			logger.Error(err, "Could not restore xDS resource snapshots, waiting for the informer caches to sync")
+		}
 	}
 
-	tcpListener, err := net.Listen("tcp", fmt.Sprintf(":%d", servingPort))
+	informerManagers, err := createInformers(serveCtx, logger, kubecontexts, xdsCache, xdsFeatures)
 	if err != nil {
-		return fmt.Errorf("could not create TCP listener on port=%d: %w", servingPort, err)
+		return fmt.Errorf("could not create Kubernetes informer managers: %w", err)
 	}
+
 	healthTCPListener, err := net.Listen("tcp", fmt.Sprintf(":%d", healthPort))
 	if err != nil {
 		return fmt.Errorf("could not create TCP listener on port=%d: %w", healthPort, err)
 	}
-	logger.V(1).Info("xDS control plane management server listening", "port", servingPort, "healthPort", healthPort)
+	logger.V(1).Info("xDS control plane health server listening", "healthPort", healthPort)
+	if !leaderElection {
+		if snapshotCache != nil {
+			snapshotCache.startWriter(serveCtx, logger, xdsCache)
+		}
+		if restored {
+			// Serve the restored snapshots until the informer caches have synced.
+			setReady(ctx, logger, healthServer)
+		}
+		go reconcileAfterCacheSync(serveCtx, logger, informerManagers, xdsCache, func() {
+			setReady(ctx, logger, healthServer)
+		})
+		if err := serveXDS(logger, server, healthServer, servingPort, nodeGroups); err != nil {
+			return err
+		}
+		if selfRegister {
+			// Using ctx instead of serveCtx, so that the registration is removed when draining starts.
+			if err := startSelfRegistration(ctx, logger, servingPort); err != nil {
+				return fmt.Errorf("could not register the control plane: %w", err)
+			}
+		}
+		return healthGRPCServer.Serve(healthTCPListener)
+	}
+
+	healthErrs := make(chan error, 1)
+	go func() {
+		healthErrs <- healthGRPCServer.Serve(healthTCPListener)
+	}()
+	err = runLeaderElection(ctx, serveCtx, logger, func(leaderCtx context.Context) {
+		logger.V(1).Info("Acquired leadership, waiting for informer caches to sync before serving")
+		if snapshotCache != nil {
+			// Only the leader writes the ConfigMap.
+			snapshotCache.startWriter(leaderCtx, logger, xdsCache)
+		}
+		if !waitForCacheSync(leaderCtx, informerManagers) {
+			logger.V(1).Info("Stopped waiting for informer caches to sync before serving")
+			return
+		}
+		validateInitialSnapshot(logger, xdsCache)
+		if err := xdsCache.ReconcileNow(logger); err != nil {
+			logger.Error(err, "Could not reconcile xDS resource snapshots after informer caches synced")
+		}
+		go resyncPeriodically(leaderCtx, logger, informerManagers, xdsCache)
+		if err := serveXDS(logger, server, healthServer, servingPort, nodeGroups); err != nil {
+			logger.Error(err, "Could not start the xDS management server after acquiring leadership")
+			healthServer.SetServingStatus(healthServiceReadiness, healthpb.HealthCheckResponse_NOT_SERVING)
+			return
+		}
+		setReady(ctx, logger, healthServer)
+		if selfRegister {
+			// Only the leader is registered, and the registration is removed when leadership is lost.
+			if err := startSelfRegistration(leaderCtx, logger, servingPort); err != nil {
+				logger.Error(err, "Could not register the control plane after acquiring leadership")
+			}
+		}
+	})
+	if err != nil {
+		// Drain in-flight RPCs and exit, so that Kubernetes restarts this process.
+		stopGRPCServer(logger, server, leaderElectionReleaseOnCancel)
+		healthGRPCServer.Stop()
+		return fmt.Errorf("stopped the xDS management server: %w", err)
+	}
+	return <-healthErrs
+}
+
+// nodeHash returns the function that determines the snapshot cache key for xDS clients.
+// With node groups, the node hash also includes the node group, see `xds.NodeGroupHash`.
+func nodeHash(logger logr.Logger, nodeGroups []xds.NodeGroup) cachev3.NodeHash {
+	var hash cachev3.NodeHash = xds.ZoneHash{}
+	if scopeMetadataKey != "" {
+		logger.V(2).Info("Scoping xDS resource snapshots by namespace from node metadata", "metadataKey", scopeMetadataKey)
+		hash = xds.ScopedZoneHash{MetadataKey: scopeMetadataKey}
+	}
+	if len(nodeGroups) == 0 {
+		return hash
+	}
+	return xds.NodeGroupHash{Groups: nodeGroups, Delegate: hash}
+}
+
+// serveXDS creates the listeners for the xDS management server, including one for each node group,
+// and starts serving in new goroutines.
+func serveXDS(logger logr.Logger, server *grpc.Server, healthServer *health.Server, servingPort int, nodeGroups []xds.NodeGroup) error {
+	listener, err := listenXDS(logger, servingPort)
+	if err != nil {
+		return err
+	}
+	if err := serveNodeGroups(logger, server, nodeGroups); err != nil {
+		_ = listener.Close()
+		return err
+	}
 	go func() {
-		err := server.Serve(tcpListener)
+		err := server.Serve(listener)
 		if err != nil {
-			healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+			healthServer.SetServingStatus(healthServiceReadiness, healthpb.HealthCheckResponse_NOT_SERVING)
 		}
 	}()
-	return healthGRPCServer.Serve(healthTCPListener)
+	return nil
+}
+
+// listenXDS creates a Unix domain socket listener if the `xds-socket` flag is set,
+// and a TCP listener otherwise.
+func listenXDS(logger logr.Logger, servingPort int) (net.Listener, error) {
+	if xdsSocket != "" {
+		// Remove a stale socket file, e.g., from a previous process that did not shut down cleanly.
+		if fileInfo, err := os.Lstat(xdsSocket); err == nil && fileInfo.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(xdsSocket); err != nil {
+				return nil, fmt.Errorf("could not remove stale Unix domain socket file %s: %w", xdsSocket, err)
+			}
+		}
+		unixListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: xdsSocket, Net: "unix"})
+		if err != nil {
+			return nil, fmt.Errorf("could not create Unix domain socket listener on path=%s: %w", xdsSocket, err)
+		}
+		// Remove the socket file when the server stops and closes the listener.
+		unixListener.SetUnlinkOnClose(true)
+		logger.V(1).Info("xDS control plane management server listening", "socket", xdsSocket)
+		return unixListener, nil
+	}
+	addr := xdsAddr
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", servingPort)
+	}
+	tcpListener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not create TCP listener on address=%s: %w", addr, err)
+	}
+	logger.V(1).Info("xDS control plane management server listening", "address", addr)
+	return tcpListener, nil
+}
+
+// reconcileAfterCacheSync creates new snapshots for all node hashes after the informer caches have synced,
+// in case events were missed during startup, calls `ready`, and then resyncs them periodically.
+func reconcileAfterCacheSync(ctx context.Context, logger logr.Logger, informerManagers []*informers.Manager, xdsCache *xds.SnapshotCache, ready func()) {
+	if !waitForCacheSync(ctx, informerManagers) {
+		return
+	}
+	validateInitialSnapshot(logger, xdsCache)
+	if err := xdsCache.ReconcileNow(logger); err != nil {
+		logger.Error(err, "Could not reconcile xDS resource snapshots after informer caches synced")
+	}
+	ready()
+	resyncPeriodically(ctx, logger, informerManagers, xdsCache)
+}
+
+// setReady changes the readiness health status to SERVING, unless the server is draining.
+//
+// Readiness does not wait for a snapshot to be sent to an xDS client, as xDS clients that
+// connect via a Kubernetes Service cannot reach this pod before it is ready. Instead, the
+// snapshots for all node hashes known at this point have been set from the synced informer
+// caches, and new node hashes receive a snapshot built from the same caches when they connect.
+// With `-cache-configmap`, the server can also be ready earlier, with restored snapshots.
+func setReady(ctx context.Context, logger logr.Logger, healthServer *health.Server) {
+	if ctx.Err() != nil {
+		return
+	}
+	logger.V(2).Info("Ready to serve xDS resources")
+	healthServer.SetServingStatus(healthServiceReadiness, healthpb.HealthCheckResponse_SERVING)
+}
+
+// resyncPeriodically updates the xDS resource cache from the informer caches, and then rebuilds
+// the snapshots for all node hashes, every `resync-interval`, until the context is done.
+// Informers re-list after watch errors, but events can still be lost, e.g., if an event handler
+// fails. Snapshots replaced via the admin API are not rebuilt until they are deleted.
+func resyncPeriodically(ctx context.Context, logger logr.Logger, informerManagers []*informers.Manager, xdsCache *xds.SnapshotCache) {
+	if resyncInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, informerManager := range informerManagers {
+				informerManager.Resync()
+			}
+			if err := xdsCache.Resync(logger); err != nil {
+				logger.Error(err, "Could not resync xDS resource snapshots")
+			}
+		}
+	}
+}
+
+// validateInitialSnapshot logs an error if the snapshot built from the synced informer caches is
+// inconsistent. The control plane keeps running, as it does not set inconsistent snapshots.
+func validateInitialSnapshot(logger logr.Logger, xdsCache *xds.SnapshotCache) {
+	if err := xdsCache.ValidateInitialSnapshot(logger); err != nil {
+		logger.Error(err, "The initial xDS resource snapshot is inconsistent")
+	}
+}
+
+func waitForCacheSync(ctx context.Context, informerManagers []*informers.Manager) bool {
+	for _, informerManager := range informerManagers {
+		if !informerManager.WaitForCacheSync(ctx) {
+			return false
+		}
+	}
+	return true
 }
 
 func registerAdminServers(servingGRPCServer *grpc.Server, healthGRPCServer *grpc.Server) (func(), error) {
@@ -138,40 +404,89 @@ func registerAdminServers(servingGRPCServer *grpc.Server, healthGRPCServer *grpc
 	}, nil
 }
 
-func xdsServerCallbackFuncs(logger logr.Logger) *serverv3.CallbackFuncs {
+func xdsServerCallbackFuncs(logger logr.Logger, guard *nodeGroupGuard, nackTracker *xds.NACKTracker) *serverv3.CallbackFuncs {
 	return &serverv3.CallbackFuncs{
-		StreamRequestFunc: func(streamID int64, request *discoveryv3.DiscoveryRequest) error {
-			logger.Info("StreamRequest", "streamID", streamID, "type", request.GetTypeUrl(), "resourceNames", request.ResourceNames)
+		StreamOpenFunc: func(ctx context.Context, streamID int64, _ string) error {
+			metrics.XDSClientConnected()
+			guard.streamOpened(ctx, streamKey{streamID: streamID})
 			return nil
 		},
-		StreamResponseFunc: func(_ context.Context, streamID int64, _ *discoveryv3.DiscoveryRequest, response *discoveryv3.DiscoveryResponse) {
-			protoMarshalOptions := protojson.MarshalOptions{
-				Multiline:    true,
-				Indent:       "  ",
-				AllowPartial: true,
+		StreamClosedFunc: func(streamID int64, _ *corev3.Node) {
+			metrics.XDSClientDisconnected()
+			guard.streamClosed(streamKey{streamID: streamID})
+			nackTracker.OnStreamClosed(streamID)
+		},
+		DeltaStreamOpenFunc: func(ctx context.Context, streamID int64, _ string) error {
+			metrics.XDSClientConnected()
+			guard.streamOpened(ctx, streamKey{streamID: streamID, delta: true})
+			return nil
+		},
+		DeltaStreamClosedFunc: func(streamID int64, _ *corev3.Node) {
+			metrics.XDSClientDisconnected()
+			guard.streamClosed(streamKey{streamID: streamID, delta: true})
+			nackTracker.OnDeltaStreamClosed(streamID)
+		},
+		StreamRequestFunc: func(streamID int64, request *discoveryv3.DiscoveryRequest) error {
+			logger.Info("StreamRequest", "streamID", streamID, "node_id", request.GetNode().GetId(), "resource_type", request.GetTypeUrl(), "resourceNames", request.ResourceNames)
+			if err := guard.checkNode(streamKey{streamID: streamID}, request.GetNode()); err != nil {
+				return err
 			}
+			nackTracker.OnStreamRequest(streamID, request)
+			return nil
+		},
+		StreamResponseFunc: func(_ context.Context, streamID int64, request *discoveryv3.DiscoveryRequest, response *discoveryv3.DiscoveryResponse) {
+			nackTracker.OnStreamResponse(streamID, response)
 			for _, anyResource := range response.Resources {
-				if anyResource == nil {
-					continue
-				}
-				protoResource, err := anyResource.UnmarshalNew()
-				if err != nil {
-					logger.Error(err, "StreamResponse: could not unmarshall Any message")
-					continue
-				}
-				jsonResourceBytes, err := protoMarshalOptions.Marshal(protoResource)
-				if err != nil {
-					logger.Error(err, "StreamResponse: could not marshall proto message to JSON")
-					continue
-				}
-				// Logging each resource instead of a slice of resources, to take advantage of multi-line logging,
-				// which is helpful for development and exploration.
-				logger.Info("StreamResponse", "streamID", streamID, "type", response.GetTypeUrl(), "resource", string(jsonResourceBytes))
+				logResource(logger, "StreamResponse", streamID, request.GetNode().GetId(), response.GetTypeUrl(), anyResource)
+			}
+		},
+		StreamDeltaRequestFunc: func(streamID int64, request *discoveryv3.DeltaDiscoveryRequest) error {
+			logger.Info("StreamDeltaRequest", "streamID", streamID, "node_id", request.GetNode().GetId(), "resource_type", request.GetTypeUrl(), "resourceNamesSubscribe", request.ResourceNamesSubscribe, "resourceNamesUnsubscribe", request.ResourceNamesUnsubscribe)
+			if err := guard.checkNode(streamKey{streamID: streamID, delta: true}, request.GetNode()); err != nil {
+				return err
+			}
+			nackTracker.OnStreamDeltaRequest(streamID, request)
+			return nil
+		},
+		StreamDeltaResponseFunc: func(streamID int64, request *discoveryv3.DeltaDiscoveryRequest, response *discoveryv3.DeltaDiscoveryResponse) {
+			nackTracker.OnStreamDeltaResponse(streamID, response)
+			for _, deltaResource := range response.Resources {
+				logResource(logger, "StreamDeltaResponse", streamID, request.GetNode().GetId(), response.GetTypeUrl(), deltaResource.GetResource())
+			}
+			if len(response.RemovedResources) > 0 {
+				logger.Info("StreamDeltaResponse", "streamID", streamID, "node_id", request.GetNode().GetId(), "resource_type", response.GetTypeUrl(), "removedResources", response.RemovedResources)
 			}
 		},
 	}
 }
 
+// logResource logs the provided xDS resource as multi-line JSON.
+func logResource(logger logr.Logger, msg string, streamID int64, nodeID string, typeURL string, anyResource *anypb.Any) {
+	if anyResource == nil {
+		return
+	}
+	protoMarshalOptions := protojson.MarshalOptions{
+		Multiline:    true,
+		Indent:       "  ",
+		AllowPartial: true,
+	}
+	protoResource, err := anyResource.UnmarshalNew()
+	if err != nil {
+		logger.Error(err, msg+": could not unmarshall Any message")
+		return
+	}
+	jsonResourceBytes, err := protoMarshalOptions.Marshal(protoResource)
+	if err != nil {
+		logger.Error(err, msg+": could not marshall proto message to JSON")
+		return
+	}
+	// Logging each resource instead of a slice of resources, to take advantage of multi-line logging,
+	// which is helpful for development and exploration.
+	logger.Info(msg, "streamID", streamID, "node_id", nodeID, "resource_type", typeURL, "resource", string(jsonResourceBytes))
+}
+
+// registerXDSServices registers both state-of-the-world and delta (incremental) xDS services,
+// as `xdsServer` implements both `StreamAggregatedResources` and `DeltaAggregatedResources`.
 func registerXDSServices(grpcServer *grpc.Server, xdsServer serverv3.Server) {
 	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
 	endpointv3.RegisterEndpointDiscoveryServiceServer(grpcServer, xdsServer)
@@ -182,19 +497,35 @@ func registerXDSServices(grpcServer *grpc.Server, xdsServer serverv3.Server) {
 	runtimev3.RegisterRuntimeDiscoveryServiceServer(grpcServer, xdsServer)
 }
 
-func createInformers(ctx context.Context, logger logr.Logger, kubecontexts []informers.Kubecontext, xdsCache *xds.SnapshotCache) error {
-	for _, kubecontext := range kubecontexts {
+// createInformers creates informer managers for the kubecontexts. If the `dataPlaneTlsSecret` feature is
+// set, the manager for the first kubecontext also watches that Secret, for SDS resources.
+func createInformers(ctx context.Context, logger logr.Logger, kubecontexts []informers.Kubecontext, xdsCache *xds.SnapshotCache, xdsFeatures *xds.Features) ([]*informers.Manager, error) {
+	watchNamespaces := informers.WatchNamespaces()
+	if len(watchNamespaces) > 0 {
+		logger.V(2).Info("Only watching resources in the provided namespaces", "namespaces", watchNamespaces)
+	}
+	informerManagers := make([]*informers.Manager, 0, len(kubecontexts))
+	for i, kubecontext := range kubecontexts {
 		informerManager, err := informers.NewManager(ctx, kubecontext.Context, xdsCache)
 		if err != nil {
-			return fmt.Errorf("could not create Kubernetes informer manager for context=%s: %w", kubecontext.Context, err)
+			return nil, fmt.Errorf("could not create Kubernetes informer manager for context=%s: %w", kubecontext.Context, err)
 		}
-		for _, informer := range kubecontext.Informers {
+		if i == 0 && xdsFeatures.DataPlaneTLSSecret != "" {
+			if err := informerManager.AddTLSSecretInformer(ctx, logger, xdsFeatures.DataPlaneTLSSecret); err != nil {
+				return nil, fmt.Errorf("could not create Kubernetes TLS Secret informer for context=%s: %w", kubecontext.Context, err)
+			}
+		}
+		for _, informer := range informers.ScopeToNamespaces(kubecontext.Informers, watchNamespaces) {
 			if err := informerManager.AddEndpointSliceInformer(ctx, logger, informer); err != nil {
-				return fmt.Errorf("could not create Kubernetes informer for context=%s for %+v: %w", kubecontext.Context, informer, err)
+				return nil, fmt.Errorf("could not create Kubernetes informer for context=%s for %+v: %w", kubecontext.Context, informer, err)
+			}
+			if err := informerManager.AddCustomResourceInformers(ctx, logger, informer); err != nil {
+				return nil, fmt.Errorf("could not create Kubernetes custom resource informers for context=%s for %+v: %w", kubecontext.Context, informer, err)
 			}
 		}
+		informerManagers = append(informerManagers, informerManager)
 	}
-	return nil
+	return informerManagers, nil
 }
 
 // serverOptions sets gRPC server options.
@@ -223,6 +554,9 @@ func serverOptions(logger logr.Logger, transportCredentials credentials.Transpor
 }
 
 func createServerCredentials(logger logr.Logger, xdsFeatures *xds.Features) (*transportCredentials, error) {
+	if tlsCertFile != "" || tlsKeyFile != "" || tlsCAFile != "" {
+		return createServerCredentialsFromFlags(logger)
+	}
 	if !xdsFeatures.EnableControlPlaneTLS {
 		logger.V(2).Info("using insecure credentials for the control plane server")
 		return &transportCredentials{
@@ -285,24 +619,99 @@ func createServerCredentials(logger logr.Logger, xdsFeatures *xds.Features) (*tr
 	}, err
 }
 
-func addServerStopBehavior(ctx context.Context, logger logr.Logger, servingGRPCServer *grpc.Server, healthGRPCServer *grpc.Server, healthServer *health.Server) {
+// createServerCredentialsFromFlags creates mTLS credentials that require and verify client
+// certificates, using the certificate, private key, and CA certificate files provided via flags.
+func createServerCredentialsFromFlags(logger logr.Logger) (*transportCredentials, error) {
+	if tlsCertFile == "" || tlsKeyFile == "" || tlsCAFile == "" {
+		return nil, fmt.Errorf("%w: tls-cert=%q tls-key=%q tls-ca=%q", errIncompleteTLSFlags, tlsCertFile, tlsKeyFile, tlsCAFile)
+	}
+	logger.V(2).Info("using mTLS with certificates from flags for the control plane server", "certFile", tlsCertFile, "keyFile", tlsKeyFile, "caFile", tlsCAFile)
+	certificate, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load server certificate from certFile=%s and keyFile=%s: %w", tlsCertFile, tlsKeyFile, err)
+	}
+	caBytes, err := os.ReadFile(tlsCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificates from file %s: %w", tlsCAFile, err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("%w: %s", errNoCACertificates, tlsCAFile)
+	}
+	return &transportCredentials{
+		TransportCredentials: credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			MinVersion:   tls.VersionTLS12,
+		}),
+	}, nil
+}
+
+// addServerStopBehavior drains the xDS management server when the context is done, e.g., on SIGTERM.
+//
+// The health status changes to NOT_SERVING, and the server stops accepting new streams.
+// Existing xDS streams continue for up to the drain timeout, and informers keep delivering
+// updates to them. After that, `cancelServe` cancels the context of the xDS server, which ends
+// the remaining streams between responses, so that xDS clients never receive a partial update.
+// Finally, the health server stops, so that `Run()` returns without an error.
+func addServerStopBehavior(ctx context.Context, logger logr.Logger, servingGRPCServer *grpc.Server, healthGRPCServer *grpc.Server, healthServer *health.Server, cancelServe context.CancelFunc) {
 	go func() {
 		<-ctx.Done()
-		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		logger.V(1).Info("Draining the xDS management server", "drainTimeout", drainTimeout)
+		healthServer.SetServingStatus(healthServiceReadiness, healthpb.HealthCheckResponse_NOT_SERVING)
 		stopped := make(chan struct{})
 		go func() {
-			logger.Info("Attempting to gracefully stop the xDS management server")
 			servingGRPCServer.GracefulStop()
 			close(stopped)
 		}()
-		t := time.NewTimer(5 * time.Second)
+		t := time.NewTimer(drainTimeout)
 		select {
-		case <-t.C:
-			logger.Info("Stopping the xDS management server immediately")
-			servingGRPCServer.Stop()
-			healthGRPCServer.Stop()
 		case <-stopped:
 			t.Stop()
+		case <-t.C:
+			logger.V(1).Info("Drain timeout reached, closing the remaining xDS streams")
+			cancelServe()
+			if !waitForStop(stopped, gracefulStopTimeout) {
+				logger.Info("Stopping the xDS management server immediately")
+				servingGRPCServer.Stop()
+			}
 		}
+		cancelServe()
+		healthGRPCServer.Stop()
 	}()
 }
+
+// waitForStop returns true if the stopped channel is closed within the timeout.
+func waitForStop(stopped <-chan struct{}, timeout time.Duration) bool {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-stopped:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// stopGRPCServer attempts to gracefully stop the server, and stops it immediately if
+// graceful stop does not complete within the timeout.
+// Returns true if graceful stop completed within the timeout.
+func stopGRPCServer(logger logr.Logger, server *grpc.Server, timeout time.Duration) bool {
+	stopped := make(chan struct{})
+	go func() {
+		logger.Info("Attempting to gracefully stop the xDS management server")
+		server.GracefulStop()
+		close(stopped)
+	}()
+	t := time.NewTimer(timeout)
+	select {
+	case <-t.C:
+		logger.Info("Stopping the xDS management server immediately")
+		server.Stop()
+		return false
+	case <-stopped:
+		t.Stop()
+		return true
+	}
+}
@@ -0,0 +1,534 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clusterconfigv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+func TestCreateServerCredentialsFromFlags(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+	emptyFile := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(emptyFile, []byte("no certificates here\n"), 0o600); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	tests := []struct {
+		name    string
+		cert    string
+		key     string
+		ca      string
+		wantErr error
+	}{
+		{
+			name:    "missing CA",
+			cert:    certFile,
+			key:     keyFile,
+			wantErr: errIncompleteTLSFlags,
+		},
+		{
+			name:    "missing certificate",
+			key:     keyFile,
+			ca:      certFile,
+			wantErr: errIncompleteTLSFlags,
+		},
+		{
+			name:    "no CA certificates in file",
+			cert:    certFile,
+			key:     keyFile,
+			ca:      emptyFile,
+			wantErr: errNoCACertificates,
+		},
+		{
+			name: "valid",
+			cert: certFile,
+			key:  keyFile,
+			ca:   certFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTLSFlags(t, tt.cert, tt.key, tt.ca)
+			creds, err := createServerCredentialsFromFlags(logr.Discard())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("createServerCredentialsFromFlags() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if protocol := creds.Info().SecurityProtocol; protocol != "tls" {
+				t.Errorf("security protocol = %q, want %q", protocol, "tls")
+			}
+		})
+	}
+}
+
+func TestServerRequiresClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+	untrustedCertFile, untrustedKeyFile := writeTestCertificate(t, t.TempDir())
+	setTLSFlags(t, certFile, keyFile, certFile)
+	serverCredentials, err := createServerCredentialsFromFlags(logr.Discard())
+	if err != nil {
+		t.Fatalf("createServerCredentialsFromFlags() error = %v", err)
+	}
+	server := grpc.NewServer(grpc.Creds(serverCredentials))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not create listener: %v", err)
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	rootCAs := x509.NewCertPool()
+	caBytes, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("could not read CA certificate: %v", err)
+	}
+	rootCAs.AppendCertsFromPEM(caBytes)
+	clientTLSCredentials := func(t *testing.T, certFile string, keyFile string) credentials.TransportCredentials {
+		t.Helper()
+		config := &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+		if certFile != "" {
+			certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				t.Fatalf("could not load client certificate: %v", err)
+			}
+			config.Certificates = []tls.Certificate{certificate}
+		}
+		return credentials.NewTLS(config)
+	}
+	tests := []struct {
+		name     string
+		creds    credentials.TransportCredentials
+		wantCode codes.Code
+	}{
+		{
+			name:     "valid client certificate",
+			creds:    clientTLSCredentials(t, certFile, keyFile),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "no client certificate",
+			creds:    clientTLSCredentials(t, "", ""),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "client certificate from an untrusted CA",
+			creds:    clientTLSCredentials(t, untrustedCertFile, untrustedKeyFile),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "plaintext",
+			creds:    insecure.NewCredentials(),
+			wantCode: codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(tt.creds))
+			if err != nil {
+				t.Fatalf("grpc.NewClient() error = %v", err)
+			}
+			defer conn.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("Check() code = %v, want %v, error = %v", code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestListenXDS(t *testing.T) {
+	dir := t.TempDir()
+	staleSocket := filepath.Join(dir, "stale.sock")
+	staleListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: staleSocket, Net: "unix"})
+	if err != nil {
+		t.Fatalf("could not create Unix domain socket listener: %v", err)
+	}
+	// Leave the socket file behind, as a process that did not shut down cleanly would.
+	staleListener.SetUnlinkOnClose(false)
+	staleListener.Close()
+	regularFile := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regularFile, nil, 0o600); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	tests := []struct {
+		name        string
+		addr        string
+		socket      string
+		wantNetwork string
+		wantErr     bool
+	}{
+		{
+			name:        "TCP address",
+			addr:        "127.0.0.1:0",
+			wantNetwork: "tcp",
+		},
+		{
+			name:        "Unix domain socket",
+			socket:      filepath.Join(dir, "xds.sock"),
+			wantNetwork: "unix",
+		},
+		{
+			name:        "stale Unix domain socket file is replaced",
+			socket:      staleSocket,
+			wantNetwork: "unix",
+		},
+		{
+			name:    "regular file is not replaced",
+			socket:  regularFile,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setXDSListenerFlags(t, tt.addr, tt.socket)
+			listener, err := listenXDS(logr.Discard(), 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("listenXDS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer listener.Close()
+			if network := listener.Addr().Network(); network != tt.wantNetwork {
+				t.Errorf("listener network = %q, want %q", network, tt.wantNetwork)
+			}
+		})
+	}
+	if _, err := os.Stat(regularFile); err != nil {
+		t.Errorf("regular file was removed: %v", err)
+	}
+}
+
+// TestXDSOverUnixDomainSocket opens an ADS stream over a Unix domain socket listener,
+// and verifies that the socket file is removed when the server stops.
+func TestXDSOverUnixDomainSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "xds.sock")
+	setXDSListenerFlags(t, "", socket)
+	listener, err := listenXDS(logr.Discard(), 0)
+	if err != nil {
+		t.Fatalf("listenXDS() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	xdsCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+	if err := xdsCache.SetSnapshot(ctx, testNode.GetLocality().GetZone(), newTestClusterSnapshot(t, "1", 2)); err != nil {
+		t.Fatalf("SetSnapshot() error = %v", err)
+	}
+	server := grpc.NewServer()
+	registerXDSServices(server, serverv3.NewServer(ctx, xdsCache, &serverv3.CallbackFuncs{}))
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		_ = server.Serve(listener)
+	}()
+
+	stream := openTestADSStream(t, "unix://"+socket)
+	receiveTestClusters(t, stream, "1", 2)
+
+	server.Stop()
+	<-served
+	if _, err := os.Lstat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Lstat() of socket file after Stop() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestRunRejectsXDSAddrAndSocket(t *testing.T) {
+	setXDSListenerFlags(t, "127.0.0.1:0", filepath.Join(t.TempDir(), "xds.sock"))
+	if err := Run(context.Background(), 0, 0, nil, nil, ""); !errors.Is(err, errXDSAddrAndSocket) {
+		t.Errorf("Run() error = %v, want %v", err, errXDSAddrAndSocket)
+	}
+}
+
+func TestSetReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancelled  bool
+		wantStatus healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:       "serving",
+			wantStatus: healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name:       "draining",
+			cancelled:  true,
+			wantStatus: healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			healthServer := health.NewServer()
+			healthServer.SetServingStatus(healthServiceReadiness, healthpb.HealthCheckResponse_NOT_SERVING)
+			setReady(ctx, logr.Discard(), healthServer)
+			response, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: healthServiceReadiness})
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if response.GetStatus() != tt.wantStatus {
+				t.Errorf("readiness status = %v, want %v", response.GetStatus(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWaitForStop(t *testing.T) {
+	stopped := make(chan struct{})
+	if waitForStop(stopped, time.Millisecond) {
+		t.Error("waitForStop() = true before the channel is closed, want false")
+	}
+	close(stopped)
+	if !waitForStop(stopped, time.Second) {
+		t.Error("waitForStop() = false after the channel is closed, want true")
+	}
+}
+
+func TestAddServerStopBehaviorWithoutStreams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serveCtx, cancelServe := context.WithCancel(context.Background())
+	defer cancelServe()
+	healthServer := health.NewServer()
+	addServerStopBehavior(ctx, logr.Discard(), grpc.NewServer(), grpc.NewServer(), healthServer, cancelServe)
+	cancel()
+	select {
+	case <-serveCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve context was not cancelled after the xDS management server stopped")
+	}
+	response, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if response.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health status = %v, want %v", response.GetStatus(), healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// TestAddServerStopBehaviorDrainsStreams verifies that an active xDS stream keeps receiving
+// complete updates while draining, and that the stream ends after the drain timeout, without
+// a partial resource set.
+func TestAddServerStopBehaviorDrainsStreams(t *testing.T) {
+	previousDrainTimeout := drainTimeout
+	drainTimeout = 500 * time.Millisecond
+	t.Cleanup(func() { drainTimeout = previousDrainTimeout })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serveCtx, cancelServe := context.WithCancel(context.Background())
+	defer cancelServe()
+
+	xdsCache := xds.NewSnapshotCache(serveCtx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+	if err := xdsCache.SetSnapshot(serveCtx, testNode.GetLocality().GetZone(), newTestClusterSnapshot(t, "1", 3)); err != nil {
+		t.Fatalf("SetSnapshot() error = %v", err)
+	}
+	server := grpc.NewServer()
+	registerXDSServices(server, serverv3.NewServer(serveCtx, xdsCache, &serverv3.CallbackFuncs{}))
+	healthGRPCServer := grpc.NewServer()
+	addServerStopBehavior(ctx, logr.Discard(), server, healthGRPCServer, health.NewServer(), cancelServe)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not create listener: %v", err)
+	}
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		_ = server.Serve(listener)
+	}()
+
+	stream := openTestADSStream(t, "passthrough:///"+listener.Addr().String())
+	receiveTestClusters(t, stream, "1", 3)
+
+	cancel()
+	if err := xdsCache.SetSnapshot(serveCtx, testNode.GetLocality().GetZone(), newTestClusterSnapshot(t, "2", 4)); err != nil {
+		t.Fatalf("SetSnapshot() error = %v", err)
+	}
+	receiveTestClusters(t, stream, "2", 4)
+	start := time.Now()
+	if response, err := stream.Recv(); err == nil {
+		t.Fatalf("Recv() after drain timeout = version %s with %d resources, want end of stream", response.GetVersionInfo(), len(response.GetResources()))
+	}
+	if elapsed := time.Since(start); elapsed > drainTimeout+gracefulStopTimeout {
+		t.Errorf("stream ended after %v, want within the drain timeout %v and the graceful stop timeout %v", elapsed, drainTimeout, gracefulStopTimeout)
+	}
+	select {
+	case <-serveCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve context was not cancelled after the drain timeout")
+	}
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("xDS management server did not stop after the drain timeout")
+	}
+}
+
+var testNode = &corev3.Node{Id: "test-node", Locality: &corev3.Locality{Zone: "zone-a"}}
+
+// newTestClusterSnapshot creates a snapshot with the provided number of Clusters.
+func newTestClusterSnapshot(t *testing.T, version string, numClusters int) *cachev3.Snapshot {
+	t.Helper()
+	clusters := make([]types.Resource, numClusters)
+	for i := range clusters {
+		clusters[i] = &clusterconfigv3.Cluster{Name: fmt.Sprintf("cluster-%d", i)}
+	}
+	snapshot, err := cachev3.NewSnapshot(version, map[string][]types.Resource{resource.ClusterType: clusters})
+	if err != nil {
+		t.Fatalf("NewSnapshot() error = %v", err)
+	}
+	return snapshot
+}
+
+// openTestADSStream opens an ADS stream to the target, and requests all Clusters for `testNode`.
+func openTestADSStream(t *testing.T, target string) discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesClient {
+	t.Helper()
+	clientConn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = clientConn.Close() })
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	t.Cleanup(cancelStream)
+	stream, err := discoveryv3.NewAggregatedDiscoveryServiceClient(clientConn).StreamAggregatedResources(streamCtx)
+	if err != nil {
+		t.Fatalf("StreamAggregatedResources() error = %v", err)
+	}
+	if err := stream.Send(&discoveryv3.DiscoveryRequest{Node: testNode, TypeUrl: resource.ClusterType}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	return stream
+}
+
+// receiveTestClusters receives the next response on the stream, and acknowledges it.
+// The response must contain the complete set of Clusters of the snapshot version.
+func receiveTestClusters(t *testing.T, stream discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesClient, wantVersion string, wantClusters int) {
+	t.Helper()
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if response.GetVersionInfo() != wantVersion || len(response.GetResources()) != wantClusters {
+		t.Fatalf("Recv() = version %s with %d resources, want version %s with %d resources", response.GetVersionInfo(), len(response.GetResources()), wantVersion, wantClusters)
+	}
+	if err := stream.Send(&discoveryv3.DiscoveryRequest{
+		Node:          testNode,
+		TypeUrl:       resource.ClusterType,
+		VersionInfo:   response.GetVersionInfo(),
+		ResponseNonce: response.GetNonce(),
+	}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+}
+
+func setXDSListenerFlags(t *testing.T, addr string, socket string) {
+	t.Helper()
+	previousAddr, previousSocket := xdsAddr, xdsSocket
+	t.Cleanup(func() {
+		xdsAddr, xdsSocket = previousAddr, previousSocket
+	})
+	xdsAddr, xdsSocket = addr, socket
+}
+
+func setTLSFlags(t *testing.T, cert string, key string, ca string) {
+	t.Helper()
+	previousCert, previousKey, previousCA := tlsCertFile, tlsKeyFile, tlsCAFile
+	t.Cleanup(func() {
+		tlsCertFile, tlsKeyFile, tlsCAFile = previousCert, previousKey, previousCA
+	})
+	tlsCertFile, tlsKeyFile, tlsCAFile = cert, key, ca
+}
+
+// writeTestCertificate writes a self-signed certificate and its private key to PEM files in the directory,
+// and returns the paths of the files.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "control-plane"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal private key: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("could not write private key: %v", err)
+	}
+	return certFile, keyFile
+}
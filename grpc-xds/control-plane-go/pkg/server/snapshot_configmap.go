@@ -0,0 +1,153 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/config"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/informers"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	// snapshotConfigMapKey is the key of the persisted snapshots in the ConfigMap data.
+	snapshotConfigMapKey = "snapshots.json"
+	// snapshotConfigMapWriteDelay coalesces bursts of snapshot updates into a single ConfigMap write.
+	snapshotConfigMapWriteDelay = time.Second
+	defaultCacheMaxAge          = 10 * time.Minute
+)
+
+// snapshotConfigMap persists xDS resource snapshots in a ConfigMap in the namespace of this pod,
+// so that a restarted control plane can serve resources before its informer caches have synced.
+type snapshotConfigMap struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+}
+
+func newSnapshotConfigMap(ctx context.Context, logger logr.Logger) (*snapshotConfigMap, error) {
+	namespace, err := config.Namespace(logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine namespace for the snapshot cache ConfigMap: %w", err)
+	}
+	// Using the kubecontext of the cluster where the control plane runs.
+	clientset, err := informers.NewClientSet(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not create Kubernetes clientset for the snapshot cache ConfigMap: %w", err)
+	}
+	return &snapshotConfigMap{
+		clientset: clientset,
+		namespace: namespace,
+		name:      cacheConfigMap,
+	}, nil
+}
+
+// restore sets the snapshots from the ConfigMap, if it exists and is not older than `cache-max-age`.
+// Returns true if the snapshots were restored.
+func (s *snapshotConfigMap) restore(ctx context.Context, logger logr.Logger, xdsCache *xds.SnapshotCache) (bool, error) {
+	configMap, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logger.V(2).Info("No snapshot cache ConfigMap found, waiting for the informer caches to sync")
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not get the snapshot cache ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	data, exists := configMap.Data[snapshotConfigMapKey]
+	if !exists {
+		return false, nil
+	}
+	restored, err := xdsCache.RestoreSnapshots(logger, []byte(data), cacheMaxAge)
+	if err != nil {
+		return false, fmt.Errorf("could not restore xDS resource snapshots from the ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return restored, nil
+}
+
+// startWriter writes the snapshots to the ConfigMap after snapshot updates, until the context is done.
+func (s *snapshotConfigMap) startWriter(ctx context.Context, logger logr.Logger, xdsCache *xds.SnapshotCache) {
+	updated := make(chan struct{}, 1)
+	xdsCache.SetSnapshotListener(func() {
+		select {
+		case updated <- struct{}{}:
+		default:
+		}
+	})
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-updated:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(snapshotConfigMapWriteDelay):
+			}
+			if err := s.write(ctx, xdsCache); err != nil {
+				logger.Error(err, "Could not write xDS resource snapshots to the ConfigMap", "configMap", s.name, "namespace", s.namespace)
+			}
+		}
+	}()
+}
+
+// write creates or updates the ConfigMap with the current snapshots.
+// ConfigMaps are limited to 1 MiB, so writes fail for very large snapshots.
+func (s *snapshotConfigMap) write(ctx context.Context, xdsCache *xds.SnapshotCache) error {
+	data, err := xdsCache.ExportSnapshots()
+	if err != nil {
+		return err
+	}
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/component": "control-plane",
+				},
+			},
+			Data: map[string]string{
+				snapshotConfigMapKey: string(data),
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create the snapshot cache ConfigMap %s/%s: %w", s.namespace, s.name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not get the snapshot cache ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	configMap.Data = map[string]string{
+		snapshotConfigMapKey: string(data),
+	}
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update the snapshot cache ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return nil
+}
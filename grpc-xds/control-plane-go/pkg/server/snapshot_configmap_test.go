@@ -0,0 +1,199 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/stream/v3"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+const (
+	testSnapshotConfigMapNamespace = "xds"
+	testSnapshotConfigMapName      = "control-plane-snapshots"
+)
+
+func newTestSnapshotConfigMap(clientset kubernetes.Interface) *snapshotConfigMap {
+	return &snapshotConfigMap{
+		clientset: clientset,
+		namespace: testSnapshotConfigMapNamespace,
+		name:      testSnapshotConfigMapName,
+	}
+}
+
+// newTestSnapshotConfigMapCache creates a snapshot cache with a Cluster watch for `testNode`,
+// so that the snapshot of its node hash is exported.
+func newTestSnapshotConfigMapCache(t *testing.T) *xds.SnapshotCache {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	xdsCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+	request := &cachev3.Request{
+		Node:    testNode,
+		TypeUrl: resource.ClusterType,
+	}
+	cancelWatch := xdsCache.CreateWatch(request, stream.NewStreamState(false, nil), make(chan cachev3.Response, 10))
+	t.Cleanup(cancelWatch)
+	return xdsCache
+}
+
+// testSnapshotConfigMapData returns persisted snapshots with the provided timestamp and one empty snapshot for `testNode`.
+func testSnapshotConfigMapData(t *testing.T, timestamp time.Time) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"timestamp": timestamp,
+		"snapshots": map[string]any{
+			testNode.GetLocality().GetZone(): map[string]any{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not marshal persisted snapshots: %v", err)
+	}
+	return string(data)
+}
+
+// setCacheMaxAge sets the `cache-max-age` flag value for the duration of the test.
+func setCacheMaxAge(t *testing.T, maxAge time.Duration) {
+	t.Helper()
+	previous := cacheMaxAge
+	cacheMaxAge = maxAge
+	t.Cleanup(func() {
+		cacheMaxAge = previous
+	})
+}
+
+func TestSnapshotConfigMapWriteAndRestore(t *testing.T) {
+	setCacheMaxAge(t, defaultCacheMaxAge)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	clientset := fake.NewSimpleClientset()
+	s := newTestSnapshotConfigMap(clientset)
+	nodeHash := testNode.GetLocality().GetZone()
+
+	source := newTestSnapshotConfigMapCache(t)
+	if err := source.SetSnapshot(ctx, nodeHash, newTestClusterSnapshot(t, "1", 2)); err != nil {
+		t.Fatalf("SetSnapshot() error = %v", err)
+	}
+	if err := s.write(ctx, source); err != nil {
+		t.Fatalf("write() creating the ConfigMap error = %v", err)
+	}
+	configMap, err := clientset.CoreV1().ConfigMaps(testSnapshotConfigMapNamespace).Get(ctx, testSnapshotConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get the ConfigMap after write(): %v", err)
+	}
+	if got := configMap.Labels["app.kubernetes.io/component"]; got != "control-plane" {
+		t.Errorf("ConfigMap label app.kubernetes.io/component = %q, want control-plane", got)
+	}
+	if _, exists := configMap.Data[snapshotConfigMapKey]; !exists {
+		t.Errorf("ConfigMap data has no key %s", snapshotConfigMapKey)
+	}
+
+	// The second write updates the existing ConfigMap.
+	if err := source.SetSnapshot(ctx, nodeHash, newTestClusterSnapshot(t, "2", 3)); err != nil {
+		t.Fatalf("SetSnapshot() error = %v", err)
+	}
+	if err := s.write(ctx, source); err != nil {
+		t.Fatalf("write() updating the ConfigMap error = %v", err)
+	}
+
+	restoredCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+	restored, err := s.restore(ctx, logr.Discard(), restoredCache)
+	if err != nil {
+		t.Fatalf("restore() error = %v", err)
+	}
+	if !restored {
+		t.Fatalf("restore() = false, want true")
+	}
+	snapshot, err := restoredCache.GetSnapshot(nodeHash)
+	if err != nil {
+		t.Fatalf("GetSnapshot() after restore() error = %v", err)
+	}
+	if got := len(snapshot.GetResources(resource.ClusterType)); got != 3 {
+		t.Errorf("restored snapshot Clusters = %d, want 3 from the updated ConfigMap", got)
+	}
+}
+
+func TestSnapshotConfigMapRestore(t *testing.T) {
+	setCacheMaxAge(t, 10*time.Minute)
+	tests := []struct {
+		name         string
+		configMap    *corev1.ConfigMap
+		wantRestored bool
+		wantErr      bool
+	}{
+		{
+			name:         "no ConfigMap",
+			wantRestored: false,
+		},
+		{
+			name:         "no snapshots key",
+			configMap:    &corev1.ConfigMap{Data: map[string]string{"other": "{}"}},
+			wantRestored: false,
+		},
+		{
+			name:         "snapshots within cache-max-age",
+			configMap:    &corev1.ConfigMap{Data: map[string]string{snapshotConfigMapKey: testSnapshotConfigMapData(t, time.Now().Add(-time.Minute))}},
+			wantRestored: true,
+		},
+		{
+			name:         "stale snapshots older than cache-max-age",
+			configMap:    &corev1.ConfigMap{Data: map[string]string{snapshotConfigMapKey: testSnapshotConfigMapData(t, time.Now().Add(-time.Hour))}},
+			wantRestored: false,
+		},
+		{
+			name:      "invalid snapshots",
+			configMap: &corev1.ConfigMap{Data: map[string]string{snapshotConfigMapKey: "not json"}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			clientset := fake.NewSimpleClientset()
+			if tt.configMap != nil {
+				tt.configMap.Name = testSnapshotConfigMapName
+				tt.configMap.Namespace = testSnapshotConfigMapNamespace
+				if err := clientset.Tracker().Add(tt.configMap); err != nil {
+					t.Fatalf("could not add ConfigMap: %v", err)
+				}
+			}
+			xdsCache := xds.NewSnapshotCache(ctx, false, xds.ZoneHash{}, xds.FixedLocalityPriority{}, &xds.Features{}, "")
+			restored, err := newTestSnapshotConfigMap(clientset).restore(ctx, logr.Discard(), xdsCache)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("restore() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if restored != tt.wantRestored {
+				t.Errorf("restore() = %t, want %t", restored, tt.wantRestored)
+			}
+			_, err = xdsCache.GetSnapshot(testNode.GetLocality().GetZone())
+			if gotSnapshot := err == nil; gotSnapshot != tt.wantRestored {
+				t.Errorf("snapshot after restore() exists = %t, want %t", gotSnapshot, tt.wantRestored)
+			}
+		})
+	}
+}
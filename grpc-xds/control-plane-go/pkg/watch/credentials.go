@@ -0,0 +1,66 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var (
+	errIncompleteTLSFlags = errors.New("both of the flags tls-cert and tls-key are required for mTLS")
+	errNoCACertificates   = errors.New("no PEM-encoded CA certificates found in file")
+)
+
+// createTransportCredentials returns TLS credentials for the connection to the xDS management server
+// if any of the `tls-*` flags is set, and plaintext credentials otherwise.
+// With `tls-cert` and `tls-key`, the client presents its certificate, e.g., for servers that require mTLS.
+func createTransportCredentials() (credentials.TransportCredentials, error) {
+	if tlsCAFile == "" && tlsCertFile == "" && tlsKeyFile == "" && tlsServerName == "" {
+		return insecure.NewCredentials(), nil
+	}
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		return nil, fmt.Errorf("%w: tls-cert=%q tls-key=%q", errIncompleteTLSFlags, tlsCertFile, tlsKeyFile)
+	}
+	tlsConfig := &tls.Config{
+		ServerName: tlsServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if tlsCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate from certFile=%s and keyFile=%s: %w", tlsCertFile, tlsKeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if tlsCAFile != "" {
+		caBytes, err := os.ReadFile(tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA certificates from file %s: %w", tlsCAFile, err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("%w: %s", errNoCACertificates, tlsCAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
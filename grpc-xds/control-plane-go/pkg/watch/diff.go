@@ -0,0 +1,74 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+// diffLine is a line of a line diff, with the operation `+` for added, `-` for removed,
+// and ` ` for unchanged lines.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines returns a line diff based on the longest common subsequence of the lines.
+// xDS resources are small enough for the quadratic time and memory.
+func diffLines(before []string, after []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:].
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	lines := make([]diffLine, 0, max(len(before), len(after)))
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			lines = append(lines, diffLine{op: ' ', text: before[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{op: '-', text: before[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{op: '+', text: after[j]})
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		lines = append(lines, diffLine{op: '-', text: before[i]})
+	}
+	for ; j < len(after); j++ {
+		lines = append(lines, diffLine{op: '+', text: after[j]})
+	}
+	return lines
+}
+
+// nearChange returns true if there is an added or removed line within `context` lines of index i.
+func nearChange(lines []diffLine, i int, context int) bool {
+	for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+		if lines[k].op != ' ' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		after  []string
+		want   []diffLine
+	}{
+		{
+			name: "both empty",
+			want: []diffLine{},
+		},
+		{
+			name:   "unchanged",
+			before: []string{"a", "b"},
+			after:  []string{"a", "b"},
+			want:   []diffLine{{' ', "a"}, {' ', "b"}},
+		},
+		{
+			name:  "added",
+			after: []string{"a"},
+			want:  []diffLine{{'+', "a"}},
+		},
+		{
+			name:   "removed",
+			before: []string{"a"},
+			want:   []diffLine{{'-', "a"}},
+		},
+		{
+			name:   "changed line",
+			before: []string{"a", "b", "c"},
+			after:  []string{"a", "x", "c"},
+			want:   []diffLine{{' ', "a"}, {'-', "b"}, {'+', "x"}, {' ', "c"}},
+		},
+		{
+			name:   "inserted and appended lines",
+			before: []string{"a", "c"},
+			after:  []string{"a", "b", "c", "d"},
+			want:   []diffLine{{' ', "a"}, {'+', "b"}, {' ', "c"}, {'+', "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.before, tt.after); !slices.Equal(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearChange(t *testing.T) {
+	lines := []diffLine{{' ', "a"}, {' ', "b"}, {'+', "c"}, {' ', "d"}, {' ', "e"}, {' ', "f"}}
+	tests := []struct {
+		i    int
+		want bool
+	}{
+		{i: 0, want: true},
+		{i: 2, want: true},
+		{i: 4, want: true},
+		{i: 5, want: false},
+	}
+	for _, tt := range tests {
+		if got := nearChange(lines, tt.i, 2); got != tt.want {
+			t.Errorf("nearChange(%d) = %t, want %t", tt.i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"flag"
+)
+
+const (
+	OutputDiff      = "diff"
+	OutputFull      = "full"
+	OutputNamesOnly = "names-only"
+
+	resourceTypeAll = "all"
+
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var (
+	serverAddr    string
+	nodeID        string
+	zone          string
+	nodeMetadata  string
+	resourceType  string
+	output        string
+	color         string
+	tlsCertFile   string
+	tlsKeyFile    string
+	tlsCAFile     string
+	tlsServerName string
+)
+
+// InitFlags initializes flags for the `watch` subcommand.
+func InitFlags(flagset *flag.FlagSet) {
+	if flagset == nil {
+		flagset = flag.CommandLine
+	}
+	flagset.StringVar(&serverAddr, "server", "localhost:50051", "(optional) address of the xDS management server, using plaintext unless a -tls-* flag is set")
+	flagset.StringVar(&nodeID, "node-id", "xds-watch", "(optional) node ID sent to the xDS management server")
+	flagset.StringVar(&zone, "zone", "", "(optional) node locality zone sent to the xDS management server, which selects the snapshot")
+	flagset.StringVar(&nodeMetadata, "node-metadata", "", "(optional) comma-separated node metadata fields sent to the xDS management server, e.g., NAMESPACE=xds, for snapshots scoped by -scope-metadata-key")
+	flagset.StringVar(&resourceType, "resource-type", resourceTypeAll, "(optional) resource type to watch, one of LDS, RDS, CDS, EDS, or all")
+	flagset.StringVar(&output, "output", OutputDiff, "(optional) output format, one of diff, full, or names-only")
+	flagset.StringVar(&color, "color", colorAuto, "(optional) colored output, one of auto, which colors output to a terminal, always, or never")
+	flagset.StringVar(&tlsCAFile, "tls-ca", "", "(optional) path to the PEM-encoded CA certificates file used to verify the server certificate, enables TLS, default is the system CA certificates")
+	flagset.StringVar(&tlsCertFile, "tls-cert", "", "(optional) path to the PEM-encoded client certificate chain file, enables mTLS together with -tls-key")
+	flagset.StringVar(&tlsKeyFile, "tls-key", "", "(optional) path to the PEM-encoded client private key file, enables mTLS together with -tls-cert")
+	flagset.StringVar(&tlsServerName, "tls-server-name", "", "(optional) server name used to verify the server certificate, enables TLS, default is the host of -server")
+}
@@ -0,0 +1,295 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	// Register the extension types used in the typed configs of the resources, for JSON output.
+	_ "github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/xds"
+)
+
+var (
+	errUnknownResourceType = errors.New("unknown resource type")
+	errUnknownOutput       = errors.New("unknown output format")
+	errUnknownColor        = errors.New("unknown color mode")
+	errInvalidNodeMetadata = errors.New("invalid node metadata")
+)
+
+// resourceTypes maps the resource type flag values to type URLs, in output order.
+var resourceTypes = []struct {
+	name    string
+	typeURL string
+}{
+	{"LDS", resource.ListenerType},
+	{"RDS", resource.RouteType},
+	{"CDS", resource.ClusterType},
+	{"EDS", resource.EndpointType},
+}
+
+// Run subscribes to all resources of the selected types on the xDS management server, using an
+// aggregated state-of-the-world stream, and writes the changes to `w` after each response,
+// until the context is done or the stream fails.
+func Run(ctx context.Context, logger logr.Logger, w io.Writer) error {
+	typeURLs, err := selectedTypeURLs()
+	if err != nil {
+		return err
+	}
+	if output != OutputDiff && output != OutputFull && output != OutputNamesOnly {
+		return fmt.Errorf("%w: output=%s, must be one of %s, %s, or %s", errUnknownOutput, output, OutputDiff, OutputFull, OutputNamesOnly)
+	}
+	if color != colorAuto && color != colorAlways && color != colorNever {
+		return fmt.Errorf("%w: color=%s, must be one of %s, %s, or %s", errUnknownColor, color, colorAuto, colorAlways, colorNever)
+	}
+	node, err := createNode()
+	if err != nil {
+		return err
+	}
+	transportCredentials, err := createTransportCredentials()
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.NewClient(serverAddr, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return fmt.Errorf("could not create gRPC client for the xDS management server at %s: %w", serverAddr, err)
+	}
+	defer conn.Close()
+	stream, err := discoveryv3.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+	if err != nil {
+		return fmt.Errorf("could not open xDS stream to %s: %w", serverAddr, err)
+	}
+	// Empty resource names subscribe to all resources of the type.
+	for _, typeURL := range typeURLs {
+		if err := stream.Send(&discoveryv3.DiscoveryRequest{Node: node, TypeUrl: typeURL}); err != nil {
+			return fmt.Errorf("could not send xDS request for type=%s: %w", typeURL, err)
+		}
+	}
+	logger.V(2).Info("Watching xDS resources", "server", serverAddr, "node", node.GetId(), "zone", zone, "typeURLs", typeURLs)
+	printer := newPrinter(w, output, color == colorAlways || (color == colorAuto && isTerminal(w)))
+	known := map[string]map[string]proto.Message{}
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("xDS stream to %s failed: %w", serverAddr, err)
+		}
+		resources, err := unmarshalResources(response)
+		if err != nil {
+			return err
+		}
+		printer.printChanges(response.GetTypeUrl(), response.GetVersionInfo(), known[response.GetTypeUrl()], resources)
+		known[response.GetTypeUrl()] = resources
+		// ACK the response, so that the server sends the next version.
+		if err := stream.Send(&discoveryv3.DiscoveryRequest{
+			Node:          node,
+			TypeUrl:       response.GetTypeUrl(),
+			VersionInfo:   response.GetVersionInfo(),
+			ResponseNonce: response.GetNonce(),
+		}); err != nil {
+			return fmt.Errorf("could not send xDS ACK for type=%s version=%s: %w", response.GetTypeUrl(), response.GetVersionInfo(), err)
+		}
+	}
+}
+
+func selectedTypeURLs() ([]string, error) {
+	var typeURLs []string
+	for _, rt := range resourceTypes {
+		if strings.EqualFold(resourceType, resourceTypeAll) || strings.EqualFold(resourceType, rt.name) {
+			typeURLs = append(typeURLs, rt.typeURL)
+		}
+	}
+	if len(typeURLs) == 0 {
+		return nil, fmt.Errorf("%w: resource-type=%s, must be one of LDS, RDS, CDS, EDS, or all", errUnknownResourceType, resourceType)
+	}
+	return typeURLs, nil
+}
+
+func createNode() (*corev3.Node, error) {
+	node := &corev3.Node{
+		Id: nodeID,
+		Locality: &corev3.Locality{
+			Zone: zone,
+		},
+	}
+	if nodeMetadata == "" {
+		return node, nil
+	}
+	fields := map[string]interface{}{}
+	for _, field := range strings.Split(nodeMetadata, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("%w: %q, expected KEY=VALUE", errInvalidNodeMetadata, field)
+		}
+		fields[key] = value
+	}
+	metadata, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidNodeMetadata, err)
+	}
+	node.Metadata = metadata
+	return node, nil
+}
+
+// unmarshalResources returns the resources of the response by name.
+func unmarshalResources(response *discoveryv3.DiscoveryResponse) (map[string]proto.Message, error) {
+	resources := make(map[string]proto.Message, len(response.GetResources()))
+	for _, anyResource := range response.GetResources() {
+		message, err := anyResource.UnmarshalNew()
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal resource of type=%s: %w", anyResource.GetTypeUrl(), err)
+		}
+		resources[cachev3.GetResourceName(message)] = message
+	}
+	return resources, nil
+}
+
+// isTerminal returns true if `w` is a character device, e.g., a terminal, and not a file or a pipe.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fileInfo, err := file.Stat()
+	return err == nil && fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// printer writes resource changes in the selected output format.
+type printer struct {
+	w      io.Writer
+	output string
+	color  bool
+}
+
+func newPrinter(w io.Writer, output string, color bool) *printer {
+	return &printer{
+		w:      w,
+		output: output,
+		color:  color,
+	}
+}
+
+// ANSI escape codes for added, removed, and changed resources and lines.
+const (
+	ansiGreen  = "\x1b[32m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// printChanges writes a header for the response, followed by the added, removed, and changed
+// resources compared to the previous response for the type, sorted by name.
+// Resources are compared using protobuf equality, and rendered as protobuf JSON.
+func (p *printer) printChanges(typeURL string, version string, previous map[string]proto.Message, current map[string]proto.Message) {
+	names := make([]string, 0, len(previous)+len(current))
+	for name := range previous {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, exists := previous[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	p.printf("", "# %s %s version=%s resources=%d\n", time.Now().Format(time.RFC3339), typeURL, version, len(current))
+	for _, name := range names {
+		before, existed := previous[name]
+		after, exists := current[name]
+		switch {
+		case !existed:
+			p.printf(ansiGreen, "+ %s\n", name)
+			if p.output != OutputNamesOnly {
+				p.printLines(ansiGreen, "+   ", marshalJSON(after))
+			}
+		case !exists:
+			p.printf(ansiRed, "- %s\n", name)
+		case !proto.Equal(before, after):
+			p.printf(ansiYellow, "~ %s\n", name)
+			switch p.output {
+			case OutputFull:
+				p.printLines("", "    ", marshalJSON(after))
+			case OutputDiff:
+				p.printDiff(marshalJSON(before), marshalJSON(after))
+			}
+		}
+	}
+}
+
+// printDiff writes a line diff of the JSON representations before and after the change,
+// with two lines of unchanged context around changes.
+func (p *printer) printDiff(before []string, after []string) {
+	const context = 2
+	lines := diffLines(before, after)
+	for i, line := range lines {
+		if line.op == ' ' && !nearChange(lines, i, context) {
+			continue
+		}
+		switch line.op {
+		case '+':
+			p.printf(ansiGreen, "+   %s\n", line.text)
+		case '-':
+			p.printf(ansiRed, "-   %s\n", line.text)
+		default:
+			p.printf("", "    %s\n", line.text)
+		}
+	}
+}
+
+func (p *printer) printLines(color string, prefix string, lines []string) {
+	for _, line := range lines {
+		p.printf(color, "%s%s\n", prefix, line)
+	}
+}
+
+// printf writes the formatted text, in the color if colored output is enabled.
+// The color is reset before a trailing newline, so that the next line starts uncolored.
+func (p *printer) printf(color string, format string, args ...interface{}) {
+	if p.color && color != "" {
+		text, newline := strings.CutSuffix(fmt.Sprintf(format, args...), "\n")
+		if newline {
+			_, _ = fmt.Fprint(p.w, color+text+ansiReset+"\n")
+			return
+		}
+		_, _ = fmt.Fprint(p.w, color+text+ansiReset)
+		return
+	}
+	_, _ = fmt.Fprintf(p.w, format, args...)
+}
+
+func marshalJSON(message proto.Message) []string {
+	jsonBytes, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(message)
+	if err != nil {
+		return []string{fmt.Sprintf("<could not marshal resource to JSON: %v>", err)}
+	}
+	return strings.Split(strings.TrimRight(string(jsonBytes), "\n"), "\n")
+}
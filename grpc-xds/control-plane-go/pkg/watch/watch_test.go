@@ -0,0 +1,386 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// testADSServer sends the responses on the first ADS stream, one after each request, and closes
+// `acked` when the last response is ACKed.
+type testADSServer struct {
+	discoveryv3.UnimplementedAggregatedDiscoveryServiceServer
+	responses []*discoveryv3.DiscoveryResponse
+	acked     chan struct{}
+}
+
+func (s *testADSServer) StreamAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	for _, response := range s.responses {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+	request, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if request.GetResponseNonce() != s.responses[len(s.responses)-1].GetNonce() {
+		return errors.New("expected an ACK of the last response")
+	}
+	close(s.acked)
+	<-stream.Context().Done()
+	return nil
+}
+
+// startTestADSServer starts a mock xDS management server that sends the responses, and returns its address.
+func startTestADSServer(t *testing.T, serverCredentials credentials.TransportCredentials, responses []*discoveryv3.DiscoveryResponse) (string, <-chan struct{}) {
+	t.Helper()
+	adsServer := &testADSServer{responses: responses, acked: make(chan struct{})}
+	server := grpc.NewServer(grpc.Creds(serverCredentials))
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(server, adsServer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not create listener: %v", err)
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), adsServer.acked
+}
+
+// newTestClusterResponse creates a CDS response with the provided version, which is also the nonce.
+func newTestClusterResponse(t *testing.T, version string, clusters ...*clusterv3.Cluster) *discoveryv3.DiscoveryResponse {
+	t.Helper()
+	response := &discoveryv3.DiscoveryResponse{
+		VersionInfo: version,
+		TypeUrl:     resource.ClusterType,
+		Nonce:       version,
+	}
+	for _, cluster := range clusters {
+		anyCluster, err := anypb.New(cluster)
+		if err != nil {
+			t.Fatalf("could not marshal Cluster %s: %v", cluster.GetName(), err)
+		}
+		response.Resources = append(response.Resources, anyCluster)
+	}
+	return response
+}
+
+func testCluster(name string, discoveryType clusterv3.Cluster_DiscoveryType) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:                 name,
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: discoveryType},
+	}
+}
+
+// setTestFlags sets the flags of the `watch` subcommand for the duration of the test.
+func setTestFlags(t *testing.T, server string, outputFormat string, colorMode string, caFile string, certFile string, keyFile string) {
+	t.Helper()
+	previous := []string{serverAddr, nodeID, zone, nodeMetadata, resourceType, output, color, tlsCAFile, tlsCertFile, tlsKeyFile, tlsServerName}
+	serverAddr, nodeID, zone, nodeMetadata, resourceType, output, color = server, "xds-watch-test", "zone-a", "", "CDS", outputFormat, colorMode
+	tlsCAFile, tlsCertFile, tlsKeyFile, tlsServerName = caFile, certFile, keyFile, ""
+	t.Cleanup(func() {
+		serverAddr, nodeID, zone, nodeMetadata, resourceType, output, color = previous[0], previous[1], previous[2], previous[3], previous[4], previous[5], previous[6]
+		tlsCAFile, tlsCertFile, tlsKeyFile, tlsServerName = previous[7], previous[8], previous[9], previous[10]
+	})
+}
+
+// runTestWatch runs the `watch` subcommand until the mock server has received the last ACK,
+// and returns the output.
+func runTestWatch(t *testing.T, acked <-chan struct{}) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out bytes.Buffer
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Run(ctx, logr.Discard(), &out)
+	}()
+	select {
+	case <-acked:
+	case err := <-errs:
+		t.Fatalf("Run() returned before the last ACK, error = %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for the last ACK")
+	}
+	cancel()
+	if err := <-errs; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return out.String()
+}
+
+var (
+	headerTimestamp = regexp.MustCompile(`(?m)^# \S+ `)
+	// protojson randomly adds whitespace after the colons in multiline output.
+	jsonColonSpace = regexp.MustCompile(`":\s+`)
+)
+
+// normalizeOutput replaces the timestamps in the headers, and the randomized protojson whitespace.
+func normalizeOutput(out string) string {
+	return jsonColonSpace.ReplaceAllString(headerTimestamp.ReplaceAllString(out, "# TIME "), `": `)
+}
+
+// colorize adds the expected ANSI escape codes to each line of the plain output.
+func colorize(plain string) string {
+	var colored strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(plain, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			colored.WriteString(ansiGreen + line + ansiReset)
+		case strings.HasPrefix(line, "-"):
+			colored.WriteString(ansiRed + line + ansiReset)
+		case strings.HasPrefix(line, "~"):
+			colored.WriteString(ansiYellow + line + ansiReset)
+		default:
+			colored.WriteString(line)
+		}
+		colored.WriteString("\n")
+	}
+	return colored.String()
+}
+
+func TestRunPrintsChanges(t *testing.T) {
+	responses := []*discoveryv3.DiscoveryResponse{
+		newTestClusterResponse(t, "1", testCluster("a", clusterv3.Cluster_EDS), testCluster("b", clusterv3.Cluster_EDS)),
+		newTestClusterResponse(t, "2", testCluster("a", clusterv3.Cluster_STRICT_DNS), testCluster("c", clusterv3.Cluster_EDS)),
+	}
+	header1 := "# TIME " + resource.ClusterType + " version=1 resources=2\n"
+	header2 := "# TIME " + resource.ClusterType + " version=2 resources=2\n"
+	added := func(name string) string {
+		return "+ " + name + "\n" +
+			"+   {\n" +
+			`+     "name": "` + name + `",` + "\n" +
+			`+     "type": "EDS"` + "\n" +
+			"+   }\n"
+	}
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "diff",
+			output: OutputDiff,
+			want: header1 + added("a") + added("b") +
+				header2 +
+				"~ a\n" +
+				"    {\n" +
+				`      "name": "a",` + "\n" +
+				`-     "type": "EDS"` + "\n" +
+				`+     "type": "STRICT_DNS"` + "\n" +
+				"    }\n" +
+				"- b\n" +
+				added("c"),
+		},
+		{
+			name:   "full",
+			output: OutputFull,
+			want: header1 + added("a") + added("b") +
+				header2 +
+				"~ a\n" +
+				"    {\n" +
+				`      "name": "a",` + "\n" +
+				`      "type": "STRICT_DNS"` + "\n" +
+				"    }\n" +
+				"- b\n" +
+				added("c"),
+		},
+		{
+			name:   "names-only",
+			output: OutputNamesOnly,
+			want: header1 + "+ a\n" + "+ b\n" +
+				header2 + "~ a\n" + "- b\n" + "+ c\n",
+		},
+	}
+	for _, tt := range tests {
+		for _, colorMode := range []string{colorNever, colorAlways} {
+			t.Run(tt.name+"/color="+colorMode, func(t *testing.T) {
+				addr, acked := startTestADSServer(t, insecure.NewCredentials(), responses)
+				setTestFlags(t, addr, tt.output, colorMode, "", "", "")
+				want := tt.want
+				if colorMode == colorAlways {
+					want = colorize(want)
+				}
+				if got := normalizeOutput(runTestWatch(t, acked)); got != want {
+					t.Errorf("output =\n%s\nwant\n%s", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestRunWithClientCertificate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("could not load server certificate: %v", err)
+	}
+	caBytes, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("could not read CA certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(caBytes)
+	serverCredentials := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	})
+	responses := []*discoveryv3.DiscoveryResponse{
+		newTestClusterResponse(t, "1", testCluster("a", clusterv3.Cluster_EDS)),
+	}
+	addr, acked := startTestADSServer(t, serverCredentials, responses)
+	setTestFlags(t, addr, OutputNamesOnly, colorNever, certFile, certFile, keyFile)
+	want := "# TIME " + resource.ClusterType + " version=1 resources=1\n+ a\n"
+	if got := normalizeOutput(runTestWatch(t, acked)); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCreateTransportCredentials(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+	notPEMFile := filepath.Join(dir, "not-pem.txt")
+	if err := os.WriteFile(notPEMFile, []byte("not PEM"), 0o600); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	tests := []struct {
+		name         string
+		caFile       string
+		certFile     string
+		keyFile      string
+		wantProtocol string
+		wantErr      error
+	}{
+		{
+			name:         "plaintext without TLS flags",
+			wantProtocol: "insecure",
+		},
+		{
+			name:         "TLS with CA certificates",
+			caFile:       certFile,
+			wantProtocol: "tls",
+		},
+		{
+			name:         "mTLS with client certificate",
+			caFile:       certFile,
+			certFile:     certFile,
+			keyFile:      keyFile,
+			wantProtocol: "tls",
+		},
+		{
+			name:     "client certificate without private key",
+			certFile: certFile,
+			wantErr:  errIncompleteTLSFlags,
+		},
+		{
+			name:    "CA file without certificates",
+			caFile:  notPEMFile,
+			wantErr: errNoCACertificates,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestFlags(t, "localhost:50051", OutputDiff, colorNever, tt.caFile, tt.certFile, tt.keyFile)
+			got, err := createTransportCredentials()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("createTransportCredentials() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if protocol := got.Info().SecurityProtocol; protocol != tt.wantProtocol {
+				t.Errorf("SecurityProtocol = %s, want %s", protocol, tt.wantProtocol)
+			}
+		})
+	}
+}
+
+func TestRunRejectsUnknownColor(t *testing.T) {
+	setTestFlags(t, "localhost:50051", OutputDiff, "sometimes", "", "", "")
+	if err := Run(context.Background(), logr.Discard(), &bytes.Buffer{}); !errors.Is(err, errUnknownColor) {
+		t.Errorf("Run() error = %v, want %v", err, errUnknownColor)
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and its private key to PEM
+// files in the directory, and returns the paths of the files.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "xds-watch-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal private key: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("could not write private key: %v", err)
+	}
+	return certFile, keyFile
+}
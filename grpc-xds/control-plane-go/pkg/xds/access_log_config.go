@@ -0,0 +1,205 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	grpcaccesslogv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	celformatterv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/formatter/cel/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	AccessLogSinkStdout = "stdout"
+	AccessLogSinkGRPC   = "grpc"
+
+	envoyAccessLoggersHTTPGRPCName = "envoy.access_loggers.http_grpc"
+	envoyFormatterCELName          = "envoy.formatter.cel"
+	// celCommand is the prefix of CEL expressions in access log format strings, e.g., `%CEL(request.path)%`.
+	celCommand = "%CEL("
+	// accessLogGRPCStatPrefix is the stat prefix of gRPC access log services that use the Google C++ gRPC client.
+	accessLogGRPCStatPrefix = "access_log"
+)
+
+// AccessLogConfig is the configuration from an `AccessLogConfig` custom resource.
+// It adds an access log to the LDS API listeners of the gRPC applications in the same namespace.
+//
+// gRPC clients ignore access logs, but Envoy proxies that use the same listeners apply them.
+type AccessLogConfig struct {
+	Namespace string
+	Name      string
+	// Sink is either `stdout` or `grpc`.
+	Sink string
+	// TextFormat and JSONFormat are alternative formats for the `stdout` sink.
+	// If both are empty, Envoy uses the default format.
+	// Format strings can contain CEL expressions, e.g., `%CEL(request.headers['x-user'])%`.
+	TextFormat string
+	// JSONFormat fields are sorted by key.
+	JSONFormat []AccessLogJSONField
+	// GRPCClusterName is the name of the cluster of the gRPC access log service in the
+	// Envoy proxy configuration, for the `grpc` sink. Alternative to GRPCAddress.
+	GRPCClusterName string
+	// GRPCAddress is the gRPC target URI of the access log service, for the `grpc` sink,
+	// e.g., `als.xds.svc.cluster.local:9000`. Alternative to GRPCClusterName.
+	GRPCAddress string
+	// LogName identifies the log in the gRPC access log service. Defaults to the name of the resource.
+	LogName string
+}
+
+// AccessLogJSONField is a key of the JSON access log format, with an Envoy format string as the value.
+type AccessLogJSONField struct {
+	Key   string
+	Value string
+}
+
+func (c AccessLogConfig) Compare(d AccessLogConfig) int {
+	if c.Namespace != d.Namespace {
+		return strings.Compare(c.Namespace, d.Namespace)
+	}
+	if c.Name != d.Name {
+		return strings.Compare(c.Name, d.Name)
+	}
+	if c.Sink != d.Sink {
+		return strings.Compare(c.Sink, d.Sink)
+	}
+	if c.TextFormat != d.TextFormat {
+		return strings.Compare(c.TextFormat, d.TextFormat)
+	}
+	if r := slices.CompareFunc(c.JSONFormat, d.JSONFormat, func(f AccessLogJSONField, g AccessLogJSONField) int {
+		if f.Key != g.Key {
+			return strings.Compare(f.Key, g.Key)
+		}
+		return strings.Compare(f.Value, g.Value)
+	}); r != 0 {
+		return r
+	}
+	if c.GRPCClusterName != d.GRPCClusterName {
+		return strings.Compare(c.GRPCClusterName, d.GRPCClusterName)
+	}
+	if c.GRPCAddress != d.GRPCAddress {
+		return strings.Compare(c.GRPCAddress, d.GRPCAddress)
+	}
+	return strings.Compare(c.LogName, d.LogName)
+}
+
+// createAccessLogs returns the access logs for the API listeners of gRPC applications in the namespace.
+func createAccessLogs(accessLogConfigs []AccessLogConfig, namespace string) ([]*accesslogv3.AccessLog, error) {
+	var accessLogs []*accesslogv3.AccessLog
+	for _, accessLogConfig := range accessLogConfigs {
+		if accessLogConfig.Namespace != namespace {
+			continue
+		}
+		accessLog, err := createAccessLog(accessLogConfig)
+		if err != nil {
+			return nil, fmt.Errorf("could not create access log for AccessLogConfig %s/%s: %w", accessLogConfig.Namespace, accessLogConfig.Name, err)
+		}
+		accessLogs = append(accessLogs, accessLog)
+	}
+	return accessLogs, nil
+}
+
+func createAccessLog(accessLogConfig AccessLogConfig) (*accesslogv3.AccessLog, error) {
+	if accessLogConfig.Sink == AccessLogSinkGRPC {
+		return createGRPCAccessLog(accessLogConfig)
+	}
+	var logFormat *corev3.SubstitutionFormatString
+	switch {
+	case accessLogConfig.TextFormat != "":
+		logFormat = createTextFormat(accessLogConfig.TextFormat)
+	case len(accessLogConfig.JSONFormat) > 0:
+		jsonFormat := make(map[string]interface{}, len(accessLogConfig.JSONFormat))
+		for _, field := range accessLogConfig.JSONFormat {
+			jsonFormat[field.Key] = field.Value
+		}
+		jsonFormatStruct, err := structpb.NewStruct(jsonFormat)
+		if err != nil {
+			return nil, fmt.Errorf("could not create JSON access log format: %w", err)
+		}
+		logFormat = &corev3.SubstitutionFormatString{
+			Format: &corev3.SubstitutionFormatString_JsonFormat{
+				JsonFormat: jsonFormatStruct,
+			},
+		}
+	}
+	if accessLogConfig.usesCEL() {
+		celFormatter, err := anypb.New(&celformatterv3.Cel{})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshall Cel formatter into Any instance: %w", err)
+		}
+		logFormat.Formatters = append(logFormat.Formatters, &corev3.TypedExtensionConfig{
+			Name:        envoyFormatterCELName,
+			TypedConfig: celFormatter,
+		})
+	}
+	return createStdoutAccessLogWithFormat(logFormat)
+}
+
+// usesCEL returns true if the text or JSON format contains CEL expressions.
+// Envoy requires the CEL formatter extension to evaluate `%CEL(...)%` commands.
+func (c AccessLogConfig) usesCEL() bool {
+	if strings.Contains(c.TextFormat, celCommand) {
+		return true
+	}
+	return slices.ContainsFunc(c.JSONFormat, func(field AccessLogJSONField) bool {
+		return strings.Contains(field.Value, celCommand)
+	})
+}
+
+// createGRPCAccessLog returns an access log configuration that sends HTTP access logs to a gRPC access log service.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/access_loggers/grpc/v3/als.proto
+func createGRPCAccessLog(accessLogConfig AccessLogConfig) (*accesslogv3.AccessLog, error) {
+	grpcService := &corev3.GrpcService{}
+	if accessLogConfig.GRPCClusterName != "" {
+		grpcService.TargetSpecifier = &corev3.GrpcService_EnvoyGrpc_{
+			EnvoyGrpc: &corev3.GrpcService_EnvoyGrpc{
+				ClusterName: accessLogConfig.GRPCClusterName,
+			},
+		}
+	} else {
+		grpcService.TargetSpecifier = &corev3.GrpcService_GoogleGrpc_{
+			GoogleGrpc: &corev3.GrpcService_GoogleGrpc{
+				TargetUri:  accessLogConfig.GRPCAddress,
+				StatPrefix: accessLogGRPCStatPrefix,
+			},
+		}
+	}
+	logName := accessLogConfig.LogName
+	if logName == "" {
+		logName = accessLogConfig.Name
+	}
+	httpGRPCAccessLog := &grpcaccesslogv3.HttpGrpcAccessLogConfig{
+		CommonConfig: &grpcaccesslogv3.CommonGrpcAccessLogConfig{
+			LogName:             logName,
+			GrpcService:         grpcService,
+			TransportApiVersion: corev3.ApiVersion_V3,
+		},
+	}
+	typedConfig, err := anypb.New(httpGRPCAccessLog)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshall HttpGrpcAccessLogConfig +%v into Any instance: %w", httpGRPCAccessLog, err)
+	}
+	return &accesslogv3.AccessLog{
+		Name: envoyAccessLoggersHTTPGRPCName,
+		ConfigType: &accesslogv3.AccessLog_TypedConfig{
+			TypedConfig: typedConfig,
+		},
+	}, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	grpcaccesslogv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	streamv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/stream/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCreateAccessLogs(t *testing.T) {
+	accessLogConfigs := []AccessLogConfig{
+		{Namespace: "default", Name: "stdout", Sink: AccessLogSinkStdout},
+		{Namespace: "other", Name: "stdout", Sink: AccessLogSinkStdout},
+		{Namespace: "default", Name: "grpc", Sink: AccessLogSinkGRPC, GRPCClusterName: "als"},
+	}
+	accessLogs, err := createAccessLogs(accessLogConfigs, "default")
+	if err != nil {
+		t.Fatalf("createAccessLogs() error = %v", err)
+	}
+	wantNames := []string{envoyAccessLoggersStdoutName, envoyAccessLoggersHTTPGRPCName}
+	if len(accessLogs) != len(wantNames) {
+		t.Fatalf("createAccessLogs() returned %d access logs, want %d", len(accessLogs), len(wantNames))
+	}
+	for i, accessLog := range accessLogs {
+		if accessLog.GetName() != wantNames[i] {
+			t.Errorf("access log %d name = %s, want %s", i, accessLog.GetName(), wantNames[i])
+		}
+	}
+}
+
+func TestCreateAccessLogStdoutFormat(t *testing.T) {
+	tests := []struct {
+		name            string
+		accessLogConfig AccessLogConfig
+		wantFormat      *corev3.SubstitutionFormatString
+		wantCEL         bool
+	}{
+		{
+			name:            "default format",
+			accessLogConfig: AccessLogConfig{Sink: AccessLogSinkStdout},
+			wantFormat:      nil,
+		},
+		{
+			name:            "text format",
+			accessLogConfig: AccessLogConfig{Sink: AccessLogSinkStdout, TextFormat: "%REQ(:PATH)%\n"},
+			wantFormat:      createTextFormat("%REQ(:PATH)%\n"),
+		},
+		{
+			name:            "text format with CEL",
+			accessLogConfig: AccessLogConfig{Sink: AccessLogSinkStdout, TextFormat: "%CEL(request.path)%\n"},
+			wantFormat:      createTextFormat("%CEL(request.path)%\n"),
+			wantCEL:         true,
+		},
+		{
+			name: "JSON format with CEL",
+			accessLogConfig: AccessLogConfig{
+				Sink: AccessLogSinkStdout,
+				JSONFormat: []AccessLogJSONField{
+					{Key: "path", Value: "%REQ(:PATH)%"},
+					{Key: "user", Value: "%CEL(request.headers['x-user'])%"},
+				},
+			},
+			wantFormat: &corev3.SubstitutionFormatString{
+				Format: &corev3.SubstitutionFormatString_JsonFormat{
+					JsonFormat: &structpb.Struct{Fields: map[string]*structpb.Value{
+						"path": structpb.NewStringValue("%REQ(:PATH)%"),
+						"user": structpb.NewStringValue("%CEL(request.headers['x-user'])%"),
+					}},
+				},
+			},
+			wantCEL: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessLog, err := createAccessLog(tt.accessLogConfig)
+			if err != nil {
+				t.Fatalf("createAccessLog() error = %v", err)
+			}
+			var stdoutAccessLog streamv3.StdoutAccessLog
+			if err := accessLog.GetTypedConfig().UnmarshalTo(&stdoutAccessLog); err != nil {
+				t.Fatalf("could not unmarshal StdoutAccessLog: %v", err)
+			}
+			logFormat := stdoutAccessLog.GetLogFormat()
+			hasCEL := len(logFormat.GetFormatters()) == 1 && logFormat.GetFormatters()[0].GetName() == envoyFormatterCELName
+			if hasCEL != tt.wantCEL {
+				t.Errorf("log format formatters = %v, want CEL formatter %t", logFormat.GetFormatters(), tt.wantCEL)
+			}
+			if tt.wantFormat == nil {
+				if logFormat != nil {
+					t.Errorf("log format = %v, want nil", logFormat)
+				}
+				return
+			}
+			formatWithoutFormatters := proto.Clone(logFormat).(*corev3.SubstitutionFormatString)
+			formatWithoutFormatters.Formatters = nil
+			if !proto.Equal(formatWithoutFormatters, tt.wantFormat) {
+				t.Errorf("log format = %v, want %v", logFormat, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestCreateGRPCAccessLog(t *testing.T) {
+	tests := []struct {
+		name            string
+		accessLogConfig AccessLogConfig
+		want            *grpcaccesslogv3.CommonGrpcAccessLogConfig
+	}{
+		{
+			name:            "cluster name and default log name",
+			accessLogConfig: AccessLogConfig{Name: "logs", Sink: AccessLogSinkGRPC, GRPCClusterName: "als"},
+			want: &grpcaccesslogv3.CommonGrpcAccessLogConfig{
+				LogName: "logs",
+				GrpcService: &corev3.GrpcService{
+					TargetSpecifier: &corev3.GrpcService_EnvoyGrpc_{
+						EnvoyGrpc: &corev3.GrpcService_EnvoyGrpc{ClusterName: "als"},
+					},
+				},
+				TransportApiVersion: corev3.ApiVersion_V3,
+			},
+		},
+		{
+			name: "address and log name",
+			accessLogConfig: AccessLogConfig{
+				Name:        "logs",
+				Sink:        AccessLogSinkGRPC,
+				GRPCAddress: "als.xds.svc.cluster.local:9000",
+				LogName:     "greeter",
+			},
+			want: &grpcaccesslogv3.CommonGrpcAccessLogConfig{
+				LogName: "greeter",
+				GrpcService: &corev3.GrpcService{
+					TargetSpecifier: &corev3.GrpcService_GoogleGrpc_{
+						GoogleGrpc: &corev3.GrpcService_GoogleGrpc{
+							TargetUri:  "als.xds.svc.cluster.local:9000",
+							StatPrefix: accessLogGRPCStatPrefix,
+						},
+					},
+				},
+				TransportApiVersion: corev3.ApiVersion_V3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessLog, err := createAccessLog(tt.accessLogConfig)
+			if err != nil {
+				t.Fatalf("createAccessLog() error = %v", err)
+			}
+			if accessLog.GetName() != envoyAccessLoggersHTTPGRPCName {
+				t.Errorf("access log name = %s, want %s", accessLog.GetName(), envoyAccessLoggersHTTPGRPCName)
+			}
+			var httpGRPCAccessLog grpcaccesslogv3.HttpGrpcAccessLogConfig
+			if err := accessLog.GetTypedConfig().UnmarshalTo(&httpGRPCAccessLog); err != nil {
+				t.Fatalf("could not unmarshal HttpGrpcAccessLogConfig: %v", err)
+			}
+			if !proto.Equal(httpGRPCAccessLog.GetCommonConfig(), tt.want) {
+				t.Errorf("common config = %v, want %v", httpGRPCAccessLog.GetCommonConfig(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Annotations on Kubernetes Services that configure the xDS resources of gRPC applications.
+const (
+	annotationPrefix                        = "xds.example.com/"
+	trafficSplitAnnotation                  = annotationPrefix + "traffic-split"
+	outlierConsecutiveErrorsAnnotation      = annotationPrefix + "outlier-consecutive-errors"
+	outlierIntervalAnnotation               = annotationPrefix + "outlier-interval"
+	outlierBaseEjectionTimeAnnotation       = annotationPrefix + "outlier-base-ejection-time"
+	cbMaxConnectionsAnnotation              = annotationPrefix + "cb-max-connections"
+	cbMaxPendingRequestsAnnotation          = annotationPrefix + "cb-max-pending-requests"
+	cbMaxRequestsAnnotation                 = annotationPrefix + "cb-max-requests"
+	cbMaxRetriesAnnotation                  = annotationPrefix + "cb-max-retries"
+	retryOnAnnotation                       = annotationPrefix + "retry-on"
+	numRetriesAnnotation                    = annotationPrefix + "num-retries"
+	perTryTimeoutAnnotation                 = annotationPrefix + "per-try-timeout"
+	lbPolicyAnnotation                      = annotationPrefix + "lb-policy"
+	ringHashMinSizeAnnotation               = annotationPrefix + "ring-hash-min-size"
+	ringHashMaxSizeAnnotation               = annotationPrefix + "ring-hash-max-size"
+	connectTimeoutAnnotation                = annotationPrefix + "connect-timeout"
+	keepaliveTimeAnnotation                 = annotationPrefix + "keepalive-time"
+	keepaliveIntervalAnnotation             = annotationPrefix + "keepalive-interval"
+	keepaliveProbesAnnotation               = annotationPrefix + "keepalive-probes"
+	faultDelayPercentAnnotation             = annotationPrefix + "fault-delay-percent"
+	faultDelayMillisAnnotation              = annotationPrefix + "fault-delay-ms"
+	faultAbortPercentAnnotation             = annotationPrefix + "fault-abort-percent"
+	faultAbortCodeAnnotation                = annotationPrefix + "fault-abort-code"
+	h2InitialStreamWindowAnnotation         = annotationPrefix + "h2-initial-stream-window"
+	h2InitialConnectionWindowAnnotation     = annotationPrefix + "h2-initial-connection-window"
+	h2MaxConcurrentStreamsAnnotation        = annotationPrefix + "h2-max-concurrent-streams"
+	grpcServiceConfigAnnotation             = annotationPrefix + "grpc-service-config"
+	upstreamProtocolAnnotation              = annotationPrefix + "upstream-protocol"
+	upstreamTLSAnnotation                   = annotationPrefix + "upstream-tls"
+	maxGRPCTimeoutAnnotation                = annotationPrefix + "max-grpc-timeout"
+	grpcTimeoutHeaderMaxAnnotation          = annotationPrefix + "grpc-timeout-header-max"
+	healthCheckProtocolAnnotation           = annotationPrefix + "health-check-protocol"
+	healthCheckIntervalAnnotation           = annotationPrefix + "health-check-interval"
+	healthCheckTimeoutAnnotation            = annotationPrefix + "health-check-timeout"
+	healthCheckUnhealthyThresholdAnnotation = annotationPrefix + "health-check-unhealthy-threshold"
+	healthCheckPathAnnotation               = annotationPrefix + "health-check-path"
+	healthCheckGRPCServiceAnnotation        = annotationPrefix + "health-check-grpc-service"
+)
+
+// uint32Annotation returns the value of the annotation as an unsigned integer.
+// Returns false if the annotation is not present, or if the value is invalid, in which case a warning is logged.
+func uint32Annotation(logger logr.Logger, annotations map[string]string, key string) (uint32, bool) {
+	value, exists := annotations[key]
+	if !exists {
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a non-negative integer", "annotation", key, "value", value, "error", err.Error())
+		return 0, false
+	}
+	return uint32(parsed), true
+}
+
+// positiveUint32Annotation is like uint32Annotation, but it also rejects zero values.
+func positiveUint32Annotation(logger logr.Logger, annotations map[string]string, key string) (uint32, bool) {
+	value, ok := uint32Annotation(logger, annotations, key)
+	if ok && value == 0 {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a positive integer", "annotation", key, "value", annotations[key])
+		return 0, false
+	}
+	return value, ok
+}
+
+// durationAnnotation returns the value of the annotation as a duration, e.g., `500ms` or `10s`.
+// Returns false if the annotation is not present, or if the value is invalid or negative,
+// in which case a warning is logged.
+func durationAnnotation(logger logr.Logger, annotations map[string]string, key string) (time.Duration, bool) {
+	value, exists := annotations[key]
+	if !exists {
+		return 0, false
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a duration", "annotation", key, "value", value, "error", err.Error())
+		return 0, false
+	}
+	if parsed < 0 {
+		logger.V(1).Info("Warning: ignoring annotation with negative duration", "annotation", key, "value", value)
+		return 0, false
+	}
+	return parsed, true
+}
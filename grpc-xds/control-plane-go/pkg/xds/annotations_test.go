@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestUint32Annotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  *string
+		want   uint32
+		wantOK bool
+	}{
+		{name: "not present", value: nil, want: 0, wantOK: false},
+		{name: "zero", value: ptr("0"), want: 0, wantOK: true},
+		{name: "positive", value: ptr("42"), want: 42, wantOK: true},
+		{name: "max uint32", value: ptr("4294967295"), want: 4294967295, wantOK: true},
+		{name: "too large", value: ptr("4294967296"), want: 0, wantOK: false},
+		{name: "negative", value: ptr("-1"), want: 0, wantOK: false},
+		{name: "not a number", value: ptr("ten"), want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.value != nil {
+				annotations["key"] = *tt.value
+			}
+			got, ok := uint32Annotation(logr.Discard(), annotations, "key")
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("uint32Annotation() = (%d, %v), want (%d, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDurationAnnotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  *string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "not present", value: nil, want: 0, wantOK: false},
+		{name: "zero", value: ptr("0s"), want: 0, wantOK: true},
+		{name: "milliseconds", value: ptr("500ms"), want: 500 * time.Millisecond, wantOK: true},
+		{name: "compound", value: ptr("1m30s"), want: 90 * time.Second, wantOK: true},
+		{name: "negative", value: ptr("-1s"), want: 0, wantOK: false},
+		{name: "missing unit", value: ptr("10"), want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.value != nil {
+				annotations["key"] = *tt.value
+			}
+			got, ok := durationAnnotation(logr.Discard(), annotations, "key")
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("durationAnnotation() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPositiveUint32Annotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  *string
+		want   uint32
+		wantOK bool
+	}{
+		{name: "not present", value: nil, want: 0, wantOK: false},
+		{name: "zero", value: ptr("0"), want: 0, wantOK: false},
+		{name: "positive", value: ptr("1"), want: 1, wantOK: true},
+		{name: "negative", value: ptr("-1"), want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.value != nil {
+				annotations["key"] = *tt.value
+			}
+			got, ok := positiveUint32Annotation(logr.Discard(), annotations, "key")
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("positiveUint32Annotation() = (%d, %v), want (%d, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
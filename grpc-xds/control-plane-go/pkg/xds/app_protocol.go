@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"strings"
+
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/go-logr/logr"
+)
+
+// Values of the `appProtocol` field of Kubernetes Service ports that select the protocol of a gRPC application.
+const (
+	AppProtocolGRPC  = "grpc"
+	AppProtocolHTTP  = "http"
+	AppProtocolHTTP2 = "http2"
+	AppProtocolTCP   = "tcp"
+
+	// appProtocolH2C is the Kubernetes standard application protocol for HTTP/2 over cleartext.
+	// [Reference]: https://kubernetes.io/docs/concepts/services-networking/service/#application-protocol
+	appProtocolH2C = "kubernetes.io/h2c"
+)
+
+var errUnsupportedAppProtocol = errors.New("unsupported appProtocol")
+
+// AppProtocolFromServicePort returns the application protocol from the `appProtocol` field of a
+// Service port or EndpointSlice port, or the empty string if the field is not set.
+// `kubernetes.io/h2c` is returned as `http2`. Unknown values are logged as warnings and ignored.
+//
+// `tcp` is rejected with an error log, and treated as not set. LDS API listeners only support
+// the HTTP connection manager, so the control plane cannot generate a TCP proxy filter chain.
+func AppProtocolFromServicePort(logger logr.Logger, appProtocol *string) string {
+	if appProtocol == nil {
+		return ""
+	}
+	protocol := strings.ToLower(strings.TrimSpace(*appProtocol))
+	switch protocol {
+	case AppProtocolGRPC, AppProtocolHTTP, AppProtocolHTTP2:
+		return protocol
+	case AppProtocolTCP:
+		logger.Error(errUnsupportedAppProtocol, "Rejecting appProtocol, LDS API listeners do not support TCP proxy filter chains, using the defaults", "appProtocol", *appProtocol)
+		return ""
+	case appProtocolH2C:
+		return AppProtocolHTTP2
+	default:
+		logger.V(1).Info("Warning: ignoring unknown appProtocol", "appProtocol", *appProtocol)
+		return ""
+	}
+}
+
+// httpCodecType returns the codec type of the HTTP connection manager for the application protocol.
+// Without an HTTP application protocol, the codec type is `AUTO`.
+func httpCodecType(appProtocol string) hcmv3.HttpConnectionManager_CodecType {
+	switch appProtocol {
+	case AppProtocolHTTP:
+		return hcmv3.HttpConnectionManager_HTTP1
+	case AppProtocolGRPC, AppProtocolHTTP2:
+		return hcmv3.HttpConnectionManager_HTTP2
+	default:
+		return hcmv3.HttpConnectionManager_AUTO
+	}
+}
+
+// upstreamProtocolForAppProtocol returns the upstream protocol of the cluster for the application protocol,
+// used if the Service has no upstream protocol annotation. Returns the empty string for the cluster defaults.
+func upstreamProtocolForAppProtocol(appProtocol string) string {
+	switch appProtocol {
+	case AppProtocolHTTP:
+		return UpstreamProtocolHTTP1
+	case AppProtocolGRPC, AppProtocolHTTP2:
+		return UpstreamProtocolHTTP2
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	upstreamhttpv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"github.com/go-logr/logr"
+)
+
+func TestAppProtocol(t *testing.T) {
+	tests := []struct {
+		appProtocol      *string
+		want             string
+		wantCodecType    hcmv3.HttpConnectionManager_CodecType
+		wantUpstreamHTTP string
+	}{
+		{
+			appProtocol:      ptr("grpc"),
+			want:             AppProtocolGRPC,
+			wantCodecType:    hcmv3.HttpConnectionManager_HTTP2,
+			wantUpstreamHTTP: UpstreamProtocolHTTP2,
+		},
+		{
+			appProtocol:      ptr("http"),
+			want:             AppProtocolHTTP,
+			wantCodecType:    hcmv3.HttpConnectionManager_HTTP1,
+			wantUpstreamHTTP: UpstreamProtocolHTTP1,
+		},
+		{
+			appProtocol:      ptr("http2"),
+			want:             AppProtocolHTTP2,
+			wantCodecType:    hcmv3.HttpConnectionManager_HTTP2,
+			wantUpstreamHTTP: UpstreamProtocolHTTP2,
+		},
+		{
+			appProtocol:      ptr("kubernetes.io/h2c"),
+			want:             AppProtocolHTTP2,
+			wantCodecType:    hcmv3.HttpConnectionManager_HTTP2,
+			wantUpstreamHTTP: UpstreamProtocolHTTP2,
+		},
+		{
+			appProtocol:      ptr(" GRPC "),
+			want:             AppProtocolGRPC,
+			wantCodecType:    hcmv3.HttpConnectionManager_HTTP2,
+			wantUpstreamHTTP: UpstreamProtocolHTTP2,
+		},
+		{
+			appProtocol:      ptr("tcp"),
+			want:             "",
+			wantCodecType:    hcmv3.HttpConnectionManager_AUTO,
+			wantUpstreamHTTP: "",
+		},
+		{
+			appProtocol:      ptr("unknown"),
+			want:             "",
+			wantCodecType:    hcmv3.HttpConnectionManager_AUTO,
+			wantUpstreamHTTP: "",
+		},
+		{
+			appProtocol:      nil,
+			want:             "",
+			wantCodecType:    hcmv3.HttpConnectionManager_AUTO,
+			wantUpstreamHTTP: "",
+		},
+	}
+	for _, tt := range tests {
+		name := "not set"
+		if tt.appProtocol != nil {
+			name = *tt.appProtocol
+		}
+		t.Run(name, func(t *testing.T) {
+			got := AppProtocolFromServicePort(logr.Discard(), tt.appProtocol)
+			if got != tt.want {
+				t.Fatalf("AppProtocolFromServicePort() = %q, want %q", got, tt.want)
+			}
+
+			listener, err := createAPIListener("greeter", "greeter", "greeter", got, nil, FaultInjection{}, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("createAPIListener() error = %v", err)
+			}
+			httpConnectionManager := &hcmv3.HttpConnectionManager{}
+			if err := listener.GetApiListener().GetApiListener().UnmarshalTo(httpConnectionManager); err != nil {
+				t.Fatalf("could not unmarshal HttpConnectionManager: %v", err)
+			}
+			if httpConnectionManager.GetCodecType() != tt.wantCodecType {
+				t.Errorf("HttpConnectionManager codec type = %v, want %v", httpConnectionManager.GetCodecType(), tt.wantCodecType)
+			}
+
+			cluster, err := createCluster("greeter", "greeter", "default", "", false, false, "")
+			if err != nil {
+				t.Fatalf("createCluster() error = %v", err)
+			}
+			app := NewGRPCApplication("default", "greeter", 50051, nil)
+			app.AppProtocol = got
+			if err := applyClusterOptions(cluster, app); err != nil {
+				t.Fatalf("applyClusterOptions() error = %v", err)
+			}
+			if upstreamHTTP := clusterUpstreamHTTPProtocol(t, cluster); upstreamHTTP != tt.wantUpstreamHTTP {
+				t.Errorf("cluster upstream protocol = %q, want %q", upstreamHTTP, tt.wantUpstreamHTTP)
+			}
+		})
+	}
+}
+
+// clusterUpstreamHTTPProtocol returns the explicit upstream HTTP protocol of the cluster,
+// or the empty string if the cluster has no HTTP protocol options.
+func clusterUpstreamHTTPProtocol(t *testing.T, cluster *clusterv3.Cluster) string {
+	t.Helper()
+	typedConfig, exists := cluster.GetTypedExtensionProtocolOptions()[envoyUpstreamsHTTPProtocolOptionsName]
+	if !exists {
+		return ""
+	}
+	httpProtocolOptions := &upstreamhttpv3.HttpProtocolOptions{}
+	if err := typedConfig.UnmarshalTo(httpProtocolOptions); err != nil {
+		t.Fatalf("could not unmarshal HttpProtocolOptions: %v", err)
+	}
+	explicitHTTPConfig := httpProtocolOptions.GetExplicitHttpConfig()
+	switch {
+	case explicitHTTPConfig.GetHttpProtocolOptions() != nil:
+		return UpstreamProtocolHTTP1
+	case explicitHTTPConfig.GetHttp2ProtocolOptions() != nil:
+		return UpstreamProtocolHTTP2
+	default:
+		t.Fatalf("unexpected HttpProtocolOptions: %+v", httpProtocolOptions)
+		return ""
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,277 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	rbacfilterv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	AuthorizationPolicyActionAllow = "ALLOW"
+	AuthorizationPolicyActionDeny  = "DENY"
+
+	// Multiple RBAC HTTP filters must have distinct names.
+	authorizationPoliciesDenyFilterName  = envoyFilterHTTPRBACName + ".authorization_policies.deny"
+	authorizationPoliciesAllowFilterName = envoyFilterHTTPRBACName + ".authorization_policies.allow"
+)
+
+// AuthorizationPolicy is the configuration from an `AuthorizationPolicy` custom resource.
+// It applies to the server listeners of all xDS-enabled gRPC servers.
+type AuthorizationPolicy struct {
+	Namespace string
+	Name      string
+	// Action is either `ALLOW` or `DENY`. If there are any `ALLOW` policies, requests that
+	// do not match any of their rules are denied. An `ALLOW` policy without rules denies all requests.
+	Action string
+	Rules  []AuthorizationRule
+}
+
+// AuthorizationRule matches requests that come from any of the principals, and that match
+// any of the methods and any of the path prefixes. Empty slices match everything.
+type AuthorizationRule struct {
+	// IPBlocks are CIDR ranges of the direct remote IP address of clients.
+	IPBlocks []string
+	// ServiceAccounts are SPIFFE IDs of clients, e.g., `spiffe://example.com/ns/default/sa/client`.
+	// Requires mTLS.
+	ServiceAccounts []string
+	// Methods are HTTP methods, e.g., `POST`. All gRPC requests use `POST`.
+	Methods []string
+	// PathPrefixes of requests, e.g., `/helloworld.Greeter/`.
+	PathPrefixes []string
+}
+
+func (p AuthorizationPolicy) Compare(q AuthorizationPolicy) int {
+	if p.Namespace != q.Namespace {
+		return strings.Compare(p.Namespace, q.Namespace)
+	}
+	if p.Name != q.Name {
+		return strings.Compare(p.Name, q.Name)
+	}
+	if p.Action != q.Action {
+		return strings.Compare(p.Action, q.Action)
+	}
+	return slices.CompareFunc(p.Rules, q.Rules, func(r AuthorizationRule, s AuthorizationRule) int {
+		return r.Compare(s)
+	})
+}
+
+func (r AuthorizationRule) Compare(s AuthorizationRule) int {
+	if c := slices.Compare(r.IPBlocks, s.IPBlocks); c != 0 {
+		return c
+	}
+	if c := slices.Compare(r.ServiceAccounts, s.ServiceAccounts); c != 0 {
+		return c
+	}
+	if c := slices.Compare(r.Methods, s.Methods); c != 0 {
+		return c
+	}
+	return slices.Compare(r.PathPrefixes, s.PathPrefixes)
+}
+
+// createAuthorizationPolicyFilters returns RBAC HTTP filters for the authorization policies.
+// The filter for `DENY` policies comes first, followed by the filter for `ALLOW` policies.
+// Returns an empty slice if there are no policies.
+// [gRFC A41]: https://github.com/grpc/proposal/blob/master/A41-xds-rbac.md
+func createAuthorizationPolicyFilters(policies []AuthorizationPolicy) ([]*hcmv3.HttpFilter, error) {
+	var filters []*hcmv3.HttpFilter
+	for _, action := range []string{AuthorizationPolicyActionDeny, AuthorizationPolicyActionAllow} {
+		rbac, found, err := createAuthorizationPolicyRBAC(policies, action)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		typedConfig, err := anypb.New(&rbacfilterv3.RBAC{Rules: rbac})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshall RBAC HTTP filter typedConfig for %s authorization policies into Any instance: %w", action, err)
+		}
+		filterName := authorizationPoliciesAllowFilterName
+		if action == AuthorizationPolicyActionDeny {
+			filterName = authorizationPoliciesDenyFilterName
+		}
+		filters = append(filters, &hcmv3.HttpFilter{
+			Name: filterName,
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{
+				TypedConfig: typedConfig,
+			},
+		})
+	}
+	return filters, nil
+}
+
+// createAuthorizationPolicyRBAC returns the RBAC rules for the policies with the provided action,
+// and false if there are no policies with that action.
+func createAuthorizationPolicyRBAC(policies []AuthorizationPolicy, action string) (*rbacv3.RBAC, bool, error) {
+	rbacAction := rbacv3.RBAC_ALLOW
+	if action == AuthorizationPolicyActionDeny {
+		rbacAction = rbacv3.RBAC_DENY
+	}
+	rbac := &rbacv3.RBAC{
+		Action:   rbacAction,
+		Policies: map[string]*rbacv3.Policy{},
+	}
+	found := false
+	for _, policy := range policies {
+		if policy.Action != action {
+			continue
+		}
+		found = true
+		for i, rule := range policy.Rules {
+			rbacPolicy, err := createRBACPolicy(rule)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid rule %d in AuthorizationPolicy %s/%s: %w", i, policy.Namespace, policy.Name, err)
+			}
+			rbac.Policies[fmt.Sprintf("%s/%s/%d", policy.Namespace, policy.Name, i)] = rbacPolicy
+		}
+	}
+	return rbac, found, nil
+}
+
+func createRBACPolicy(rule AuthorizationRule) (*rbacv3.Policy, error) {
+	var principals []*rbacv3.Principal
+	for _, ipBlock := range rule.IPBlocks {
+		cidrRange, err := createCIDRRange(ipBlock)
+		if err != nil {
+			return nil, err
+		}
+		principals = append(principals, &rbacv3.Principal{
+			Identifier: &rbacv3.Principal_DirectRemoteIp{
+				DirectRemoteIp: cidrRange,
+			},
+		})
+	}
+	for _, serviceAccount := range rule.ServiceAccounts {
+		principals = append(principals, &rbacv3.Principal{
+			Identifier: &rbacv3.Principal_Authenticated_{
+				Authenticated: &rbacv3.Principal_Authenticated{
+					PrincipalName: &matcherv3.StringMatcher{
+						MatchPattern: &matcherv3.StringMatcher_Exact{
+							Exact: serviceAccount,
+						},
+					},
+				},
+			},
+		})
+	}
+	principal := &rbacv3.Principal{
+		Identifier: &rbacv3.Principal_Any{
+			Any: true,
+		},
+	}
+	if len(principals) > 0 {
+		principal = &rbacv3.Principal{
+			Identifier: &rbacv3.Principal_OrIds{
+				OrIds: &rbacv3.Principal_Set{
+					Ids: principals,
+				},
+			},
+		}
+	}
+
+	var permissions []*rbacv3.Permission
+	if len(rule.Methods) > 0 {
+		methods := make([]*rbacv3.Permission, len(rule.Methods))
+		for i, method := range rule.Methods {
+			methods[i] = &rbacv3.Permission{
+				Rule: &rbacv3.Permission_Header{
+					Header: &routev3.HeaderMatcher{
+						Name: ":method",
+						HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+							StringMatch: &matcherv3.StringMatcher{
+								MatchPattern: &matcherv3.StringMatcher_Exact{
+									Exact: method,
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		permissions = append(permissions, orPermissions(methods))
+	}
+	if len(rule.PathPrefixes) > 0 {
+		pathPrefixes := make([]*rbacv3.Permission, len(rule.PathPrefixes))
+		for i, pathPrefix := range rule.PathPrefixes {
+			pathPrefixes[i] = &rbacv3.Permission{
+				Rule: &rbacv3.Permission_UrlPath{
+					UrlPath: &matcherv3.PathMatcher{
+						Rule: &matcherv3.PathMatcher_Path{
+							Path: &matcherv3.StringMatcher{
+								MatchPattern: &matcherv3.StringMatcher_Prefix{
+									Prefix: pathPrefix,
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		permissions = append(permissions, orPermissions(pathPrefixes))
+	}
+	permission := &rbacv3.Permission{
+		Rule: &rbacv3.Permission_Any{
+			Any: true,
+		},
+	}
+	if len(permissions) > 0 {
+		permission = &rbacv3.Permission{
+			Rule: &rbacv3.Permission_AndRules{
+				AndRules: &rbacv3.Permission_Set{
+					Rules: permissions,
+				},
+			},
+		}
+	}
+
+	return &rbacv3.Policy{
+		Permissions: []*rbacv3.Permission{permission},
+		Principals:  []*rbacv3.Principal{principal},
+	}, nil
+}
+
+func orPermissions(permissions []*rbacv3.Permission) *rbacv3.Permission {
+	return &rbacv3.Permission{
+		Rule: &rbacv3.Permission_OrRules{
+			OrRules: &rbacv3.Permission_Set{
+				Rules: permissions,
+			},
+		},
+	}
+}
+
+func createCIDRRange(ipBlock string) (*corev3.CidrRange, error) {
+	_, ipNet, err := net.ParseCIDR(ipBlock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR range %s: %w", ipBlock, err)
+	}
+	prefixLen, _ := ipNet.Mask.Size()
+	return &corev3.CidrRange{
+		AddressPrefix: ipNet.IP.String(),
+		PrefixLen:     wrapperspb.UInt32(uint32(prefixLen)),
+	}, nil
+}
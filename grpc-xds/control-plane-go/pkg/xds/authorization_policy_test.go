@@ -0,0 +1,171 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbacfilterv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCreateCIDRRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		ipBlock string
+		want    *corev3.CidrRange
+		wantErr bool
+	}{
+		{
+			name:    "IPv4",
+			ipBlock: "10.0.0.0/8",
+			want: &corev3.CidrRange{
+				AddressPrefix: "10.0.0.0",
+				PrefixLen:     wrapperspb.UInt32(8),
+			},
+		},
+		{
+			name:    "IPv4 host bits are masked",
+			ipBlock: "192.168.1.17/24",
+			want: &corev3.CidrRange{
+				AddressPrefix: "192.168.1.0",
+				PrefixLen:     wrapperspb.UInt32(24),
+			},
+		},
+		{
+			name:    "IPv6",
+			ipBlock: "2001:db8::/32",
+			want: &corev3.CidrRange{
+				AddressPrefix: "2001:db8::",
+				PrefixLen:     wrapperspb.UInt32(32),
+			},
+		},
+		{
+			name:    "address without prefix length",
+			ipBlock: "10.0.0.1",
+			wantErr: true,
+		},
+		{
+			name:    "not an address",
+			ipBlock: "example.com/8",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := createCIDRRange(tt.ipBlock)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createCIDRRange(%q) error = %v, wantErr %v", tt.ipBlock, err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("createCIDRRange(%q) = %v, want %v", tt.ipBlock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAuthorizationPolicyFilters(t *testing.T) {
+	allow := AuthorizationPolicy{
+		Namespace: "default",
+		Name:      "allow",
+		Action:    AuthorizationPolicyActionAllow,
+		Rules:     []AuthorizationRule{{Methods: []string{"POST"}}},
+	}
+	deny := AuthorizationPolicy{
+		Namespace: "default",
+		Name:      "deny",
+		Action:    AuthorizationPolicyActionDeny,
+		Rules:     []AuthorizationRule{{IPBlocks: []string{"10.0.0.0/8"}}},
+	}
+	tests := []struct {
+		name      string
+		policies  []AuthorizationPolicy
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name:     "no policies",
+			policies: nil,
+		},
+		{
+			name:      "allow only",
+			policies:  []AuthorizationPolicy{allow},
+			wantNames: []string{authorizationPoliciesAllowFilterName},
+		},
+		{
+			name:      "deny filter comes before allow filter",
+			policies:  []AuthorizationPolicy{allow, deny},
+			wantNames: []string{authorizationPoliciesDenyFilterName, authorizationPoliciesAllowFilterName},
+		},
+		{
+			name: "invalid IP block",
+			policies: []AuthorizationPolicy{{
+				Namespace: "default",
+				Name:      "invalid",
+				Action:    AuthorizationPolicyActionDeny,
+				Rules:     []AuthorizationRule{{IPBlocks: []string{"10.0.0.1"}}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := createAuthorizationPolicyFilters(tt.policies)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createAuthorizationPolicyFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantNames) {
+				t.Fatalf("createAuthorizationPolicyFilters() returned %d filters, want %d", len(got), len(tt.wantNames))
+			}
+			for i, filter := range got {
+				if filter.GetName() != tt.wantNames[i] {
+					t.Errorf("filter %d name = %s, want %s", i, filter.GetName(), tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateAuthorizationPolicyFiltersActions(t *testing.T) {
+	allow := AuthorizationPolicy{Namespace: "default", Name: "allow", Action: AuthorizationPolicyActionAllow}
+	deny := AuthorizationPolicy{
+		Namespace: "default",
+		Name:      "deny",
+		Action:    AuthorizationPolicyActionDeny,
+		Rules:     []AuthorizationRule{{}, {}},
+	}
+	filters, err := createAuthorizationPolicyFilters([]AuthorizationPolicy{allow, deny})
+	if err != nil {
+		t.Fatalf("createAuthorizationPolicyFilters() error = %v", err)
+	}
+	wantActions := []rbacv3.RBAC_Action{rbacv3.RBAC_DENY, rbacv3.RBAC_ALLOW}
+	wantPolicies := []int{2, 0}
+	for i, filter := range filters {
+		var rbac rbacfilterv3.RBAC
+		if err := filter.GetTypedConfig().UnmarshalTo(&rbac); err != nil {
+			t.Fatalf("filter %d typedConfig could not be unmarshalled: %v", i, err)
+		}
+		if rbac.GetRules().GetAction() != wantActions[i] {
+			t.Errorf("filter %d action = %v, want %v", i, rbac.GetRules().GetAction(), wantActions[i])
+		}
+		// An `ALLOW` policy without rules has no RBAC policies, so it denies all requests.
+		if len(rbac.GetRules().GetPolicies()) != wantPolicies[i] {
+			t.Errorf("filter %d has %d RBAC policies, want %d", i, len(rbac.GetRules().GetPolicies()), wantPolicies[i])
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// Benchmarks for building, setting, and serializing snapshots. Run them with:
+//
+//	go test -run '^$' -bench . -benchmem ./pkg/xds/
+
+var benchmarkSizes = []int{10, 100, 1000}
+
+// testGRPCApplications creates `numApps` gRPC applications with `numEndpoints` endpoints each.
+func testGRPCApplications(numApps int, numEndpoints int) []GRPCApplication {
+	apps := make([]GRPCApplication, numApps)
+	for i := range apps {
+		apps[i] = testGRPCApplication(fmt.Sprintf("app-%d", i), numEndpoints)
+	}
+	return apps
+}
+
+// BenchmarkBuildSnapshotClusters measures building snapshots with many CDS clusters, one per application.
+func BenchmarkBuildSnapshotClusters(b *testing.B) {
+	for _, numApps := range benchmarkSizes {
+		apps := testGRPCApplications(numApps, 1)
+		b.Run(fmt.Sprintf("clusters=%d", numApps), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildTestSnapshot(b, "1", apps)
+			}
+		})
+	}
+}
+
+// BenchmarkBuildSnapshotEndpoints measures building snapshots with many EDS endpoints in one cluster.
+func BenchmarkBuildSnapshotEndpoints(b *testing.B) {
+	for _, numEndpoints := range benchmarkSizes {
+		apps := testGRPCApplications(1, numEndpoints)
+		b.Run(fmt.Sprintf("endpoints=%d", numEndpoints), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildTestSnapshot(b, "1", apps)
+			}
+		})
+	}
+}
+
+// BenchmarkSetSnapshot measures setting a snapshot in the go-control-plane snapshot cache.
+// Each iteration sets a snapshot with a new version, as for a snapshot update.
+func BenchmarkSetSnapshot(b *testing.B) {
+	ctx := context.Background()
+	for _, numApps := range benchmarkSizes {
+		builder, err := NewSnapshotBuilder(testZone, FixedLocalityPriority{}, &Features{}, nil, "xds.example.com").
+			AddGRPCApplications(testGRPCApplications(numApps, 10))
+		if err != nil {
+			b.Fatalf("AddGRPCApplications(): %v", err)
+		}
+		b.Run(fmt.Sprintf("clusters=%d", numApps), func(b *testing.B) {
+			cache := cachev3.NewSnapshotCache(false, ZoneHash{}, nil)
+			snapshots := make([]*cachev3.Snapshot, 2)
+			for j := range snapshots {
+				version := fmt.Sprint(j)
+				snapshots[j], err = builder.Build(func(resource.Type, []types.Resource) string {
+					return version
+				})
+				if err != nil {
+					b.Fatalf("Build(): %v", err)
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := cache.SetSnapshot(ctx, testZone, snapshots[i%len(snapshots)]); err != nil {
+					b.Fatalf("SetSnapshot(): %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMarshalSnapshot measures serializing all resources of a snapshot to protobuf bytes.
+func BenchmarkMarshalSnapshot(b *testing.B) {
+	for _, numApps := range benchmarkSizes {
+		snapshot := buildTestSnapshot(b, "1", testGRPCApplications(numApps, 10))
+		b.Run(fmt.Sprintf("clusters=%d", numApps), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, resources := range snapshot.Resources {
+					for _, r := range resources.Items {
+						if _, err := proto.Marshal(r.Resource); err != nil {
+							b.Fatalf("proto.Marshal(): %v", err)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// CircuitBreakers configures the thresholds of the `DEFAULT` routing priority of a cluster.
+// Zero values are unset, so that xDS clients use their defaults.
+type CircuitBreakers struct {
+	MaxConnections     uint32
+	MaxPendingRequests uint32
+	MaxRequests        uint32
+	MaxRetries         uint32
+}
+
+// CircuitBreakersFromAnnotations reads the circuit breaker thresholds from Service annotations.
+// Annotations with values that are not positive integers are logged as warnings and ignored.
+func CircuitBreakersFromAnnotations(logger logr.Logger, annotations map[string]string) CircuitBreakers {
+	var circuitBreakers CircuitBreakers
+	if maxConnections, ok := positiveUint32Annotation(logger, annotations, cbMaxConnectionsAnnotation); ok {
+		circuitBreakers.MaxConnections = maxConnections
+	}
+	if maxPendingRequests, ok := positiveUint32Annotation(logger, annotations, cbMaxPendingRequestsAnnotation); ok {
+		circuitBreakers.MaxPendingRequests = maxPendingRequests
+	}
+	if maxRequests, ok := positiveUint32Annotation(logger, annotations, cbMaxRequestsAnnotation); ok {
+		circuitBreakers.MaxRequests = maxRequests
+	}
+	if maxRetries, ok := positiveUint32Annotation(logger, annotations, cbMaxRetriesAnnotation); ok {
+		circuitBreakers.MaxRetries = maxRetries
+	}
+	return circuitBreakers
+}
+
+func (c CircuitBreakers) Compare(d CircuitBreakers) int {
+	if c.MaxConnections != d.MaxConnections {
+		return cmp.Compare(c.MaxConnections, d.MaxConnections)
+	}
+	if c.MaxPendingRequests != d.MaxPendingRequests {
+		return cmp.Compare(c.MaxPendingRequests, d.MaxPendingRequests)
+	}
+	if c.MaxRequests != d.MaxRequests {
+		return cmp.Compare(c.MaxRequests, d.MaxRequests)
+	}
+	return cmp.Compare(c.MaxRetries, d.MaxRetries)
+}
+
+// createCircuitBreakers returns nil if no thresholds are set.
+// gRPC clients only support `max_requests`, the other thresholds apply to Envoy proxies.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/circuit_breaker.proto
+func createCircuitBreakers(c CircuitBreakers) *clusterv3.CircuitBreakers {
+	if c == (CircuitBreakers{}) {
+		return nil
+	}
+	thresholds := &clusterv3.CircuitBreakers_Thresholds{
+		Priority: corev3.RoutingPriority_DEFAULT,
+	}
+	if c.MaxConnections > 0 {
+		thresholds.MaxConnections = wrapperspb.UInt32(c.MaxConnections)
+	}
+	if c.MaxPendingRequests > 0 {
+		thresholds.MaxPendingRequests = wrapperspb.UInt32(c.MaxPendingRequests)
+	}
+	if c.MaxRequests > 0 {
+		thresholds.MaxRequests = wrapperspb.UInt32(c.MaxRequests)
+	}
+	if c.MaxRetries > 0 {
+		thresholds.MaxRetries = wrapperspb.UInt32(c.MaxRetries)
+	}
+	return &clusterv3.CircuitBreakers{
+		Thresholds: []*clusterv3.CircuitBreakers_Thresholds{thresholds},
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCircuitBreakersFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        CircuitBreakers
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        CircuitBreakers{},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				cbMaxConnectionsAnnotation:     "100",
+				cbMaxPendingRequestsAnnotation: "200",
+				cbMaxRequestsAnnotation:        "300",
+				cbMaxRetriesAnnotation:         "3",
+			},
+			want: CircuitBreakers{
+				MaxConnections:     100,
+				MaxPendingRequests: 200,
+				MaxRequests:        300,
+				MaxRetries:         3,
+			},
+		},
+		{
+			name: "zero and invalid values are ignored",
+			annotations: map[string]string{
+				cbMaxConnectionsAnnotation:     "0",
+				cbMaxPendingRequestsAnnotation: "many",
+				cbMaxRequestsAnnotation:        "10",
+			},
+			want: CircuitBreakers{MaxRequests: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CircuitBreakersFromAnnotations(logr.Discard(), tt.annotations); got != tt.want {
+				t.Errorf("CircuitBreakersFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateCircuitBreakers(t *testing.T) {
+	tests := []struct {
+		name            string
+		circuitBreakers CircuitBreakers
+		want            *clusterv3.CircuitBreakers
+	}{
+		{
+			name:            "unset",
+			circuitBreakers: CircuitBreakers{},
+			want:            nil,
+		},
+		{
+			name:            "max requests only",
+			circuitBreakers: CircuitBreakers{MaxRequests: 10},
+			want: &clusterv3.CircuitBreakers{
+				Thresholds: []*clusterv3.CircuitBreakers_Thresholds{{
+					Priority:    corev3.RoutingPriority_DEFAULT,
+					MaxRequests: wrapperspb.UInt32(10),
+				}},
+			},
+		},
+		{
+			name: "all thresholds",
+			circuitBreakers: CircuitBreakers{
+				MaxConnections:     100,
+				MaxPendingRequests: 200,
+				MaxRequests:        300,
+				MaxRetries:         3,
+			},
+			want: &clusterv3.CircuitBreakers{
+				Thresholds: []*clusterv3.CircuitBreakers_Thresholds{{
+					Priority:           corev3.RoutingPriority_DEFAULT,
+					MaxConnections:     wrapperspb.UInt32(100),
+					MaxPendingRequests: wrapperspb.UInt32(200),
+					MaxRequests:        wrapperspb.UInt32(300),
+					MaxRetries:         wrapperspb.UInt32(3),
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createCircuitBreakers(tt.circuitBreakers); !proto.Equal(got, tt.want) {
+				t.Errorf("createCircuitBreakers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSnapshotCacheCircuitBreakers pushes gRPC applications through the snapshot cache,
+// and reads the circuit breaker thresholds back from the Clusters in the snapshot.
+func TestSnapshotCacheCircuitBreakers(t *testing.T) {
+	c, _ := newTestSnapshotCache(t)
+	tests := []struct {
+		name            string
+		circuitBreakers CircuitBreakers
+		want            *clusterv3.CircuitBreakers
+	}{
+		{
+			name:            "thresholds are set",
+			circuitBreakers: CircuitBreakers{MaxRequests: 300, MaxRetries: 3},
+			want: &clusterv3.CircuitBreakers{
+				Thresholds: []*clusterv3.CircuitBreakers_Thresholds{{
+					Priority:    corev3.RoutingPriority_DEFAULT,
+					MaxRequests: wrapperspb.UInt32(300),
+					MaxRetries:  wrapperspb.UInt32(3),
+				}},
+			},
+		},
+		{
+			name:            "thresholds are updated",
+			circuitBreakers: CircuitBreakers{MaxConnections: 100},
+			want: &clusterv3.CircuitBreakers{
+				Thresholds: []*clusterv3.CircuitBreakers_Thresholds{{
+					Priority:       corev3.RoutingPriority_DEFAULT,
+					MaxConnections: wrapperspb.UInt32(100),
+				}},
+			},
+		},
+		{
+			name:            "thresholds are removed",
+			circuitBreakers: CircuitBreakers{},
+			want:            nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := testGRPCApplication("greeter", 1)
+			app.CircuitBreakers = tt.circuitBreakers
+			if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", []GRPCApplication{app}); err != nil {
+				t.Fatalf("UpdateResources(): %v", err)
+			}
+			snapshot, err := c.GetSnapshot(testZone)
+			if err != nil {
+				t.Fatalf("GetSnapshot(): %v", err)
+			}
+			clusters := snapshot.GetResources(resource.ClusterType)
+			cluster, ok := clusters["greeter"].(*clusterv3.Cluster)
+			if !ok {
+				t.Fatalf("snapshot has no Cluster greeter, clusters = %v", clusters)
+			}
+			if !proto.Equal(cluster.GetCircuitBreakers(), tt.want) {
+				t.Errorf("CircuitBreakers = %v, want %v", cluster.GetCircuitBreakers(), tt.want)
+			}
+		})
+	}
+}
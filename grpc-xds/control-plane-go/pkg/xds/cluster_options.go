@@ -0,0 +1,34 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+)
+
+// applyClusterOptions configures the CDS Cluster using the optional configuration of the gRPC application,
+// e.g., from Service annotations.
+func applyClusterOptions(cluster *clusterv3.Cluster, app GRPCApplication) error {
+	cluster.OutlierDetection = createOutlierDetection(app.OutlierDetection)
+	cluster.CircuitBreakers = createCircuitBreakers(app.CircuitBreakers)
+	applyLBPolicy(cluster, app.LBPolicy)
+	applyConnectionOptions(cluster, app.ConnectionOptions)
+	cluster.HealthChecks = createHealthChecks(app.HealthCheck)
+	upstreamProtocol := app.UpstreamProtocol
+	if upstreamProtocol == "" {
+		upstreamProtocol = upstreamProtocolForAppProtocol(app.AppProtocol)
+	}
+	return applyUpstreamProtocol(cluster, upstreamProtocol, app.HTTP2ProtocolOptions)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"math"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ConnectionOptions configures the connections from xDS clients to the endpoints of a cluster.
+// Zero values are unset, so that the cluster uses the defaults.
+type ConnectionOptions struct {
+	ConnectTimeout    time.Duration
+	KeepaliveTime     time.Duration
+	KeepaliveInterval time.Duration
+	KeepaliveProbes   uint32
+	// KeepaliveDisabled is true if one of the keepalive annotations has the value `0`.
+	// The cluster then has no TCP keepalive configuration, even if other keepalive annotations are present.
+	KeepaliveDisabled bool
+}
+
+// ConnectionOptionsFromAnnotations reads the connection options from Service annotations.
+// Annotations with invalid values are logged as warnings and ignored.
+func ConnectionOptionsFromAnnotations(logger logr.Logger, annotations map[string]string) ConnectionOptions {
+	var connectionOptions ConnectionOptions
+	if connectTimeout, ok := durationAnnotation(logger, annotations, connectTimeoutAnnotation); ok {
+		if connectTimeout > 0 {
+			connectionOptions.ConnectTimeout = connectTimeout
+		} else {
+			logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a positive duration", "annotation", connectTimeoutAnnotation, "value", annotations[connectTimeoutAnnotation])
+		}
+	}
+	if keepaliveTime, ok := durationAnnotation(logger, annotations, keepaliveTimeAnnotation); ok {
+		connectionOptions.KeepaliveTime = keepaliveTime
+		connectionOptions.KeepaliveDisabled = connectionOptions.KeepaliveDisabled || keepaliveTime == 0
+	}
+	if keepaliveInterval, ok := durationAnnotation(logger, annotations, keepaliveIntervalAnnotation); ok {
+		connectionOptions.KeepaliveInterval = keepaliveInterval
+		connectionOptions.KeepaliveDisabled = connectionOptions.KeepaliveDisabled || keepaliveInterval == 0
+	}
+	if keepaliveProbes, ok := uint32Annotation(logger, annotations, keepaliveProbesAnnotation); ok {
+		connectionOptions.KeepaliveProbes = keepaliveProbes
+		connectionOptions.KeepaliveDisabled = connectionOptions.KeepaliveDisabled || keepaliveProbes == 0
+	}
+	return connectionOptions
+}
+
+func (o ConnectionOptions) Compare(p ConnectionOptions) int {
+	if o.ConnectTimeout != p.ConnectTimeout {
+		return cmp.Compare(o.ConnectTimeout, p.ConnectTimeout)
+	}
+	if o.KeepaliveTime != p.KeepaliveTime {
+		return cmp.Compare(o.KeepaliveTime, p.KeepaliveTime)
+	}
+	if o.KeepaliveInterval != p.KeepaliveInterval {
+		return cmp.Compare(o.KeepaliveInterval, p.KeepaliveInterval)
+	}
+	if o.KeepaliveProbes != p.KeepaliveProbes {
+		return cmp.Compare(o.KeepaliveProbes, p.KeepaliveProbes)
+	}
+	if o.KeepaliveDisabled == p.KeepaliveDisabled {
+		return 0
+	}
+	if !o.KeepaliveDisabled {
+		return -1
+	}
+	return 1
+}
+
+// applyConnectionOptions sets the connect timeout and the TCP keepalive configuration of the cluster.
+// The cluster keeps its default connect timeout if the timeout is not set.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/core/v3/address.proto#config-core-v3-tcpkeepalive
+func applyConnectionOptions(cluster *clusterv3.Cluster, o ConnectionOptions) {
+	if o.ConnectTimeout > 0 {
+		cluster.ConnectTimeout = durationpb.New(o.ConnectTimeout)
+	}
+	cluster.UpstreamConnectionOptions = createUpstreamConnectionOptions(o)
+}
+
+// createUpstreamConnectionOptions returns nil if keepalive is disabled, or if no keepalive fields are set.
+func createUpstreamConnectionOptions(o ConnectionOptions) *clusterv3.UpstreamConnectionOptions {
+	if o.KeepaliveDisabled || (o.KeepaliveTime == 0 && o.KeepaliveInterval == 0 && o.KeepaliveProbes == 0) {
+		return nil
+	}
+	tcpKeepalive := &corev3.TcpKeepalive{}
+	if o.KeepaliveTime > 0 {
+		tcpKeepalive.KeepaliveTime = wrapperspb.UInt32(durationSeconds(o.KeepaliveTime))
+	}
+	if o.KeepaliveInterval > 0 {
+		tcpKeepalive.KeepaliveInterval = wrapperspb.UInt32(durationSeconds(o.KeepaliveInterval))
+	}
+	if o.KeepaliveProbes > 0 {
+		tcpKeepalive.KeepaliveProbes = wrapperspb.UInt32(o.KeepaliveProbes)
+	}
+	return &clusterv3.UpstreamConnectionOptions{
+		TcpKeepalive: tcpKeepalive,
+	}
+}
+
+// durationSeconds converts the duration to whole seconds, rounding up,
+// as TCP keepalive durations have a resolution of seconds.
+func durationSeconds(d time.Duration) uint32 {
+	seconds := d / time.Second
+	if d%time.Second != 0 {
+		seconds++
+	}
+	if seconds > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(seconds)
+}
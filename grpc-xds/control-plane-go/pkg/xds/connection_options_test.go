@@ -0,0 +1,154 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestConnectionOptionsFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        ConnectionOptions
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        ConnectionOptions{},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				connectTimeoutAnnotation:    "2s",
+				keepaliveTimeAnnotation:     "30s",
+				keepaliveIntervalAnnotation: "5s",
+				keepaliveProbesAnnotation:   "3",
+			},
+			want: ConnectionOptions{
+				ConnectTimeout:    2 * time.Second,
+				KeepaliveTime:     30 * time.Second,
+				KeepaliveInterval: 5 * time.Second,
+				KeepaliveProbes:   3,
+			},
+		},
+		{
+			name: "zero keepalive disables keepalive",
+			annotations: map[string]string{
+				keepaliveTimeAnnotation:   "30s",
+				keepaliveProbesAnnotation: "0",
+			},
+			want: ConnectionOptions{
+				KeepaliveTime:     30 * time.Second,
+				KeepaliveDisabled: true,
+			},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				connectTimeoutAnnotation:    "0s",
+				keepaliveTimeAnnotation:     "-1s",
+				keepaliveIntervalAnnotation: "often",
+			},
+			want: ConnectionOptions{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConnectionOptionsFromAnnotations(logr.Discard(), tt.annotations); got != tt.want {
+				t.Errorf("ConnectionOptionsFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyConnectionOptions(t *testing.T) {
+	defaultConnectTimeout := durationpb.New(5 * time.Second)
+	tests := []struct {
+		name              string
+		connectionOptions ConnectionOptions
+		want              *clusterv3.Cluster
+	}{
+		{
+			name:              "unset",
+			connectionOptions: ConnectionOptions{},
+			want:              &clusterv3.Cluster{ConnectTimeout: defaultConnectTimeout},
+		},
+		{
+			name: "connect timeout and keepalive",
+			connectionOptions: ConnectionOptions{
+				ConnectTimeout:    time.Second,
+				KeepaliveTime:     1500 * time.Millisecond,
+				KeepaliveProbes:   3,
+				KeepaliveInterval: 0,
+			},
+			want: &clusterv3.Cluster{
+				ConnectTimeout: durationpb.New(time.Second),
+				UpstreamConnectionOptions: &clusterv3.UpstreamConnectionOptions{
+					TcpKeepalive: &corev3.TcpKeepalive{
+						KeepaliveTime:   wrapperspb.UInt32(2),
+						KeepaliveProbes: wrapperspb.UInt32(3),
+					},
+				},
+			},
+		},
+		{
+			name: "keepalive disabled",
+			connectionOptions: ConnectionOptions{
+				KeepaliveTime:     30 * time.Second,
+				KeepaliveDisabled: true,
+			},
+			want: &clusterv3.Cluster{ConnectTimeout: defaultConnectTimeout},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &clusterv3.Cluster{ConnectTimeout: defaultConnectTimeout}
+			applyConnectionOptions(cluster, tt.connectionOptions)
+			if !proto.Equal(cluster, tt.want) {
+				t.Errorf("applyConnectionOptions() cluster = %v, want %v", cluster, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationSeconds(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     uint32
+	}{
+		{duration: 0, want: 0},
+		{duration: time.Millisecond, want: 1},
+		{duration: time.Second, want: 1},
+		{duration: 1001 * time.Millisecond, want: 2},
+		{duration: time.Duration(math.MaxInt64), want: math.MaxUint32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.duration.String(), func(t *testing.T) {
+			if got := durationSeconds(tt.duration); got != tt.want {
+				t.Errorf("durationSeconds(%v) = %d, want %d", tt.duration, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// dryRunNodeHash is the node hash of the snapshots created in dry-run mode.
+// It has no zone and no scope, so the snapshots contain resources from all namespaces.
+const dryRunNodeHash = ""
+
+// dryRunResourceTypes are the resource types written in dry-run mode, in output order.
+// SDS resources are not written, as they contain private keys.
+var dryRunResourceTypes = []resource.Type{
+	resource.ListenerType,
+	resource.RouteType,
+	resource.ClusterType,
+	resource.EndpointType,
+}
+
+// dryRunWriter writes snapshots as JSON Lines, with one xDS resource per line.
+type dryRunWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// dryRunResource is the JSON representation of one xDS resource in dry-run mode.
+type dryRunResource struct {
+	NodeHash string          `json:"nodeHash"`
+	TypeURL  string          `json:"typeUrl"`
+	Version  string          `json:"version"`
+	Name     string          `json:"name"`
+	Resource json.RawMessage `json:"resource"`
+}
+
+// EnableDryRun makes the cache write new snapshots to `w` instead of storing them in the delegate cache,
+// so xDS clients never receive them. Snapshots are created for a node hash without zone and scope,
+// as there are no xDS clients in dry-run mode.
+//
+// Call this method before starting informers that update the cache.
+func (c *SnapshotCache) EnableDryRun(w io.Writer) {
+	c.dryRun = &dryRunWriter{w: w}
+}
+
+// WriteSnapshot creates a snapshot from the current gRPC application configuration for the
+// provided node hash, and writes it to `w` in the dry-run format, without storing it in the cache.
+func (c *SnapshotCache) WriteSnapshot(w io.Writer, nodeHash string) error {
+	snapshot, _, err := c.buildSnapshot(nodeHash, c.appsCache.GetAll(), nil)
+	if err != nil {
+		return err
+	}
+	return (&dryRunWriter{w: w}).write(nodeHash, snapshot)
+}
+
+// nodeHashes returns the node hashes that receive new snapshots.
+func (c *SnapshotCache) nodeHashes() []string {
+	if c.dryRun != nil {
+		return []string{dryRunNodeHash}
+	}
+	return c.delegate.GetStatusKeys()
+}
+
+func (c *SnapshotCache) writeDryRunSnapshot(nodeHash string, snapshot cachev3.ResourceSnapshot) error {
+	return c.dryRun.write(nodeHash, snapshot)
+}
+
+func (d *dryRunWriter) write(nodeHash string, snapshot cachev3.ResourceSnapshot) error {
+	marshalOptions := protojson.MarshalOptions{
+		UseProtoNames: true,
+	}
+	var lines []byte
+	for _, typeURL := range dryRunResourceTypes {
+		resources := snapshot.GetResources(typeURL)
+		names := make([]string, 0, len(resources))
+		for name := range resources {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			resourceJSONBytes, err := marshalOptions.Marshal(resources[name])
+			if err != nil {
+				return fmt.Errorf("could not marshal resource type=%s name=%s to JSON: %w", typeURL, name, err)
+			}
+			line, err := json.Marshal(dryRunResource{
+				NodeHash: nodeHash,
+				TypeURL:  typeURL,
+				Version:  snapshot.GetVersion(typeURL),
+				Name:     name,
+				Resource: resourceJSONBytes,
+			})
+			if err != nil {
+				return fmt.Errorf("could not marshal dry-run output for resource type=%s name=%s: %w", typeURL, name, err)
+			}
+			lines = append(append(lines, line...), '\n')
+		}
+	}
+	// Write all resources of the snapshot at once, so that output from concurrent updates is not interleaved.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.w.Write(lines); err != nil {
+		return fmt.Errorf("could not write dry-run snapshot for nodeHash=%s: %w", nodeHash, err)
+	}
+	return nil
+}
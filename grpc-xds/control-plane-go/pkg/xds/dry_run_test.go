@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func TestDryRunWriterWrite(t *testing.T) {
+	snapshot := buildTestSnapshot(t, "1", []GRPCApplication{
+		testGRPCApplication("b", 1),
+		testGRPCApplication("a", 1),
+	})
+	var buf bytes.Buffer
+	if err := (&dryRunWriter{w: &buf}).write("us-central1-a", snapshot); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	var lines []dryRunResource
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line dryRunResource
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("could not unmarshal line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		t.Fatal("write() wrote no lines")
+	}
+
+	typeOrder := func(typeURL string) int {
+		return slices.Index(dryRunResourceTypes, typeURL)
+	}
+	for i, line := range lines {
+		if line.NodeHash != "us-central1-a" || line.Version != "1" {
+			t.Errorf("line %d nodeHash=%q version=%q, want nodeHash=%q version=%q", i, line.NodeHash, line.Version, "us-central1-a", "1")
+		}
+		if typeOrder(line.TypeURL) < 0 {
+			t.Errorf("line %d has unexpected typeUrl=%s", i, line.TypeURL)
+		}
+		if len(line.Resource) == 0 {
+			t.Errorf("line %d typeUrl=%s name=%s has no resource", i, line.TypeURL, line.Name)
+		}
+		if i == 0 {
+			continue
+		}
+		previous := lines[i-1]
+		if typeOrder(previous.TypeURL) > typeOrder(line.TypeURL) ||
+			(previous.TypeURL == line.TypeURL && previous.Name >= line.Name) {
+			t.Errorf("line %d typeUrl=%s name=%s is out of order after typeUrl=%s name=%s", i, line.TypeURL, line.Name, previous.TypeURL, previous.Name)
+		}
+	}
+	if lines[0].TypeURL != resource.ListenerType {
+		t.Errorf("first line typeUrl=%s, want %s", lines[0].TypeURL, resource.ListenerType)
+	}
+}
@@ -15,7 +15,7 @@
 package xds
 
 // EndpointAddress represents a socket ipAddress,
-// with an IP address (e.g., "0.0.0.0" or "[::]"), and a port.
+// with an IP address (e.g., "0.0.0.0" or "::", without square brackets), and a port.
 type EndpointAddress struct {
 	Host string
 	Port uint32
@@ -28,12 +28,20 @@ const (
 	Draining
 )
 
+// EndpointStatusFromConditions maps EndpointSlice endpoint conditions to a serving status.
+// The `ready` condition reflects the `Ready` condition of the Pod, and a nil value is interpreted as ready.
+// A nil `serving` condition is interpreted as the value of `ready`.
+// [Reference]: https://kubernetes.io/docs/concepts/services-networking/endpoint-slices/#conditions
 func EndpointStatusFromConditions(c discoveryv1.EndpointConditions) EndpointStatus {
 	if c.Terminating != nil && *c.Terminating {
 		return Draining
 	}
-	if c.Ready != nil && *c.Ready && c.Serving != nil && *c.Serving {
-		// https://kubernetes.io/docs/concepts/services-networking/endpoint-slices/#ready
+	ready := c.Ready == nil || *c.Ready
+	serving := ready
+	if c.Serving != nil {
+		serving = *c.Serving
+	}
+	if ready && serving {
 		return Healthy
 	}
 	return Unhealthy
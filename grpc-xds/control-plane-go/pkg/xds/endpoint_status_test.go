@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+func TestEndpointStatusFromConditions(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name       string
+		conditions discoveryv1.EndpointConditions
+		want       EndpointStatus
+		wantHealth corev3.HealthStatus
+	}{
+		{
+			name:       "no conditions",
+			conditions: discoveryv1.EndpointConditions{},
+			want:       Healthy,
+			wantHealth: corev3.HealthStatus_HEALTHY,
+		},
+		{
+			name:       "ready",
+			conditions: discoveryv1.EndpointConditions{Ready: &yes},
+			want:       Healthy,
+			wantHealth: corev3.HealthStatus_HEALTHY,
+		},
+		{
+			name:       "ready and serving",
+			conditions: discoveryv1.EndpointConditions{Ready: &yes, Serving: &yes},
+			want:       Healthy,
+			wantHealth: corev3.HealthStatus_HEALTHY,
+		},
+		{
+			name:       "not ready",
+			conditions: discoveryv1.EndpointConditions{Ready: &no},
+			want:       Unhealthy,
+			wantHealth: corev3.HealthStatus_UNHEALTHY,
+		},
+		{
+			name:       "ready but not serving",
+			conditions: discoveryv1.EndpointConditions{Ready: &yes, Serving: &no},
+			want:       Unhealthy,
+			wantHealth: corev3.HealthStatus_UNHEALTHY,
+		},
+		{
+			name:       "not ready but serving",
+			conditions: discoveryv1.EndpointConditions{Ready: &no, Serving: &yes},
+			want:       Unhealthy,
+			wantHealth: corev3.HealthStatus_UNHEALTHY,
+		},
+		{
+			name:       "terminating",
+			conditions: discoveryv1.EndpointConditions{Ready: &no, Serving: &yes, Terminating: &yes},
+			want:       Draining,
+			wantHealth: corev3.HealthStatus_DRAINING,
+		},
+		{
+			name:       "not terminating",
+			conditions: discoveryv1.EndpointConditions{Ready: &yes, Terminating: &no},
+			want:       Healthy,
+			wantHealth: corev3.HealthStatus_HEALTHY,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EndpointStatusFromConditions(tt.conditions)
+			if got != tt.want {
+				t.Errorf("EndpointStatusFromConditions() = %v, want %v", got, tt.want)
+			}
+			if health := got.HealthStatus(); health != tt.wantHealth {
+				t.Errorf("HealthStatus() = %v, want %v", health, tt.wantHealth)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	envoyFilterHTTPExtAuthzName = "envoy.filters.http.ext_authz"
+
+	// DefaultExtAuthzTimeout is the default timeout of calls to the external authorization service,
+	// the same as the Envoy default.
+	DefaultExtAuthzTimeout = 200 * time.Millisecond
+)
+
+// ExtAuthzPolicy is the configuration from an `ExtAuthzPolicy` custom resource.
+// It adds an `ext_authz` HTTP filter to the LDS API listeners of the gRPC applications
+// in the same namespace with Service labels that match the selector.
+type ExtAuthzPolicy struct {
+	Namespace string
+	Name      string
+	// ClusterName is the name of the cluster of the external authorization service.
+	ClusterName string
+	// Authority is optional. It is the `:authority` header of calls to the external authorization service,
+	// and defaults to the cluster name.
+	Authority string
+	// Timeout of calls to the external authorization service.
+	Timeout time.Duration
+	// FailureModeAllow allows requests if the external authorization service is unavailable.
+	FailureModeAllow bool
+	// MatchLabels selects the gRPC applications by the labels of their Services.
+	// An empty selector matches all gRPC applications in the namespace.
+	MatchLabels map[string]string
+}
+
+func (p ExtAuthzPolicy) Compare(q ExtAuthzPolicy) int {
+	if p.Namespace != q.Namespace {
+		return strings.Compare(p.Namespace, q.Namespace)
+	}
+	if p.Name != q.Name {
+		return strings.Compare(p.Name, q.Name)
+	}
+	if p.ClusterName != q.ClusterName {
+		return strings.Compare(p.ClusterName, q.ClusterName)
+	}
+	if p.Authority != q.Authority {
+		return strings.Compare(p.Authority, q.Authority)
+	}
+	if p.Timeout != q.Timeout {
+		return cmp.Compare(p.Timeout, q.Timeout)
+	}
+	if p.FailureModeAllow != q.FailureModeAllow {
+		if p.FailureModeAllow {
+			return 1
+		}
+		return -1
+	}
+	return compareLabels(p.MatchLabels, q.MatchLabels)
+}
+
+// Matches returns true if the gRPC application is in the namespace of the policy,
+// and the labels of its Service match the selector of the policy.
+func (p ExtAuthzPolicy) Matches(app GRPCApplication) bool {
+	if p.Namespace != app.Namespace {
+		return false
+	}
+	for key, value := range p.MatchLabels {
+		if labelValue, exists := app.Labels[key]; !exists || labelValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// createExtAuthzFilters returns the `ext_authz` HTTP filters for the API listener of the gRPC application,
+// one per matching policy, in the order of the policies.
+func createExtAuthzFilters(policies []ExtAuthzPolicy, app GRPCApplication) ([]*hcmv3.HttpFilter, error) {
+	var filters []*hcmv3.HttpFilter
+	for _, policy := range policies {
+		if !policy.Matches(app) {
+			continue
+		}
+		typedConfig, err := anypb.New(&extauthzv3.ExtAuthz{
+			Services: &extauthzv3.ExtAuthz_GrpcService{
+				GrpcService: &corev3.GrpcService{
+					TargetSpecifier: &corev3.GrpcService_EnvoyGrpc_{
+						EnvoyGrpc: &corev3.GrpcService_EnvoyGrpc{
+							ClusterName: policy.ClusterName,
+							Authority:   policy.Authority,
+						},
+					},
+					Timeout: durationpb.New(policy.Timeout),
+				},
+			},
+			FailureModeAllow:    policy.FailureModeAllow,
+			TransportApiVersion: corev3.ApiVersion_V3,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshall ExtAuthz HTTP filter typedConfig for ExtAuthzPolicy %s/%s into Any instance: %w", policy.Namespace, policy.Name, err)
+		}
+		filters = append(filters, &hcmv3.HttpFilter{
+			// Multiple ext_authz HTTP filters must have distinct names.
+			Name: fmt.Sprintf("%s.%s.%s", envoyFilterHTTPExtAuthzName, policy.Namespace, policy.Name),
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{
+				TypedConfig: typedConfig,
+			},
+		})
+	}
+	return filters, nil
+}
+
+// compareLabels compares label maps by their sorted keys and values.
+func compareLabels(a map[string]string, b map[string]string) int {
+	return slices.Compare(sortedLabels(a), sortedLabels(b))
+}
+
+func sortedLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return pairs
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+)
+
+func TestExtAuthzPolicyMatches(t *testing.T) {
+	app := GRPCApplication{Namespace: "default", Labels: map[string]string{"team": "a", "tier": "backend"}}
+	tests := []struct {
+		name   string
+		policy ExtAuthzPolicy
+		want   bool
+	}{
+		{name: "empty selector", policy: ExtAuthzPolicy{Namespace: "default"}, want: true},
+		{name: "matching labels", policy: ExtAuthzPolicy{Namespace: "default", MatchLabels: map[string]string{"team": "a"}}, want: true},
+		{name: "different label value", policy: ExtAuthzPolicy{Namespace: "default", MatchLabels: map[string]string{"team": "b"}}, want: false},
+		{name: "missing label", policy: ExtAuthzPolicy{Namespace: "default", MatchLabels: map[string]string{"env": "prod"}}, want: false},
+		{name: "other namespace", policy: ExtAuthzPolicy{Namespace: "other"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Matches(app); got != tt.want {
+				t.Errorf("Matches() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateExtAuthzFilters(t *testing.T) {
+	app := GRPCApplication{Namespace: "default", Labels: map[string]string{"team": "a"}}
+	policies := []ExtAuthzPolicy{
+		{Namespace: "default", Name: "first", ClusterName: "authz", Timeout: time.Second, FailureModeAllow: true},
+		{Namespace: "default", Name: "other-team", ClusterName: "authz", MatchLabels: map[string]string{"team": "b"}},
+		{Namespace: "default", Name: "second", ClusterName: "authz-2", Authority: "authz.example.com", Timeout: DefaultExtAuthzTimeout},
+	}
+	filters, err := createExtAuthzFilters(policies, app)
+	if err != nil {
+		t.Fatalf("createExtAuthzFilters() error = %v", err)
+	}
+	wantNames := []string{
+		envoyFilterHTTPExtAuthzName + ".default.first",
+		envoyFilterHTTPExtAuthzName + ".default.second",
+	}
+	if len(filters) != len(wantNames) {
+		t.Fatalf("createExtAuthzFilters() returned %d filters, want %d", len(filters), len(wantNames))
+	}
+	for i, filter := range filters {
+		if filter.GetName() != wantNames[i] {
+			t.Errorf("filter %d name = %s, want %s", i, filter.GetName(), wantNames[i])
+		}
+	}
+	var extAuthz extauthzv3.ExtAuthz
+	if err := filters[1].GetTypedConfig().UnmarshalTo(&extAuthz); err != nil {
+		t.Fatalf("could not unmarshal ExtAuthz: %v", err)
+	}
+	envoyGRPC := extAuthz.GetGrpcService().GetEnvoyGrpc()
+	if envoyGRPC.GetClusterName() != "authz-2" || envoyGRPC.GetAuthority() != "authz.example.com" {
+		t.Errorf("envoyGrpc = %v, want clusterName=authz-2 authority=authz.example.com", envoyGRPC)
+	}
+	if got := extAuthz.GetGrpcService().GetTimeout().AsDuration(); got != DefaultExtAuthzTimeout {
+		t.Errorf("timeout = %v, want %v", got, DefaultExtAuthzTimeout)
+	}
+	if extAuthz.GetFailureModeAllow() {
+		t.Error("failureModeAllow = true, want false")
+	}
+}
+
+func TestCompareLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		a    map[string]string
+		b    map[string]string
+		want int
+	}{
+		{name: "both empty", a: nil, b: map[string]string{}, want: 0},
+		{name: "same labels", a: map[string]string{"a": "1", "b": "2"}, b: map[string]string{"b": "2", "a": "1"}, want: 0},
+		{name: "different value", a: map[string]string{"a": "1"}, b: map[string]string{"a": "2"}, want: -1},
+		{name: "fewer labels", a: map[string]string{"a": "1"}, b: map[string]string{"a": "1", "b": "2"}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareLabels(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareLabels() = %d, want %d", got, tt.want)
+			}
+			if got := compareLabels(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareLabels() with swapped arguments = %d, want %d", got, -tt.want)
+			}
+		})
+	}
+}
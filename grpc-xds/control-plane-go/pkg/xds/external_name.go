@@ -0,0 +1,165 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// externalNameTLSPort is the port that enables upstream TLS for ExternalName Services without the annotation.
+	externalNameTLSPort = 443
+	// systemCACertificatesPath is the CA bundle used to validate the certificates of external upstreams.
+	systemCACertificatesPath = "/etc/ssl/certs/ca-certificates.crt"
+)
+
+// NewExternalNameGRPCApplication creates a GRPCApplication for a Kubernetes Service of type `ExternalName`.
+// The CDS Cluster resolves the external name directly, instead of using EDS, so the application has no endpoints.
+func NewExternalNameGRPCApplication(namespace string, name string, externalName string, port uint32) GRPCApplication {
+	app := NewGRPCApplication(namespace, name, port, nil)
+	app.EDSServiceName = ""
+	app.ExternalName = externalName
+	return app
+}
+
+// UpstreamTLSFromAnnotations returns true if connections to the upstream of an ExternalName Service use TLS,
+// either because the port is 443, or because the upstream TLS annotation is `true`.
+// An invalid annotation value is logged and ignored.
+func UpstreamTLSFromAnnotations(logger logr.Logger, annotations map[string]string, port uint32) bool {
+	value, exists := annotations[upstreamTLSAnnotation]
+	if !exists {
+		return port == externalNameTLSPort
+	}
+	upstreamTLS, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected true or false", "annotation", upstreamTLSAnnotation, "value", value, "error", err.Error())
+		return port == externalNameTLSPort
+	}
+	return upstreamTLS || port == externalNameTLSPort
+}
+
+// createExternalNameCluster creates a CDS Cluster for an ExternalName Service, with the external
+// name and port as the only endpoint, in an inline load assignment.
+// The cluster type is `STATIC` if the external name is an IP address, and `STRICT_DNS` otherwise.
+// gRPC clients do not support these cluster types outside of aggregate clusters, see gRFC A37,
+// so these clusters are intended for Envoy proxies.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/upstream/service_discovery
+func createExternalNameCluster(name string, externalName string, port uint32, upstreamTLS bool) (*clusterv3.Cluster, error) {
+	host := socketAddressHost(externalName)
+	_, err := netip.ParseAddr(host)
+	isIPAddress := err == nil
+	discoveryType := clusterv3.Cluster_STRICT_DNS
+	if isIPAddress {
+		discoveryType = clusterv3.Cluster_STATIC
+	}
+	cluster := clusterv3.Cluster{
+		Name: name,
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: discoveryType,
+		},
+		LoadAssignment: &endpointv3.ClusterLoadAssignment{
+			ClusterName: name,
+			Endpoints: []*endpointv3.LocalityLbEndpoints{
+				{
+					LbEndpoints: []*endpointv3.LbEndpoint{
+						{
+							HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+								Endpoint: &endpointv3.Endpoint{
+									Address: &corev3.Address{
+										Address: &corev3.Address_SocketAddress{
+											SocketAddress: &corev3.SocketAddress{
+												Protocol: corev3.SocketAddress_TCP,
+												Address:  host,
+												PortSpecifier: &corev3.SocketAddress_PortValue{
+													PortValue: port,
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		ConnectTimeout: &durationpb.Duration{
+			Seconds: 3, // default is 5s
+		},
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+	}
+
+	if upstreamTLS {
+		upstreamTLSContext := createExternalNameUpstreamTLSContext(host, isIPAddress)
+		anyWrappedUpstreamTLSContext, err := anypb.New(upstreamTLSContext)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshall UpstreamTlsContext +%v into Any instance: %w", upstreamTLSContext, err)
+		}
+		cluster.TransportSocket = &corev3.TransportSocket{
+			Name: envoyTransportSocketsTLSName,
+			ConfigType: &corev3.TransportSocket_TypedConfig{
+				TypedConfig: anyWrappedUpstreamTLSContext,
+			},
+		}
+	}
+
+	return &cluster, nil
+}
+
+// createExternalNameUpstreamTLSContext validates the certificates of the external upstream using the
+// system CA certificates, instead of the mesh certificate provider, as the upstream is not part of the mesh.
+// For DNS names, the upstream TLS context sets SNI, and the certificate must have the name as a DNS SAN.
+func createExternalNameUpstreamTLSContext(host string, isIPAddress bool) *tlsv3.UpstreamTlsContext {
+	validationContext := &tlsv3.CertificateValidationContext{
+		TrustedCa: &corev3.DataSource{
+			Specifier: &corev3.DataSource_Filename{
+				Filename: systemCACertificatesPath,
+			},
+		},
+	}
+	upstreamTLSContext := &tlsv3.UpstreamTlsContext{
+		CommonTlsContext: &tlsv3.CommonTlsContext{
+			ValidationContextType: &tlsv3.CommonTlsContext_ValidationContext{
+				ValidationContext: validationContext,
+			},
+		},
+	}
+	if !isIPAddress {
+		upstreamTLSContext.Sni = host
+		validationContext.MatchTypedSubjectAltNames = []*tlsv3.SubjectAltNameMatcher{
+			{
+				SanType: tlsv3.SubjectAltNameMatcher_DNS,
+				Matcher: &matcherv3.StringMatcher{
+					MatchPattern: &matcherv3.StringMatcher_Exact{
+						Exact: host,
+					},
+				},
+			},
+		}
+	}
+	return upstreamTLSContext
+}
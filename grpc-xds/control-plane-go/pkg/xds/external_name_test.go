@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/go-logr/logr"
+)
+
+func TestUpstreamTLSFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		port        uint32
+		want        bool
+	}{
+		{name: "no annotation", port: 80, want: false},
+		{name: "no annotation on TLS port", port: externalNameTLSPort, want: true},
+		{name: "annotation true", annotations: map[string]string{upstreamTLSAnnotation: "true"}, port: 8443, want: true},
+		{name: "annotation false on TLS port", annotations: map[string]string{upstreamTLSAnnotation: "false"}, port: externalNameTLSPort, want: true},
+		{name: "invalid annotation", annotations: map[string]string{upstreamTLSAnnotation: "yes please"}, port: 80, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UpstreamTLSFromAnnotations(logr.Discard(), tt.annotations, tt.port); got != tt.want {
+				t.Errorf("UpstreamTLSFromAnnotations() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateExternalNameCluster(t *testing.T) {
+	tests := []struct {
+		name         string
+		externalName string
+		upstreamTLS  bool
+		wantType     clusterv3.Cluster_DiscoveryType
+		wantAddress  string
+		wantSNI      string
+	}{
+		{
+			name:         "DNS name",
+			externalName: "api.example.com",
+			wantType:     clusterv3.Cluster_STRICT_DNS,
+			wantAddress:  "api.example.com",
+		},
+		{
+			name:         "DNS name with TLS",
+			externalName: "api.example.com",
+			upstreamTLS:  true,
+			wantType:     clusterv3.Cluster_STRICT_DNS,
+			wantAddress:  "api.example.com",
+			wantSNI:      "api.example.com",
+		},
+		{
+			name:         "IPv4 address with TLS",
+			externalName: "192.0.2.1",
+			upstreamTLS:  true,
+			wantType:     clusterv3.Cluster_STATIC,
+			wantAddress:  "192.0.2.1",
+		},
+		{
+			name:         "bracketed IPv6 address",
+			externalName: "[2001:DB8::1]",
+			wantType:     clusterv3.Cluster_STATIC,
+			wantAddress:  "2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := createExternalNameCluster("cluster", tt.externalName, 8443, tt.upstreamTLS)
+			if err != nil {
+				t.Fatalf("createExternalNameCluster() error = %v", err)
+			}
+			if cluster.GetType() != tt.wantType {
+				t.Errorf("cluster type = %v, want %v", cluster.GetType(), tt.wantType)
+			}
+			socketAddress := cluster.GetLoadAssignment().GetEndpoints()[0].GetLbEndpoints()[0].GetEndpoint().GetAddress().GetSocketAddress()
+			if socketAddress.GetAddress() != tt.wantAddress || socketAddress.GetPortValue() != 8443 {
+				t.Errorf("socket address = %s:%d, want %s:8443", socketAddress.GetAddress(), socketAddress.GetPortValue(), tt.wantAddress)
+			}
+			if !tt.upstreamTLS {
+				if cluster.GetTransportSocket() != nil {
+					t.Errorf("transport socket = %v, want none", cluster.GetTransportSocket())
+				}
+				return
+			}
+			var upstreamTLSContext tlsv3.UpstreamTlsContext
+			if err := cluster.GetTransportSocket().GetTypedConfig().UnmarshalTo(&upstreamTLSContext); err != nil {
+				t.Fatalf("could not unmarshal UpstreamTlsContext: %v", err)
+			}
+			if upstreamTLSContext.GetSni() != tt.wantSNI {
+				t.Errorf("SNI = %q, want %q", upstreamTLSContext.GetSni(), tt.wantSNI)
+			}
+			validationContext := upstreamTLSContext.GetCommonTlsContext().GetValidationContext()
+			if validationContext.GetTrustedCa().GetFilename() != systemCACertificatesPath {
+				t.Errorf("trusted CA = %v, want %s", validationContext.GetTrustedCa(), systemCACertificatesPath)
+			}
+			if wantSANs := len(tt.wantSNI) != 0; (len(validationContext.GetMatchTypedSubjectAltNames()) != 0) != wantSANs {
+				t.Errorf("SAN matchers = %v, want present=%t", validationContext.GetMatchTypedSubjectAltNames(), wantSANs)
+			}
+		})
+	}
+}
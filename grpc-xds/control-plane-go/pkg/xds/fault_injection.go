@@ -0,0 +1,144 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"time"
+
+	faultcommonv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	faultv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	maxFaultPercent = 100
+	// Abort codes in this range are gRPC status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+	minFaultAbortGRPCStatus = 1
+	maxFaultAbortGRPCStatus = 16
+	// Abort codes in this range are HTTP status codes.
+	minFaultAbortHTTPStatus = 200
+	maxFaultAbortHTTPStatus = 599
+)
+
+// FaultInjection configures delays and aborts for a percentage of the requests from xDS clients
+// to a gRPC application. Zero percentages disable the delay or abort.
+type FaultInjection struct {
+	DelayPercent uint32
+	Delay        time.Duration
+	AbortPercent uint32
+	// AbortCode is a gRPC status code if it is between 1 and 16, and an HTTP status code otherwise.
+	AbortCode uint32
+}
+
+// FaultInjectionFromAnnotations reads the fault injection configuration from Service annotations.
+// A delay requires both the percentage and the duration, and an abort requires both the percentage
+// and the code. Annotations with invalid values, or without the other annotation, are logged as
+// warnings and ignored.
+func FaultInjectionFromAnnotations(logger logr.Logger, annotations map[string]string) FaultInjection {
+	var faultInjection FaultInjection
+	delayPercent, hasDelayPercent := faultPercentAnnotation(logger, annotations, faultDelayPercentAnnotation)
+	delayMillis, hasDelayMillis := uint32Annotation(logger, annotations, faultDelayMillisAnnotation)
+	if hasDelayPercent && hasDelayMillis {
+		faultInjection.DelayPercent = delayPercent
+		faultInjection.Delay = time.Duration(delayMillis) * time.Millisecond
+	} else if hasDelayPercent || hasDelayMillis {
+		logger.V(1).Info("Warning: ignoring fault delay annotations, both are required", "annotations", []string{faultDelayPercentAnnotation, faultDelayMillisAnnotation})
+	}
+	abortPercent, hasAbortPercent := faultPercentAnnotation(logger, annotations, faultAbortPercentAnnotation)
+	abortCode, hasAbortCode := faultAbortCodeFromAnnotations(logger, annotations)
+	if hasAbortPercent && hasAbortCode {
+		faultInjection.AbortPercent = abortPercent
+		faultInjection.AbortCode = abortCode
+	} else if hasAbortPercent || hasAbortCode {
+		logger.V(1).Info("Warning: ignoring fault abort annotations, both are required", "annotations", []string{faultAbortPercentAnnotation, faultAbortCodeAnnotation})
+	}
+	return faultInjection
+}
+
+func faultPercentAnnotation(logger logr.Logger, annotations map[string]string, key string) (uint32, bool) {
+	percent, ok := uint32Annotation(logger, annotations, key)
+	if ok && percent > maxFaultPercent {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a percentage between 0 and 100", "annotation", key, "value", annotations[key])
+		return 0, false
+	}
+	return percent, ok
+}
+
+func faultAbortCodeFromAnnotations(logger logr.Logger, annotations map[string]string) (uint32, bool) {
+	code, ok := uint32Annotation(logger, annotations, faultAbortCodeAnnotation)
+	if ok && !isGRPCStatusAbortCode(code) && (code < minFaultAbortHTTPStatus || code > maxFaultAbortHTTPStatus) {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a gRPC status code between 1 and 16, or an HTTP status code between 200 and 599", "annotation", faultAbortCodeAnnotation, "value", annotations[faultAbortCodeAnnotation])
+		return 0, false
+	}
+	return code, ok
+}
+
+func isGRPCStatusAbortCode(code uint32) bool {
+	return code >= minFaultAbortGRPCStatus && code <= maxFaultAbortGRPCStatus
+}
+
+func (f FaultInjection) Compare(g FaultInjection) int {
+	if f.DelayPercent != g.DelayPercent {
+		return cmp.Compare(f.DelayPercent, g.DelayPercent)
+	}
+	if f.Delay != g.Delay {
+		return cmp.Compare(f.Delay, g.Delay)
+	}
+	if f.AbortPercent != g.AbortPercent {
+		return cmp.Compare(f.AbortPercent, g.AbortPercent)
+	}
+	return cmp.Compare(f.AbortCode, g.AbortCode)
+}
+
+// createHTTPFault returns the config of the fault injection HTTP filter. The config is empty if
+// fault injection is not enabled, so that it can be enabled per route using `typed_per_filter_config`.
+// [gRFC A33]: https://github.com/grpc/proposal/blob/master/A33-Fault-Injection.md
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/http/fault/v3/fault.proto
+func createHTTPFault(f FaultInjection) *faultv3.HTTPFault {
+	httpFault := &faultv3.HTTPFault{}
+	if f.DelayPercent > 0 {
+		httpFault.Delay = &faultcommonv3.FaultDelay{
+			FaultDelaySecifier: &faultcommonv3.FaultDelay_FixedDelay{
+				FixedDelay: durationpb.New(f.Delay),
+			},
+			Percentage: createFaultPercent(f.DelayPercent),
+		}
+	}
+	if f.AbortPercent > 0 {
+		httpFault.Abort = &faultv3.FaultAbort{
+			Percentage: createFaultPercent(f.AbortPercent),
+		}
+		if isGRPCStatusAbortCode(f.AbortCode) {
+			httpFault.Abort.ErrorType = &faultv3.FaultAbort_GrpcStatus{
+				GrpcStatus: f.AbortCode,
+			}
+		} else {
+			httpFault.Abort.ErrorType = &faultv3.FaultAbort_HttpStatus{
+				HttpStatus: f.AbortCode,
+			}
+		}
+	}
+	return httpFault
+}
+
+func createFaultPercent(percent uint32) *typev3.FractionalPercent {
+	return &typev3.FractionalPercent{
+		Numerator:   percent,
+		Denominator: typev3.FractionalPercent_HUNDRED,
+	}
+}
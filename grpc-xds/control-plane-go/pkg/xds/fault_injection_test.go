@@ -0,0 +1,154 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	faultcommonv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	faultv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestFaultInjectionFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        FaultInjection
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        FaultInjection{},
+		},
+		{
+			name: "delay and gRPC status abort",
+			annotations: map[string]string{
+				faultDelayPercentAnnotation: "10",
+				faultDelayMillisAnnotation:  "250",
+				faultAbortPercentAnnotation: "5",
+				faultAbortCodeAnnotation:    "14",
+			},
+			want: FaultInjection{
+				DelayPercent: 10,
+				Delay:        250 * time.Millisecond,
+				AbortPercent: 5,
+				AbortCode:    14,
+			},
+		},
+		{
+			name: "HTTP status abort",
+			annotations: map[string]string{
+				faultAbortPercentAnnotation: "100",
+				faultAbortCodeAnnotation:    "503",
+			},
+			want: FaultInjection{AbortPercent: 100, AbortCode: 503},
+		},
+		{
+			name: "delay without duration is ignored",
+			annotations: map[string]string{
+				faultDelayPercentAnnotation: "10",
+			},
+			want: FaultInjection{},
+		},
+		{
+			name: "abort without percentage is ignored",
+			annotations: map[string]string{
+				faultAbortCodeAnnotation: "14",
+			},
+			want: FaultInjection{},
+		},
+		{
+			name: "percentage above 100 is ignored",
+			annotations: map[string]string{
+				faultDelayPercentAnnotation: "101",
+				faultDelayMillisAnnotation:  "250",
+			},
+			want: FaultInjection{},
+		},
+		{
+			name: "abort code outside the gRPC and HTTP status ranges is ignored",
+			annotations: map[string]string{
+				faultAbortPercentAnnotation: "5",
+				faultAbortCodeAnnotation:    "100",
+			},
+			want: FaultInjection{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FaultInjectionFromAnnotations(logr.Discard(), tt.annotations); got != tt.want {
+				t.Errorf("FaultInjectionFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateHTTPFault(t *testing.T) {
+	tests := []struct {
+		name           string
+		faultInjection FaultInjection
+		want           *faultv3.HTTPFault
+	}{
+		{
+			name:           "disabled",
+			faultInjection: FaultInjection{},
+			want:           &faultv3.HTTPFault{},
+		},
+		{
+			name:           "delay",
+			faultInjection: FaultInjection{DelayPercent: 10, Delay: 250 * time.Millisecond},
+			want: &faultv3.HTTPFault{
+				Delay: &faultcommonv3.FaultDelay{
+					FaultDelaySecifier: &faultcommonv3.FaultDelay_FixedDelay{
+						FixedDelay: durationpb.New(250 * time.Millisecond),
+					},
+					Percentage: &typev3.FractionalPercent{Numerator: 10, Denominator: typev3.FractionalPercent_HUNDRED},
+				},
+			},
+		},
+		{
+			name:           "gRPC status abort",
+			faultInjection: FaultInjection{AbortPercent: 5, AbortCode: 14},
+			want: &faultv3.HTTPFault{
+				Abort: &faultv3.FaultAbort{
+					ErrorType:  &faultv3.FaultAbort_GrpcStatus{GrpcStatus: 14},
+					Percentage: &typev3.FractionalPercent{Numerator: 5, Denominator: typev3.FractionalPercent_HUNDRED},
+				},
+			},
+		},
+		{
+			name:           "HTTP status abort",
+			faultInjection: FaultInjection{AbortPercent: 5, AbortCode: 503},
+			want: &faultv3.HTTPFault{
+				Abort: &faultv3.FaultAbort{
+					ErrorType:  &faultv3.FaultAbort_HttpStatus{HttpStatus: 503},
+					Percentage: &typev3.FractionalPercent{Numerator: 5, Denominator: typev3.FractionalPercent_HUNDRED},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createHTTPFault(tt.faultInjection); !proto.Equal(got, tt.want) {
+				t.Errorf("createHTTPFault() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
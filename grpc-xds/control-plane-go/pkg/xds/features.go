@@ -22,4 +22,8 @@ type Features struct {
 	EnableDataPlaneTLS             bool `yaml:"enableDataPlaneTls"`
 	RequireDataPlaneClientCerts    bool `yaml:"requireDataPlaneClientCerts"`
 	EnableFederation               bool `yaml:"enableFederation"`
+	// DataPlaneTLSSecret is the name of a Kubernetes TLS Secret, `<namespace>/<name>`, that provides the
+	// certificates in data plane TLS contexts via SDS, instead of certificate provider instances.
+	// For Envoy proxies, as gRPC xDS clients do not support SDS.
+	DataPlaneTLSSecret string `yaml:"dataPlaneTlsSecret"`
 }
@@ -29,6 +29,42 @@ type GRPCApplication struct {
 	PathPrefix             string
 	Port                   uint32
 	Endpoints              []GRPCApplicationEndpoints
+	// TrafficSplit is optional. If present, the route sends traffic to the listed clusters by weight,
+	// instead of to `ClusterName`.
+	TrafficSplit []ClusterWeight
+	// OutlierDetection is optional. Zero values use the xDS client defaults.
+	OutlierDetection OutlierDetection
+	// CircuitBreakers is optional. Zero values use the xDS client defaults.
+	CircuitBreakers CircuitBreakers
+	// RetryPolicy is optional. If RetryOn is empty, requests are not retried.
+	RetryPolicy RetryPolicy
+	// GRPCTimeouts is optional. Nil values do not limit the deadlines of requests.
+	GRPCTimeouts GRPCTimeouts
+	// LBPolicy is optional. If Policy is empty, the cluster uses `ROUND_ROBIN`.
+	LBPolicy LBPolicy
+	// ConnectionOptions is optional. Zero values use the default connect timeout, and no TCP keepalive.
+	ConnectionOptions ConnectionOptions
+	// HealthCheck is optional. If Protocol is empty, the cluster has no active health checks.
+	HealthCheck HealthCheck
+	// FaultInjection is optional. Zero values do not inject faults.
+	FaultInjection FaultInjection
+	// HTTP2ProtocolOptions is optional. Zero values use the HTTP/2 defaults of the xDS client for upstream connections.
+	HTTP2ProtocolOptions HTTP2ProtocolOptions
+	// GRPCServiceConfig is an optional gRPC service config JSON document, in compact form.
+	GRPCServiceConfig string
+	// UpstreamProtocol is optional. If empty, the cluster uses HTTP/2 if HTTP2ProtocolOptions are set,
+	// and the xDS client default otherwise.
+	UpstreamProtocol string
+	// AppProtocol is the optional `appProtocol` of the Service port, see `AppProtocolFromServicePort()`.
+	// It selects the codec of the LDS API listener, and the upstream protocol if UpstreamProtocol is empty.
+	AppProtocol string
+	// Labels are the labels of the Kubernetes Service, used to select applications for `ExtAuthzPolicy` resources.
+	Labels map[string]string
+	// ExternalName is the DNS name or IP address of a Kubernetes Service of type `ExternalName`.
+	// If set, the CDS Cluster resolves this name instead of using EDS, and EDSServiceName is empty.
+	ExternalName string
+	// UpstreamTLS enables TLS for connections to the ExternalName, validated using the system CA certificates.
+	UpstreamTLS bool
 }
 
 // NewGRPCApplication is a convenience function that creates a GRPCApplication where the
@@ -79,6 +115,60 @@ func (a GRPCApplication) Compare(b GRPCApplication) int {
 	if a.Port != b.Port {
 		return int(a.Port - b.Port)
 	}
+	if c := slices.CompareFunc(a.TrafficSplit, b.TrafficSplit,
+		func(v ClusterWeight, w ClusterWeight) int {
+			return v.Compare(w)
+		}); c != 0 {
+		return c
+	}
+	if c := a.OutlierDetection.Compare(b.OutlierDetection); c != 0 {
+		return c
+	}
+	if c := a.CircuitBreakers.Compare(b.CircuitBreakers); c != 0 {
+		return c
+	}
+	if c := a.RetryPolicy.Compare(b.RetryPolicy); c != 0 {
+		return c
+	}
+	if c := a.GRPCTimeouts.Compare(b.GRPCTimeouts); c != 0 {
+		return c
+	}
+	if c := a.LBPolicy.Compare(b.LBPolicy); c != 0 {
+		return c
+	}
+	if c := a.ConnectionOptions.Compare(b.ConnectionOptions); c != 0 {
+		return c
+	}
+	if c := a.HealthCheck.Compare(b.HealthCheck); c != 0 {
+		return c
+	}
+	if c := a.FaultInjection.Compare(b.FaultInjection); c != 0 {
+		return c
+	}
+	if c := a.HTTP2ProtocolOptions.Compare(b.HTTP2ProtocolOptions); c != 0 {
+		return c
+	}
+	if a.GRPCServiceConfig != b.GRPCServiceConfig {
+		return strings.Compare(a.GRPCServiceConfig, b.GRPCServiceConfig)
+	}
+	if a.UpstreamProtocol != b.UpstreamProtocol {
+		return strings.Compare(a.UpstreamProtocol, b.UpstreamProtocol)
+	}
+	if a.AppProtocol != b.AppProtocol {
+		return strings.Compare(a.AppProtocol, b.AppProtocol)
+	}
+	if c := compareLabels(a.Labels, b.Labels); c != 0 {
+		return c
+	}
+	if a.ExternalName != b.ExternalName {
+		return strings.Compare(a.ExternalName, b.ExternalName)
+	}
+	if a.UpstreamTLS != b.UpstreamTLS {
+		if a.UpstreamTLS {
+			return 1
+		}
+		return -1
+	}
 	return slices.CompareFunc(a.Endpoints, b.Endpoints,
 		func(e GRPCApplicationEndpoints, f GRPCApplicationEndpoints) int {
 			return e.Compare(f)
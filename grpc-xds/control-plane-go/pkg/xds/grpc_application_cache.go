@@ -57,6 +57,16 @@ func (c *GRPCApplicationCache) Get(kubecontextName string, namespace string) []G
 	return c.cache[key(kubecontextName, namespace)]
 }
 
+// Find returns the application with the provided cluster name, and false if it is not in the cache.
+func (c *GRPCApplicationCache) Find(kubecontextName string, namespace string, clusterName string) (GRPCApplication, bool) {
+	for _, app := range c.Get(kubecontextName, namespace) {
+		if app.ClusterName == clusterName {
+			return app, true
+		}
+	}
+	return GRPCApplication{}, false
+}
+
 func (c *GRPCApplicationCache) GetAll() []GRPCApplication {
 	apps := []GRPCApplication{}
 	c.mu.RLock()
@@ -0,0 +1,204 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCRoute is the configuration from a `GRPCRoute` custom resource.
+// It adds routes with header matchers to the RDS RouteConfiguration of a gRPC application.
+type GRPCRoute struct {
+	Namespace string
+	Name      string
+	// Service is the name of the Kubernetes Service, and of the RouteConfiguration, that the routes are added to.
+	Service string
+	Rules   []GRPCRouteRule
+}
+
+// GRPCRouteRule sends requests that match all the header matchers to the cluster.
+type GRPCRouteRule struct {
+	// HeaderMatchers are sorted, see `Compare()`.
+	HeaderMatchers []HeaderMatcher
+	ClusterName    string
+}
+
+// HeaderMatcher matches a request header by exact value, or by prefix.
+// If both Exact and Prefix are empty, the header must be present.
+type HeaderMatcher struct {
+	Name   string
+	Exact  string
+	Prefix string
+}
+
+func (r GRPCRoute) Compare(s GRPCRoute) int {
+	if r.Namespace != s.Namespace {
+		return strings.Compare(r.Namespace, s.Namespace)
+	}
+	if r.Name != s.Name {
+		return strings.Compare(r.Name, s.Name)
+	}
+	if r.Service != s.Service {
+		return strings.Compare(r.Service, s.Service)
+	}
+	return slices.CompareFunc(r.Rules, s.Rules, func(a GRPCRouteRule, b GRPCRouteRule) int {
+		return a.Compare(b)
+	})
+}
+
+func (r GRPCRouteRule) Compare(s GRPCRouteRule) int {
+	if c := slices.CompareFunc(r.HeaderMatchers, s.HeaderMatchers, func(a HeaderMatcher, b HeaderMatcher) int {
+		return a.Compare(b)
+	}); c != 0 {
+		return c
+	}
+	return strings.Compare(r.ClusterName, s.ClusterName)
+}
+
+func (m HeaderMatcher) Compare(n HeaderMatcher) int {
+	if m.Name != n.Name {
+		return strings.Compare(m.Name, n.Name)
+	}
+	if m.Exact != n.Exact {
+		return strings.Compare(m.Exact, n.Exact)
+	}
+	return strings.Compare(m.Prefix, n.Prefix)
+}
+
+// grpcRouteEntry is a rule with the position of its route, used to order the routes in a virtual host.
+type grpcRouteEntry struct {
+	route GRPCRoute
+	rule  GRPCRouteRule
+	index int
+}
+
+// addGRPCRoutes adds routes with header matchers for the rules of the GRPCRoutes to the
+// RouteConfigurations named after their Services, before the catch-all route. Routes with more
+// header matchers come first, as they are more specific, and then routes in the order of the
+// GRPCRoutes and their rules. Rules for clusters that are not in the snapshot are skipped,
+// so that xDS clients never receive routes to unknown clusters.
+//
+// The routes copy the route action of the catch-all route, e.g., the retry policy, except for the cluster.
+func (b *SnapshotBuilder) addGRPCRoutes() {
+	entriesByService := map[string][]grpcRouteEntry{}
+	for _, route := range b.grpcRoutes {
+		for i, rule := range route.Rules {
+			entriesByService[route.Service] = append(entriesByService[route.Service], grpcRouteEntry{route: route, rule: rule, index: i})
+		}
+	}
+	for service, entries := range entriesByService {
+		slices.SortStableFunc(entries, func(e grpcRouteEntry, f grpcRouteEntry) int {
+			if c := cmp.Compare(len(f.rule.HeaderMatchers), len(e.rule.HeaderMatchers)); c != 0 {
+				return c
+			}
+			if c := e.route.Compare(f.route); c != 0 {
+				return c
+			}
+			return cmp.Compare(e.index, f.index)
+		})
+		b.addHeaderRoutes(service, entries, func(clusterName string) string { return clusterName })
+		if b.features.EnableFederation {
+			b.addHeaderRoutes(xdstpRouteConfiguration(b.authority, service), entries, func(clusterName string) string {
+				return xdstpCluster(b.authority, clusterName)
+			})
+		}
+	}
+}
+
+func (b *SnapshotBuilder) addHeaderRoutes(routeConfigurationName string, entries []grpcRouteEntry, clusterName func(string) string) {
+	routeConfiguration, ok := b.routeConfigurations[routeConfigurationName].(*routev3.RouteConfiguration)
+	if !ok {
+		return
+	}
+	for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+		routes := virtualHost.GetRoutes()
+		if len(routes) == 0 {
+			continue
+		}
+		catchAllRoute := routes[len(routes)-1]
+		var headerRoutes []*routev3.Route
+		for _, entry := range entries {
+			name := clusterName(entry.rule.ClusterName)
+			if b.clusters[name] == nil {
+				continue
+			}
+			headerRoute := createHeaderRoute(catchAllRoute, entry.rule.HeaderMatchers, name)
+			if headerRoute != nil {
+				headerRoutes = append(headerRoutes, headerRoute)
+			}
+		}
+		virtualHost.Routes = append(headerRoutes, routes...)
+	}
+}
+
+// createHeaderRoute returns a copy of the catch-all route with the header matchers and the cluster,
+// or nil if the catch-all route does not have a route action.
+// [gRFC A28]: https://github.com/grpc/proposal/blob/master/A28-xds-traffic-splitting-and-routing.md
+func createHeaderRoute(catchAllRoute *routev3.Route, headerMatchers []HeaderMatcher, clusterName string) *routev3.Route {
+	if catchAllRoute.GetRoute() == nil {
+		return nil
+	}
+	route, ok := proto.Clone(catchAllRoute).(*routev3.Route)
+	if !ok {
+		return nil
+	}
+	for _, headerMatcher := range headerMatchers {
+		route.Match.Headers = append(route.Match.Headers, createHeaderMatcher(headerMatcher))
+	}
+	route.GetRoute().ClusterSpecifier = &routev3.RouteAction_Cluster{
+		Cluster: clusterName,
+	}
+	return route
+}
+
+func createHeaderMatcher(headerMatcher HeaderMatcher) *routev3.HeaderMatcher {
+	switch {
+	case headerMatcher.Exact != "":
+		return &routev3.HeaderMatcher{
+			Name: headerMatcher.Name,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+				StringMatch: &matcherv3.StringMatcher{
+					MatchPattern: &matcherv3.StringMatcher_Exact{
+						Exact: headerMatcher.Exact,
+					},
+				},
+			},
+		}
+	case headerMatcher.Prefix != "":
+		return &routev3.HeaderMatcher{
+			Name: headerMatcher.Name,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+				StringMatch: &matcherv3.StringMatcher{
+					MatchPattern: &matcherv3.StringMatcher_Prefix{
+						Prefix: headerMatcher.Prefix,
+					},
+				},
+			},
+		}
+	default:
+		return &routev3.HeaderMatcher{
+			Name: headerMatcher.Name,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		}
+	}
+}
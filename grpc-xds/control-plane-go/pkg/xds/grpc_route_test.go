@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCreateHeaderMatcher(t *testing.T) {
+	tests := []struct {
+		name          string
+		headerMatcher HeaderMatcher
+		want          *routev3.HeaderMatcher
+	}{
+		{
+			name:          "exact",
+			headerMatcher: HeaderMatcher{Name: "x-version", Exact: "v2"},
+			want: &routev3.HeaderMatcher{
+				Name: "x-version",
+				HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+					StringMatch: &matcherv3.StringMatcher{
+						MatchPattern: &matcherv3.StringMatcher_Exact{Exact: "v2"},
+					},
+				},
+			},
+		},
+		{
+			name:          "prefix",
+			headerMatcher: HeaderMatcher{Name: "x-user", Prefix: "test-"},
+			want: &routev3.HeaderMatcher{
+				Name: "x-user",
+				HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{
+					StringMatch: &matcherv3.StringMatcher{
+						MatchPattern: &matcherv3.StringMatcher_Prefix{Prefix: "test-"},
+					},
+				},
+			},
+		},
+		{
+			name:          "present",
+			headerMatcher: HeaderMatcher{Name: "x-debug"},
+			want: &routev3.HeaderMatcher{
+				Name:                 "x-debug",
+				HeaderMatchSpecifier: &routev3.HeaderMatcher_PresentMatch{PresentMatch: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createHeaderMatcher(tt.headerMatcher); !proto.Equal(got, tt.want) {
+				t.Errorf("createHeaderMatcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateHeaderRoute(t *testing.T) {
+	catchAllRoute := &routev3.Route{
+		Match: &routev3.RouteMatch{
+			PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: ""},
+		},
+		Action: &routev3.Route_Route{
+			Route: &routev3.RouteAction{
+				ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: "greeter"},
+				RetryPolicy:      &routev3.RetryPolicy{NumRetries: wrapperspb.UInt32(2)},
+			},
+		},
+	}
+	catchAllRouteCopy := proto.Clone(catchAllRoute)
+
+	got := createHeaderRoute(catchAllRoute, []HeaderMatcher{{Name: "x-debug"}}, "greeter-canary")
+	want := &routev3.Route{
+		Match: &routev3.RouteMatch{
+			PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: ""},
+			Headers:       []*routev3.HeaderMatcher{createHeaderMatcher(HeaderMatcher{Name: "x-debug"})},
+		},
+		Action: &routev3.Route_Route{
+			Route: &routev3.RouteAction{
+				ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: "greeter-canary"},
+				RetryPolicy:      &routev3.RetryPolicy{NumRetries: wrapperspb.UInt32(2)},
+			},
+		},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("createHeaderRoute() = %v, want %v", got, want)
+	}
+	if !proto.Equal(catchAllRoute, catchAllRouteCopy) {
+		t.Errorf("createHeaderRoute() modified the catch-all route: %v", catchAllRoute)
+	}
+
+	nonForwardingRoute := &routev3.Route{
+		Match:  &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: ""}},
+		Action: &routev3.Route_NonForwardingAction{NonForwardingAction: &routev3.NonForwardingAction{}},
+	}
+	if got := createHeaderRoute(nonForwardingRoute, []HeaderMatcher{{Name: "x-debug"}}, "greeter-canary"); got != nil {
+		t.Errorf("createHeaderRoute() without a route action = %v, want nil", got)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	xdstypev3 "github.com/cncf/xds/go/xds/type/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// grpcServiceConfigFilterName is the key of the service config in `typed_per_filter_config` of virtual hosts.
+	grpcServiceConfigFilterName = "grpc.service_config"
+	grpcServiceConfigTypeURL    = "type.googleapis.com/grpc.service_config.ServiceConfig"
+	// maxRetryThrottlingTokens is the upper limit of `retryThrottling.maxTokens` from gRFC A6.
+	maxRetryThrottlingTokens = 1000
+)
+
+var errInvalidGRPCServiceConfig = errors.New("invalid gRPC service config")
+
+// serviceConfig is the JSON representation of a gRPC service config, used to validate the annotation value.
+// [Reference]: https://github.com/grpc/grpc-proto/blob/master/grpc/service_config/service_config.proto
+type serviceConfig struct {
+	LoadBalancingPolicy string                       `json:"loadBalancingPolicy,omitempty"`
+	LoadBalancingConfig []map[string]json.RawMessage `json:"loadBalancingConfig,omitempty"`
+	MethodConfig        []methodConfig               `json:"methodConfig,omitempty"`
+	RetryThrottling     *struct {
+		MaxTokens  float64 `json:"maxTokens"`
+		TokenRatio float64 `json:"tokenRatio"`
+	} `json:"retryThrottling,omitempty"`
+	HealthCheckConfig *struct {
+		ServiceName string `json:"serviceName"`
+	} `json:"healthCheckConfig,omitempty"`
+}
+
+type methodConfig struct {
+	Name []struct {
+		Service string `json:"service,omitempty"`
+		Method  string `json:"method,omitempty"`
+	} `json:"name,omitempty"`
+	WaitForReady            *bool   `json:"waitForReady,omitempty"`
+	Timeout                 string  `json:"timeout,omitempty"`
+	MaxRequestMessageBytes  *uint32 `json:"maxRequestMessageBytes,omitempty"`
+	MaxResponseMessageBytes *uint32 `json:"maxResponseMessageBytes,omitempty"`
+	RetryPolicy             *struct {
+		MaxAttempts          int          `json:"maxAttempts"`
+		InitialBackoff       string       `json:"initialBackoff"`
+		MaxBackoff           string       `json:"maxBackoff"`
+		BackoffMultiplier    float64      `json:"backoffMultiplier"`
+		RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+	} `json:"retryPolicy,omitempty"`
+	HedgingPolicy *struct {
+		MaxAttempts         int          `json:"maxAttempts"`
+		HedgingDelay        string       `json:"hedgingDelay,omitempty"`
+		NonFatalStatusCodes []codes.Code `json:"nonFatalStatusCodes,omitempty"`
+	} `json:"hedgingPolicy,omitempty"`
+}
+
+// GRPCServiceConfigFromAnnotations returns the gRPC service config JSON document from the Service annotation,
+// in compact form. Returns an empty string if the annotation is not present, and an error if the value is not
+// a valid service config, e.g., malformed JSON, unknown fields, or invalid retry policy values.
+func GRPCServiceConfigFromAnnotations(annotations map[string]string) (string, error) {
+	value, exists := annotations[grpcServiceConfigAnnotation]
+	if !exists {
+		return "", nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	var config serviceConfig
+	if err := decoder.Decode(&config); err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidGRPCServiceConfig, err)
+	}
+	if decoder.More() {
+		return "", fmt.Errorf("%w: unexpected data after the JSON object", errInvalidGRPCServiceConfig)
+	}
+	if err := config.validate(); err != nil {
+		return "", err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(value)); err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidGRPCServiceConfig, err)
+	}
+	return compact.String(), nil
+}
+
+// validate checks the values that gRPC clients reject, see gRFC A6 for the retry and hedging policies.
+func (c serviceConfig) validate() error {
+	for i, lbConfig := range c.LoadBalancingConfig {
+		if len(lbConfig) != 1 {
+			return fmt.Errorf("%w: loadBalancingConfig[%d] must have exactly one policy, got %d", errInvalidGRPCServiceConfig, i, len(lbConfig))
+		}
+	}
+	for i, methodConfig := range c.MethodConfig {
+		if err := methodConfig.validate(fmt.Sprintf("methodConfig[%d]", i)); err != nil {
+			return err
+		}
+	}
+	if t := c.RetryThrottling; t != nil {
+		if t.MaxTokens <= 0 || t.MaxTokens > maxRetryThrottlingTokens {
+			return fmt.Errorf("%w: retryThrottling.maxTokens must be in (0, %d], got %v", errInvalidGRPCServiceConfig, maxRetryThrottlingTokens, t.MaxTokens)
+		}
+		if t.TokenRatio <= 0 {
+			return fmt.Errorf("%w: retryThrottling.tokenRatio must be positive, got %v", errInvalidGRPCServiceConfig, t.TokenRatio)
+		}
+	}
+	return nil
+}
+
+func (c methodConfig) validate(path string) error {
+	for i, name := range c.Name {
+		if name.Service == "" && name.Method != "" {
+			return fmt.Errorf("%w: %s.name[%d] has a method=%q but no service", errInvalidGRPCServiceConfig, path, i, name.Method)
+		}
+	}
+	if c.Timeout != "" && !validServiceConfigDuration(c.Timeout, false) {
+		return fmt.Errorf("%w: %s.timeout=%q must be a non-negative number of seconds with the suffix s, e.g., 1.5s", errInvalidGRPCServiceConfig, path, c.Timeout)
+	}
+	if c.RetryPolicy != nil && c.HedgingPolicy != nil {
+		return fmt.Errorf("%w: %s has both retryPolicy and hedgingPolicy", errInvalidGRPCServiceConfig, path)
+	}
+	if p := c.RetryPolicy; p != nil {
+		if p.MaxAttempts < 2 {
+			return fmt.Errorf("%w: %s.retryPolicy.maxAttempts must be at least 2, got %d", errInvalidGRPCServiceConfig, path, p.MaxAttempts)
+		}
+		if !validServiceConfigDuration(p.InitialBackoff, true) {
+			return fmt.Errorf("%w: %s.retryPolicy.initialBackoff=%q must be a positive number of seconds with the suffix s", errInvalidGRPCServiceConfig, path, p.InitialBackoff)
+		}
+		if !validServiceConfigDuration(p.MaxBackoff, true) {
+			return fmt.Errorf("%w: %s.retryPolicy.maxBackoff=%q must be a positive number of seconds with the suffix s", errInvalidGRPCServiceConfig, path, p.MaxBackoff)
+		}
+		if p.BackoffMultiplier <= 0 {
+			return fmt.Errorf("%w: %s.retryPolicy.backoffMultiplier must be positive, got %v", errInvalidGRPCServiceConfig, path, p.BackoffMultiplier)
+		}
+		if len(p.RetryableStatusCodes) == 0 {
+			return fmt.Errorf("%w: %s.retryPolicy.retryableStatusCodes must not be empty", errInvalidGRPCServiceConfig, path)
+		}
+	}
+	if p := c.HedgingPolicy; p != nil {
+		if p.MaxAttempts < 2 {
+			return fmt.Errorf("%w: %s.hedgingPolicy.maxAttempts must be at least 2, got %d", errInvalidGRPCServiceConfig, path, p.MaxAttempts)
+		}
+		if p.HedgingDelay != "" && !validServiceConfigDuration(p.HedgingDelay, false) {
+			return fmt.Errorf("%w: %s.hedgingPolicy.hedgingDelay=%q must be a non-negative number of seconds with the suffix s", errInvalidGRPCServiceConfig, path, p.HedgingDelay)
+		}
+	}
+	return nil
+}
+
+// validServiceConfigDuration checks the JSON representation of `google.protobuf.Duration`,
+// which is a number of seconds with the suffix `s`, e.g., `1.5s`.
+func validServiceConfigDuration(value string, positive bool) bool {
+	secondsValue, found := strings.CutSuffix(value, "s")
+	if !found {
+		return false
+	}
+	seconds, err := strconv.ParseFloat(secondsValue, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds < 0 || (positive && seconds == 0) {
+		return false
+	}
+	return true
+}
+
+// applyGRPCServiceConfig adds the service config to the `typed_per_filter_config` of the virtual hosts,
+// as a `TypedStruct` with the type URL of `grpc.service_config.ServiceConfig`. The config is wrapped in
+// a `FilterConfig` marked as optional, so that xDS clients without a filter of that name ignore it,
+// instead of rejecting the route configuration.
+func applyGRPCServiceConfig(routeConfiguration *routev3.RouteConfiguration, serviceConfigJSON string) error {
+	if serviceConfigJSON == "" {
+		return nil
+	}
+	value := &structpb.Struct{}
+	if err := protojson.Unmarshal([]byte(serviceConfigJSON), value); err != nil {
+		return fmt.Errorf("could not convert gRPC service config to Struct: %w", err)
+	}
+	typedStruct, err := anypb.New(&xdstypev3.TypedStruct{
+		TypeUrl: grpcServiceConfigTypeURL,
+		Value:   value,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshall TypedStruct for gRPC service config into Any instance: %w", err)
+	}
+	filterConfig, err := anypb.New(&routev3.FilterConfig{
+		Config:     typedStruct,
+		IsOptional: true,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshall FilterConfig for gRPC service config into Any instance: %w", err)
+	}
+	for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+		if virtualHost.TypedPerFilterConfig == nil {
+			virtualHost.TypedPerFilterConfig = map[string]*anypb.Any{}
+		}
+		virtualHost.TypedPerFilterConfig[grpcServiceConfigFilterName] = filterConfig
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"testing"
+
+	xdstypev3 "github.com/cncf/xds/go/xds/type/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+func TestGRPCServiceConfigFromAnnotations(t *testing.T) {
+	const retryPolicy = `"retryPolicy": {"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`
+	tests := []struct {
+		name    string
+		value   *string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "no annotation",
+			value: nil,
+			want:  "",
+		},
+		{
+			name:  "valid service config is compacted",
+			value: ptr(`{ "loadBalancingConfig": [ {"round_robin": {}} ], "methodConfig": [{"name": [{"service": "helloworld.Greeter"}], "timeout": "1.5s", ` + retryPolicy + `}] }`),
+			want:  `{"loadBalancingConfig":[{"round_robin":{}}],"methodConfig":[{"name":[{"service":"helloworld.Greeter"}],"timeout":"1.5s","retryPolicy":{"maxAttempts":3,"initialBackoff":"0.1s","maxBackoff":"1s","backoffMultiplier":2,"retryableStatusCodes":["UNAVAILABLE"]}}]}`,
+		},
+		{
+			name:    "malformed JSON",
+			value:   ptr(`{"loadBalancingConfig": `),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "unknown field",
+			value:   ptr(`{"unknownField": true}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "data after the JSON object",
+			value:   ptr(`{} {}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "load balancing config with two policies",
+			value:   ptr(`{"loadBalancingConfig": [{"round_robin": {}, "pick_first": {}}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "method without service",
+			value:   ptr(`{"methodConfig": [{"name": [{"method": "SayHello"}]}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "timeout without suffix",
+			value:   ptr(`{"methodConfig": [{"timeout": "2"}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "retry policy with one attempt",
+			value:   ptr(`{"methodConfig": [{"retryPolicy": {"maxAttempts": 1, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "retry policy with zero backoff",
+			value:   ptr(`{"methodConfig": [{"retryPolicy": {"maxAttempts": 3, "initialBackoff": "0s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "retry policy without status codes",
+			value:   ptr(`{"methodConfig": [{"retryPolicy": {"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": []}}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "both retry and hedging policies",
+			value:   ptr(`{"methodConfig": [{` + retryPolicy + `, "hedgingPolicy": {"maxAttempts": 2}}]}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+		{
+			name:    "retry throttling with too many tokens",
+			value:   ptr(`{"retryThrottling": {"maxTokens": 1001, "tokenRatio": 0.1}}`),
+			wantErr: errInvalidGRPCServiceConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.value != nil {
+				annotations[grpcServiceConfigAnnotation] = *tt.value
+			}
+			got, err := GRPCServiceConfigFromAnnotations(annotations)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GRPCServiceConfigFromAnnotations() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GRPCServiceConfigFromAnnotations() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidServiceConfigDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		positive bool
+		want     bool
+	}{
+		{value: "1.5s", want: true},
+		{value: "0s", want: true},
+		{value: "0s", positive: true, want: false},
+		{value: "-1s", want: false},
+		{value: "1", want: false},
+		{value: "1ms", want: false},
+		{value: "NaNs", want: false},
+		{value: "Infs", want: false},
+	}
+	for _, tt := range tests {
+		if got := validServiceConfigDuration(tt.value, tt.positive); got != tt.want {
+			t.Errorf("validServiceConfigDuration(%q, %t) = %t, want %t", tt.value, tt.positive, got, tt.want)
+		}
+	}
+}
+
+func TestApplyGRPCServiceConfig(t *testing.T) {
+	routeConfiguration := &routev3.RouteConfiguration{
+		VirtualHosts: []*routev3.VirtualHost{{Name: "a"}, {Name: "b"}},
+	}
+	if err := applyGRPCServiceConfig(routeConfiguration, ""); err != nil {
+		t.Fatalf("applyGRPCServiceConfig() with empty config error = %v", err)
+	}
+	for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+		if virtualHost.GetTypedPerFilterConfig() != nil {
+			t.Fatalf("virtual host %s typedPerFilterConfig = %v, want nil", virtualHost.GetName(), virtualHost.GetTypedPerFilterConfig())
+		}
+	}
+
+	if err := applyGRPCServiceConfig(routeConfiguration, `{"loadBalancingConfig":[{"round_robin":{}}]}`); err != nil {
+		t.Fatalf("applyGRPCServiceConfig() error = %v", err)
+	}
+	for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+		var filterConfig routev3.FilterConfig
+		if err := virtualHost.GetTypedPerFilterConfig()[grpcServiceConfigFilterName].UnmarshalTo(&filterConfig); err != nil {
+			t.Fatalf("virtual host %s: could not unmarshal FilterConfig: %v", virtualHost.GetName(), err)
+		}
+		if !filterConfig.GetIsOptional() {
+			t.Errorf("virtual host %s FilterConfig isOptional = false, want true", virtualHost.GetName())
+		}
+		var typedStruct xdstypev3.TypedStruct
+		if err := filterConfig.GetConfig().UnmarshalTo(&typedStruct); err != nil {
+			t.Fatalf("virtual host %s: could not unmarshal TypedStruct: %v", virtualHost.GetName(), err)
+		}
+		if typedStruct.GetTypeUrl() != grpcServiceConfigTypeURL {
+			t.Errorf("virtual host %s TypedStruct typeUrl = %s, want %s", virtualHost.GetName(), typedStruct.GetTypeUrl(), grpcServiceConfigTypeURL)
+		}
+		if _, exists := typedStruct.GetValue().GetFields()["loadBalancingConfig"]; !exists {
+			t.Errorf("virtual host %s TypedStruct value = %v, want loadBalancingConfig", virtualHost.GetName(), typedStruct.GetValue())
+		}
+	}
+}
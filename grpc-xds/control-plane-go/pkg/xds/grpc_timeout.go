@@ -0,0 +1,87 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"time"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// GRPCTimeouts configures the maximum deadlines of requests to a gRPC application.
+// Nil values are unset. Zero values are set and mean no limit, as in the xDS API.
+type GRPCTimeouts struct {
+	// MaxGRPCTimeout is the maximum duration of requests on the route,
+	// used if the request has no `grpc-timeout` header, or if GRPCTimeoutHeaderMax is unset.
+	MaxGRPCTimeout *time.Duration
+	// GRPCTimeoutHeaderMax limits the deadline from the `grpc-timeout` header of requests.
+	GRPCTimeoutHeaderMax *time.Duration
+}
+
+// GRPCTimeoutsFromAnnotations reads the gRPC timeouts from Service annotations.
+// Annotations with invalid values are logged as warnings and ignored.
+func GRPCTimeoutsFromAnnotations(logger logr.Logger, annotations map[string]string) GRPCTimeouts {
+	var grpcTimeouts GRPCTimeouts
+	if maxGRPCTimeout, ok := durationAnnotation(logger, annotations, maxGRPCTimeoutAnnotation); ok {
+		grpcTimeouts.MaxGRPCTimeout = &maxGRPCTimeout
+	}
+	if grpcTimeoutHeaderMax, ok := durationAnnotation(logger, annotations, grpcTimeoutHeaderMaxAnnotation); ok {
+		grpcTimeouts.GRPCTimeoutHeaderMax = &grpcTimeoutHeaderMax
+	}
+	return grpcTimeouts
+}
+
+func (t GRPCTimeouts) Compare(u GRPCTimeouts) int {
+	if c := compareOptionalDurations(t.MaxGRPCTimeout, u.MaxGRPCTimeout); c != 0 {
+		return c
+	}
+	return compareOptionalDurations(t.GRPCTimeoutHeaderMax, u.GRPCTimeoutHeaderMax)
+}
+
+// compareOptionalDurations sorts unset durations before set durations.
+func compareOptionalDurations(a *time.Duration, b *time.Duration) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	default:
+		return cmp.Compare(*a, *b)
+	}
+}
+
+// createMaxStreamDuration returns nil if neither timeout is set.
+// The deprecated `max_grpc_timeout` field of the route action is replaced by `max_stream_duration`,
+// which gRPC clients use to limit the deadline of requests.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/route/v3/route_components.proto#config-route-v3-routeaction-maxstreamduration
+// [gRFC A31]: https://github.com/grpc/proposal/blob/master/A31-xds-timeout-support-and-config-selector.md
+func createMaxStreamDuration(t GRPCTimeouts) *routev3.RouteAction_MaxStreamDuration {
+	if t.MaxGRPCTimeout == nil && t.GRPCTimeoutHeaderMax == nil {
+		return nil
+	}
+	maxStreamDuration := &routev3.RouteAction_MaxStreamDuration{}
+	if t.MaxGRPCTimeout != nil {
+		maxStreamDuration.MaxStreamDuration = durationpb.New(*t.MaxGRPCTimeout)
+	}
+	if t.GRPCTimeoutHeaderMax != nil {
+		maxStreamDuration.GrpcTimeoutHeaderMax = durationpb.New(*t.GRPCTimeoutHeaderMax)
+	}
+	return maxStreamDuration
+}
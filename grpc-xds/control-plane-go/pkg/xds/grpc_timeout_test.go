@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestGRPCTimeoutsFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        GRPCTimeouts
+	}{
+		{
+			name: "no annotations",
+			want: GRPCTimeouts{},
+		},
+		{
+			name: "both annotations",
+			annotations: map[string]string{
+				maxGRPCTimeoutAnnotation:       "30s",
+				grpcTimeoutHeaderMaxAnnotation: "0s",
+			},
+			want: GRPCTimeouts{MaxGRPCTimeout: ptr(30 * time.Second), GRPCTimeoutHeaderMax: ptr(time.Duration(0))},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				maxGRPCTimeoutAnnotation:       "soon",
+				grpcTimeoutHeaderMaxAnnotation: "-1s",
+			},
+			want: GRPCTimeouts{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GRPCTimeoutsFromAnnotations(logr.Discard(), tt.annotations); got.Compare(tt.want) != 0 {
+				t.Errorf("GRPCTimeoutsFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareOptionalDurations(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *time.Duration
+		b    *time.Duration
+		want int
+	}{
+		{name: "both unset", want: 0},
+		{name: "unset before set", a: nil, b: ptr(time.Duration(0)), want: -1},
+		{name: "set before unset", a: ptr(time.Duration(0)), b: nil, want: 1},
+		{name: "shorter before longer", a: ptr(time.Second), b: ptr(time.Minute), want: -1},
+		{name: "equal", a: ptr(time.Second), b: ptr(time.Second), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareOptionalDurations(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareOptionalDurations() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateMaxStreamDuration(t *testing.T) {
+	tests := []struct {
+		name         string
+		grpcTimeouts GRPCTimeouts
+		want         *routev3.RouteAction_MaxStreamDuration
+	}{
+		{
+			name: "unset",
+			want: nil,
+		},
+		{
+			name:         "max gRPC timeout only",
+			grpcTimeouts: GRPCTimeouts{MaxGRPCTimeout: ptr(30 * time.Second)},
+			want:         &routev3.RouteAction_MaxStreamDuration{MaxStreamDuration: durationpb.New(30 * time.Second)},
+		},
+		{
+			name:         "both",
+			grpcTimeouts: GRPCTimeouts{MaxGRPCTimeout: ptr(30 * time.Second), GRPCTimeoutHeaderMax: ptr(time.Duration(0))},
+			want: &routev3.RouteAction_MaxStreamDuration{
+				MaxStreamDuration:    durationpb.New(30 * time.Second),
+				GrpcTimeoutHeaderMax: durationpb.New(0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createMaxStreamDuration(tt.grpcTimeouts); !proto.Equal(got, tt.want) {
+				t.Errorf("createMaxStreamDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
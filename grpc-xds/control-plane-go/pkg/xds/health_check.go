@@ -0,0 +1,184 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Values of the health check protocol annotation.
+const (
+	HealthCheckProtocolHTTP = "http"
+	HealthCheckProtocolGRPC = "grpc"
+	HealthCheckProtocolTCP  = "tcp"
+)
+
+// Defaults for health check fields that are required by Envoy, but not set by annotations.
+const (
+	DefaultHealthCheckInterval           = 10 * time.Second
+	DefaultHealthCheckTimeout            = 1 * time.Second
+	DefaultHealthCheckPath               = "/"
+	DefaultHealthCheckUnhealthyThreshold = 3
+	healthCheckHealthyThreshold          = 1
+)
+
+var errUnknownHealthCheckProtocol = errors.New("unknown health check protocol")
+
+// HealthCheck configures active health checking of the endpoints of a cluster by Envoy proxies.
+// gRPC clients do not support active health checking, and ignore it.
+// If Protocol is empty, the cluster has no health checks. Other zero values use the defaults.
+type HealthCheck struct {
+	Protocol           string
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold uint32
+	// Path is the request path of HTTP health checks.
+	Path string
+	// GRPCServiceName is the service name of gRPC health checks. Empty checks the health of the server.
+	GRPCServiceName string
+}
+
+// HealthCheckFromAnnotations reads the health check configuration from Service annotations.
+// Returns an error for unknown protocols, so that the caller can keep the previous valid health check.
+// Other annotations with invalid values are logged as warnings and ignored.
+func HealthCheckFromAnnotations(logger logr.Logger, annotations map[string]string) (HealthCheck, error) {
+	value, exists := annotations[healthCheckProtocolAnnotation]
+	if !exists {
+		for _, key := range []string{healthCheckIntervalAnnotation, healthCheckTimeoutAnnotation, healthCheckUnhealthyThresholdAnnotation, healthCheckPathAnnotation, healthCheckGRPCServiceAnnotation} {
+			if _, exists := annotations[key]; exists {
+				logger.V(1).Info("Warning: ignoring health check annotation without a protocol", "annotation", key, "protocolAnnotation", healthCheckProtocolAnnotation)
+			}
+		}
+		return HealthCheck{}, nil
+	}
+	healthCheck := HealthCheck{
+		Protocol: strings.ToLower(strings.TrimSpace(value)),
+	}
+	switch healthCheck.Protocol {
+	case HealthCheckProtocolHTTP, HealthCheckProtocolGRPC, HealthCheckProtocolTCP:
+	default:
+		return HealthCheck{}, fmt.Errorf("%w: %s=%s, must be one of %s, %s, or %s", errUnknownHealthCheckProtocol, healthCheckProtocolAnnotation, value,
+			HealthCheckProtocolHTTP, HealthCheckProtocolGRPC, HealthCheckProtocolTCP)
+	}
+	for key, duration := range map[string]*time.Duration{
+		healthCheckIntervalAnnotation: &healthCheck.Interval,
+		healthCheckTimeoutAnnotation:  &healthCheck.Timeout,
+	} {
+		if parsed, ok := durationAnnotation(logger, annotations, key); ok {
+			if parsed > 0 {
+				*duration = parsed
+			} else {
+				logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a positive duration", "annotation", key, "value", annotations[key])
+			}
+		}
+	}
+	if unhealthyThreshold, ok := positiveUint32Annotation(logger, annotations, healthCheckUnhealthyThresholdAnnotation); ok {
+		healthCheck.UnhealthyThreshold = unhealthyThreshold
+	}
+	if path, exists := annotations[healthCheckPathAnnotation]; exists {
+		switch {
+		case healthCheck.Protocol != HealthCheckProtocolHTTP:
+			logger.V(1).Info("Warning: ignoring health check path annotation, as the health check protocol is not http", "protocol", healthCheck.Protocol)
+		case !strings.HasPrefix(path, "/"):
+			logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a path starting with /", "annotation", healthCheckPathAnnotation, "value", path)
+		default:
+			healthCheck.Path = path
+		}
+	}
+	if serviceName, exists := annotations[healthCheckGRPCServiceAnnotation]; exists {
+		if healthCheck.Protocol == HealthCheckProtocolGRPC {
+			healthCheck.GRPCServiceName = serviceName
+		} else {
+			logger.V(1).Info("Warning: ignoring health check gRPC service annotation, as the health check protocol is not grpc", "protocol", healthCheck.Protocol)
+		}
+	}
+	return healthCheck, nil
+}
+
+func (h HealthCheck) Compare(i HealthCheck) int {
+	if h.Protocol != i.Protocol {
+		return strings.Compare(h.Protocol, i.Protocol)
+	}
+	if h.Interval != i.Interval {
+		return cmp.Compare(h.Interval, i.Interval)
+	}
+	if h.Timeout != i.Timeout {
+		return cmp.Compare(h.Timeout, i.Timeout)
+	}
+	if h.UnhealthyThreshold != i.UnhealthyThreshold {
+		return cmp.Compare(h.UnhealthyThreshold, i.UnhealthyThreshold)
+	}
+	if h.Path != i.Path {
+		return strings.Compare(h.Path, i.Path)
+	}
+	return strings.Compare(h.GRPCServiceName, i.GRPCServiceName)
+}
+
+// createHealthChecks returns nil if the health check protocol is not set.
+// Envoy requires the interval, timeout, and thresholds, so unset fields use the defaults.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/core/v3/health_check.proto
+func createHealthChecks(h HealthCheck) []*corev3.HealthCheck {
+	if h.Protocol == "" {
+		return nil
+	}
+	healthCheck := &corev3.HealthCheck{
+		Interval:           durationpb.New(DefaultHealthCheckInterval),
+		Timeout:            durationpb.New(DefaultHealthCheckTimeout),
+		UnhealthyThreshold: wrapperspb.UInt32(DefaultHealthCheckUnhealthyThreshold),
+		HealthyThreshold:   wrapperspb.UInt32(healthCheckHealthyThreshold),
+	}
+	if h.Interval > 0 {
+		healthCheck.Interval = durationpb.New(h.Interval)
+	}
+	if h.Timeout > 0 {
+		healthCheck.Timeout = durationpb.New(h.Timeout)
+	}
+	if h.UnhealthyThreshold > 0 {
+		healthCheck.UnhealthyThreshold = wrapperspb.UInt32(h.UnhealthyThreshold)
+	}
+	path := DefaultHealthCheckPath
+	if h.Path != "" {
+		path = h.Path
+	}
+	switch h.Protocol {
+	case HealthCheckProtocolHTTP:
+		healthCheck.HealthChecker = &corev3.HealthCheck_HttpHealthCheck_{
+			HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{
+				Path: path,
+			},
+		}
+	case HealthCheckProtocolGRPC:
+		healthCheck.HealthChecker = &corev3.HealthCheck_GrpcHealthCheck_{
+			GrpcHealthCheck: &corev3.HealthCheck_GrpcHealthCheck{
+				ServiceName: h.GRPCServiceName,
+			},
+		}
+	case HealthCheckProtocolTCP:
+		// Without payloads, a TCP health check only checks that a connection can be established.
+		healthCheck.HealthChecker = &corev3.HealthCheck_TcpHealthCheck_{
+			TcpHealthCheck: &corev3.HealthCheck_TcpHealthCheck{},
+		}
+	}
+	return []*corev3.HealthCheck{healthCheck}
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestHealthCheckFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        HealthCheck
+		wantErr     error
+	}{
+		{
+			name: "no annotations",
+			want: HealthCheck{},
+		},
+		{
+			name:        "annotations without protocol are ignored",
+			annotations: map[string]string{healthCheckIntervalAnnotation: "5s"},
+			want:        HealthCheck{},
+		},
+		{
+			name: "http",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotation:           " HTTP ",
+				healthCheckIntervalAnnotation:           "5s",
+				healthCheckTimeoutAnnotation:            "500ms",
+				healthCheckUnhealthyThresholdAnnotation: "2",
+				healthCheckPathAnnotation:               "/healthz",
+				healthCheckGRPCServiceAnnotation:        "helloworld.Greeter",
+			},
+			want: HealthCheck{
+				Protocol:           HealthCheckProtocolHTTP,
+				Interval:           5 * time.Second,
+				Timeout:            500 * time.Millisecond,
+				UnhealthyThreshold: 2,
+				Path:               "/healthz",
+			},
+		},
+		{
+			name: "grpc",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotation:    "grpc",
+				healthCheckPathAnnotation:        "/healthz",
+				healthCheckGRPCServiceAnnotation: "helloworld.Greeter",
+			},
+			want: HealthCheck{Protocol: HealthCheckProtocolGRPC, GRPCServiceName: "helloworld.Greeter"},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				healthCheckProtocolAnnotation:           "http",
+				healthCheckIntervalAnnotation:           "0s",
+				healthCheckTimeoutAnnotation:            "soon",
+				healthCheckUnhealthyThresholdAnnotation: "0",
+				healthCheckPathAnnotation:               "healthz",
+			},
+			want: HealthCheck{Protocol: HealthCheckProtocolHTTP},
+		},
+		{
+			name:        "unknown protocol",
+			annotations: map[string]string{healthCheckProtocolAnnotation: "udp"},
+			wantErr:     errUnknownHealthCheckProtocol,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HealthCheckFromAnnotations(logr.Discard(), tt.annotations)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("HealthCheckFromAnnotations() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HealthCheckFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateHealthChecks(t *testing.T) {
+	withDefaults := func(healthChecker func(*corev3.HealthCheck)) []*corev3.HealthCheck {
+		healthCheck := &corev3.HealthCheck{
+			Interval:           durationpb.New(DefaultHealthCheckInterval),
+			Timeout:            durationpb.New(DefaultHealthCheckTimeout),
+			UnhealthyThreshold: wrapperspb.UInt32(DefaultHealthCheckUnhealthyThreshold),
+			HealthyThreshold:   wrapperspb.UInt32(healthCheckHealthyThreshold),
+		}
+		healthChecker(healthCheck)
+		return []*corev3.HealthCheck{healthCheck}
+	}
+	tests := []struct {
+		name        string
+		healthCheck HealthCheck
+		want        []*corev3.HealthCheck
+	}{
+		{
+			name: "no protocol",
+			want: nil,
+		},
+		{
+			name:        "http with defaults",
+			healthCheck: HealthCheck{Protocol: HealthCheckProtocolHTTP},
+			want: withDefaults(func(h *corev3.HealthCheck) {
+				h.HealthChecker = &corev3.HealthCheck_HttpHealthCheck_{HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{Path: DefaultHealthCheckPath}}
+			}),
+		},
+		{
+			name: "grpc with all fields",
+			healthCheck: HealthCheck{
+				Protocol:           HealthCheckProtocolGRPC,
+				Interval:           5 * time.Second,
+				Timeout:            500 * time.Millisecond,
+				UnhealthyThreshold: 2,
+				GRPCServiceName:    "helloworld.Greeter",
+			},
+			want: withDefaults(func(h *corev3.HealthCheck) {
+				h.Interval = durationpb.New(5 * time.Second)
+				h.Timeout = durationpb.New(500 * time.Millisecond)
+				h.UnhealthyThreshold = wrapperspb.UInt32(2)
+				h.HealthChecker = &corev3.HealthCheck_GrpcHealthCheck_{GrpcHealthCheck: &corev3.HealthCheck_GrpcHealthCheck{ServiceName: "helloworld.Greeter"}}
+			}),
+		},
+		{
+			name:        "tcp",
+			healthCheck: HealthCheck{Protocol: HealthCheckProtocolTCP},
+			want: withDefaults(func(h *corev3.HealthCheck) {
+				h.HealthChecker = &corev3.HealthCheck_TcpHealthCheck_{TcpHealthCheck: &corev3.HealthCheck_TcpHealthCheck{}}
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := createHealthChecks(tt.healthCheck)
+			if len(got) != len(tt.want) {
+				t.Fatalf("createHealthChecks() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !proto.Equal(got[i], tt.want[i]) {
+					t.Errorf("createHealthChecks()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
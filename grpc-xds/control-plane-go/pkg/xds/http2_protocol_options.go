@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"math"
+	"strconv"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	envoyUpstreamsHTTPProtocolOptionsName = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
+
+	// Limits of the HTTP/2 protocol options in Envoy.
+	// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/core/v3/protocol.proto#config-core-v3-http2protocoloptions
+	http2MinWindowSize           = 65535
+	http2MaxWindowSize           = math.MaxInt32
+	http2MinMaxConcurrentStreams = 1
+	http2MaxMaxConcurrentStreams = math.MaxInt32
+)
+
+// HTTP2ProtocolOptionsFromAnnotations reads the HTTP/2 protocol options of upstream connections from Service annotations.
+// Values outside the limits of Envoy are clamped, and other invalid values are ignored, with warnings in both cases.
+func HTTP2ProtocolOptionsFromAnnotations(logger logr.Logger, annotations map[string]string) HTTP2ProtocolOptions {
+	var options HTTP2ProtocolOptions
+	if value, ok := clampedUint32Annotation(logger, annotations, h2InitialStreamWindowAnnotation, http2MinWindowSize, http2MaxWindowSize); ok {
+		options.InitialStreamWindowSize = value
+	}
+	if value, ok := clampedUint32Annotation(logger, annotations, h2InitialConnectionWindowAnnotation, http2MinWindowSize, http2MaxWindowSize); ok {
+		options.InitialConnectionWindowSize = value
+	}
+	if value, ok := clampedUint32Annotation(logger, annotations, h2MaxConcurrentStreamsAnnotation, http2MinMaxConcurrentStreams, http2MaxMaxConcurrentStreams); ok {
+		options.MaxConcurrentStreams = value
+	}
+	return options
+}
+
+// clampedUint32Annotation returns the value of the annotation as a positive integer, clamped to [minValue, maxValue].
+// Returns false if the annotation is not present, or if the value is not a positive integer.
+// Logs a warning if the value is invalid or clamped.
+func clampedUint32Annotation(logger logr.Logger, annotations map[string]string, key string, minValue uint32, maxValue uint32) (uint32, bool) {
+	value, exists := annotations[key]
+	if !exists {
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || parsed == 0 {
+		logger.V(1).Info("Warning: ignoring annotation with invalid value, expected a positive integer", "annotation", key, "value", value)
+		return 0, false
+	}
+	clamped := min(max(parsed, uint64(minValue)), uint64(maxValue))
+	if clamped != parsed {
+		logger.V(1).Info("Warning: clamping annotation value to the supported range", "annotation", key, "value", value, "min", minValue, "max", maxValue, "clamped", clamped)
+	}
+	return uint32(clamped), true
+}
+
+func (o HTTP2ProtocolOptions) Compare(p HTTP2ProtocolOptions) int {
+	if o.MaxConcurrentStreams != p.MaxConcurrentStreams {
+		return cmp.Compare(o.MaxConcurrentStreams, p.MaxConcurrentStreams)
+	}
+	if o.InitialStreamWindowSize != p.InitialStreamWindowSize {
+		return cmp.Compare(o.InitialStreamWindowSize, p.InitialStreamWindowSize)
+	}
+	return cmp.Compare(o.InitialConnectionWindowSize, p.InitialConnectionWindowSize)
+}
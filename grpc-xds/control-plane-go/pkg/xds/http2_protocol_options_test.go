@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestHTTP2ProtocolOptionsFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        HTTP2ProtocolOptions
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        HTTP2ProtocolOptions{},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				h2InitialStreamWindowAnnotation:     "1048576",
+				h2InitialConnectionWindowAnnotation: "2097152",
+				h2MaxConcurrentStreamsAnnotation:    "100",
+			},
+			want: HTTP2ProtocolOptions{
+				InitialStreamWindowSize:     1048576,
+				InitialConnectionWindowSize: 2097152,
+				MaxConcurrentStreams:        100,
+			},
+		},
+		{
+			name: "values outside the limits are clamped",
+			annotations: map[string]string{
+				h2InitialStreamWindowAnnotation:     "1024",
+				h2InitialConnectionWindowAnnotation: "4294967295",
+			},
+			want: HTTP2ProtocolOptions{
+				InitialStreamWindowSize:     http2MinWindowSize,
+				InitialConnectionWindowSize: http2MaxWindowSize,
+			},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				h2InitialStreamWindowAnnotation:  "0",
+				h2MaxConcurrentStreamsAnnotation: "-1",
+			},
+			want: HTTP2ProtocolOptions{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTP2ProtocolOptionsFromAnnotations(logr.Discard(), tt.annotations); got != tt.want {
+				t.Errorf("HTTP2ProtocolOptionsFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampedUint32Annotation(t *testing.T) {
+	const key = "key"
+	tests := []struct {
+		value  string
+		want   uint32
+		wantOK bool
+	}{
+		{value: "5", want: 5, wantOK: true},
+		{value: "1", want: 2, wantOK: true},
+		{value: "11", want: 10, wantOK: true},
+		{value: "18446744073709551615", want: 10, wantOK: true},
+		{value: "0", want: 0, wantOK: false},
+		{value: "many", want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := clampedUint32Annotation(logr.Discard(), map[string]string{key: tt.value}, key, 2, 10)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("clampedUint32Annotation(%q) = (%d, %t), want (%d, %t)", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+	if _, ok := clampedUint32Annotation(logr.Discard(), nil, key, 0, math.MaxUint32); ok {
+		t.Error("clampedUint32Annotation() without the annotation = true, want false")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// socketAddressHost returns the IP address in the form expected in the `Address`
+// field of a `SocketAddress`, i.e., without the square brackets around IPv6
+// addresses, and in canonical form, e.g., "[::]" becomes "::".
+// Values that are not IP addresses are returned unchanged.
+func socketAddressHost(host string) string {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	addr, err := netip.ParseAddr(trimmed)
+	if err != nil {
+		return host
+	}
+	return addr.String()
+}
+
+// isUnspecifiedAddress returns true if the host is an unspecified IP address,
+// i.e., "0.0.0.0" or "::". Servers can listen on these addresses,
+// but xDS clients cannot connect to them.
+func isUnspecifiedAddress(host string) bool {
+	addr, err := netip.ParseAddr(socketAddressHost(host))
+	return err == nil && addr.IsUnspecified()
+}
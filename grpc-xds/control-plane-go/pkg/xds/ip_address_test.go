@@ -0,0 +1,59 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import "testing"
+
+func TestSocketAddressHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "10.0.0.1", want: "10.0.0.1"},
+		{host: "fd00::1", want: "fd00::1"},
+		{host: "[fd00::1]", want: "fd00::1"},
+		{host: "[::]", want: "::"},
+		{host: "fd00:0:0:0:0:0:0:1", want: "fd00::1"},
+		{host: "greeter.default.svc.cluster.local", want: "greeter.default.svc.cluster.local"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := socketAddressHost(tt.host); got != tt.want {
+				t.Errorf("socketAddressHost(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnspecifiedAddress(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "0.0.0.0", want: true},
+		{host: "::", want: true},
+		{host: "[::]", want: true},
+		{host: "10.0.0.1", want: false},
+		{host: "::1", want: false},
+		{host: "localhost", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := isUnspecifiedAddress(tt.host); got != tt.want {
+				t.Errorf("isUnspecifiedAddress(%q) = %t, want %t", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// maxRingHashSize is the maximum ring size allowed by Envoy and gRPC.
+const maxRingHashSize = 8388608
+
+var (
+	errUnknownLBPolicy       = errors.New("unknown load balancing policy")
+	errInvalidRingHashSize   = fmt.Errorf("ring hash sizes must be positive integers up to %d", maxRingHashSize)
+	errRingHashMinExceedsMax = errors.New("ring hash minimum size must not exceed the maximum size")
+)
+
+// lbPolicies maps `lb-policy` annotation values to CDS Cluster load balancing policies.
+// gRPC clients support `ROUND_ROBIN`, `RING_HASH`, and `LEAST_REQUEST`.
+// [gRFC A42]: https://github.com/grpc/proposal/blob/master/A42-xds-ring-hash-lb-policy.md
+var lbPolicies = map[string]clusterv3.Cluster_LbPolicy{
+	"ROUND_ROBIN":   clusterv3.Cluster_ROUND_ROBIN,
+	"LEAST_REQUEST": clusterv3.Cluster_LEAST_REQUEST,
+	"RING_HASH":     clusterv3.Cluster_RING_HASH,
+	"RANDOM":        clusterv3.Cluster_RANDOM,
+	"MAGLEV":        clusterv3.Cluster_MAGLEV,
+}
+
+// LBPolicy configures the load balancing policy of a cluster.
+// An empty Policy means `ROUND_ROBIN`. Zero ring hash sizes are unset.
+type LBPolicy struct {
+	Policy          string
+	RingHashMinSize uint64
+	RingHashMaxSize uint64
+}
+
+// LBPolicyFromAnnotations reads the load balancing policy from Service annotations.
+// Returns an error for unknown policies and invalid ring hash sizes, so that the caller
+// can keep the previous valid load balancing policy.
+func LBPolicyFromAnnotations(logger logr.Logger, annotations map[string]string) (LBPolicy, error) {
+	var lbPolicy LBPolicy
+	if policy, exists := annotations[lbPolicyAnnotation]; exists {
+		policy = strings.ToUpper(strings.TrimSpace(policy))
+		if _, known := lbPolicies[policy]; !known {
+			return LBPolicy{}, fmt.Errorf("%w: %s=%s", errUnknownLBPolicy, lbPolicyAnnotation, annotations[lbPolicyAnnotation])
+		}
+		lbPolicy.Policy = policy
+	}
+	for key, size := range map[string]*uint64{
+		ringHashMinSizeAnnotation: &lbPolicy.RingHashMinSize,
+		ringHashMaxSizeAnnotation: &lbPolicy.RingHashMaxSize,
+	} {
+		value, exists := annotations[key]
+		if !exists {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || parsed == 0 || parsed > maxRingHashSize {
+			return LBPolicy{}, fmt.Errorf("%w: %s=%s", errInvalidRingHashSize, key, value)
+		}
+		*size = parsed
+	}
+	if lbPolicy.RingHashMinSize > 0 && lbPolicy.RingHashMaxSize > 0 && lbPolicy.RingHashMinSize > lbPolicy.RingHashMaxSize {
+		return LBPolicy{}, fmt.Errorf("%w: min=%d max=%d", errRingHashMinExceedsMax, lbPolicy.RingHashMinSize, lbPolicy.RingHashMaxSize)
+	}
+	if (lbPolicy.RingHashMinSize > 0 || lbPolicy.RingHashMaxSize > 0) && lbPolicy.Policy != "RING_HASH" {
+		logger.V(1).Info("Warning: ignoring ring hash size annotations, as the load balancing policy is not RING_HASH", "lbPolicy", lbPolicy.Policy)
+		lbPolicy.RingHashMinSize = 0
+		lbPolicy.RingHashMaxSize = 0
+	}
+	return lbPolicy, nil
+}
+
+func (l LBPolicy) Compare(m LBPolicy) int {
+	if l.Policy != m.Policy {
+		return strings.Compare(l.Policy, m.Policy)
+	}
+	if l.RingHashMinSize != m.RingHashMinSize {
+		return cmp.Compare(l.RingHashMinSize, m.RingHashMinSize)
+	}
+	return cmp.Compare(l.RingHashMaxSize, m.RingHashMaxSize)
+}
+
+// applyLBPolicy sets the load balancing policy of the cluster.
+// The cluster is left unchanged if the policy is empty.
+func applyLBPolicy(cluster *clusterv3.Cluster, l LBPolicy) {
+	policy, exists := lbPolicies[l.Policy]
+	if !exists {
+		return
+	}
+	cluster.LbPolicy = policy
+	if policy == clusterv3.Cluster_RING_HASH && (l.RingHashMinSize > 0 || l.RingHashMaxSize > 0) {
+		ringHashLbConfig := &clusterv3.Cluster_RingHashLbConfig{}
+		if l.RingHashMinSize > 0 {
+			ringHashLbConfig.MinimumRingSize = wrapperspb.UInt64(l.RingHashMinSize)
+		}
+		if l.RingHashMaxSize > 0 {
+			ringHashLbConfig.MaximumRingSize = wrapperspb.UInt64(l.RingHashMaxSize)
+		}
+		cluster.LbConfig = &clusterv3.Cluster_RingHashLbConfig_{
+			RingHashLbConfig: ringHashLbConfig,
+		}
+	}
+}
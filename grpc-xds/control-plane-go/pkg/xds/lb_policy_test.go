@@ -0,0 +1,149 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestLBPolicyFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        LBPolicy
+		wantErr     error
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        LBPolicy{},
+		},
+		{
+			name:        "policy is case insensitive",
+			annotations: map[string]string{lbPolicyAnnotation: " least_request "},
+			want:        LBPolicy{Policy: "LEAST_REQUEST"},
+		},
+		{
+			name: "ring hash sizes",
+			annotations: map[string]string{
+				lbPolicyAnnotation:        "RING_HASH",
+				ringHashMinSizeAnnotation: "1024",
+				ringHashMaxSizeAnnotation: "8388608",
+			},
+			want: LBPolicy{Policy: "RING_HASH", RingHashMinSize: 1024, RingHashMaxSize: 8388608},
+		},
+		{
+			name: "ring hash sizes are ignored for other policies",
+			annotations: map[string]string{
+				lbPolicyAnnotation:        "ROUND_ROBIN",
+				ringHashMinSizeAnnotation: "1024",
+			},
+			want: LBPolicy{Policy: "ROUND_ROBIN"},
+		},
+		{
+			name:        "unknown policy",
+			annotations: map[string]string{lbPolicyAnnotation: "WEIGHTED"},
+			wantErr:     errUnknownLBPolicy,
+		},
+		{
+			name: "zero ring hash size",
+			annotations: map[string]string{
+				lbPolicyAnnotation:        "RING_HASH",
+				ringHashMinSizeAnnotation: "0",
+			},
+			wantErr: errInvalidRingHashSize,
+		},
+		{
+			name: "ring hash size exceeds the maximum",
+			annotations: map[string]string{
+				lbPolicyAnnotation:        "RING_HASH",
+				ringHashMaxSizeAnnotation: "8388609",
+			},
+			wantErr: errInvalidRingHashSize,
+		},
+		{
+			name: "ring hash minimum size exceeds the maximum size",
+			annotations: map[string]string{
+				lbPolicyAnnotation:        "RING_HASH",
+				ringHashMinSizeAnnotation: "2048",
+				ringHashMaxSizeAnnotation: "1024",
+			},
+			wantErr: errRingHashMinExceedsMax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LBPolicyFromAnnotations(logr.Discard(), tt.annotations)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LBPolicyFromAnnotations() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LBPolicyFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLBPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		lbPolicy LBPolicy
+		want     *clusterv3.Cluster
+	}{
+		{
+			name:     "empty policy leaves the cluster unchanged",
+			lbPolicy: LBPolicy{},
+			want:     &clusterv3.Cluster{Name: "cluster"},
+		},
+		{
+			name:     "least request",
+			lbPolicy: LBPolicy{Policy: "LEAST_REQUEST"},
+			want:     &clusterv3.Cluster{Name: "cluster", LbPolicy: clusterv3.Cluster_LEAST_REQUEST},
+		},
+		{
+			name:     "ring hash without sizes",
+			lbPolicy: LBPolicy{Policy: "RING_HASH"},
+			want:     &clusterv3.Cluster{Name: "cluster", LbPolicy: clusterv3.Cluster_RING_HASH},
+		},
+		{
+			name:     "ring hash with maximum size",
+			lbPolicy: LBPolicy{Policy: "RING_HASH", RingHashMaxSize: 4096},
+			want: &clusterv3.Cluster{
+				Name:     "cluster",
+				LbPolicy: clusterv3.Cluster_RING_HASH,
+				LbConfig: &clusterv3.Cluster_RingHashLbConfig_{
+					RingHashLbConfig: &clusterv3.Cluster_RingHashLbConfig{
+						MaximumRingSize: wrapperspb.UInt64(4096),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &clusterv3.Cluster{Name: "cluster"}
+			applyLBPolicy(cluster, tt.lbPolicy)
+			if !proto.Equal(cluster, tt.want) {
+				t.Errorf("applyLBPolicy() cluster = %v, want %v", cluster, tt.want)
+			}
+		})
+	}
+}
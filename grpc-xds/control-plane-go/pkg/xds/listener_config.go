@@ -0,0 +1,134 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"time"
+
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	streamv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/stream/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	envoyAccessLoggersStdoutName = "envoy.access_loggers.stdout"
+)
+
+// ListenerConfig contains settings for the HTTP connection manager of LDS API listeners
+// that can be provided in a config file. Zero values are unset, so that xDS clients use their defaults.
+//
+// gRPC clients ignore most of these settings, but they apply to Envoy proxies that use the same listeners.
+type ListenerConfig struct {
+	HTTP2ProtocolOptions HTTP2ProtocolOptions `yaml:"http2ProtocolOptions"`
+	StreamIdleTimeout    time.Duration        `yaml:"streamIdleTimeout"`
+	RequestTimeout       time.Duration        `yaml:"requestTimeout"`
+	MaxRequestHeadersKB  uint32               `yaml:"maxRequestHeadersKb"`
+	// AccessLogFormat is an Envoy format string for access logs written to stdout. Empty disables access logs.
+	// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log/usage#format-strings
+	AccessLogFormat string `yaml:"accessLogFormat"`
+}
+
+// HTTP2ProtocolOptions of the HTTP connection manager.
+type HTTP2ProtocolOptions struct {
+	MaxConcurrentStreams        uint32 `yaml:"maxConcurrentStreams"`
+	InitialStreamWindowSize     uint32 `yaml:"initialStreamWindowSize"`
+	InitialConnectionWindowSize uint32 `yaml:"initialConnectionWindowSize"`
+}
+
+// applyListenerConfig sets the fields of the HTTP connection manager from the listener configuration.
+// A nil listener configuration leaves the HTTP connection manager unchanged.
+func applyListenerConfig(httpConnectionManager *hcmv3.HttpConnectionManager, listenerConfig *ListenerConfig) error {
+	if listenerConfig == nil {
+		return nil
+	}
+	if listenerConfig.HTTP2ProtocolOptions != (HTTP2ProtocolOptions{}) {
+		httpConnectionManager.Http2ProtocolOptions = createHTTP2ProtocolOptions(listenerConfig.HTTP2ProtocolOptions)
+	}
+	if listenerConfig.StreamIdleTimeout > 0 {
+		httpConnectionManager.StreamIdleTimeout = durationpb.New(listenerConfig.StreamIdleTimeout)
+	}
+	if listenerConfig.RequestTimeout > 0 {
+		httpConnectionManager.RequestTimeout = durationpb.New(listenerConfig.RequestTimeout)
+	}
+	if listenerConfig.MaxRequestHeadersKB > 0 {
+		httpConnectionManager.MaxRequestHeadersKb = wrapperspb.UInt32(listenerConfig.MaxRequestHeadersKB)
+	}
+	if listenerConfig.AccessLogFormat != "" {
+		accessLog, err := createStdoutAccessLog(listenerConfig.AccessLogFormat)
+		if err != nil {
+			return err
+		}
+		httpConnectionManager.AccessLog = []*accesslogv3.AccessLog{accessLog}
+	}
+	return nil
+}
+
+func createHTTP2ProtocolOptions(options HTTP2ProtocolOptions) *corev3.Http2ProtocolOptions {
+	http2ProtocolOptions := &corev3.Http2ProtocolOptions{}
+	if options.MaxConcurrentStreams > 0 {
+		http2ProtocolOptions.MaxConcurrentStreams = wrapperspb.UInt32(options.MaxConcurrentStreams)
+	}
+	if options.InitialStreamWindowSize > 0 {
+		http2ProtocolOptions.InitialStreamWindowSize = wrapperspb.UInt32(options.InitialStreamWindowSize)
+	}
+	if options.InitialConnectionWindowSize > 0 {
+		http2ProtocolOptions.InitialConnectionWindowSize = wrapperspb.UInt32(options.InitialConnectionWindowSize)
+	}
+	return http2ProtocolOptions
+}
+
+// createStdoutAccessLog returns an access log configuration that writes to stdout using the provided format.
+func createStdoutAccessLog(format string) (*accesslogv3.AccessLog, error) {
+	return createStdoutAccessLogWithFormat(createTextFormat(format))
+}
+
+// createStdoutAccessLogWithFormat returns an access log configuration that writes to stdout using the provided
+// format, or using the Envoy default format if the format is nil.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/access_loggers/stream/v3/stream.proto
+func createStdoutAccessLogWithFormat(logFormat *corev3.SubstitutionFormatString) (*accesslogv3.AccessLog, error) {
+	stdoutAccessLog := &streamv3.StdoutAccessLog{}
+	if logFormat != nil {
+		stdoutAccessLog.AccessLogFormat = &streamv3.StdoutAccessLog_LogFormat{
+			LogFormat: logFormat,
+		}
+	}
+	typedConfig, err := anypb.New(stdoutAccessLog)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshall StdoutAccessLog +%v into Any instance: %w", stdoutAccessLog, err)
+	}
+	return &accesslogv3.AccessLog{
+		Name: envoyAccessLoggersStdoutName,
+		ConfigType: &accesslogv3.AccessLog_TypedConfig{
+			TypedConfig: typedConfig,
+		},
+	}, nil
+}
+
+func createTextFormat(format string) *corev3.SubstitutionFormatString {
+	return &corev3.SubstitutionFormatString{
+		Format: &corev3.SubstitutionFormatString_TextFormatSource{
+			TextFormatSource: &corev3.DataSource{
+				Specifier: &corev3.DataSource_InlineString{
+					InlineString: format,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestApplyListenerConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		listenerConfig *ListenerConfig
+		want           *hcmv3.HttpConnectionManager
+	}{
+		{
+			name:           "nil listener configuration",
+			listenerConfig: nil,
+			want:           &hcmv3.HttpConnectionManager{StatPrefix: "greeter"},
+		},
+		{
+			name:           "empty listener configuration",
+			listenerConfig: &ListenerConfig{},
+			want:           &hcmv3.HttpConnectionManager{StatPrefix: "greeter"},
+		},
+		{
+			name: "timeouts, headers, and HTTP/2 options",
+			listenerConfig: &ListenerConfig{
+				HTTP2ProtocolOptions: HTTP2ProtocolOptions{MaxConcurrentStreams: 100},
+				StreamIdleTimeout:    5 * time.Minute,
+				RequestTimeout:       30 * time.Second,
+				MaxRequestHeadersKB:  96,
+			},
+			want: &hcmv3.HttpConnectionManager{
+				StatPrefix:           "greeter",
+				Http2ProtocolOptions: &corev3.Http2ProtocolOptions{MaxConcurrentStreams: wrapperspb.UInt32(100)},
+				StreamIdleTimeout:    durationpb.New(5 * time.Minute),
+				RequestTimeout:       durationpb.New(30 * time.Second),
+				MaxRequestHeadersKb:  wrapperspb.UInt32(96),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &hcmv3.HttpConnectionManager{StatPrefix: "greeter"}
+			if err := applyListenerConfig(got, tt.listenerConfig); err != nil {
+				t.Fatalf("applyListenerConfig() error = %v", err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("applyListenerConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyListenerConfigAccessLog(t *testing.T) {
+	got := &hcmv3.HttpConnectionManager{}
+	if err := applyListenerConfig(got, &ListenerConfig{AccessLogFormat: "%RESPONSE_CODE%\n"}); err != nil {
+		t.Fatalf("applyListenerConfig() error = %v", err)
+	}
+	if len(got.GetAccessLog()) != 1 || got.GetAccessLog()[0].GetName() != envoyAccessLoggersStdoutName {
+		t.Errorf("access logs = %v, want one %s access log", got.GetAccessLog(), envoyAccessLoggersStdoutName)
+	}
+}
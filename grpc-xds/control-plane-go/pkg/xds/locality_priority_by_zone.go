@@ -48,7 +48,7 @@ import (
 type LocalityPriorityByZone struct{}
 
 // BuildPriorityMap constructs the priority map for the provided zones, based on the zone of the requesting node.
-// Assumption: The nodeHash value (the first argument) is the zone name of the requesting node.
+// Assumption: The zone part of the node hash (the first argument) is the zone name of the requesting node.
 func (l LocalityPriorityByZone) BuildPriorityMap(nodeZone string, zonesToPrioritize []string) map[string]uint32 {
 	region := regionRegexp.FindString(nodeZone)
 	superRegion := superRegionRegexp.FindString(nodeZone)
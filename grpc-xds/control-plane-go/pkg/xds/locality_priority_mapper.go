@@ -16,8 +16,8 @@ package xds
 
 // LocalityPriorityMapper determines EDS ClusterLoadAssignment locality priorites.
 type LocalityPriorityMapper interface {
-	// BuildPriorityMap constructs the priority map for the provided zones, based on the node hash.
-	BuildPriorityMap(nodeHash string, zones []string) map[string]uint32
+	// BuildPriorityMap constructs the priority map for the provided zones, based on the zone part of the node hash.
+	BuildPriorityMap(nodeZone string, zones []string) map[string]uint32
 }
 
 // FixedLocalityPriority returns an empty map.
@@ -0,0 +1,257 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
+)
+
+// DefaultNACKRetryDelay is the default delay before the last accepted resources are set again after a NACK.
+const DefaultNACKRetryDelay = 5 * time.Second
+
+// NACKTracker handles xDS NACKs, i.e., DiscoveryRequests and DeltaDiscoveryRequests with `error_detail`.
+//
+// For each stream and resource type, it records the nonce and version of the latest response, so that a
+// NACK can be correlated with the rejected version. For delta streams, the version is the
+// `system_version_info` of the response, which is the snapshot version of the resource type.
+// It also records the resources of the latest version that xDS clients of each node hash accepted (ACKed).
+// After a NACK, and after the retry delay, if the snapshot for the node hash still has the rejected version,
+// the tracker sets a snapshot with the last accepted resources of that type, so that xDS clients that connect
+// later, or reconnect, do not receive the rejected resources. The next snapshot update from informer events
+// or resync pushes the current configuration again.
+//
+// The callbacks must be called from the xDS server callbacks, see `serverv3.CallbackFuncs`.
+type NACKTracker struct {
+	ctx    context.Context
+	logger logr.Logger
+	cache  *SnapshotCache
+	delay  time.Duration
+
+	mu sync.Mutex
+	// responses stores the nonce and version of the latest response for each stream and resource type.
+	responses map[nackStreamKey]nackResponse
+	// nacks stores the nonce and rejected version of the latest NACK, by node hash and resource type.
+	nacks map[string]map[string]nackResponse
+	// accepted stores the resources of the latest ACKed version, by node hash and resource type.
+	accepted map[string]map[string]cachev3.Resources
+}
+
+// nackStreamKey identifies a resource type on a stream. State-of-the-world and delta streams
+// have separate stream IDs, which can be equal.
+type nackStreamKey struct {
+	streamID int64
+	delta    bool
+	typeURL  string
+}
+
+type nackResponse struct {
+	nonce   string
+	version string
+}
+
+// NewNACKTracker creates a NACK tracker for the xDS resource cache. Retries stop when the context is done.
+func NewNACKTracker(ctx context.Context, logger logr.Logger, cache *SnapshotCache, delay time.Duration) *NACKTracker {
+	return &NACKTracker{
+		ctx:       ctx,
+		logger:    logger.WithValues("component", "nack-tracker"),
+		cache:     cache,
+		delay:     delay,
+		responses: map[nackStreamKey]nackResponse{},
+		nacks:     map[string]map[string]nackResponse{},
+		accepted:  map[string]map[string]cachev3.Resources{},
+	}
+}
+
+// OnStreamResponse records the nonce and version of a response sent on the stream.
+func (t *NACKTracker) OnStreamResponse(streamID int64, response *discoveryv3.DiscoveryResponse) {
+	if t == nil {
+		return
+	}
+	t.recordResponse(nackStreamKey{streamID: streamID, typeURL: response.GetTypeUrl()}, response.GetNonce(), response.GetVersionInfo())
+}
+
+// OnStreamDeltaResponse records the nonce and system version of a response sent on the delta stream.
+func (t *NACKTracker) OnStreamDeltaResponse(streamID int64, response *discoveryv3.DeltaDiscoveryResponse) {
+	if t == nil {
+		return
+	}
+	t.recordResponse(nackStreamKey{streamID: streamID, delta: true, typeURL: response.GetTypeUrl()}, response.GetNonce(), response.GetSystemVersionInfo())
+}
+
+// OnStreamRequest handles ACKs and NACKs of responses on the stream.
+// Initial requests, and requests for nonces of older responses, are ignored.
+func (t *NACKTracker) OnStreamRequest(streamID int64, request *discoveryv3.DiscoveryRequest) {
+	if t == nil {
+		return
+	}
+	t.handleRequest(nackStreamKey{streamID: streamID, typeURL: request.GetTypeUrl()}, request.GetNode(), request.GetResponseNonce(), request.GetErrorDetail())
+}
+
+// OnStreamDeltaRequest handles ACKs and NACKs of responses on the delta stream, in the same way as `OnStreamRequest()`.
+func (t *NACKTracker) OnStreamDeltaRequest(streamID int64, request *discoveryv3.DeltaDiscoveryRequest) {
+	if t == nil {
+		return
+	}
+	t.handleRequest(nackStreamKey{streamID: streamID, delta: true, typeURL: request.GetTypeUrl()}, request.GetNode(), request.GetResponseNonce(), request.GetErrorDetail())
+}
+
+// OnStreamClosed removes the responses recorded for the stream.
+func (t *NACKTracker) OnStreamClosed(streamID int64) {
+	if t == nil {
+		return
+	}
+	t.removeStream(streamID, false)
+}
+
+// OnDeltaStreamClosed removes the responses recorded for the delta stream.
+func (t *NACKTracker) OnDeltaStreamClosed(streamID int64) {
+	if t == nil {
+		return
+	}
+	t.removeStream(streamID, true)
+}
+
+func (t *NACKTracker) recordResponse(key nackStreamKey, nonce string, version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.responses[key] = nackResponse{
+		nonce:   nonce,
+		version: version,
+	}
+}
+
+func (t *NACKTracker) handleRequest(key nackStreamKey, node *corev3.Node, nonce string, errorDetail *status.Status) {
+	if nonce == "" {
+		return
+	}
+	nodeHash := t.cache.hash.ID(node)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	response, exists := t.responses[key]
+	if !exists || response.nonce != nonce {
+		return
+	}
+	if errorDetail == nil {
+		t.recordACK(nodeHash, key.typeURL, response.version)
+		return
+	}
+	reason := codes.Code(errorDetail.GetCode()).String()
+	t.logger.V(1).Info("Warning: xDS client rejected resources (NACK)",
+		"nodeHash", nodeHash, "nodeID", node.GetId(), "streamID", key.streamID, "delta", key.delta, "typeURL", key.typeURL,
+		"nonce", response.nonce, "rejectedVersion", response.version, "reason", reason, "message", errorDetail.GetMessage())
+	metrics.XDSNACK(key.typeURL, reason)
+	if t.nacks[nodeHash] == nil {
+		t.nacks[nodeHash] = map[string]nackResponse{}
+	}
+	if previous, exists := t.nacks[nodeHash][key.typeURL]; exists && previous.version == response.version {
+		// A retry for this version is already scheduled, e.g., after a NACK from another xDS client.
+		return
+	}
+	t.nacks[nodeHash][key.typeURL] = response
+	time.AfterFunc(t.delay, func() {
+		t.retry(nodeHash, key.typeURL, response)
+	})
+}
+
+func (t *NACKTracker) removeStream(streamID int64, delta bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.responses {
+		if key.streamID == streamID && key.delta == delta {
+			delete(t.responses, key)
+		}
+	}
+}
+
+// recordACK stores the resources of the accepted version, if the current snapshot has that version.
+// Must be called with the mutex held.
+func (t *NACKTracker) recordACK(nodeHash string, typeURL string, version string) {
+	if nack, exists := t.nacks[nodeHash][typeURL]; exists && nack.version == version {
+		return
+	}
+	delete(t.nacks[nodeHash], typeURL)
+	snapshot, err := t.cache.delegate.GetSnapshot(nodeHash)
+	if err != nil {
+		return
+	}
+	cacheSnapshot, ok := snapshot.(*cachev3.Snapshot)
+	if !ok || cacheSnapshot.GetVersion(typeURL) != version {
+		return
+	}
+	if t.accepted[nodeHash] == nil {
+		t.accepted[nodeHash] = map[string]cachev3.Resources{}
+	}
+	t.accepted[nodeHash][typeURL] = cacheSnapshot.Resources[cachev3.GetResponseType(typeURL)]
+}
+
+// retry sets a snapshot with the last accepted resources of the resource type for the node hash,
+// unless the NACK was superseded by a newer NACK or an ACK, or the snapshot changed since the NACK.
+func (t *NACKTracker) retry(nodeHash string, typeURL string, nack nackResponse) {
+	if t.ctx.Err() != nil {
+		return
+	}
+	logger := t.logger.WithValues("nodeHash", nodeHash, "typeURL", typeURL, "rejectedVersion", nack.version)
+	reverted, ok := t.revertedSnapshot(logger, nodeHash, typeURL, nack)
+	if !ok {
+		return
+	}
+	// Not holding the mutex, as setting the snapshot sends responses, which call `OnStreamResponse()`.
+	if err := t.cache.setSnapshot(nodeHash, reverted, []string{typeURL}, time.Now()); err != nil {
+		logger.Error(err, "Could not set the last accepted resources again after NACK")
+	}
+}
+
+// revertedSnapshot returns a copy of the current snapshot for the node hash, with the last accepted
+// resources of the resource type, and false if the resources should not be set again.
+func (t *NACKTracker) revertedSnapshot(logger logr.Logger, nodeHash string, typeURL string, nack nackResponse) (*cachev3.Snapshot, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if current, exists := t.nacks[nodeHash][typeURL]; !exists || current != nack {
+		return nil, false
+	}
+	delete(t.nacks[nodeHash], typeURL)
+	accepted, exists := t.accepted[nodeHash][typeURL]
+	if !exists {
+		logger.V(1).Info("Warning: No accepted resources to set again after NACK")
+		return nil, false
+	}
+	snapshot, err := t.cache.delegate.GetSnapshot(nodeHash)
+	if err != nil {
+		return nil, false
+	}
+	cacheSnapshot, ok := snapshot.(*cachev3.Snapshot)
+	if !ok || cacheSnapshot.GetVersion(typeURL) != nack.version {
+		logger.V(2).Info("Snapshot changed after NACK, not setting the accepted resources again")
+		return nil, false
+	}
+	logger.V(2).Info("Setting the last accepted resources again after NACK", "acceptedVersion", accepted.Version)
+	// Resources is an array, so this copies the resources of all types, and the current snapshot is unchanged.
+	reverted := &cachev3.Snapshot{
+		Resources: cacheSnapshot.Resources,
+	}
+	reverted.Resources[cachev3.GetResponseType(typeURL)] = accepted
+	return reverted, true
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// nackTestStream sends responses and requests for CDS to the NACK tracker, as the xDS server callbacks do.
+type nackTestStream struct {
+	name    string
+	respond func(tracker *NACKTracker, nonce string, version string)
+	request func(tracker *NACKTracker, nonce string, errorDetail *status.Status)
+}
+
+var nackTestStreams = []nackTestStream{
+	{
+		name: "state of the world",
+		respond: func(tracker *NACKTracker, nonce string, version string) {
+			tracker.OnStreamResponse(1, &discoveryv3.DiscoveryResponse{TypeUrl: resource.ClusterType, Nonce: nonce, VersionInfo: version})
+		},
+		request: func(tracker *NACKTracker, nonce string, errorDetail *status.Status) {
+			tracker.OnStreamRequest(1, &discoveryv3.DiscoveryRequest{Node: testNode, TypeUrl: resource.ClusterType, ResponseNonce: nonce, ErrorDetail: errorDetail})
+		},
+	},
+	{
+		name: "delta",
+		respond: func(tracker *NACKTracker, nonce string, version string) {
+			tracker.OnStreamDeltaResponse(1, &discoveryv3.DeltaDiscoveryResponse{TypeUrl: resource.ClusterType, Nonce: nonce, SystemVersionInfo: version})
+		},
+		request: func(tracker *NACKTracker, nonce string, errorDetail *status.Status) {
+			tracker.OnStreamDeltaRequest(1, &discoveryv3.DeltaDiscoveryRequest{Node: testNode, TypeUrl: resource.ClusterType, ResponseNonce: nonce, ErrorDetail: errorDetail})
+		},
+	},
+}
+
+var nackErrorDetail = &status.Status{Code: int32(codes.InvalidArgument), Message: "rejected"}
+
+func TestNACKTrackerRestoresAcceptedResources(t *testing.T) {
+	for _, s := range nackTestStreams {
+		t.Run(s.name, func(t *testing.T) {
+			c, _ := newTestSnapshotCache(t)
+			tracker := NewNACKTracker(context.Background(), logr.Discard(), c, 10*time.Millisecond)
+			app := testGRPCApplication("app", 3)
+			v1 := updateAndGetClusters(t, c, app)
+			s.respond(tracker, "1", v1.version)
+			s.request(tracker, "1", nil)
+
+			app.OutlierDetection = OutlierDetection{ConsecutiveErrors: 5}
+			v2 := updateAndGetClusters(t, c, app)
+			if v2.version == v1.version {
+				t.Fatalf("CDS version did not change after update: %s", v2.version)
+			}
+			s.respond(tracker, "2", v2.version)
+			s.request(tracker, "2", nackErrorDetail)
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !resourcesEqual(currentClusters(t, c), v1.resources) {
+				if time.Now().After(deadline) {
+					t.Fatal("timed out waiting for the accepted CDS resources to be restored after NACK")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestNACKTrackerKeepsNewerSnapshot(t *testing.T) {
+	for _, s := range nackTestStreams {
+		t.Run(s.name, func(t *testing.T) {
+			c, _ := newTestSnapshotCache(t)
+			delay := 50 * time.Millisecond
+			tracker := NewNACKTracker(context.Background(), logr.Discard(), c, delay)
+			app := testGRPCApplication("app", 3)
+			v1 := updateAndGetClusters(t, c, app)
+			s.respond(tracker, "1", v1.version)
+			s.request(tracker, "1", nil)
+
+			app.OutlierDetection = OutlierDetection{ConsecutiveErrors: 5}
+			v2 := updateAndGetClusters(t, c, app)
+			s.respond(tracker, "2", v2.version)
+			s.request(tracker, "2", nackErrorDetail)
+
+			// A newer snapshot before the retry delay supersedes the NACK.
+			app.OutlierDetection = OutlierDetection{ConsecutiveErrors: 7}
+			v3 := updateAndGetClusters(t, c, app)
+			time.Sleep(4 * delay)
+			if !resourcesEqual(currentClusters(t, c), v3.resources) {
+				t.Error("CDS resources changed after NACK, want the newer snapshot to be kept")
+			}
+		})
+	}
+}
+
+type testClusters struct {
+	version   string
+	resources []types.Resource
+}
+
+// updateAndGetClusters updates the gRPC application in the cache, and returns the CDS version and resources of the new snapshot.
+func updateAndGetClusters(t *testing.T, c *SnapshotCache, app GRPCApplication) testClusters {
+	t.Helper()
+	if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", []GRPCApplication{app}); err != nil {
+		t.Fatalf("UpdateResources(): %v", err)
+	}
+	snapshot, err := c.delegate.GetSnapshot(testZone)
+	if err != nil {
+		t.Fatalf("GetSnapshot(): %v", err)
+	}
+	var clusters []types.Resource
+	for _, cluster := range snapshot.GetResources(resource.ClusterType) {
+		clusters = append(clusters, cluster)
+	}
+	return testClusters{
+		version:   snapshot.GetVersion(resource.ClusterType),
+		resources: clusters,
+	}
+}
+
+func currentClusters(t *testing.T, c *SnapshotCache) map[string]types.Resource {
+	t.Helper()
+	snapshot, err := c.delegate.GetSnapshot(testZone)
+	if err != nil {
+		t.Fatalf("GetSnapshot(): %v", err)
+	}
+	return snapshot.GetResources(resource.ClusterType)
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"slices"
+	"sync"
+)
+
+// comparer is implemented by configuration types that can be sorted and compared for changes.
+type comparer[T any] interface {
+	Compare(other T) int
+}
+
+// namespacedCache stores configuration from Kubernetes resources other than EndpointSlices,
+// e.g., custom resources. The key is `<kubecontext>/<namespace>`, as for `GRPCApplicationCache`.
+type namespacedCache[T comparer[T]] struct {
+	mu    sync.RWMutex
+	cache map[string][]T
+}
+
+func newNamespacedCache[T comparer[T]]() *namespacedCache[T] {
+	return &namespacedCache[T]{
+		cache: map[string][]T{},
+	}
+}
+
+// Put returns true iff the update changed the cache.
+func (c *namespacedCache[T]) Put(kubecontextName string, namespace string, values []T) bool {
+	if values == nil {
+		values = []T{}
+	}
+	slices.SortFunc(values, func(a T, b T) int {
+		return a.Compare(b)
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := key(kubecontextName, namespace)
+	oldValues := c.cache[key]
+	c.cache[key] = values
+	return !slices.EqualFunc(oldValues, values, func(a T, b T) bool {
+		return a.Compare(b) == 0
+	})
+}
+
+// GetAll returns the values for all keys, sorted, so that snapshots are deterministic.
+func (c *namespacedCache[T]) GetAll() []T {
+	values := []T{}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, valuesForKey := range c.cache {
+		values = append(values, valuesForKey...)
+	}
+	slices.SortFunc(values, func(a T, b T) int {
+		return a.Compare(b)
+	})
+	return values
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+)
+
+// nodeHashGroupSeparator separates the node group from the rest of node hashes from `NodeGroupHash`.
+// Neither zone names, Kubernetes namespace names, nor node group names contain this character.
+const nodeHashGroupSeparator = "@"
+
+var (
+	errNodeGroupConflict  = errors.New("node group selectors conflict")
+	errDuplicateNodeGroup = errors.New("duplicate node group")
+	errInvalidNodeGroup   = errors.New("invalid node group")
+)
+
+// NodeGroup is a group of xDS clients, selected by node metadata, that connect to the
+// xDS management server on a separate port, e.g., gateway Envoy proxies.
+type NodeGroup struct {
+	// Name of the group, e.g., `gateway`.
+	Name string `yaml:"name"`
+	// Port of the xDS management server listener for the group.
+	Port int `yaml:"port"`
+	// NodeSelector lists node metadata fields and their string values, which must all match.
+	NodeSelector map[string]string `yaml:"nodeSelector"`
+	// Namespaces is optional. If present, snapshots for the group only contain resources from these namespaces.
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// Matches returns true if the node metadata contains all the fields of the node selector, with the same values.
+func (g NodeGroup) Matches(node *corev3.Node) bool {
+	fields := node.GetMetadata().GetFields()
+	for key, value := range g.NodeSelector {
+		field, exists := fields[key]
+		if !exists || field.GetStringValue() != value {
+			return false
+		}
+	}
+	return true
+}
+
+// conflicts returns true if a node could match the selectors of both groups,
+// i.e., if no field in both selectors has different values.
+func (g NodeGroup) conflicts(other NodeGroup) bool {
+	for key, value := range g.NodeSelector {
+		if otherValue, exists := other.NodeSelector[key]; exists && otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateNodeGroups returns an error if the node groups have empty or duplicate names or ports,
+// empty selectors, or if a node could match the selectors of more than one group.
+func ValidateNodeGroups(nodeGroups []NodeGroup) error {
+	for i, group := range nodeGroups {
+		if group.Name == "" || strings.ContainsAny(group.Name, nodeHashGroupSeparator+nodeHashScopeSeparator) {
+			return fmt.Errorf("%w: name=%q must not be empty, and must not contain %q or %q", errInvalidNodeGroup, group.Name, nodeHashGroupSeparator, nodeHashScopeSeparator)
+		}
+		if group.Port <= 0 || group.Port > 65535 {
+			return fmt.Errorf("%w: name=%s port=%d", errInvalidNodeGroup, group.Name, group.Port)
+		}
+		if len(group.NodeSelector) == 0 {
+			return fmt.Errorf("%w: name=%s has an empty nodeSelector", errInvalidNodeGroup, group.Name)
+		}
+		for _, other := range nodeGroups[:i] {
+			if group.Name == other.Name || group.Port == other.Port {
+				return fmt.Errorf("%w: name=%s port=%d and name=%s port=%d", errDuplicateNodeGroup, other.Name, other.Port, group.Name, group.Port)
+			}
+			if group.conflicts(other) {
+				return fmt.Errorf("%w: a node can match both name=%s nodeSelector=%v and name=%s nodeSelector=%v", errNodeGroupConflict, other.Name, other.NodeSelector, group.Name, group.NodeSelector)
+			}
+		}
+	}
+	return nil
+}
+
+// NodeGroupHash appends the name of the matching node group to the node hash from the delegate,
+// e.g., `us-central1-a@gateway`, so that xDS clients in different node groups access different
+// cache snapshots. Nodes that do not match any group use the node hash from the delegate.
+//
+// `ValidateNodeGroups()` ensures that a node matches at most one group.
+type NodeGroupHash struct {
+	Groups   []NodeGroup
+	Delegate cachev3.NodeHash
+}
+
+var _ cachev3.NodeHash = &NodeGroupHash{}
+
+func (h NodeGroupHash) ID(node *corev3.Node) string {
+	nodeHash := h.Delegate.ID(node)
+	if group, found := FindNodeGroup(h.Groups, node); found {
+		return nodeHash + nodeHashGroupSeparator + group.Name
+	}
+	return nodeHash
+}
+
+// FindNodeGroup returns the node group with a selector that matches the node, and false if there is none.
+func FindNodeGroup(nodeGroups []NodeGroup, node *corev3.Node) (NodeGroup, bool) {
+	for _, group := range nodeGroups {
+		if group.Matches(node) {
+			return group, true
+		}
+	}
+	return NodeGroup{}, false
+}
+
+// splitNodeGroup returns the node hash without the node group, and the name of the node group.
+// The node group is empty for node hashes without a node group.
+func splitNodeGroup(nodeHash string) (string, string) {
+	nodeHashWithoutGroup, group, _ := strings.Cut(nodeHash, nodeHashGroupSeparator)
+	return nodeHashWithoutGroup, group
+}
+
+// SetNodeGroups replaces the node groups, so that snapshots for node hashes of a group with
+// namespaces only contain resources from those namespaces. Must be called before xDS clients connect.
+func (c *SnapshotCache) SetNodeGroups(nodeGroups []NodeGroup) {
+	namespacesByGroup := make(map[string][]string, len(nodeGroups))
+	for _, group := range nodeGroups {
+		if len(group.Namespaces) > 0 {
+			namespacesByGroup[group.Name] = slices.Clone(group.Namespaces)
+		}
+	}
+	c.nodeGroupNamespaces = namespacesByGroup
+}
+
+// namespaceInScope returns true if resources from the namespace belong in the snapshot for the node hash,
+// based on the scope of the node hash, see `ScopedZoneHash`, and the namespaces of its node group, if any.
+// An empty namespace is in scope for all node hashes.
+func (c *SnapshotCache) namespaceInScope(nodeHash string, namespace string) bool {
+	if !nodeHashInScope(nodeHash, namespace) {
+		return false
+	}
+	_, group := splitNodeGroup(nodeHash)
+	namespaces, exists := c.nodeGroupNamespaces[group]
+	return !exists || namespace == "" || slices.Contains(namespaces, namespace)
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func testNodeWithMetadata(fields map[string]string) *corev3.Node {
+	metadata := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	for key, value := range fields {
+		metadata.Fields[key] = structpb.NewStringValue(value)
+	}
+	return &corev3.Node{Id: "node", Locality: &corev3.Locality{Zone: testZone}, Metadata: metadata}
+}
+
+func TestValidateNodeGroups(t *testing.T) {
+	gateway := NodeGroup{Name: "gateway", Port: 50052, NodeSelector: map[string]string{"role": "gateway"}}
+	tests := []struct {
+		name       string
+		nodeGroups []NodeGroup
+		wantErr    error
+	}{
+		{name: "no groups"},
+		{
+			name: "disjoint selectors",
+			nodeGroups: []NodeGroup{
+				gateway,
+				{Name: "egress", Port: 50053, NodeSelector: map[string]string{"role": "egress"}},
+			},
+		},
+		{name: "empty name", nodeGroups: []NodeGroup{{Port: 50052, NodeSelector: gateway.NodeSelector}}, wantErr: errInvalidNodeGroup},
+		{name: "name with separator", nodeGroups: []NodeGroup{{Name: "a@b", Port: 50052, NodeSelector: gateway.NodeSelector}}, wantErr: errInvalidNodeGroup},
+		{name: "invalid port", nodeGroups: []NodeGroup{{Name: "gateway", Port: 65536, NodeSelector: gateway.NodeSelector}}, wantErr: errInvalidNodeGroup},
+		{name: "empty selector", nodeGroups: []NodeGroup{{Name: "gateway", Port: 50052}}, wantErr: errInvalidNodeGroup},
+		{
+			name:       "duplicate name",
+			nodeGroups: []NodeGroup{gateway, {Name: "gateway", Port: 50053, NodeSelector: map[string]string{"role": "egress"}}},
+			wantErr:    errDuplicateNodeGroup,
+		},
+		{
+			name:       "duplicate port",
+			nodeGroups: []NodeGroup{gateway, {Name: "egress", Port: 50052, NodeSelector: map[string]string{"role": "egress"}}},
+			wantErr:    errDuplicateNodeGroup,
+		},
+		{
+			name:       "overlapping selectors",
+			nodeGroups: []NodeGroup{gateway, {Name: "edge", Port: 50053, NodeSelector: map[string]string{"tier": "edge"}}},
+			wantErr:    errNodeGroupConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNodeGroups(tt.nodeGroups); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateNodeGroups() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNodeGroupMatches(t *testing.T) {
+	group := NodeGroup{Name: "gateway", NodeSelector: map[string]string{"role": "gateway", "tier": "edge"}}
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     bool
+	}{
+		{name: "all fields match", metadata: map[string]string{"role": "gateway", "tier": "edge", "other": "x"}, want: true},
+		{name: "different value", metadata: map[string]string{"role": "gateway", "tier": "internal"}, want: false},
+		{name: "missing field", metadata: map[string]string{"role": "gateway"}, want: false},
+		{name: "no metadata", metadata: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := group.Matches(testNodeWithMetadata(tt.metadata)); got != tt.want {
+				t.Errorf("Matches() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeGroupHashID(t *testing.T) {
+	hash := NodeGroupHash{
+		Groups:   []NodeGroup{{Name: "gateway", Port: 50052, NodeSelector: map[string]string{"role": "gateway"}}},
+		Delegate: ZoneHash{},
+	}
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     string
+	}{
+		{name: "node in group", metadata: map[string]string{"role": "gateway"}, want: testZone + "@gateway"},
+		{name: "node without group", metadata: map[string]string{"role": "sidecar"}, want: testZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeHash := hash.ID(testNodeWithMetadata(tt.metadata))
+			if nodeHash != tt.want {
+				t.Errorf("ID() = %q, want %q", nodeHash, tt.want)
+			}
+			if zone, _ := splitNodeGroup(nodeHash); zone != testZone {
+				t.Errorf("splitNodeGroup(%q) node hash = %q, want %q", nodeHash, zone, testZone)
+			}
+		})
+	}
+}
+
+func TestNamespaceInScope(t *testing.T) {
+	c := &SnapshotCache{}
+	c.SetNodeGroups([]NodeGroup{
+		{Name: "gateway", Namespaces: []string{"foo"}},
+		{Name: "egress"},
+	})
+	tests := []struct {
+		name      string
+		nodeHash  string
+		namespace string
+		want      bool
+	}{
+		{name: "node hash without group", nodeHash: "us-central1-a", namespace: "bar", want: true},
+		{name: "group namespace", nodeHash: "us-central1-a@gateway", namespace: "foo", want: true},
+		{name: "namespace outside group", nodeHash: "us-central1-a@gateway", namespace: "bar", want: false},
+		{name: "empty namespace", nodeHash: "us-central1-a@gateway", namespace: "", want: true},
+		{name: "group without namespaces", nodeHash: "us-central1-a@egress", namespace: "bar", want: true},
+		{name: "namespace outside scope", nodeHash: "us-central1-a/bar@gateway", namespace: "foo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.namespaceInScope(tt.nodeHash, tt.namespace); got != tt.want {
+				t.Errorf("namespaceInScope(%q, %q) = %t, want %t", tt.nodeHash, tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
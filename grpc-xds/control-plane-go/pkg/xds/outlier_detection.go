@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// OutlierDetection configures ejection of unhealthy hosts from a cluster.
+// Zero values are unset, so that xDS clients use their defaults.
+type OutlierDetection struct {
+	ConsecutiveErrors uint32
+	Interval          time.Duration
+	BaseEjectionTime  time.Duration
+}
+
+// OutlierDetectionFromAnnotations reads the outlier detection configuration from Service annotations.
+// Annotations with invalid values are logged as warnings and ignored.
+func OutlierDetectionFromAnnotations(logger logr.Logger, annotations map[string]string) OutlierDetection {
+	var outlierDetection OutlierDetection
+	if consecutiveErrors, ok := uint32Annotation(logger, annotations, outlierConsecutiveErrorsAnnotation); ok {
+		outlierDetection.ConsecutiveErrors = consecutiveErrors
+	}
+	if interval, ok := durationAnnotation(logger, annotations, outlierIntervalAnnotation); ok {
+		outlierDetection.Interval = interval
+	}
+	if baseEjectionTime, ok := durationAnnotation(logger, annotations, outlierBaseEjectionTimeAnnotation); ok {
+		outlierDetection.BaseEjectionTime = baseEjectionTime
+	}
+	return outlierDetection
+}
+
+func (o OutlierDetection) Compare(p OutlierDetection) int {
+	if o.ConsecutiveErrors != p.ConsecutiveErrors {
+		return cmp.Compare(o.ConsecutiveErrors, p.ConsecutiveErrors)
+	}
+	if o.Interval != p.Interval {
+		return cmp.Compare(o.Interval, p.Interval)
+	}
+	return cmp.Compare(o.BaseEjectionTime, p.BaseEjectionTime)
+}
+
+// createOutlierDetection returns nil if no outlier detection fields are set.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/outlier_detection.proto
+func createOutlierDetection(o OutlierDetection) *clusterv3.OutlierDetection {
+	if o == (OutlierDetection{}) {
+		return nil
+	}
+	outlierDetection := &clusterv3.OutlierDetection{}
+	if o.ConsecutiveErrors > 0 {
+		outlierDetection.Consecutive_5Xx = wrapperspb.UInt32(o.ConsecutiveErrors)
+	}
+	if o.Interval > 0 {
+		outlierDetection.Interval = durationpb.New(o.Interval)
+	}
+	if o.BaseEjectionTime > 0 {
+		outlierDetection.BaseEjectionTime = durationpb.New(o.BaseEjectionTime)
+	}
+	return outlierDetection
+}
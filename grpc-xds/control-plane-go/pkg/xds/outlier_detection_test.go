@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestOutlierDetectionFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        OutlierDetection
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        OutlierDetection{},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				outlierConsecutiveErrorsAnnotation: "5",
+				outlierIntervalAnnotation:          "10s",
+				outlierBaseEjectionTimeAnnotation:  "30s",
+			},
+			want: OutlierDetection{
+				ConsecutiveErrors: 5,
+				Interval:          10 * time.Second,
+				BaseEjectionTime:  30 * time.Second,
+			},
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				outlierConsecutiveErrorsAnnotation: "-1",
+				outlierIntervalAnnotation:          "10",
+				outlierBaseEjectionTimeAnnotation:  "-30s",
+			},
+			want: OutlierDetection{},
+		},
+		{
+			name: "zero consecutive errors",
+			annotations: map[string]string{
+				outlierConsecutiveErrorsAnnotation: "0",
+				outlierIntervalAnnotation:          "500ms",
+			},
+			want: OutlierDetection{Interval: 500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OutlierDetectionFromAnnotations(logr.Discard(), tt.annotations); got != tt.want {
+				t.Errorf("OutlierDetectionFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOutlierDetection(t *testing.T) {
+	tests := []struct {
+		name             string
+		outlierDetection OutlierDetection
+		want             *clusterv3.OutlierDetection
+	}{
+		{
+			name:             "unset",
+			outlierDetection: OutlierDetection{},
+			want:             nil,
+		},
+		{
+			name:             "consecutive errors only",
+			outlierDetection: OutlierDetection{ConsecutiveErrors: 3},
+			want:             &clusterv3.OutlierDetection{Consecutive_5Xx: wrapperspb.UInt32(3)},
+		},
+		{
+			name: "all fields",
+			outlierDetection: OutlierDetection{
+				ConsecutiveErrors: 5,
+				Interval:          10 * time.Second,
+				BaseEjectionTime:  30 * time.Second,
+			},
+			want: &clusterv3.OutlierDetection{
+				Consecutive_5Xx:  wrapperspb.UInt32(5),
+				Interval:         durationpb.New(10 * time.Second),
+				BaseEjectionTime: durationpb.New(30 * time.Second),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createOutlierDetection(tt.outlierDetection); !proto.Equal(got, tt.want) {
+				t.Errorf("createOutlierDetection() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitconfigv3 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	envoyFilterHTTPRateLimitName = "envoy.filters.http.ratelimit"
+
+	// DefaultRateLimitTimeout is the default timeout of calls to the rate limit service,
+	// the same as the Envoy default.
+	DefaultRateLimitTimeout = 20 * time.Millisecond
+
+	// maxRateLimitStage is the highest stage of rate limit filters and route rate limits allowed by Envoy.
+	maxRateLimitStage = 10
+)
+
+// RateLimitPolicy is the configuration from a `RateLimitPolicy` custom resource.
+// It adds a `ratelimit` HTTP filter to the LDS API listeners of the gRPC applications in the
+// same namespace with Service labels that match the selector, and rate limit actions to the
+// routes of their RDS route configurations.
+type RateLimitPolicy struct {
+	Namespace string
+	Name      string
+	// RateLimitServiceCluster is the name of the cluster of the rate limit service.
+	RateLimitServiceCluster string
+	// Domain of the rate limit service configuration for requests from this policy.
+	Domain string
+	// Timeout of calls to the rate limit service.
+	Timeout time.Duration
+	// FailureModeDeny rejects requests if the rate limit service is unavailable.
+	FailureModeDeny bool
+	// Actions create the descriptor entries that the rate limit service receives for each request.
+	Actions []RateLimitAction
+	// MatchLabels selects the gRPC applications by the labels of their Services.
+	// An empty selector matches all gRPC applications in the namespace.
+	MatchLabels map[string]string
+}
+
+// RateLimitAction is a `header_value_match` rate limit action, i.e., a descriptor entry with
+// the key `header_match` and the descriptor value, if the request headers match the header matchers,
+// or, with ExpectMatch false, if they do not match.
+type RateLimitAction struct {
+	DescriptorValue string
+	ExpectMatch     bool
+	// HeaderMatchers are sorted, see `HeaderMatcher.Compare()`.
+	HeaderMatchers []HeaderMatcher
+}
+
+func (p RateLimitPolicy) Compare(q RateLimitPolicy) int {
+	if p.Namespace != q.Namespace {
+		return strings.Compare(p.Namespace, q.Namespace)
+	}
+	if p.Name != q.Name {
+		return strings.Compare(p.Name, q.Name)
+	}
+	if p.RateLimitServiceCluster != q.RateLimitServiceCluster {
+		return strings.Compare(p.RateLimitServiceCluster, q.RateLimitServiceCluster)
+	}
+	if p.Domain != q.Domain {
+		return strings.Compare(p.Domain, q.Domain)
+	}
+	if p.Timeout != q.Timeout {
+		return cmp.Compare(p.Timeout, q.Timeout)
+	}
+	if p.FailureModeDeny != q.FailureModeDeny {
+		if p.FailureModeDeny {
+			return 1
+		}
+		return -1
+	}
+	if c := slices.CompareFunc(p.Actions, q.Actions, func(a RateLimitAction, b RateLimitAction) int {
+		return a.Compare(b)
+	}); c != 0 {
+		return c
+	}
+	return compareLabels(p.MatchLabels, q.MatchLabels)
+}
+
+func (a RateLimitAction) Compare(b RateLimitAction) int {
+	if a.DescriptorValue != b.DescriptorValue {
+		return strings.Compare(a.DescriptorValue, b.DescriptorValue)
+	}
+	if a.ExpectMatch != b.ExpectMatch {
+		if a.ExpectMatch {
+			return 1
+		}
+		return -1
+	}
+	return slices.CompareFunc(a.HeaderMatchers, b.HeaderMatchers, func(m HeaderMatcher, n HeaderMatcher) int {
+		return m.Compare(n)
+	})
+}
+
+// Matches returns true if the gRPC application is in the namespace of the policy,
+// and the labels of its Service match the selector of the policy.
+func (p RateLimitPolicy) Matches(app GRPCApplication) bool {
+	if p.Namespace != app.Namespace {
+		return false
+	}
+	for key, value := range p.MatchLabels {
+		if labelValue, exists := app.Labels[key]; !exists || labelValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingRateLimitPolicies returns the policies that match the gRPC application, in the order of the policies,
+// up to the number of stages supported by Envoy. The index of a policy in the result is its stage, so that the
+// `ratelimit` filter of each policy only applies the rate limit actions of the same policy.
+func matchingRateLimitPolicies(policies []RateLimitPolicy, app GRPCApplication) []RateLimitPolicy {
+	var matching []RateLimitPolicy
+	for _, policy := range policies {
+		if policy.Matches(app) && len(matching) <= maxRateLimitStage {
+			matching = append(matching, policy)
+		}
+	}
+	return matching
+}
+
+// createRateLimitFilters returns the `ratelimit` HTTP filters for the API listener of the gRPC application,
+// one per matching policy, with the stage of the policy.
+func createRateLimitFilters(policies []RateLimitPolicy, app GRPCApplication) ([]*hcmv3.HttpFilter, error) {
+	var filters []*hcmv3.HttpFilter
+	for stage, policy := range matchingRateLimitPolicies(policies, app) {
+		typedConfig, err := anypb.New(&ratelimitv3.RateLimit{
+			Domain:          policy.Domain,
+			Stage:           uint32(stage),
+			Timeout:         durationpb.New(policy.Timeout),
+			FailureModeDeny: policy.FailureModeDeny,
+			RateLimitService: &ratelimitconfigv3.RateLimitServiceConfig{
+				GrpcService: &corev3.GrpcService{
+					TargetSpecifier: &corev3.GrpcService_EnvoyGrpc_{
+						EnvoyGrpc: &corev3.GrpcService_EnvoyGrpc{
+							ClusterName: policy.RateLimitServiceCluster,
+						},
+					},
+				},
+				TransportApiVersion: corev3.ApiVersion_V3,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshall RateLimit HTTP filter typedConfig for RateLimitPolicy %s/%s into Any instance: %w", policy.Namespace, policy.Name, err)
+		}
+		filters = append(filters, &hcmv3.HttpFilter{
+			// Multiple ratelimit HTTP filters must have distinct names.
+			Name: fmt.Sprintf("%s.%s.%s", envoyFilterHTTPRateLimitName, policy.Namespace, policy.Name),
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{
+				TypedConfig: typedConfig,
+			},
+		})
+	}
+	return filters, nil
+}
+
+// applyRateLimits adds the rate limit actions of the matching policies to the routes of the route configuration,
+// with the same stages as the `ratelimit` HTTP filters from `createRateLimitFilters()`.
+// Routes for header-based routing are copies of these routes, so they get the same rate limits.
+func applyRateLimits(routeConfiguration *routev3.RouteConfiguration, policies []RateLimitPolicy, app GRPCApplication) {
+	var rateLimits []*routev3.RateLimit
+	for stage, policy := range matchingRateLimitPolicies(policies, app) {
+		rateLimits = append(rateLimits, &routev3.RateLimit{
+			Stage:   wrapperspb.UInt32(uint32(stage)),
+			Actions: createRateLimitActions(policy.Actions),
+		})
+	}
+	if len(rateLimits) == 0 {
+		return
+	}
+	for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+		for _, route := range virtualHost.GetRoutes() {
+			if routeAction := route.GetRoute(); routeAction != nil {
+				routeAction.RateLimits = append(routeAction.RateLimits, rateLimits...)
+			}
+		}
+	}
+}
+
+// createRateLimitActions returns `header_value_match` actions. Envoy only calls the rate limit service
+// if all actions of a rate limit produce descriptor entries.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/route/v3/route_components.proto#config-route-v3-ratelimit-action-headervaluematch
+func createRateLimitActions(actions []RateLimitAction) []*routev3.RateLimit_Action {
+	rateLimitActions := make([]*routev3.RateLimit_Action, len(actions))
+	for i, action := range actions {
+		headers := make([]*routev3.HeaderMatcher, len(action.HeaderMatchers))
+		for j, headerMatcher := range action.HeaderMatchers {
+			headers[j] = createHeaderMatcher(headerMatcher)
+		}
+		rateLimitActions[i] = &routev3.RateLimit_Action{
+			ActionSpecifier: &routev3.RateLimit_Action_HeaderValueMatch_{
+				HeaderValueMatch: &routev3.RateLimit_Action_HeaderValueMatch{
+					DescriptorValue: action.DescriptorValue,
+					ExpectMatch:     wrapperspb.Bool(action.ExpectMatch),
+					Headers:         headers,
+				},
+			},
+		}
+	}
+	return rateLimitActions
+}
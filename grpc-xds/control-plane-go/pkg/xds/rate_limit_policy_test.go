@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"testing"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+)
+
+func TestMatchingRateLimitPolicies(t *testing.T) {
+	app := GRPCApplication{Namespace: "default", Labels: map[string]string{"team": "a"}}
+	var policies []RateLimitPolicy
+	for i := 0; i < maxRateLimitStage+3; i++ {
+		policies = append(policies, RateLimitPolicy{Namespace: "default", Name: fmt.Sprintf("policy-%02d", i)})
+	}
+	policies = append([]RateLimitPolicy{
+		{Namespace: "other", Name: "other-namespace"},
+		{Namespace: "default", Name: "other-team", MatchLabels: map[string]string{"team": "b"}},
+	}, policies...)
+	matching := matchingRateLimitPolicies(policies, app)
+	if len(matching) != maxRateLimitStage+1 {
+		t.Fatalf("matchingRateLimitPolicies() returned %d policies, want %d", len(matching), maxRateLimitStage+1)
+	}
+	for stage, policy := range matching {
+		if want := fmt.Sprintf("policy-%02d", stage); policy.Name != want {
+			t.Errorf("policy at stage %d = %s, want %s", stage, policy.Name, want)
+		}
+	}
+}
+
+func TestCreateRateLimitFilters(t *testing.T) {
+	app := GRPCApplication{Namespace: "default"}
+	policies := []RateLimitPolicy{
+		{Namespace: "default", Name: "first", RateLimitServiceCluster: "ratelimit", Domain: "a", Timeout: DefaultRateLimitTimeout},
+		{Namespace: "default", Name: "second", RateLimitServiceCluster: "ratelimit", Domain: "b", Timeout: DefaultRateLimitTimeout, FailureModeDeny: true},
+	}
+	filters, err := createRateLimitFilters(policies, app)
+	if err != nil {
+		t.Fatalf("createRateLimitFilters() error = %v", err)
+	}
+	if len(filters) != len(policies) {
+		t.Fatalf("createRateLimitFilters() returned %d filters, want %d", len(filters), len(policies))
+	}
+	for stage, filter := range filters {
+		policy := policies[stage]
+		if want := envoyFilterHTTPRateLimitName + ".default." + policy.Name; filter.GetName() != want {
+			t.Errorf("filter %d name = %s, want %s", stage, filter.GetName(), want)
+		}
+		var rateLimit ratelimitv3.RateLimit
+		if err := filter.GetTypedConfig().UnmarshalTo(&rateLimit); err != nil {
+			t.Fatalf("could not unmarshal RateLimit: %v", err)
+		}
+		if rateLimit.GetStage() != uint32(stage) || rateLimit.GetDomain() != policy.Domain || rateLimit.GetFailureModeDeny() != policy.FailureModeDeny {
+			t.Errorf("filter %d RateLimit = %v, want stage=%d domain=%s failureModeDeny=%t", stage, &rateLimit, stage, policy.Domain, policy.FailureModeDeny)
+		}
+		if got := rateLimit.GetRateLimitService().GetGrpcService().GetEnvoyGrpc().GetClusterName(); got != policy.RateLimitServiceCluster {
+			t.Errorf("filter %d rate limit service cluster = %s, want %s", stage, got, policy.RateLimitServiceCluster)
+		}
+	}
+}
+
+func TestApplyRateLimits(t *testing.T) {
+	app := GRPCApplication{Namespace: "default"}
+	policies := []RateLimitPolicy{{
+		Namespace: "default",
+		Name:      "policy",
+		Actions: []RateLimitAction{{
+			DescriptorValue: "debug",
+			ExpectMatch:     true,
+			HeaderMatchers:  []HeaderMatcher{{Name: "x-debug", Exact: "true"}},
+		}},
+	}}
+	routeConfiguration := &routev3.RouteConfiguration{
+		VirtualHosts: []*routev3.VirtualHost{{
+			Routes: []*routev3.Route{
+				{Action: &routev3.Route_Route{Route: &routev3.RouteAction{}}},
+				{Action: &routev3.Route_DirectResponse{DirectResponse: &routev3.DirectResponseAction{Status: 404}}},
+			},
+		}},
+	}
+	applyRateLimits(routeConfiguration, policies, app)
+	rateLimits := routeConfiguration.GetVirtualHosts()[0].GetRoutes()[0].GetRoute().GetRateLimits()
+	if len(rateLimits) != 1 {
+		t.Fatalf("route has %d rate limits, want 1", len(rateLimits))
+	}
+	headerValueMatch := rateLimits[0].GetActions()[0].GetHeaderValueMatch()
+	if headerValueMatch.GetDescriptorValue() != "debug" || !headerValueMatch.GetExpectMatch().GetValue() {
+		t.Errorf("header value match = %v, want descriptorValue=debug expectMatch=true", headerValueMatch)
+	}
+	if headers := headerValueMatch.GetHeaders(); len(headers) != 1 || headers[0].GetName() != "x-debug" {
+		t.Errorf("header matchers = %v, want one matcher for x-debug", headers)
+	}
+
+	unchanged := &routev3.RouteConfiguration{
+		VirtualHosts: []*routev3.VirtualHost{{Routes: []*routev3.Route{{Action: &routev3.Route_Route{Route: &routev3.RouteAction{}}}}}},
+	}
+	applyRateLimits(unchanged, policies, GRPCApplication{Namespace: "other"})
+	if rateLimits := unchanged.GetVirtualHosts()[0].GetRoutes()[0].GetRoute().GetRateLimits(); len(rateLimits) != 0 {
+		t.Errorf("route for application without matching policies has rate limits %v", rateLimits)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	reconcileInitialBackoff = 100 * time.Millisecond
+	reconcileMaxBackoff     = 30 * time.Second
+	// reconcileJitterFactor is the maximum fraction of the back-off that is added as jitter.
+	reconcileJitterFactor = 0.1
+	// DefaultMaxReconcileAttempts is the default number of retries of a failed snapshot update for a node hash.
+	DefaultMaxReconcileAttempts = 10
+)
+
+// reconciler retries failed snapshot updates per node hash, with exponential back-off and jitter.
+type reconciler struct {
+	logger      logr.Logger
+	queue       workqueue.RateLimitingInterface
+	maxAttempts atomic.Int32
+	// reconcile creates a new snapshot for the node hash.
+	reconcile func(nodeHash string) error
+}
+
+func newReconciler(logger logr.Logger, reconcile func(nodeHash string) error) *reconciler {
+	r := &reconciler{
+		logger: logger,
+		queue: workqueue.NewRateLimitingQueueWithConfig(
+			jitterRateLimiter{
+				RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(reconcileInitialBackoff, reconcileMaxBackoff),
+				max:         reconcileMaxBackoff,
+			},
+			workqueue.RateLimitingQueueConfig{Name: "xds-snapshots"},
+		),
+		reconcile: reconcile,
+	}
+	r.maxAttempts.Store(DefaultMaxReconcileAttempts)
+	return r
+}
+
+// run processes retries until the context is done.
+func (r *reconciler) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		r.queue.ShutDown()
+	}()
+	for r.processNext() {
+	}
+}
+
+// retry schedules a new snapshot update for the node hash after the back-off for the node hash.
+func (r *reconciler) retry(nodeHash string) {
+	r.queue.AddRateLimited(nodeHash)
+}
+
+// forget resets the back-off for the node hash, after a successful snapshot update.
+func (r *reconciler) forget(nodeHash string) {
+	r.queue.Forget(nodeHash)
+}
+
+func (r *reconciler) processNext() bool {
+	item, shutdown := r.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer r.queue.Done(item)
+	nodeHash, ok := item.(string)
+	if !ok {
+		r.queue.Forget(item)
+		return true
+	}
+	attempt := r.queue.NumRequeues(item)
+	if err := r.reconcile(nodeHash); err != nil {
+		if attempt+1 >= int(r.maxAttempts.Load()) {
+			r.logger.Error(err, "Giving up on xDS resource snapshot update after the maximum number of attempts", "nodeHash", nodeHash, "attempts", attempt+1)
+			r.queue.Forget(item)
+			return true
+		}
+		r.logger.V(1).Info("Warning: retrying failed xDS resource snapshot update", "nodeHash", nodeHash, "attempt", attempt+1, "error", err.Error())
+		r.queue.AddRateLimited(item)
+		return true
+	}
+	r.logger.V(2).Info("Reconciled xDS resource snapshot", "nodeHash", nodeHash, "attempts", attempt+1)
+	r.queue.Forget(item)
+	return true
+}
+
+// jitterRateLimiter adds up to `reconcileJitterFactor` of jitter to the back-off of the delegate
+// rate limiter, so that retries for many node hashes do not happen at the same time.
+type jitterRateLimiter struct {
+	workqueue.RateLimiter
+	max time.Duration
+}
+
+func (l jitterRateLimiter) When(item interface{}) time.Duration {
+	return min(wait.Jitter(l.RateLimiter.When(item), reconcileJitterFactor), l.max)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var errTestReconcile = errors.New("test reconcile error")
+
+func TestReconcilerRetriesUntilSuccess(t *testing.T) {
+	var calls int
+	r := newReconciler(logr.Discard(), func(nodeHash string) error {
+		calls++
+		if calls == 1 {
+			return errTestReconcile
+		}
+		return nil
+	})
+	t.Cleanup(r.queue.ShutDown)
+
+	r.retry("us-central1-a")
+	for i := 0; i < 2; i++ {
+		if !r.processNext() {
+			t.Fatalf("processNext() call %d = false, want true", i+1)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("reconcile calls = %d, want 2", calls)
+	}
+	if got := r.queue.NumRequeues("us-central1-a"); got != 0 {
+		t.Errorf("requeues after successful reconcile = %d, want 0", got)
+	}
+	if got := r.queue.Len(); got != 0 {
+		t.Errorf("queue length after successful reconcile = %d, want 0", got)
+	}
+}
+
+func TestReconcilerGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int
+	r := newReconciler(logr.Discard(), func(nodeHash string) error {
+		calls++
+		return errTestReconcile
+	})
+	t.Cleanup(r.queue.ShutDown)
+	// The failed snapshot update that calls `retry()` is the first attempt.
+	r.maxAttempts.Store(3)
+
+	r.retry("us-central1-a")
+	for i := 0; i < 2; i++ {
+		if !r.processNext() {
+			t.Fatalf("processNext() call %d = false, want true", i+1)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("reconcile calls = %d, want 2", calls)
+	}
+	if got := r.queue.NumRequeues("us-central1-a"); got != 0 {
+		t.Errorf("requeues after giving up = %d, want 0", got)
+	}
+	if got := r.queue.Len(); got != 0 {
+		t.Errorf("queue length after giving up = %d, want 0", got)
+	}
+}
+
+func TestReconcilerProcessNextAfterShutDown(t *testing.T) {
+	r := newReconciler(logr.Discard(), func(string) error { return nil })
+	r.queue.ShutDown()
+	if r.processNext() {
+		t.Error("processNext() after shutdown = true, want false")
+	}
+}
+
+func TestJitterRateLimiterWhen(t *testing.T) {
+	const maxBackoff = time.Second
+	l := jitterRateLimiter{
+		RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(100*time.Millisecond, maxBackoff),
+		max:         maxBackoff,
+	}
+	backoff := 100 * time.Millisecond
+	for i := 0; i < 10; i++ {
+		got := l.When("us-central1-a")
+		wantMin := min(backoff, maxBackoff)
+		wantMax := min(time.Duration(float64(backoff)*(1+reconcileJitterFactor)), maxBackoff)
+		if got < wantMin || got > wantMax {
+			t.Errorf("When() call %d = %v, want between %v and %v", i+1, got, wantMin, wantMax)
+		}
+		backoff *= 2
+	}
+}
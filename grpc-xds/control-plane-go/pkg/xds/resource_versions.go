@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// resourceVersions assigns versions to xDS resources, with one monotonic counter per resource type.
+// A counter is only incremented when resources of that type change, so that xDS clients do not
+// receive unchanged resource types again, e.g., when only EDS ClusterLoadAssignments change.
+//
+// Versions have the format `<counter>.<instance>`, where the instance is the start time of the
+// process, so that versions from a restarted control plane never match versions from before.
+type resourceVersions struct {
+	instance string
+	// counters is not modified after creation, so concurrent reads are safe.
+	counters map[resource.Type]*atomic.Uint64
+	// other is the counter for resource types without a counter in the map.
+	other atomic.Uint64
+}
+
+func newResourceVersions() *resourceVersions {
+	counters := map[resource.Type]*atomic.Uint64{}
+	for _, typeURL := range []resource.Type{resource.ListenerType, resource.RouteType, resource.ClusterType, resource.EndpointType, resource.SecretType} {
+		counters[typeURL] = &atomic.Uint64{}
+	}
+	return &resourceVersions{
+		instance: strconv.FormatInt(time.Now().UnixNano(), 10),
+		counters: counters,
+	}
+}
+
+// versionFor returns the version of the previous snapshot for the resource type if the resources
+// are equal to the resources of that type in the previous snapshot. Otherwise, it increments the
+// counter for the resource type and returns a new version. `previous` can be nil.
+func (v *resourceVersions) versionFor(previous cachev3.ResourceSnapshot, typeURL resource.Type, resources []types.Resource) string {
+	if previous != nil && resourcesEqual(previous.GetResources(typeURL), resources) {
+		return previous.GetVersion(typeURL)
+	}
+	counter, exists := v.counters[typeURL]
+	if !exists {
+		counter = &v.other
+	}
+	return fmt.Sprintf("%d.%s", counter.Add(1), v.instance)
+}
+
+// SnapshotEqual returns true if the snapshots contain the same resources for every resource type,
+// using protobuf equality, regardless of the resource versions.
+// Setting a snapshot that is equal to the current snapshot does not change what xDS clients receive,
+// so callers can skip it.
+func SnapshotEqual(a cachev3.ResourceSnapshot, b cachev3.ResourceSnapshot) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	for responseType := types.ResponseType(0); responseType < types.UnknownType; responseType++ {
+		typeURL, err := cachev3.GetResponseTypeURL(responseType)
+		if err != nil {
+			return false
+		}
+		resources := b.GetResources(typeURL)
+		resourceSlice := make([]types.Resource, 0, len(resources))
+		for _, r := range resources {
+			resourceSlice = append(resourceSlice, r)
+		}
+		if !resourcesEqual(a.GetResources(typeURL), resourceSlice) {
+			return false
+		}
+	}
+	return true
+}
+
+// resourcesEqual returns true if the named resources contain the same resources as the slice.
+func resourcesEqual(named map[string]types.Resource, resources []types.Resource) bool {
+	if len(named) != len(resources) {
+		return false
+	}
+	for _, r := range resources {
+		other, exists := named[cachev3.GetResourceName(r)]
+		if !exists || !proto.Equal(r, other) {
+			return false
+		}
+	}
+	return true
+}
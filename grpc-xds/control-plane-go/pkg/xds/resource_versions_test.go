@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func TestResourceVersionsVersionFor(t *testing.T) {
+	v := newResourceVersions()
+	clusters := []types.Resource{&clusterv3.Cluster{Name: "a"}}
+
+	first := v.versionFor(nil, resource.ClusterType, clusters)
+	if want := "1." + v.instance; first != want {
+		t.Fatalf("versionFor() with no previous snapshot = %s, want %s", first, want)
+	}
+	if got, want := v.versionFor(nil, resource.EndpointType, nil), "1."+v.instance; got != want {
+		t.Errorf("versionFor() for another resource type = %s, want %s", got, want)
+	}
+
+	previous, err := cachev3.NewSnapshot("", map[resource.Type][]types.Resource{resource.ClusterType: clusters})
+	if err != nil {
+		t.Fatalf("NewSnapshot(): %v", err)
+	}
+	// NewSnapshot uses the same version for all resource types, so set the one under test.
+	previous.Resources[types.Cluster].Version = first
+
+	unchanged := []types.Resource{&clusterv3.Cluster{Name: "a"}}
+	if got := v.versionFor(previous, resource.ClusterType, unchanged); got != first {
+		t.Errorf("versionFor() with unchanged resources = %s, want %s", got, first)
+	}
+	changed := []types.Resource{&clusterv3.Cluster{Name: "a"}, &clusterv3.Cluster{Name: "b"}}
+	if got, want := v.versionFor(previous, resource.ClusterType, changed), "2."+v.instance; got != want {
+		t.Errorf("versionFor() with changed resources = %s, want %s", got, want)
+	}
+}
+
+func TestResourcesEqual(t *testing.T) {
+	tests := []struct {
+		name      string
+		named     map[string]types.Resource
+		resources []types.Resource
+		want      bool
+	}{
+		{
+			name: "both empty",
+			want: true,
+		},
+		{
+			name:      "same resources",
+			named:     map[string]types.Resource{"a": &clusterv3.Cluster{Name: "a"}},
+			resources: []types.Resource{&clusterv3.Cluster{Name: "a"}},
+			want:      true,
+		},
+		{
+			name:      "different number of resources",
+			named:     map[string]types.Resource{"a": &clusterv3.Cluster{Name: "a"}},
+			resources: []types.Resource{&clusterv3.Cluster{Name: "a"}, &clusterv3.Cluster{Name: "b"}},
+			want:      false,
+		},
+		{
+			name:      "different names",
+			named:     map[string]types.Resource{"a": &clusterv3.Cluster{Name: "a"}},
+			resources: []types.Resource{&clusterv3.Cluster{Name: "b"}},
+			want:      false,
+		},
+		{
+			name:      "different contents",
+			named:     map[string]types.Resource{"a": &clusterv3.Cluster{Name: "a"}},
+			resources: []types.Resource{&clusterv3.Cluster{Name: "a", LbPolicy: clusterv3.Cluster_RING_HASH}},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resourcesEqual(tt.named, tt.resources); got != tt.want {
+				t.Errorf("resourcesEqual() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
+)
+
+// Resync rebuilds the snapshot for each node hash in the cache from the cached configuration,
+// and sets it only if it differs from the current snapshot, e.g., if an earlier update was lost.
+// Resources are compared using protobuf equality, see `SnapshotEqual()`, so unchanged snapshots
+// do not cause xDS responses. Node hashes with a snapshot from `SetSnapshot()`, e.g., via the
+// admin API, are skipped until `ClearSnapshot()`.
+// Failed snapshot updates are retried with back-off, as for other updates.
+func (c *SnapshotCache) Resync(logger logr.Logger) error {
+	logger.V(4).Info("Resyncing xDS resource snapshots for all node hashes")
+	apps := c.appsCache.GetAll()
+	var errs []error
+	for _, nodeHash := range c.nodeHashes() {
+		if c.overridden(nodeHash) {
+			logger.V(4).Info("Skipping resync of the xDS resource snapshot set via SetSnapshot()", "nodeHash", nodeHash)
+			continue
+		}
+		start := time.Now()
+		previous, err := c.delegate.GetSnapshot(nodeHash)
+		if err != nil {
+			previous = nil
+		}
+		snapshot, changedTypes, err := c.buildSnapshot(nodeHash, apps, previous)
+		if err != nil {
+			errs = append(errs, err)
+			c.reconciler.retry(nodeHash)
+			continue
+		}
+		if previous != nil && SnapshotEqual(previous, snapshot) {
+			continue
+		}
+		logger.V(1).Info("Warning: Resync found differences from the current xDS resource snapshot, setting a new snapshot", "nodeHash", nodeHash, "changedTypes", changedTypes)
+		metrics.XDSSnapshotResyncCorrected(changedTypes...)
+		if err := c.setSnapshot(nodeHash, snapshot, changedTypes, start); err != nil {
+			errs = append(errs, err)
+			c.reconciler.retry(nodeHash)
+			continue
+		}
+		c.reconciler.forget(nodeHash)
+	}
+	return errors.Join(errs...)
+}
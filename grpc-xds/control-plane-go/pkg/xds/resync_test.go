@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"testing"
+
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/go-logr/logr"
+)
+
+func TestResync(t *testing.T) {
+	c, delegate := newTestSnapshotCache(t)
+	if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", []GRPCApplication{testGRPCApplication("app", 3)}); err != nil {
+		t.Fatalf("UpdateResources(): %v", err)
+	}
+	want, err := c.delegate.GetSnapshot(testZone)
+	if err != nil {
+		t.Fatalf("GetSnapshot(): %v", err)
+	}
+
+	if err := c.Resync(logr.Discard()); err != nil {
+		t.Fatalf("Resync() error = %v", err)
+	}
+	if delegate.setSnapshotCalls != 1 {
+		t.Errorf("SetSnapshot() calls after Resync() without differences = %d, want 1", delegate.setSnapshotCalls)
+	}
+
+	// Replace the snapshot without the counting delegate, as a lost update or the admin API would.
+	replacement, err := cachev3.NewSnapshot("replacement", nil)
+	if err != nil {
+		t.Fatalf("NewSnapshot(): %v", err)
+	}
+	if err := delegate.SnapshotCache.SetSnapshot(context.Background(), testZone, replacement); err != nil {
+		t.Fatalf("SetSnapshot(): %v", err)
+	}
+	if err := c.Resync(logr.Discard()); err != nil {
+		t.Fatalf("Resync() error = %v", err)
+	}
+	if delegate.setSnapshotCalls != 2 {
+		t.Errorf("SetSnapshot() calls after Resync() with differences = %d, want 2", delegate.setSnapshotCalls)
+	}
+	got, err := c.delegate.GetSnapshot(testZone)
+	if err != nil {
+		t.Fatalf("GetSnapshot(): %v", err)
+	}
+	if !SnapshotEqual(got, want) {
+		t.Errorf("snapshot after Resync() is not equal to the snapshot from the cached configuration")
+	}
+}
+
+func TestResyncAndUpdatesSkipSnapshotsSetViaSetSnapshot(t *testing.T) {
+	c, _ := newTestSnapshotCache(t)
+	apps := []GRPCApplication{testGRPCApplication("app", 3)}
+	if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", apps); err != nil {
+		t.Fatalf("UpdateResources(): %v", err)
+	}
+	override, err := cachev3.NewSnapshot("override", nil)
+	if err != nil {
+		t.Fatalf("NewSnapshot(): %v", err)
+	}
+	if err := c.SetSnapshot(context.Background(), testZone, override); err != nil {
+		t.Fatalf("SetSnapshot(): %v", err)
+	}
+
+	apps = append(apps, testGRPCApplication("other", 2))
+	if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", apps); err != nil {
+		t.Fatalf("UpdateResources(): %v", err)
+	}
+	if err := c.Resync(logr.Discard()); err != nil {
+		t.Fatalf("Resync() error = %v", err)
+	}
+	got, err := c.GetSnapshot(testZone)
+	if err != nil {
+		t.Fatalf("GetSnapshot(): %v", err)
+	}
+	if !SnapshotEqual(got, override) {
+		t.Errorf("snapshot after UpdateResources() and Resync() is not the snapshot from SetSnapshot()")
+	}
+
+	c.ClearSnapshot(testZone)
+	if err := c.createNewSnapshot(testZone, apps); err != nil {
+		t.Fatalf("createNewSnapshot() error = %v", err)
+	}
+	got, err = c.GetSnapshot(testZone)
+	if err != nil {
+		t.Fatalf("GetSnapshot() after ClearSnapshot(): %v", err)
+	}
+	if SnapshotEqual(got, override) {
+		t.Errorf("snapshot after ClearSnapshot() is still the snapshot from SetSnapshot()")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"strings"
+	"time"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// knownRetryOnConditions are the Envoy `x-envoy-retry-on` and `x-envoy-retry-grpc-on` conditions.
+// gRPC clients only support the gRPC status code conditions, and ignore the others.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/router_filter#x-envoy-retry-on
+// [gRFC A44]: https://github.com/grpc/proposal/blob/master/A44-xds-retry.md
+var knownRetryOnConditions = map[string]bool{
+	// HTTP conditions.
+	"5xx":                        true,
+	"gateway-error":              true,
+	"reset":                      true,
+	"reset-before-request":       true,
+	"connect-failure":            true,
+	"envoy-ratelimited":          true,
+	"retriable-4xx":              true,
+	"refused-stream":             true,
+	"retriable-status-codes":     true,
+	"retriable-headers":          true,
+	"http3-post-connect-failure": true,
+	// gRPC conditions.
+	"cancelled":          true,
+	"deadline-exceeded":  true,
+	"internal":           true,
+	"resource-exhausted": true,
+	"unavailable":        true,
+}
+
+// RetryPolicy configures retries of requests to a gRPC application.
+// Zero values of NumRetries and PerTryTimeout are unset, so that xDS clients use their defaults.
+type RetryPolicy struct {
+	// RetryOn is a comma-separated list of retry conditions. Empty means no retries.
+	RetryOn       string
+	NumRetries    uint32
+	PerTryTimeout time.Duration
+}
+
+// RetryPolicyFromAnnotations reads the retry policy from Service annotations.
+// Annotations with invalid values are logged as warnings and ignored. The retry policy is
+// only used if the `retry-on` annotation is present and only contains known conditions.
+func RetryPolicyFromAnnotations(logger logr.Logger, annotations map[string]string) RetryPolicy {
+	var retryPolicy RetryPolicy
+	if retryOn, exists := annotations[retryOnAnnotation]; exists {
+		conditions, ok := parseRetryOn(retryOn)
+		if !ok {
+			logger.V(1).Info("Warning: ignoring annotation with unknown retry conditions", "annotation", retryOnAnnotation, "value", retryOn)
+		} else {
+			retryPolicy.RetryOn = conditions
+		}
+	}
+	if numRetries, ok := positiveUint32Annotation(logger, annotations, numRetriesAnnotation); ok {
+		retryPolicy.NumRetries = numRetries
+	}
+	if perTryTimeout, ok := durationAnnotation(logger, annotations, perTryTimeoutAnnotation); ok {
+		retryPolicy.PerTryTimeout = perTryTimeout
+	}
+	return retryPolicy
+}
+
+// parseRetryOn returns the normalized comma-separated list of retry conditions.
+// Returns false if the list is empty or contains an unknown condition.
+func parseRetryOn(retryOn string) (string, bool) {
+	var conditions []string
+	for _, condition := range strings.Split(retryOn, ",") {
+		condition = strings.TrimSpace(condition)
+		if !knownRetryOnConditions[condition] {
+			return "", false
+		}
+		conditions = append(conditions, condition)
+	}
+	return strings.Join(conditions, ","), true
+}
+
+func (r RetryPolicy) Compare(s RetryPolicy) int {
+	if r.RetryOn != s.RetryOn {
+		return cmp.Compare(r.RetryOn, s.RetryOn)
+	}
+	if r.NumRetries != s.NumRetries {
+		return cmp.Compare(r.NumRetries, s.NumRetries)
+	}
+	return cmp.Compare(r.PerTryTimeout, s.PerTryTimeout)
+}
+
+// createRetryPolicy returns nil if no retry conditions are set.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/route/v3/route_components.proto#config-route-v3-retrypolicy
+func createRetryPolicy(r RetryPolicy) *routev3.RetryPolicy {
+	if r.RetryOn == "" {
+		return nil
+	}
+	retryPolicy := &routev3.RetryPolicy{
+		RetryOn: r.RetryOn,
+	}
+	if r.NumRetries > 0 {
+		retryPolicy.NumRetries = wrapperspb.UInt32(r.NumRetries)
+	}
+	if r.PerTryTimeout > 0 {
+		retryPolicy.PerTryTimeout = durationpb.New(r.PerTryTimeout)
+	}
+	return retryPolicy
+}
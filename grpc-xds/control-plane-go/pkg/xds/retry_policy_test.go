@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestRetryPolicyFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        RetryPolicy
+	}{
+		{
+			name:        "no annotations",
+			annotations: nil,
+			want:        RetryPolicy{},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				retryOnAnnotation:       "unavailable, cancelled",
+				numRetriesAnnotation:    "3",
+				perTryTimeoutAnnotation: "2s",
+			},
+			want: RetryPolicy{
+				RetryOn:       "unavailable,cancelled",
+				NumRetries:    3,
+				PerTryTimeout: 2 * time.Second,
+			},
+		},
+		{
+			name: "unknown retry condition",
+			annotations: map[string]string{
+				retryOnAnnotation:    "unavailable,not-found",
+				numRetriesAnnotation: "3",
+			},
+			want: RetryPolicy{NumRetries: 3},
+		},
+		{
+			name: "empty retry condition",
+			annotations: map[string]string{
+				retryOnAnnotation: "unavailable,",
+			},
+			want: RetryPolicy{},
+		},
+		{
+			name: "zero retries are ignored",
+			annotations: map[string]string{
+				retryOnAnnotation:    "5xx",
+				numRetriesAnnotation: "0",
+			},
+			want: RetryPolicy{RetryOn: "5xx"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryPolicyFromAnnotations(logr.Discard(), tt.annotations); got != tt.want {
+				t.Errorf("RetryPolicyFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		retryPolicy RetryPolicy
+		want        *routev3.RetryPolicy
+	}{
+		{
+			name:        "no retry conditions",
+			retryPolicy: RetryPolicy{NumRetries: 3},
+			want:        nil,
+		},
+		{
+			name:        "retry conditions only",
+			retryPolicy: RetryPolicy{RetryOn: "unavailable"},
+			want:        &routev3.RetryPolicy{RetryOn: "unavailable"},
+		},
+		{
+			name: "all fields",
+			retryPolicy: RetryPolicy{
+				RetryOn:       "unavailable,cancelled",
+				NumRetries:    3,
+				PerTryTimeout: 2 * time.Second,
+			},
+			want: &routev3.RetryPolicy{
+				RetryOn:       "unavailable,cancelled",
+				NumRetries:    wrapperspb.UInt32(3),
+				PerTryTimeout: durationpb.New(2 * time.Second),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createRetryPolicy(tt.retryPolicy); !proto.Equal(got, tt.want) {
+				t.Errorf("createRetryPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
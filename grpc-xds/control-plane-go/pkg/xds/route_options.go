@@ -0,0 +1,35 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// applyRouteOptions configures the routes of the RDS RouteConfiguration using the optional
+// configuration of the gRPC application, e.g., from Service annotations.
+func applyRouteOptions(routeConfiguration *routev3.RouteConfiguration, app GRPCApplication) error {
+	for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+		for _, route := range virtualHost.GetRoutes() {
+			routeAction := route.GetRoute()
+			if routeAction == nil {
+				continue
+			}
+			routeAction.RetryPolicy = createRetryPolicy(app.RetryPolicy)
+			routeAction.MaxStreamDuration = createMaxStreamDuration(app.GRPCTimeouts)
+		}
+	}
+	return applyGRPCServiceConfig(routeConfiguration, app.GRPCServiceConfig)
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+)
+
+// nodeHashScopeSeparator separates the zone and the scope in node hashes from `ScopedZoneHash`.
+// Neither zone names nor Kubernetes namespace names contain this character.
+const nodeHashScopeSeparator = "/"
+
+// ScopedZoneHash uses `locality.zone` and the value of a node metadata field as the node hash,
+// e.g., `us-central1-a/foo`. All xDS clients in the same zone and with the same metadata value
+// access the same cache snapshot, and the snapshot only contains resources for gRPC applications
+// (and authorization policies) in the namespace named by the metadata value.
+//
+// xDS clients without the metadata field use the zone as the node hash, as with `ZoneHash`,
+// and their snapshots contain resources for all gRPC applications.
+type ScopedZoneHash struct {
+	// MetadataKey is the name of the node metadata field that contains the namespace, e.g., `NAMESPACE`.
+	MetadataKey string
+}
+
+var _ cachev3.NodeHash = &ScopedZoneHash{}
+
+func (h ScopedZoneHash) ID(node *corev3.Node) string {
+	zone := ZoneHash{}.ID(node)
+	scope := node.GetMetadata().GetFields()[h.MetadataKey].GetStringValue()
+	if scope == "" {
+		return zone
+	}
+	return zone + nodeHashScopeSeparator + scope
+}
+
+// splitNodeHash returns the zone and the scope of the node hash, ignoring the node group, if any.
+// The scope is empty for node hashes that are not scoped.
+func splitNodeHash(nodeHash string) (string, string) {
+	nodeHashWithoutGroup, _ := splitNodeGroup(nodeHash)
+	zone, scope, _ := strings.Cut(nodeHashWithoutGroup, nodeHashScopeSeparator)
+	return zone, scope
+}
+
+// nodeHashInScope returns true if resources from the namespace belong in the snapshot for the node hash.
+// An empty namespace is in scope for all node hashes.
+func nodeHashInScope(nodeHash string, namespace string) bool {
+	_, scope := splitNodeHash(nodeHash)
+	return scope == "" || namespace == "" || scope == namespace
+}
+
+// filterByScope returns the values with a namespace that is in scope, according to `inScope`.
+func filterByScope[T any](inScope func(namespace string) bool, values []T, namespace func(T) string) []T {
+	var filtered []T
+	for _, value := range values {
+		if inScope(namespace(value)) {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/stream/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestScopedZoneHashID(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *corev3.Node
+		wantHash string
+	}{
+		{
+			name:     "nil node",
+			node:     nil,
+			wantHash: "",
+		},
+		{
+			name: "zone without metadata",
+			node: &corev3.Node{
+				Locality: &corev3.Locality{Zone: "us-central1-a"},
+			},
+			wantHash: "us-central1-a",
+		},
+		{
+			name: "zone and namespace",
+			node: &corev3.Node{
+				Locality: &corev3.Locality{Zone: "us-central1-a"},
+				Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+					"NAMESPACE": structpb.NewStringValue("foo"),
+				}},
+			},
+			wantHash: "us-central1-a/foo",
+		},
+		{
+			name: "empty namespace",
+			node: &corev3.Node{
+				Locality: &corev3.Locality{Zone: "us-central1-a"},
+				Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+					"NAMESPACE": structpb.NewStringValue(""),
+				}},
+			},
+			wantHash: "us-central1-a",
+		},
+		{
+			name: "other metadata field",
+			node: &corev3.Node{
+				Locality: &corev3.Locality{Zone: "us-central1-a"},
+				Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+					"POD_NAMESPACE": structpb.NewStringValue("foo"),
+				}},
+			},
+			wantHash: "us-central1-a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (ScopedZoneHash{MetadataKey: "NAMESPACE"}).ID(tt.node); got != tt.wantHash {
+				t.Errorf("ID() = %q, want %q", got, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestSplitNodeHash(t *testing.T) {
+	tests := []struct {
+		nodeHash  string
+		wantZone  string
+		wantScope string
+	}{
+		{nodeHash: "", wantZone: "", wantScope: ""},
+		{nodeHash: "us-central1-a", wantZone: "us-central1-a", wantScope: ""},
+		{nodeHash: "us-central1-a/foo", wantZone: "us-central1-a", wantScope: "foo"},
+		{nodeHash: "us-central1-a@gateway", wantZone: "us-central1-a", wantScope: ""},
+		{nodeHash: "us-central1-a/foo@gateway", wantZone: "us-central1-a", wantScope: "foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nodeHash, func(t *testing.T) {
+			zone, scope := splitNodeHash(tt.nodeHash)
+			if zone != tt.wantZone || scope != tt.wantScope {
+				t.Errorf("splitNodeHash(%q) = (%q, %q), want (%q, %q)", tt.nodeHash, zone, scope, tt.wantZone, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestNodeHashInScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodeHash  string
+		namespace string
+		want      bool
+	}{
+		{name: "unscoped node hash", nodeHash: "us-central1-a", namespace: "foo", want: true},
+		{name: "same namespace", nodeHash: "us-central1-a/foo", namespace: "foo", want: true},
+		{name: "other namespace", nodeHash: "us-central1-a/foo", namespace: "bar", want: false},
+		{name: "empty namespace", nodeHash: "us-central1-a/foo", namespace: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeHashInScope(tt.nodeHash, tt.namespace); got != tt.want {
+				t.Errorf("nodeHashInScope(%q, %q) = %v, want %v", tt.nodeHash, tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterByScope(t *testing.T) {
+	policies := []AuthorizationPolicy{
+		{Namespace: "foo", Name: "a"},
+		{Namespace: "bar", Name: "b"},
+		{Namespace: "foo", Name: "c"},
+	}
+	inScope := func(namespace string) bool {
+		return nodeHashInScope("us-central1-a/foo", namespace)
+	}
+	got := filterByScope(inScope, policies, func(policy AuthorizationPolicy) string { return policy.Namespace })
+	var gotNames []string
+	for _, policy := range got {
+		gotNames = append(gotNames, policy.Name)
+	}
+	if wantNames := []string{"a", "c"}; !slices.Equal(gotNames, wantNames) {
+		t.Errorf("filterByScope() names = %v, want %v", gotNames, wantNames)
+	}
+}
+
+// TestUpdateResourcesScopedToNamespace verifies that updates from one namespace do not change
+// the snapshot, including its versions, for node hashes scoped to another namespace.
+func TestUpdateResourcesScopedToNamespace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	hash := ScopedZoneHash{MetadataKey: "NAMESPACE"}
+	c := NewSnapshotCache(ctx, false, hash, FixedLocalityPriority{}, &Features{}, "")
+	nodeFoo := testNodeWithMetadata(map[string]string{"NAMESPACE": "foo"})
+	nodeBar := testNodeWithMetadata(map[string]string{"NAMESPACE": "bar"})
+	for _, node := range []*corev3.Node{nodeFoo, nodeBar} {
+		cancelWatch := c.CreateWatch(&cachev3.Request{Node: node, TypeUrl: resource.ClusterType}, stream.NewStreamState(false, nil), make(chan cachev3.Response, 10))
+		t.Cleanup(cancelWatch)
+	}
+	endpoints := []GRPCApplicationEndpoints{NewGRPCApplicationEndpoints("node-1", testZone, []string{"10.0.0.1"}, Healthy)}
+	if err := c.UpdateResources(ctx, logr.Discard(), "kubecontext", "foo", []GRPCApplication{NewGRPCApplication("foo", "greeter", 50051, endpoints)}); err != nil {
+		t.Fatalf("UpdateResources(foo): %v", err)
+	}
+	before, err := c.GetSnapshot(hash.ID(nodeFoo))
+	if err != nil {
+		t.Fatalf("GetSnapshot(foo): %v", err)
+	}
+
+	if err := c.UpdateResources(ctx, logr.Discard(), "kubecontext", "bar", []GRPCApplication{NewGRPCApplication("bar", "other", 50051, endpoints)}); err != nil {
+		t.Fatalf("UpdateResources(bar): %v", err)
+	}
+
+	after, err := c.GetSnapshot(hash.ID(nodeFoo))
+	if err != nil {
+		t.Fatalf("GetSnapshot(foo): %v", err)
+	}
+	for _, typeURL := range []string{resource.ListenerType, resource.RouteType, resource.ClusterType, resource.EndpointType} {
+		if after.GetVersion(typeURL) != before.GetVersion(typeURL) {
+			t.Errorf("version of %s for NAMESPACE=foo = %q, want unchanged %q", typeURL, after.GetVersion(typeURL), before.GetVersion(typeURL))
+		}
+	}
+	if _, found := after.GetResources(resource.ClusterType)["other"]; found {
+		t.Error("snapshot for NAMESPACE=foo contains Cluster other from namespace bar")
+	}
+	snapshotBar, err := c.GetSnapshot(hash.ID(nodeBar))
+	if err != nil {
+		t.Fatalf("GetSnapshot(bar): %v", err)
+	}
+	clustersBar := snapshotBar.GetResources(resource.ClusterType)
+	if _, found := clustersBar["other"]; !found {
+		t.Errorf("snapshot for NAMESPACE=bar clusters = %v, want Cluster other", clustersBar)
+	}
+	if _, found := clustersBar["greeter"]; found {
+		t.Error("snapshot for NAMESPACE=bar contains Cluster greeter from namespace foo")
+	}
+}
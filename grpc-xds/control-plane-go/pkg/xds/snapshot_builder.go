@@ -17,17 +17,17 @@ package xds
 import (
 	"fmt"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
-	"time"
 
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
-	faultv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
 	rbacfilterv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
 	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
@@ -67,14 +67,24 @@ type SnapshotBuilder struct {
 	clusterLoadAssignments  map[string]types.Resource
 	endpointsByCluster      map[string][]GRPCApplicationEndpoints
 	serverListenerAddresses map[EndpointAddress]bool
+	authorizationPolicies   []AuthorizationPolicy
+	grpcRoutes              []GRPCRoute
+	accessLogConfigs        []AccessLogConfig
+	extAuthzPolicies        []ExtAuthzPolicy
+	rateLimitPolicies       []RateLimitPolicy
+	secrets                 map[string]types.Resource
 	nodeHash                string
-	localityPriorityMapper  LocalityPriorityMapper
-	features                *Features
-	authority               string
+	// zone of the node hash, used to prioritize EDS localities.
+	zone                   string
+	localityPriorityMapper LocalityPriorityMapper
+	features               *Features
+	listenerConfig         *ListenerConfig
+	authority              string
 }
 
 // NewSnapshotBuilder initializes the builder.
-func NewSnapshotBuilder(nodeHash string, localityPriorityMapper LocalityPriorityMapper, features *Features, authority string) *SnapshotBuilder {
+func NewSnapshotBuilder(nodeHash string, localityPriorityMapper LocalityPriorityMapper, features *Features, listenerConfig *ListenerConfig, authority string) *SnapshotBuilder {
+	zone, _ := splitNodeHash(nodeHash)
 	return &SnapshotBuilder{
 		listeners:               make(map[string]types.Resource),
 		routeConfigurations:     make(map[string]types.Resource),
@@ -82,18 +92,54 @@ func NewSnapshotBuilder(nodeHash string, localityPriorityMapper LocalityPriority
 		clusterLoadAssignments:  make(map[string]types.Resource),
 		endpointsByCluster:      make(map[string][]GRPCApplicationEndpoints),
 		serverListenerAddresses: make(map[EndpointAddress]bool),
+		secrets:                 make(map[string]types.Resource),
 		nodeHash:                nodeHash,
+		zone:                    zone,
 		localityPriorityMapper:  localityPriorityMapper,
 		features:                features,
+		listenerConfig:          listenerConfig,
 		authority:               authority,
 	}
 }
 
+// AddAccessLogConfigs adds access logs to the API listeners of the gRPC applications in the same namespace.
+// Must be called before `AddGRPCApplications()`.
+func (b *SnapshotBuilder) AddAccessLogConfigs(accessLogConfigs []AccessLogConfig) *SnapshotBuilder {
+	b.accessLogConfigs = append(b.accessLogConfigs, accessLogConfigs...)
+	return b
+}
+
+// AddExtAuthzPolicies adds `ext_authz` HTTP filters to the API listeners of the matching gRPC applications.
+// Must be called before `AddGRPCApplications()`.
+func (b *SnapshotBuilder) AddExtAuthzPolicies(policies []ExtAuthzPolicy) *SnapshotBuilder {
+	b.extAuthzPolicies = append(b.extAuthzPolicies, policies...)
+	return b
+}
+
+// AddRateLimitPolicies adds `ratelimit` HTTP filters to the API listeners, and rate limit actions to the routes,
+// of the matching gRPC applications. Must be called before `AddGRPCApplications()`.
+func (b *SnapshotBuilder) AddRateLimitPolicies(policies []RateLimitPolicy) *SnapshotBuilder {
+	b.rateLimitPolicies = append(b.rateLimitPolicies, policies...)
+	return b
+}
+
 // AddGRPCApplications adds the provided application configurations to the xDS resource snapshot.
 func (b *SnapshotBuilder) AddGRPCApplications(apps []GRPCApplication) (*SnapshotBuilder, error) {
 	for _, app := range apps {
 		if b.listeners[app.ListenerName] == nil {
-			apiListener, err := createAPIListener(app.ListenerName, app.ListenerName, app.RouteConfigurationName)
+			accessLogs, err := createAccessLogs(b.accessLogConfigs, app.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("could not create access logs for gRPC application %+v: %w", app, err)
+			}
+			extAuthzFilters, err := createExtAuthzFilters(b.extAuthzPolicies, app)
+			if err != nil {
+				return nil, fmt.Errorf("could not create ext_authz HTTP filters for gRPC application %+v: %w", app, err)
+			}
+			rateLimitFilters, err := createRateLimitFilters(b.rateLimitPolicies, app)
+			if err != nil {
+				return nil, fmt.Errorf("could not create ratelimit HTTP filters for gRPC application %+v: %w", app, err)
+			}
+			apiListener, err := createAPIListener(app.ListenerName, app.ListenerName, app.RouteConfigurationName, app.AppProtocol, b.listenerConfig, app.FaultInjection, extAuthzFilters, rateLimitFilters, accessLogs)
 			if err != nil {
 				return nil, fmt.Errorf("could not create LDS API listener for gRPC application %+v: %w", app, err)
 			}
@@ -101,7 +147,7 @@ func (b *SnapshotBuilder) AddGRPCApplications(apps []GRPCApplication) (*Snapshot
 			if b.features.EnableFederation {
 				xdstpListenerName := xdstpListener(b.authority, app.ListenerName)
 				xdstpRouteConfigurationName := xdstpRouteConfiguration(b.authority, app.RouteConfigurationName)
-				xdstpListener, err := createAPIListener(xdstpListenerName, app.ListenerName, xdstpRouteConfigurationName)
+				xdstpListener, err := createAPIListener(xdstpListenerName, app.ListenerName, xdstpRouteConfigurationName, app.AppProtocol, b.listenerConfig, app.FaultInjection, extAuthzFilters, rateLimitFilters, accessLogs)
 				if err != nil {
 					return nil, fmt.Errorf("could not create federation LDS API listener for authority=%s and gRPC application %+v: %w", b.authority, app, err)
 				}
@@ -109,15 +155,29 @@ func (b *SnapshotBuilder) AddGRPCApplications(apps []GRPCApplication) (*Snapshot
 			}
 		}
 		if b.routeConfigurations[app.RouteConfigurationName] == nil {
-			routeConfiguration := createRouteConfiguration(app.RouteConfigurationName, app.ListenerName, app.PathPrefix, app.ClusterName)
+			routeConfiguration := createRouteConfiguration(app.RouteConfigurationName, app.ListenerName, app.PathPrefix, app.ClusterName, app.TrafficSplit)
+			if err := applyRouteOptions(routeConfiguration, app); err != nil {
+				return nil, fmt.Errorf("could not apply route options to RDS RouteConfiguration for gRPC application %+v: %w", app, err)
+			}
+			applyRateLimits(routeConfiguration, b.rateLimitPolicies, app)
 			b.routeConfigurations[routeConfiguration.Name] = routeConfiguration
 			if b.features.EnableFederation {
 				xdstpRouteConfigurationName := xdstpRouteConfiguration(b.authority, app.RouteConfigurationName)
 				xdstpClusterName := xdstpCluster(b.authority, app.ClusterName)
-				xdstpRouteConfiguration := createRouteConfiguration(xdstpRouteConfigurationName, app.ListenerName, app.PathPrefix, xdstpClusterName)
+				xdstpRouteConfiguration := createRouteConfiguration(xdstpRouteConfigurationName, app.ListenerName, app.PathPrefix, xdstpClusterName, xdstpTrafficSplit(b.authority, app.TrafficSplit))
+				if err := applyRouteOptions(xdstpRouteConfiguration, app); err != nil {
+					return nil, fmt.Errorf("could not apply route options to federation RDS RouteConfiguration for authority=%s and gRPC application %+v: %w", b.authority, app, err)
+				}
+				applyRateLimits(xdstpRouteConfiguration, b.rateLimitPolicies, app)
 				b.routeConfigurations[xdstpRouteConfiguration.Name] = xdstpRouteConfiguration
 			}
 		}
+		if app.ExternalName != "" {
+			if err := b.addExternalNameClusters(app); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if b.clusters[app.ClusterName] == nil {
 			cluster, err := createCluster(
 				app.ClusterName,
@@ -125,10 +185,14 @@ func (b *SnapshotBuilder) AddGRPCApplications(apps []GRPCApplication) (*Snapshot
 				app.Namespace,
 				app.ServiceAccountName,
 				b.features.EnableDataPlaneTLS,
-				b.features.RequireDataPlaneClientCerts)
+				b.features.RequireDataPlaneClientCerts,
+				b.features.DataPlaneTLSSecret)
 			if err != nil {
 				return nil, fmt.Errorf("could not create CDS Cluster for gRPC application %+v: %w", app, err)
 			}
+			if err := applyClusterOptions(cluster, app); err != nil {
+				return nil, fmt.Errorf("could not apply cluster options to CDS Cluster for gRPC application %+v: %w", app, err)
+			}
 			b.clusters[cluster.Name] = cluster
 			if b.features.EnableFederation {
 				xdstpClusterName := xdstpCluster(b.authority, app.ClusterName)
@@ -139,27 +203,59 @@ func (b *SnapshotBuilder) AddGRPCApplications(apps []GRPCApplication) (*Snapshot
 					app.Namespace,
 					app.ServiceAccountName,
 					b.features.EnableDataPlaneTLS,
-					b.features.RequireDataPlaneClientCerts)
+					b.features.RequireDataPlaneClientCerts,
+					b.features.DataPlaneTLSSecret)
 				if err != nil {
 					return nil, fmt.Errorf("could not create federation CDS Cluster for authority=%s and gRPC application %+v: %w", b.authority, app, err)
 				}
+				if err := applyClusterOptions(xdstpCluster, app); err != nil {
+					return nil, fmt.Errorf("could not apply cluster options to federation CDS Cluster for authority=%s and gRPC application %+v: %w", b.authority, app, err)
+				}
 				b.clusters[xdstpCluster.Name] = xdstpCluster
 			}
 		}
 		// Merge endpoints from multiple informers for the same app:
 		endpointsByClusterKey := fmt.Sprintf("%s-%d", app.ClusterName, app.Port)
 		b.endpointsByCluster[endpointsByClusterKey] = append(b.endpointsByCluster[endpointsByClusterKey], app.Endpoints...)
-		clusterLoadAssignment := createClusterLoadAssignment(app.EDSServiceName, app.Port, b.nodeHash, b.localityPriorityMapper, b.endpointsByCluster[endpointsByClusterKey])
+		clusterLoadAssignment := createClusterLoadAssignment(app.EDSServiceName, app.Port, b.zone, b.localityPriorityMapper, b.endpointsByCluster[endpointsByClusterKey])
 		b.clusterLoadAssignments[clusterLoadAssignment.ClusterName] = clusterLoadAssignment
 		if b.features.EnableFederation {
 			xdstpEDSServiceName := xdstpEdsService(b.authority, app.EDSServiceName)
-			xdstpClusterLoadAssignment := createClusterLoadAssignment(xdstpEDSServiceName, app.Port, b.nodeHash, b.localityPriorityMapper, b.endpointsByCluster[endpointsByClusterKey])
+			xdstpClusterLoadAssignment := createClusterLoadAssignment(xdstpEDSServiceName, app.Port, b.zone, b.localityPriorityMapper, b.endpointsByCluster[endpointsByClusterKey])
 			b.clusterLoadAssignments[xdstpClusterLoadAssignment.ClusterName] = xdstpClusterLoadAssignment
 		}
 	}
 	return b, nil
 }
 
+// addExternalNameClusters adds the CDS Cluster for a gRPC application backed by an ExternalName Service.
+// These clusters have no EDS ClusterLoadAssignment, as the endpoint is resolved by the xDS client.
+func (b *SnapshotBuilder) addExternalNameClusters(app GRPCApplication) error {
+	if b.clusters[app.ClusterName] != nil {
+		return nil
+	}
+	cluster, err := createExternalNameCluster(app.ClusterName, app.ExternalName, app.Port, app.UpstreamTLS)
+	if err != nil {
+		return fmt.Errorf("could not create CDS Cluster for ExternalName gRPC application %+v: %w", app, err)
+	}
+	if err := applyClusterOptions(cluster, app); err != nil {
+		return fmt.Errorf("could not apply cluster options to CDS Cluster for ExternalName gRPC application %+v: %w", app, err)
+	}
+	b.clusters[cluster.Name] = cluster
+	if b.features.EnableFederation {
+		xdstpClusterName := xdstpCluster(b.authority, app.ClusterName)
+		xdstpCluster, err := createExternalNameCluster(xdstpClusterName, app.ExternalName, app.Port, app.UpstreamTLS)
+		if err != nil {
+			return fmt.Errorf("could not create federation CDS Cluster for authority=%s and ExternalName gRPC application %+v: %w", b.authority, app, err)
+		}
+		if err := applyClusterOptions(xdstpCluster, app); err != nil {
+			return fmt.Errorf("could not apply cluster options to federation CDS Cluster for authority=%s and ExternalName gRPC application %+v: %w", b.authority, app, err)
+		}
+		b.clusters[xdstpCluster.Name] = xdstpCluster
+	}
+	return nil
+}
+
 func xdstpListener(authority string, listenerName string) string {
 	return fmt.Sprintf("xdstp://%s/envoy.config.listener.v3.Listener/%s", authority, listenerName)
 }
@@ -176,6 +272,20 @@ func xdstpEdsService(authority string, serviceName string) string {
 	return fmt.Sprintf("xdstp://%s/envoy.config.endpoint.v3.ClusterLoadAssignment/%s", authority, serviceName)
 }
 
+func xdstpTrafficSplit(authority string, trafficSplit []ClusterWeight) []ClusterWeight {
+	if trafficSplit == nil {
+		return nil
+	}
+	xdstpTrafficSplit := make([]ClusterWeight, len(trafficSplit))
+	for i, clusterWeight := range trafficSplit {
+		xdstpTrafficSplit[i] = ClusterWeight{
+			ClusterName: xdstpCluster(authority, clusterWeight.ClusterName),
+			Weight:      clusterWeight.Weight,
+		}
+	}
+	return xdstpTrafficSplit
+}
+
 // AddServerListenerAddresses adds server listeners and associated route
 // configurations with the provided IP addresses and ports to the snapshot.
 func (b *SnapshotBuilder) AddServerListenerAddresses(addresses []EndpointAddress) *SnapshotBuilder {
@@ -185,26 +295,56 @@ func (b *SnapshotBuilder) AddServerListenerAddresses(addresses []EndpointAddress
 	return b
 }
 
+// AddAuthorizationPolicies adds the provided policies to the RBAC HTTP filters of the server listeners.
+func (b *SnapshotBuilder) AddAuthorizationPolicies(policies []AuthorizationPolicy) *SnapshotBuilder {
+	b.authorizationPolicies = append(b.authorizationPolicies, policies...)
+	return b
+}
+
+// AddGRPCRoutes adds routes with header matchers from the provided GRPCRoutes to the route configurations.
+func (b *SnapshotBuilder) AddGRPCRoutes(routes []GRPCRoute) *SnapshotBuilder {
+	b.grpcRoutes = append(b.grpcRoutes, routes...)
+	return b
+}
+
+// AddTLSSecrets adds SDS resources for the provided TLS Secrets to the snapshot.
+func (b *SnapshotBuilder) AddTLSSecrets(tlsSecrets []TLSSecret) *SnapshotBuilder {
+	for _, tlsSecret := range tlsSecrets {
+		for _, secret := range createSecrets(tlsSecret) {
+			b.secrets[secret.Name] = secret
+		}
+	}
+	return b
+}
+
 // Build adds the server listeners and route configuration for the node hash, and then builds the snapshot.
-func (b *SnapshotBuilder) Build() (cachev3.ResourceSnapshot, error) {
+// `versionFor` returns the version for the resources of each resource type.
+func (b *SnapshotBuilder) Build(versionFor func(typeURL resource.Type, resources []types.Resource) string) (*cachev3.Snapshot, error) {
 	rbacPerRouteConfig, err := createRBACPerRouteConfig("xds", "host-certs")
 	if err != nil {
 		return nil, fmt.Errorf("could not marshall RBACPerRoute typedConfig into Any instance: %w", err)
 	}
+	authorizationPolicyFilters, err := createAuthorizationPolicyFilters(b.authorizationPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("could not create RBAC HTTP filters for authorization policies: %w", err)
+	}
 	for address := range b.serverListenerAddresses {
 		serverListener, err := createServerListener(
 			address.Host,
 			address.Port,
 			serverListenerRouteConfigurationName,
 			rbacPerRouteConfig,
+			authorizationPolicyFilters,
 			b.features.ServerListenerUsesRDS,
 			b.features.EnableDataPlaneTLS,
-			b.features.RequireDataPlaneClientCerts)
+			b.features.RequireDataPlaneClientCerts,
+			b.features.DataPlaneTLSSecret)
 		if err != nil {
 			return nil, fmt.Errorf("could not create server Listener for address %s:%d: %w", address.Host, address.Port, err)
 		}
 		b.listeners[serverListener.Name] = serverListener
 	}
+	b.addGRPCRoutes()
 	if len(b.serverListenerAddresses) > 0 {
 		routeConfigurationForServerListener := createRouteConfigurationForServerListener(serverListenerRouteConfigurationName, rbacPerRouteConfig)
 		b.routeConfigurations[routeConfigurationForServerListener.Name] = routeConfigurationForServerListener
@@ -234,14 +374,22 @@ func (b *SnapshotBuilder) Build() (cachev3.ResourceSnapshot, error) {
 		clusterLoadAssignments[l] = clusterLoadAssignment
 		l++
 	}
+	secrets := make([]types.Resource, 0, len(b.secrets))
+	for _, secret := range b.secrets {
+		secrets = append(secrets, secret)
+	}
 
-	version := strconv.FormatInt(time.Now().UnixNano(), 10)
-	return cachev3.NewSnapshot(version, map[resource.Type][]types.Resource{
+	snapshot := &cachev3.Snapshot{}
+	for typeURL, resources := range map[resource.Type][]types.Resource{
 		resource.ListenerType: listeners,
 		resource.RouteType:    routeConfigurations,
 		resource.ClusterType:  clusters,
 		resource.EndpointType: clusterLoadAssignments,
-	})
+		resource.SecretType:   secrets,
+	} {
+		snapshot.Resources[cachev3.GetResponseType(typeURL)] = cachev3.NewResources(versionFor(typeURL, resources), resources)
+	}
+	return snapshot, nil
 }
 
 func createRBACPerRouteConfig(allowNamespaces ...string) (*anypb.Any, error) {
@@ -283,12 +431,14 @@ func createRBACPerRouteConfig(allowNamespaces ...string) (*anypb.Any, error) {
 	})
 }
 
-// createAPIListener returns an LDS API listener
+// createAPIListener returns an LDS API listener, with optional HTTP connection manager settings from the listener configuration,
+// with the fault injection configuration of the gRPC application, with `ext_authz` and `ratelimit` HTTP filters,
+// and with additional access logs, e.g., from `AccessLogConfig`s.
 //
 // [gRFC A27]: https://github.com/grpc/proposal/blob/master/A27-xds-global-load-balancing.md#listener-proto
 // [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/listener/v3/api_listener.proto
-func createAPIListener(name string, statPrefix string, routeConfigurationName string) (*listenerv3.Listener, error) {
-	httpFaultFilterTypedConfig, err := anypb.New(&faultv3.HTTPFault{})
+func createAPIListener(name string, statPrefix string, routeConfigurationName string, appProtocol string, listenerConfig *ListenerConfig, faultInjection FaultInjection, extAuthzFilters []*hcmv3.HttpFilter, rateLimitFilters []*hcmv3.HttpFilter, accessLogs []*accesslogv3.AccessLog) (*listenerv3.Listener, error) {
+	httpFaultFilterTypedConfig, err := anypb.New(createHTTPFault(faultInjection))
 	if err != nil {
 		return nil, fmt.Errorf("could not marshall HTTPFault typedConfig into Any instance: %w", err)
 	}
@@ -299,7 +449,7 @@ func createAPIListener(name string, statPrefix string, routeConfigurationName st
 		return nil, fmt.Errorf("could not marshall Router typedConfig into Any instance: %w", err)
 	}
 	httpConnectionManager := &hcmv3.HttpConnectionManager{
-		CodecType: hcmv3.HttpConnectionManager_AUTO,
+		CodecType: httpCodecType(appProtocol),
 		// https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/stats#config-http-conn-man-stats
 		StatPrefix: statPrefix,
 		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
@@ -321,15 +471,23 @@ func createAPIListener(name string, statPrefix string, routeConfigurationName st
 					TypedConfig: httpFaultFilterTypedConfig,
 				},
 			},
-			{
-				// Router must be the last filter.
-				Name: envoyFilterHTTPRouterName,
-				ConfigType: &hcmv3.HttpFilter_TypedConfig{
-					TypedConfig: routerFilterTypedConfig,
-				},
-			},
 		},
 	}
+	// External authorization and rate limit filters go between fault injection and the router,
+	// so that unauthorized requests do not count towards rate limits.
+	httpConnectionManager.HttpFilters = append(httpConnectionManager.HttpFilters, extAuthzFilters...)
+	httpConnectionManager.HttpFilters = append(httpConnectionManager.HttpFilters, rateLimitFilters...)
+	httpConnectionManager.HttpFilters = append(httpConnectionManager.HttpFilters, &hcmv3.HttpFilter{
+		// Router must be the last filter.
+		Name: envoyFilterHTTPRouterName,
+		ConfigType: &hcmv3.HttpFilter_TypedConfig{
+			TypedConfig: routerFilterTypedConfig,
+		},
+	})
+	if err := applyListenerConfig(httpConnectionManager, listenerConfig); err != nil {
+		return nil, fmt.Errorf("could not apply listener configuration to HttpConnectionManager for API listener %s: %w", name, err)
+	}
+	httpConnectionManager.AccessLog = append(httpConnectionManager.AccessLog, accessLogs...)
 	anyWrappedHTTPConnectionManager, err := anypb.New(httpConnectionManager)
 	if err != nil {
 		return nil, fmt.Errorf("could not marshall HttpConnectionManager +%v into Any instance: %w", httpConnectionManager, err)
@@ -343,7 +501,7 @@ func createAPIListener(name string, statPrefix string, routeConfigurationName st
 }
 
 // createServerListener returns a listener for xDS clients that serve gRPC services.
-func createServerListener(host string, port uint32, routeConfigurationName string, rbacPerRouteConfig *anypb.Any, useRDS bool, enableTLS bool, requireClientCerts bool) (*listenerv3.Listener, error) {
+func createServerListener(host string, port uint32, routeConfigurationName string, rbacPerRouteConfig *anypb.Any, authorizationPolicyFilters []*hcmv3.HttpFilter, useRDS bool, enableTLS bool, requireClientCerts bool, tlsSecretName string) (*listenerv3.Listener, error) {
 	routerTypedConfig, err := anypb.New(&routerv3.Router{})
 	if err != nil {
 		return nil, fmt.Errorf("could not marshall Router HTTP filter typedConfig into Any instance: %w", err)
@@ -354,7 +512,7 @@ func createServerListener(host string, port uint32, routeConfigurationName strin
 	if err != nil {
 		return nil, fmt.Errorf("could not marshall RBAC HTTP filter typedConfig into Any instance: %w", err)
 	}
-	httpConnectionManager := createHTTPConnectionManagerForServerListener(routeConfigurationName, routerTypedConfig, rbacFilterTypedConfig, rbacPerRouteConfig, useRDS, enableTLS, requireClientCerts)
+	httpConnectionManager := createHTTPConnectionManagerForServerListener(routeConfigurationName, routerTypedConfig, rbacFilterTypedConfig, rbacPerRouteConfig, authorizationPolicyFilters, useRDS, enableTLS, requireClientCerts)
 	anyWrappedHTTPConnectionManager, err := anypb.New(httpConnectionManager)
 	if err != nil {
 		return nil, fmt.Errorf("could not marshall HttpConnectionManager +%v into Any instance: %w", httpConnectionManager, err)
@@ -370,7 +528,7 @@ func createServerListener(host string, port uint32, routeConfigurationName strin
 		Address: &corev3.Address{
 			Address: &corev3.Address_SocketAddress{
 				SocketAddress: &corev3.SocketAddress{
-					Address: host,
+					Address: socketAddressHost(host),
 					PortSpecifier: &corev3.SocketAddress_PortValue{
 						PortValue: port,
 					},
@@ -395,7 +553,7 @@ func createServerListener(host string, port uint32, routeConfigurationName strin
 	}
 
 	if enableTLS {
-		downstreamTLSContext := createDownstreamTLSContext(requireClientCerts)
+		downstreamTLSContext := createDownstreamTLSContext(requireClientCerts, tlsSecretName)
 		anyWrappedDownstreamTLSContext, err := anypb.New(downstreamTLSContext)
 		if err != nil {
 			return nil, fmt.Errorf("could not marshall DownstreamTlsContext %+v into Any instance: %w", downstreamTLSContext, err)
@@ -411,7 +569,7 @@ func createServerListener(host string, port uint32, routeConfigurationName strin
 	return &serverListener, nil
 }
 
-func createHTTPConnectionManagerForServerListener(routeConfigurationName string, routerFilterTypedConfig *anypb.Any, rbacFilterTypedConfig *anypb.Any, rbacPerRouteTypedConfig *anypb.Any, useRDS bool, enableTLS bool, requireClientCerts bool) *hcmv3.HttpConnectionManager {
+func createHTTPConnectionManagerForServerListener(routeConfigurationName string, routerFilterTypedConfig *anypb.Any, rbacFilterTypedConfig *anypb.Any, rbacPerRouteTypedConfig *anypb.Any, authorizationPolicyFilters []*hcmv3.HttpFilter, useRDS bool, enableTLS bool, requireClientCerts bool) *hcmv3.HttpConnectionManager {
 	httpConnectionManager := hcmv3.HttpConnectionManager{
 		CodecType:  hcmv3.HttpConnectionManager_AUTO,
 		StatPrefix: routeConfigurationName,
@@ -457,6 +615,9 @@ func createHTTPConnectionManagerForServerListener(routeConfigurationName string,
 		}
 	}
 
+	// Prepend RBAC HTTP filters for authorization policies. Not append, as Router must be the last HTTP filter.
+	httpConnectionManager.HttpFilters = append(slices.Clone(authorizationPolicyFilters), httpConnectionManager.HttpFilters...)
+
 	if enableTLS && requireClientCerts {
 		// Prepend RBAC HTTP filter. Not append, as Router must be the last HTTP filter.
 		httpConnectionManager.HttpFilters = append([]*hcmv3.HttpFilter{
@@ -475,7 +636,8 @@ func createHTTPConnectionManagerForServerListener(routeConfigurationName string,
 // createDownstreamTLSContext configures:
 // 1. gRPC server TLS certificate provider
 // 2. certificate authorities (CAs) to validate gRPC client certificates.
-func createDownstreamTLSContext(requireClientCerts bool) *tlsv3.DownstreamTlsContext {
+// If tlsSecretName is not empty, the certificates come from SDS instead, see `useTLSSecret()`.
+func createDownstreamTLSContext(requireClientCerts bool, tlsSecretName string) *tlsv3.DownstreamTlsContext {
 	downstreamTLSContext := tlsv3.DownstreamTlsContext{
 		CommonTlsContext: &tlsv3.CommonTlsContext{
 			// Set server certificate:
@@ -509,6 +671,10 @@ func createDownstreamTLSContext(requireClientCerts bool) *tlsv3.DownstreamTlsCon
 		}
 	}
 
+	if tlsSecretName != "" {
+		useTLSSecret(downstreamTLSContext.CommonTlsContext, tlsSecretName)
+	}
+
 	return &downstreamTLSContext
 }
 
@@ -518,7 +684,16 @@ func createDownstreamTLSContext(requireClientCerts bool) *tlsv3.DownstreamTlsCon
 // The virtual host Name is not used for routing.
 // The virtual host domain must match the request `:authority`
 // Te routePrefix parameter can be an empty string.
-func createRouteConfiguration(name string, virtualHostName string, routePrefix string, clusterName string) *routev3.RouteConfiguration {
+// If trafficSplit is not empty, the route uses weighted clusters instead of clusterName.
+func createRouteConfiguration(name string, virtualHostName string, routePrefix string, clusterName string, trafficSplit []ClusterWeight) *routev3.RouteConfiguration {
+	routeAction := &routev3.RouteAction{
+		ClusterSpecifier: &routev3.RouteAction_Cluster{
+			Cluster: clusterName,
+		},
+	}
+	if len(trafficSplit) > 0 {
+		routeAction.ClusterSpecifier = createWeightedClusters(virtualHostName, trafficSplit)
+	}
 	return &routev3.RouteConfiguration{
 		Name: name,
 		VirtualHosts: []*routev3.VirtualHost{
@@ -533,11 +708,7 @@ func createRouteConfiguration(name string, virtualHostName string, routePrefix s
 							},
 						},
 						Action: &routev3.Route_Route{
-							Route: &routev3.RouteAction{
-								ClusterSpecifier: &routev3.RouteAction_Cluster{
-									Cluster: clusterName,
-								},
-							},
+							Route: routeAction,
 						},
 					},
 				},
@@ -546,6 +717,25 @@ func createRouteConfiguration(name string, virtualHostName string, routePrefix s
 	}
 }
 
+// createWeightedClusters returns a cluster specifier for traffic splitting between clusters.
+// [gRFC A28]: https://github.com/grpc/proposal/blob/master/A28-xds-traffic-splitting-and-routing.md
+func createWeightedClusters(virtualHostName string, trafficSplit []ClusterWeight) *routev3.RouteAction_WeightedClusters {
+	clusterWeights := make([]*routev3.WeightedCluster_ClusterWeight, len(trafficSplit))
+	for i, clusterWeight := range trafficSplit {
+		clusterWeights[i] = &routev3.WeightedCluster_ClusterWeight{
+			Name:   clusterWeight.ClusterName,
+			Weight: wrapperspb.UInt32(clusterWeight.Weight),
+		}
+	}
+	return &routev3.RouteAction_WeightedClusters{
+		WeightedClusters: &routev3.WeightedCluster{
+			Clusters: clusterWeights,
+			// Runtime keys are ignored by gRPC, but allow Envoy proxies to override the weights.
+			RuntimeKeyPrefix: "routing.traffic_split." + virtualHostName,
+		},
+	}
+}
+
 // createRouteConfigurationForServerListener returns an RDS route configuration for the server listeners.
 func createRouteConfigurationForServerListener(name string, rbacPerRouteConfig *anypb.Any) *routev3.RouteConfiguration {
 	return &routev3.RouteConfiguration{
@@ -580,7 +770,7 @@ func createRouteConfigurationForServerListener(name string, rbacPerRouteConfig *
 
 // createCluster definition for CDS.
 // [gRFC A27]: https://github.com/grpc/proposal/blob/972b69ab1f0f7f6079af81a8c2b8a01a15ce3bec/A27-xds-global-load-balancing.md#cluster-proto
-func createCluster(name string, edsServiceName string, namespace string, serviceAccountName string, enableTLS bool, requireClientCerts bool) (*clusterv3.Cluster, error) {
+func createCluster(name string, edsServiceName string, namespace string, serviceAccountName string, enableTLS bool, requireClientCerts bool, tlsSecretName string) (*clusterv3.Cluster, error) {
 	cluster := clusterv3.Cluster{
 		Name: name,
 		ClusterDiscoveryType: &clusterv3.Cluster_Type{
@@ -602,7 +792,7 @@ func createCluster(name string, edsServiceName string, namespace string, service
 	}
 
 	if enableTLS {
-		upstreamTLSContext := createUpstreamTLSContext(namespace, serviceAccountName, requireClientCerts)
+		upstreamTLSContext := createUpstreamTLSContext(namespace, serviceAccountName, requireClientCerts, tlsSecretName)
 		anyWrappedUpstreamTLSContext, err := anypb.New(upstreamTLSContext)
 		if err != nil {
 			return nil, fmt.Errorf("could not marshall UpstreamTlsContext +%v into Any instance: %w", upstreamTLSContext, err)
@@ -622,7 +812,8 @@ func createCluster(name string, edsServiceName string, namespace string, service
 // 1. gRPC client TLS certificate provider
 // 2. certificate authorities (CAs) to validate gRPC server certificates, including server authorization.
 // Important: Assumes that the client application k8s Service account name matches the application name!
-func createUpstreamTLSContext(namespace string, serviceAccountName string, requireClientCerts bool) *tlsv3.UpstreamTlsContext {
+// If tlsSecretName is not empty, the certificates come from SDS instead, see `useTLSSecret()`.
+func createUpstreamTLSContext(namespace string, serviceAccountName string, requireClientCerts bool, tlsSecretName string) *tlsv3.UpstreamTlsContext {
 	upstreamTLSContext := tlsv3.UpstreamTlsContext{
 		CommonTlsContext: &tlsv3.CommonTlsContext{
 			// Validate gRPC server certificates:
@@ -664,13 +855,17 @@ func createUpstreamTLSContext(namespace string, serviceAccountName string, requi
 		}
 	}
 
+	if tlsSecretName != "" {
+		useTLSSecret(upstreamTLSContext.CommonTlsContext, tlsSecretName)
+	}
+
 	return &upstreamTLSContext
 }
 
 // createClusterLoadAssignment for EDS.
 // `edsServiceName` must match the `ServiceName` in the `EDSClusterConfig` in the CDS Cluster resource.
 // [gRFC A27]: https://github.com/grpc/proposal/blob/972b69ab1f0f7f6079af81a8c2b8a01a15ce3bec/A27-xds-global-load-balancing.md#clusterloadassignment-proto
-func createClusterLoadAssignment(edsServiceName string, port uint32, nodeHash string, localityPriorityMapper LocalityPriorityMapper, endpoints []GRPCApplicationEndpoints) *endpointv3.ClusterLoadAssignment {
+func createClusterLoadAssignment(edsServiceName string, port uint32, nodeZone string, localityPriorityMapper LocalityPriorityMapper, endpoints []GRPCApplicationEndpoints) *endpointv3.ClusterLoadAssignment {
 	// addressesByZone := map[string][]string{}
 	// for _, endpoint := range endpoints {
 	//	addressesByZone[endpoint.Zone] = append(addressesByZone[endpoint.Zone], endpoint.Addresses...)
@@ -679,18 +874,19 @@ func createClusterLoadAssignment(edsServiceName string, port uint32, nodeHash st
 	for _, endpoint := range endpoints {
 		endpointsByZone[endpoint.Zone] = append(endpointsByZone[endpoint.Zone], endpoint)
 	}
-	zones := make([]string, len(endpointsByZone))
-	i := 0
+	zones := make([]string, 0, len(endpointsByZone))
 	for zone := range endpointsByZone {
-		zones[i] = zone
-		i++
+		zones = append(zones, zone)
 	}
-	zonePriorities := localityPriorityMapper.BuildPriorityMap(nodeHash, zones)
+	// Sort localities and endpoints, so that equal configuration results in equal resources, see `SnapshotEqual()`.
+	slices.Sort(zones)
+	zonePriorities := localityPriorityMapper.BuildPriorityMap(nodeZone, zones)
 	cla := &endpointv3.ClusterLoadAssignment{
 		ClusterName: edsServiceName,
 		Endpoints:   []*endpointv3.LocalityLbEndpoints{},
 	}
-	for zone, endpoints := range endpointsByZone {
+	for _, zone := range zones {
+		endpoints := endpointsByZone[zone]
 		localityLbEndpoints := &endpointv3.LocalityLbEndpoints{
 			// LbEndpoints is mandatory.
 			LbEndpoints: []*endpointv3.LbEndpoint{},
@@ -705,6 +901,10 @@ func createClusterLoadAssignment(edsServiceName string, port uint32, nodeHash st
 		}
 		for _, endpoint := range endpoints {
 			for _, address := range endpoint.Addresses {
+				if isUnspecifiedAddress(address) {
+					// Not a valid destination address.
+					continue
+				}
 				localityLbEndpoints.LbEndpoints = append(localityLbEndpoints.LbEndpoints,
 					&endpointv3.LbEndpoint{
 						HealthStatus: endpoint.EndpointStatus.HealthStatus(),
@@ -716,7 +916,7 @@ func createClusterLoadAssignment(edsServiceName string, port uint32, nodeHash st
 									Address: &corev3.Address_SocketAddress{
 										SocketAddress: &corev3.SocketAddress{
 											Protocol: corev3.SocketAddress_TCP,
-											Address:  address, // mandatory, IPv4 or IPv6
+											Address:  socketAddressHost(address), // mandatory, IPv4 or IPv6
 											PortSpecifier: &corev3.SocketAddress_PortValue{
 												PortValue: port, // mandatory
 											},
@@ -728,6 +928,9 @@ func createClusterLoadAssignment(edsServiceName string, port uint32, nodeHash st
 					})
 			}
 		}
+		slices.SortStableFunc(localityLbEndpoints.LbEndpoints, func(a *endpointv3.LbEndpoint, b *endpointv3.LbEndpoint) int {
+			return strings.Compare(a.GetEndpoint().GetAddress().GetSocketAddress().GetAddress(), b.GetEndpoint().GetAddress().GetSocketAddress().GetAddress())
+		})
 		cla.Endpoints = append(cla.Endpoints, localityLbEndpoints)
 	}
 	return cla
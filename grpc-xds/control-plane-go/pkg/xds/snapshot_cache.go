@@ -21,12 +21,19 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/server/stream/v3"
 	"github.com/go-logr/logr"
 
 	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/logging"
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
 )
 
 // Server listener resource names typically follow the template `grpc/server?xds.resource.listening_address=%s`.
@@ -61,6 +68,39 @@ type SnapshotCache struct {
 	features *Features
 	// authority is the authority name of this control plane for xDS federation.
 	authority string
+	// listenerConfig contains optional HTTP connection manager settings for LDS API listeners.
+	// It can be replaced at runtime, see `SetListenerConfig()`.
+	listenerConfig atomic.Pointer[ListenerConfig]
+	// authorizationPolicies stores the most recent configuration from `AuthorizationPolicy` custom resources.
+	authorizationPolicies *namespacedCache[AuthorizationPolicy]
+	// grpcRoutes stores the most recent configuration from `GRPCRoute` custom resources.
+	grpcRoutes *namespacedCache[GRPCRoute]
+	// tlsSecrets stores the most recent TLS Secrets for SDS resources, see `Features.DataPlaneTLSSecret`.
+	tlsSecrets *namespacedCache[TLSSecret]
+	// accessLogConfigs stores the most recent configuration from `AccessLogConfig` custom resources.
+	accessLogConfigs *namespacedCache[AccessLogConfig]
+	// extAuthzPolicies stores the most recent configuration from `ExtAuthzPolicy` custom resources.
+	extAuthzPolicies *namespacedCache[ExtAuthzPolicy]
+	// rateLimitPolicies stores the most recent configuration from `RateLimitPolicy` custom resources.
+	rateLimitPolicies *namespacedCache[RateLimitPolicy]
+	// versions assigns versions to resources in new snapshots, per resource type.
+	versions *resourceVersions
+	// reconciler retries failed snapshot updates, see `createNewSnapshots()`.
+	reconciler *reconciler
+	// dryRun receives new snapshots instead of the delegate cache, if set, see `EnableDryRun()`.
+	dryRun *dryRunWriter
+	// snapshotListener is called after each successful snapshot update, if set, see `SetSnapshotListener()`.
+	snapshotListener atomic.Pointer[func()]
+	// restored is true while the delegate cache serves restored snapshots, see `RestoreSnapshots()`.
+	restored           atomic.Bool
+	restoredMu         sync.Mutex
+	restoredNodeHashes []string
+	// nodeGroupNamespaces limits the namespaces in snapshots for node groups, see `SetNodeGroups()`.
+	nodeGroupNamespaces map[string][]string
+	// overriddenNodeHashes contains the node hashes with snapshots set via `SetSnapshot()`, e.g., from
+	// the admin API. Updates, resyncs, and retries skip these node hashes until `ClearSnapshot()`.
+	overriddenMu         sync.Mutex
+	overriddenNodeHashes map[string]bool
 }
 
 var _ cachev3.Cache = &SnapshotCache{}
@@ -70,17 +110,45 @@ var _ cachev3.Cache = &SnapshotCache{}
 // If `allowPartialRequests` is true, the DiscoveryServer will respond to requests for a resource
 // type even if some resources in the snapshot are not named in the request.
 func NewSnapshotCache(ctx context.Context, allowPartialRequests bool, hash cachev3.NodeHash, localityPriorityMapper LocalityPriorityMapper, features *Features, authority string) *SnapshotCache {
-	return &SnapshotCache{
+	c := &SnapshotCache{
 		ctx:                    ctx,
-		logger:                 logging.FromContext(ctx),
+		logger:                 logging.FromContext(ctx).WithValues("component", "snapshot-cache"),
 		delegate:               cachev3.NewSnapshotCache(!allowPartialRequests, hash, logging.SnapshotCacheLogger(ctx)),
 		hash:                   hash,
 		localityPriorityMapper: localityPriorityMapper,
 		appsCache:              NewGRPCApplicationCache(),
 		serverListenerCache:    NewServerListenerCache(),
+		authorizationPolicies:  newNamespacedCache[AuthorizationPolicy](),
+		grpcRoutes:             newNamespacedCache[GRPCRoute](),
+		tlsSecrets:             newNamespacedCache[TLSSecret](),
+		accessLogConfigs:       newNamespacedCache[AccessLogConfig](),
+		extAuthzPolicies:       newNamespacedCache[ExtAuthzPolicy](),
+		rateLimitPolicies:      newNamespacedCache[RateLimitPolicy](),
+		versions:               newResourceVersions(),
+		overriddenNodeHashes:   map[string]bool{},
 		features:               features,
 		authority:              authority,
 	}
+	c.reconciler = newReconciler(c.logger.WithValues("component", "reconciler"), func(nodeHash string) error {
+		return c.createNewSnapshot(nodeHash, c.appsCache.GetAll())
+	})
+	go c.reconciler.run(ctx)
+	return c
+}
+
+// SetMaxReconcileAttempts sets the maximum number of attempts to update the snapshot for a
+// node hash after a failed update, before giving up until the next update.
+func (c *SnapshotCache) SetMaxReconcileAttempts(maxAttempts int) {
+	c.reconciler.maxAttempts.Store(int32(maxAttempts))
+}
+
+// ReconcileNow creates new snapshots for all node hashes in the cache from the current
+// configuration, e.g., after the informer caches have synced on startup, in case events were
+// missed. Failed snapshot updates are retried with back-off, as for other updates.
+func (c *SnapshotCache) ReconcileNow(logger logr.Logger) error {
+	logger.V(2).Info("Reconciling xDS resource snapshots for all node hashes")
+	c.supersedeRestoredSnapshots(logger)
+	return c.createNewSnapshots("", c.appsCache.GetAll())
 }
 
 // CreateWatch intercepts stream creation before delegating, and if it is a new Listener stream, does the following:
@@ -93,32 +161,57 @@ func NewSnapshotCache(ctx context.Context, allowPartialRequests bool, hash cache
 //
 // This solves (in a slightly hacky way) bootstrapping of xDS-enabled gRPC servers.
 func (c *SnapshotCache) CreateWatch(request *cachev3.Request, state stream.StreamState, responses chan cachev3.Response) (cancel func()) {
-	if request != nil && len(request.ResourceNames) > 0 && request.GetTypeUrl() == "type.googleapis.com/envoy.config.listener.v3.Listener" {
+	if request != nil && len(request.ResourceNames) > 0 && request.GetTypeUrl() == resource.ListenerType {
 		c.logger.Info("CreateWatch", "request.ResourceNames", request.ResourceNames)
-		nodeHash := c.hash.ID(request.GetNode())
-		addressesFromRequest, err := findServerListenerAddresses(request.ResourceNames)
-		if err != nil {
-			c.logger.Error(err, "Problem encountered when looking for server listener addresses in new Listener stream request", "nodeHash", nodeHash)
+		if err := c.handleServerListenerNames(request.GetNode(), request.ResourceNames); err != nil {
+			c.logger.Error(err, "Could not handle server listener names in new Listener stream request")
 			return func() {}
 		}
-		changes := c.serverListenerCache.Add(nodeHash, addressesFromRequest)
-		_, err = c.delegate.GetSnapshot(nodeHash)
-		if err != nil || changes {
-			apps := c.appsCache.GetAll()
-			if err := c.createNewSnapshot(nodeHash, apps); err != nil {
-				c.logger.Error(err, "Could not set new xDS resource snapshot", "nodeHash", nodeHash, "apps", apps)
-				return func() {}
-			}
-		}
 	}
 	return c.delegate.CreateWatch(request, state, responses)
 }
 
+// CreateDeltaWatch intercepts delta (incremental) stream creation before delegating,
+// and handles server listener subscriptions in the same way as `CreateWatch()`.
+//
+// The delegate cache computes per-resource versions for each snapshot, so delta
+// streams only receive the resources that changed since the previous response.
+func (c *SnapshotCache) CreateDeltaWatch(request *cachev3.DeltaRequest, state stream.StreamState, responses chan cachev3.DeltaResponse) (cancel func()) {
+	if request != nil && len(request.ResourceNamesSubscribe) > 0 && request.GetTypeUrl() == resource.ListenerType {
+		c.logger.Info("CreateDeltaWatch", "request.ResourceNamesSubscribe", request.ResourceNamesSubscribe)
+		if err := c.handleServerListenerNames(request.GetNode(), request.ResourceNamesSubscribe); err != nil {
+			c.logger.Error(err, "Could not handle server listener names in new delta Listener stream request")
+			return func() {}
+		}
+	}
+	return c.delegate.CreateDeltaWatch(request, state, responses)
+}
+
+// handleServerListenerNames adds any server listener addresses found in the provided
+// resource names to the server listener cache, and creates a new snapshot for the
+// node hash if there is no existing snapshot, or if new server listener addresses were found.
+func (c *SnapshotCache) handleServerListenerNames(node *corev3.Node, resourceNames []string) error {
+	nodeHash := c.hash.ID(node)
+	addressesFromRequest, err := findServerListenerAddresses(resourceNames)
+	if err != nil {
+		return fmt.Errorf("problem encountered when looking for server listener addresses for nodeHash=%s: %w", nodeHash, err)
+	}
+	changes := c.serverListenerCache.Add(nodeHash, addressesFromRequest)
+	_, err = c.delegate.GetSnapshot(nodeHash)
+	if err != nil || changes {
+		apps := c.appsCache.GetAll()
+		if err := c.createNewSnapshot(nodeHash, apps); err != nil {
+			c.reconciler.retry(nodeHash)
+			return fmt.Errorf("could not set new xDS resource snapshot for nodeHash=%s and apps=%+v: %w", nodeHash, apps, err)
+		}
+	}
+	return nil
+}
+
 // UpdateResources creates a new snapshot for each node hash in the cache,
 // based on the provided gRPC application configuration,
 // with the addition of server listeners and their associated route configurations.
 func (c *SnapshotCache) UpdateResources(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, updatedApps []GRPCApplication) error {
-	var errs []error
 	changed := c.appsCache.Put(kubecontextName, namespace, updatedApps)
 	if !changed {
 		logger.V(2).Info("No application updates, so not generating new xDS resource snapshots")
@@ -126,10 +219,102 @@ func (c *SnapshotCache) UpdateResources(_ context.Context, logger logr.Logger, k
 	}
 	apps := c.appsCache.GetAll()
 	logger.V(2).Info("Application updates, generating new xDS resource snapshots", "apps", apps)
-	for _, nodeHash := range c.delegate.GetStatusKeys() {
+	return c.createNewSnapshots(namespace, apps)
+}
+
+// UpdateAuthorizationPolicies creates a new snapshot for each node hash in the cache,
+// if the provided authorization policies changed the cached policies for the kubecontext and namespace.
+func (c *SnapshotCache) UpdateAuthorizationPolicies(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, policies []AuthorizationPolicy) error {
+	if !c.authorizationPolicies.Put(kubecontextName, namespace, policies) {
+		logger.V(2).Info("No authorization policy updates, so not generating new xDS resource snapshots")
+		return nil
+	}
+	logger.V(2).Info("Authorization policy updates, generating new xDS resource snapshots", "policies", policies)
+	return c.createNewSnapshots(namespace, c.appsCache.GetAll())
+}
+
+// UpdateGRPCRoutes creates a new snapshot for each node hash in the cache,
+// if the provided GRPCRoutes changed the cached routes for the kubecontext and namespace.
+func (c *SnapshotCache) UpdateGRPCRoutes(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, routes []GRPCRoute) error {
+	if !c.grpcRoutes.Put(kubecontextName, namespace, routes) {
+		logger.V(2).Info("No GRPCRoute updates, so not generating new xDS resource snapshots")
+		return nil
+	}
+	logger.V(2).Info("GRPCRoute updates, generating new xDS resource snapshots", "grpcRoutes", routes)
+	return c.createNewSnapshots(namespace, c.appsCache.GetAll())
+}
+
+// UpdateAccessLogConfigs creates a new snapshot for each node hash in the cache,
+// if the provided access log configurations changed the cached configurations for the kubecontext and namespace.
+// The new snapshots contain updated LDS API listeners for the gRPC applications in the namespace.
+func (c *SnapshotCache) UpdateAccessLogConfigs(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, accessLogConfigs []AccessLogConfig) error {
+	if !c.accessLogConfigs.Put(kubecontextName, namespace, accessLogConfigs) {
+		logger.V(2).Info("No AccessLogConfig updates, so not generating new xDS resource snapshots")
+		return nil
+	}
+	logger.V(2).Info("AccessLogConfig updates, generating new xDS resource snapshots", "accessLogConfigs", accessLogConfigs)
+	return c.createNewSnapshots(namespace, c.appsCache.GetAll())
+}
+
+// UpdateExtAuthzPolicies creates a new snapshot for each node hash in the cache,
+// if the provided ext_authz policies changed the cached policies for the kubecontext and namespace.
+// The new snapshots contain updated LDS API listeners for the gRPC applications in the namespace,
+// so deleting a policy removes its `ext_authz` HTTP filter.
+func (c *SnapshotCache) UpdateExtAuthzPolicies(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, policies []ExtAuthzPolicy) error {
+	if !c.extAuthzPolicies.Put(kubecontextName, namespace, policies) {
+		logger.V(2).Info("No ExtAuthzPolicy updates, so not generating new xDS resource snapshots")
+		return nil
+	}
+	logger.V(2).Info("ExtAuthzPolicy updates, generating new xDS resource snapshots", "extAuthzPolicies", policies)
+	return c.createNewSnapshots(namespace, c.appsCache.GetAll())
+}
+
+// UpdateRateLimitPolicies creates a new snapshot for each node hash in the cache,
+// if the provided rate limit policies changed the cached policies for the kubecontext and namespace.
+// The `ratelimit` HTTP filters in LDS API listeners and the rate limit actions in RDS route configurations
+// change in the same snapshot, so xDS clients receive both updates from the same snapshot version.
+// Node hashes with the namespace out of scope, e.g., of other node groups, do not get new snapshots.
+func (c *SnapshotCache) UpdateRateLimitPolicies(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, policies []RateLimitPolicy) error {
+	if !c.rateLimitPolicies.Put(kubecontextName, namespace, policies) {
+		logger.V(2).Info("No RateLimitPolicy updates, so not generating new xDS resource snapshots")
+		return nil
+	}
+	logger.V(2).Info("RateLimitPolicy updates, generating new xDS resource snapshots", "rateLimitPolicies", policies)
+	return c.createNewSnapshots(namespace, c.appsCache.GetAll())
+}
+
+// UpdateTLSSecrets creates a new snapshot for each node hash in the cache,
+// if the provided TLS Secrets changed the cached Secrets for the kubecontext and namespace.
+// All node hashes receive the new snapshot, as the TLS contexts of all clusters and server
+// listeners reference the Secret.
+func (c *SnapshotCache) UpdateTLSSecrets(_ context.Context, logger logr.Logger, kubecontextName string, namespace string, tlsSecrets []TLSSecret) error {
+	if !c.tlsSecrets.Put(kubecontextName, namespace, tlsSecrets) {
+		logger.V(2).Info("No TLS Secret updates, so not generating new xDS resource snapshots")
+		return nil
+	}
+	// Not logging the Secrets, as they contain private keys.
+	logger.V(2).Info("TLS Secret updates, generating new xDS resource snapshots")
+	return c.createNewSnapshots("", c.appsCache.GetAll())
+}
+
+// createNewSnapshots sets a new snapshot for each node hash in the cache that has the namespace in scope.
+// Node hashes with a different scope are skipped, so that their xDS clients do not see updates
+// for resources in other namespaces. An empty namespace means all node hashes.
+//
+// Failed snapshot updates are retried with exponential back-off, so that the cache does not stay
+// stale until the next update. The errors are still returned, for logging.
+func (c *SnapshotCache) createNewSnapshots(namespace string, apps []GRPCApplication) error {
+	var errs []error
+	for _, nodeHash := range c.nodeHashes() {
+		if !c.namespaceInScope(nodeHash, namespace) {
+			continue
+		}
 		if err := c.createNewSnapshot(nodeHash, apps); err != nil {
 			errs = append(errs, err)
+			c.reconciler.retry(nodeHash)
+			continue
 		}
+		c.reconciler.forget(nodeHash)
 	}
 	if len(errs) > 0 {
 		return errors.Join(errs...)
@@ -137,25 +322,132 @@ func (c *SnapshotCache) UpdateResources(_ context.Context, logger logr.Logger, k
 	return nil
 }
 
+// SetListenerConfig replaces the listener configuration, and if it changed,
+// creates a new snapshot for each node hash in the cache.
+func (c *SnapshotCache) SetListenerConfig(logger logr.Logger, listenerConfig *ListenerConfig) error {
+	previous := c.listenerConfig.Swap(listenerConfig)
+	if previous != nil && listenerConfig != nil && *previous == *listenerConfig {
+		logger.V(2).Info("No listener configuration changes, so not generating new xDS resource snapshots")
+		return nil
+	}
+	logger.V(2).Info("Listener configuration updated, generating new xDS resource snapshots", "listenerConfig", listenerConfig)
+	return c.createNewSnapshots("", c.appsCache.GetAll())
+}
+
+// ListenerConfig returns the current listener configuration, or nil if it has not been set.
+func (c *SnapshotCache) ListenerConfig() *ListenerConfig {
+	return c.listenerConfig.Load()
+}
+
 // createNewSnapshot sets a new snapshot for the provided `nodeHash` and gRPC application configuration.
+// If the node hash is scoped to a namespace, the snapshot only contains resources from that namespace.
+//
+// In dry-run mode, the snapshot is written to the dry-run writer instead, see `EnableDryRun()`.
+// While restored snapshots are in use, no snapshot is set, see `RestoreSnapshots()`.
+// No snapshot is set for node hashes with a snapshot from `SetSnapshot()`, until `ClearSnapshot()`.
+//
+// If the resources of all types are equal to the current snapshot, no snapshot is set,
+// so that events that do not change the xDS resources, e.g., EndpointSlice updates
+// that only change fields not used for EDS, do not cause xDS responses.
 func (c *SnapshotCache) createNewSnapshot(nodeHash string, apps []GRPCApplication) error {
-	c.logger.Info("Creating a new snapshot", "nodeHash", nodeHash, "apps", apps)
-	snapshotBuilder, err := NewSnapshotBuilder(nodeHash, c.localityPriorityMapper, c.features, c.authority).AddGRPCApplications(apps)
+	if c.restored.Load() {
+		// Keep serving the restored snapshots until the informer caches have synced.
+		return nil
+	}
+	if c.overridden(nodeHash) {
+		c.logger.V(4).Info("Keeping the xDS resource snapshot set via SetSnapshot()", "nodeHash", nodeHash)
+		return nil
+	}
+	start := time.Now()
+	previous, err := c.delegate.GetSnapshot(nodeHash)
 	if err != nil {
-		return fmt.Errorf("could not create xDS resource snapshot builder for nodeHash=%s: %w", nodeHash, err)
+		previous = nil
 	}
-	snapshot, err := snapshotBuilder.
-		AddServerListenerAddresses(c.serverListenerCache.Get(nodeHash)).
-		Build()
+	snapshot, changedTypes, err := c.buildSnapshot(nodeHash, apps, previous)
 	if err != nil {
-		return fmt.Errorf("could not create new xDS resource snapshot for nodeHash=%s: %w", nodeHash, err)
+		return err
+	}
+	if previous != nil && SnapshotEqual(previous, snapshot) {
+		c.logger.V(4).Info("No xDS resource changes, keeping the current snapshot", "nodeHash", nodeHash)
+		return nil
+	}
+	return c.setSnapshot(nodeHash, snapshot, changedTypes, start)
+}
+
+// setSnapshot validates the snapshot and sets it for the provided `nodeHash`,
+// or writes it to the dry-run writer in dry-run mode.
+// `start` is the time when building the snapshot started, for metrics.
+func (c *SnapshotCache) setSnapshot(nodeHash string, snapshot *cachev3.Snapshot, changedTypes []string, start time.Time) error {
+	// Skip inconsistent snapshots, e.g., with routes to clusters that do not exist. The reconciler retries.
+	if err := validateSnapshot(nodeHash, snapshot); err != nil {
+		c.logger.Error(err, "Skipping xDS resource snapshot update")
+		return err
+	}
+	if c.dryRun != nil {
+		return c.writeDryRunSnapshot(nodeHash, snapshot)
 	}
 	if err := c.delegate.SetSnapshot(c.ctx, nodeHash, snapshot); err != nil {
 		return fmt.Errorf("could not set new xDS resource snapshot for nodeHash=%s: %w", nodeHash, err)
 	}
+	metrics.XDSSnapshotUpdated(time.Since(start), changedTypes...)
+	if listener := c.snapshotListener.Load(); listener != nil {
+		(*listener)()
+	}
 	return nil
 }
 
+// buildSnapshot creates a snapshot for the provided `nodeHash` and gRPC application configuration,
+// and returns it together with the resource types that changed compared to the `previous` snapshot.
+func (c *SnapshotCache) buildSnapshot(nodeHash string, apps []GRPCApplication, previous cachev3.ResourceSnapshot) (*cachev3.Snapshot, []string, error) {
+	inScope := func(namespace string) bool {
+		return c.namespaceInScope(nodeHash, namespace)
+	}
+	apps = filterByScope(inScope, apps, func(app GRPCApplication) string {
+		return app.Namespace
+	})
+	authorizationPolicies := filterByScope(inScope, c.authorizationPolicies.GetAll(), func(policy AuthorizationPolicy) string {
+		return policy.Namespace
+	})
+	grpcRoutes := filterByScope(inScope, c.grpcRoutes.GetAll(), func(route GRPCRoute) string {
+		return route.Namespace
+	})
+	accessLogConfigs := filterByScope(inScope, c.accessLogConfigs.GetAll(), func(accessLogConfig AccessLogConfig) string {
+		return accessLogConfig.Namespace
+	})
+	extAuthzPolicies := filterByScope(inScope, c.extAuthzPolicies.GetAll(), func(policy ExtAuthzPolicy) string {
+		return policy.Namespace
+	})
+	rateLimitPolicies := filterByScope(inScope, c.rateLimitPolicies.GetAll(), func(policy RateLimitPolicy) string {
+		return policy.Namespace
+	})
+	c.logger.Info("Creating a new snapshot", "nodeHash", nodeHash, "apps", apps)
+	snapshotBuilder, err := NewSnapshotBuilder(nodeHash, c.localityPriorityMapper, c.features, c.listenerConfig.Load(), c.authority).
+		AddAccessLogConfigs(accessLogConfigs).
+		AddExtAuthzPolicies(extAuthzPolicies).
+		AddRateLimitPolicies(rateLimitPolicies).
+		AddGRPCApplications(apps)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create xDS resource snapshot builder for nodeHash=%s: %w", nodeHash, err)
+	}
+	var changedTypes []string
+	snapshot, err := snapshotBuilder.
+		AddServerListenerAddresses(c.serverListenerCache.Get(nodeHash)).
+		AddAuthorizationPolicies(authorizationPolicies).
+		AddGRPCRoutes(grpcRoutes).
+		AddTLSSecrets(c.tlsSecrets.GetAll()).
+		Build(func(typeURL resource.Type, resources []types.Resource) string {
+			version := c.versions.versionFor(previous, typeURL, resources)
+			if previous == nil || version != previous.GetVersion(typeURL) {
+				changedTypes = append(changedTypes, typeURL)
+			}
+			return version
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create new xDS resource snapshot for nodeHash=%s: %w", nodeHash, err)
+	}
+	return snapshot, changedTypes, nil
+}
+
 // findServerListenerAddresses looks for server Listener names in the provided
 // slice and extracts the address and port for each server Listener found.
 // TODO: Handle xDS federation server Listener names using `xdstp://` names,
@@ -182,10 +474,44 @@ func findServerListenerAddresses(names []string) ([]EndpointAddress, error) {
 	return addresses, nil
 }
 
-// CreateDeltaWatch just delegates, since gRPC does not support delta/incremental xDS currently.
-// TODO: Handle request for gRPC server Listeners once gRPC implementation support delta/incremental xDS.
-func (c *SnapshotCache) CreateDeltaWatch(request *cachev3.DeltaRequest, state stream.StreamState, responses chan cachev3.DeltaResponse) (cancel func()) {
-	return c.delegate.CreateDeltaWatch(request, state, responses)
+// GetGRPCApplication returns the most recent configuration of the gRPC application with the provided name,
+// and false if there is no configuration for that application.
+func (c *SnapshotCache) GetGRPCApplication(kubecontextName string, namespace string, name string) (GRPCApplication, bool) {
+	return c.appsCache.Find(kubecontextName, namespace, name)
+}
+
+// GetSnapshot returns the current snapshot for the provided node hash.
+func (c *SnapshotCache) GetSnapshot(nodeHash string) (cachev3.ResourceSnapshot, error) {
+	return c.delegate.GetSnapshot(nodeHash)
+}
+
+// SetSnapshot replaces the snapshot for the provided node hash, e.g., from the admin API.
+// Updates from Kubernetes informers, resyncs, and retries do not replace the snapshot
+// for this node hash until `ClearSnapshot()` is called.
+func (c *SnapshotCache) SetSnapshot(ctx context.Context, nodeHash string, snapshot cachev3.ResourceSnapshot) error {
+	c.overriddenMu.Lock()
+	defer c.overriddenMu.Unlock()
+	if err := c.delegate.SetSnapshot(ctx, nodeHash, snapshot); err != nil {
+		return err
+	}
+	c.overriddenNodeHashes[nodeHash] = true
+	return nil
+}
+
+// ClearSnapshot removes the snapshot for the provided node hash, including a snapshot from `SetSnapshot()`.
+// xDS clients with this node hash do not receive resources until the next snapshot is created.
+func (c *SnapshotCache) ClearSnapshot(nodeHash string) {
+	c.overriddenMu.Lock()
+	defer c.overriddenMu.Unlock()
+	delete(c.overriddenNodeHashes, nodeHash)
+	c.delegate.ClearSnapshot(nodeHash)
+}
+
+// overridden returns true if the snapshot for the provided node hash was set via `SetSnapshot()`.
+func (c *SnapshotCache) overridden(nodeHash string) bool {
+	c.overriddenMu.Lock()
+	defer c.overriddenMu.Unlock()
+	return c.overriddenNodeHashes[nodeHash]
 }
 
 func (c *SnapshotCache) Fetch(ctx context.Context, request *cachev3.Request) (cachev3.Response, error) {
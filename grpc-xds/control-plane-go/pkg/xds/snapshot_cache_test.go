@@ -0,0 +1,310 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/stream/v3"
+	"github.com/go-logr/logr"
+)
+
+const testZone = "zone-a"
+
+var testNode = &corev3.Node{Id: "test-node", Locality: &corev3.Locality{Zone: testZone}}
+
+// countingSnapshotCache counts the calls to `SetSnapshot()` before delegating.
+type countingSnapshotCache struct {
+	cachev3.SnapshotCache
+	setSnapshotCalls int
+}
+
+func (c *countingSnapshotCache) SetSnapshot(ctx context.Context, nodeHash string, snapshot cachev3.ResourceSnapshot) error {
+	c.setSnapshotCalls++
+	return c.SnapshotCache.SetSnapshot(ctx, nodeHash, snapshot)
+}
+
+// newTestSnapshotCache creates a SnapshotCache with a counting delegate cache, and with a CDS watch
+// for a node in `testZone`, so that updates create snapshots for that node hash.
+func newTestSnapshotCache(t *testing.T) (*SnapshotCache, *countingSnapshotCache) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := NewSnapshotCache(ctx, false, ZoneHash{}, FixedLocalityPriority{}, &Features{}, "xds.example.com")
+	delegate := &countingSnapshotCache{SnapshotCache: c.delegate}
+	c.delegate = delegate
+	request := &cachev3.Request{
+		Node:    testNode,
+		TypeUrl: resource.ClusterType,
+	}
+	cancelWatch := c.CreateWatch(request, stream.NewStreamState(false, nil), make(chan cachev3.Response, 10))
+	t.Cleanup(cancelWatch)
+	return c, delegate
+}
+
+// testGRPCApplication creates a gRPC application with the provided number of endpoints in `testZone`.
+func testGRPCApplication(name string, numEndpoints int) GRPCApplication {
+	addresses := make([]string, numEndpoints)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+	}
+	endpoints := []GRPCApplicationEndpoints{NewGRPCApplicationEndpoints("node-1", testZone, addresses, Healthy)}
+	return NewGRPCApplication("default", name, 50051, endpoints)
+}
+
+// testMultiZoneGRPCApplication creates a gRPC application with endpoints on two nodes in each of
+// `testZone`, zone-b, and zone-c. If `reversed` is true, the endpoints are in reverse order,
+// as for applications that are not created using `NewGRPCApplication()`, which sorts the endpoints.
+func testMultiZoneGRPCApplication(name string, reversed bool) GRPCApplication {
+	var endpoints []GRPCApplicationEndpoints
+	for i, zone := range []string{testZone, "zone-b", "zone-c"} {
+		endpoints = append(endpoints,
+			NewGRPCApplicationEndpoints(zone+"-node-1", zone, []string{fmt.Sprintf("10.1.%d.2", i)}, Healthy),
+			NewGRPCApplicationEndpoints(zone+"-node-2", zone, []string{fmt.Sprintf("10.1.%d.1", i)}, Healthy),
+		)
+	}
+	app := NewGRPCApplication("default", name, 50051, endpoints)
+	if reversed {
+		slices.Reverse(app.Endpoints)
+	}
+	return app
+}
+
+// buildTestSnapshot builds a snapshot for the provided apps, with the same version for all resource types.
+func buildTestSnapshot(tb testing.TB, version string, apps []GRPCApplication) *cachev3.Snapshot {
+	tb.Helper()
+	builder, err := NewSnapshotBuilder(testZone, FixedLocalityPriority{}, &Features{}, nil, "xds.example.com").AddGRPCApplications(apps)
+	if err != nil {
+		tb.Fatalf("AddGRPCApplications(): %v", err)
+	}
+	snapshot, err := builder.Build(func(resource.Type, []types.Resource) string {
+		return version
+	})
+	if err != nil {
+		tb.Fatalf("Build(): %v", err)
+	}
+	return snapshot
+}
+
+func TestSnapshotEqual(t *testing.T) {
+	app := testGRPCApplication("app", 3)
+	tests := []struct {
+		name string
+		a    cachev3.ResourceSnapshot
+		b    cachev3.ResourceSnapshot
+		want bool
+	}{
+		{
+			name: "both nil",
+			want: true,
+		},
+		{
+			name: "one nil",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{app}),
+			want: false,
+		},
+		{
+			name: "same resources with different versions",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{app}),
+			b:    buildTestSnapshot(t, "2", []GRPCApplication{app}),
+			want: true,
+		},
+		{
+			name: "different endpoints",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{app}),
+			b:    buildTestSnapshot(t, "1", []GRPCApplication{testGRPCApplication("app", 4)}),
+			want: false,
+		},
+		{
+			name: "additional application",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{app}),
+			b:    buildTestSnapshot(t, "1", []GRPCApplication{app, testGRPCApplication("other", 3)}),
+			want: false,
+		},
+		{
+			name: "same multi-zone endpoints",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{testMultiZoneGRPCApplication("app", false)}),
+			b:    buildTestSnapshot(t, "1", []GRPCApplication{testMultiZoneGRPCApplication("app", false)}),
+			want: true,
+		},
+		{
+			name: "same multi-zone endpoints in a different order",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{testMultiZoneGRPCApplication("app", false)}),
+			b:    buildTestSnapshot(t, "1", []GRPCApplication{testMultiZoneGRPCApplication("app", true)}),
+			want: true,
+		},
+		{
+			name: "multi-zone and single-zone endpoints",
+			a:    buildTestSnapshot(t, "1", []GRPCApplication{testMultiZoneGRPCApplication("app", false)}),
+			b:    buildTestSnapshot(t, "1", []GRPCApplication{app}),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SnapshotEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("SnapshotEqual() = %t, want %t", got, tt.want)
+			}
+			if got := SnapshotEqual(tt.b, tt.a); got != tt.want {
+				t.Errorf("SnapshotEqual() with swapped arguments = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateResourcesSkipsUnchangedSnapshots(t *testing.T) {
+	app := testGRPCApplication("app", 3)
+	appWithLabels := app
+	appWithLabels.Labels = map[string]string{"team": "a"}
+	tests := []struct {
+		name                 string
+		updates              [][]GRPCApplication
+		wantSetSnapshotCalls int
+	}{
+		{
+			name:                 "duplicate event",
+			updates:              [][]GRPCApplication{{app}, {app}},
+			wantSetSnapshotCalls: 1,
+		},
+		{
+			name:                 "change that does not affect xDS resources",
+			updates:              [][]GRPCApplication{{app}, {appWithLabels}},
+			wantSetSnapshotCalls: 1,
+		},
+		{
+			name:                 "new endpoint",
+			updates:              [][]GRPCApplication{{app}, {testGRPCApplication("app", 4)}},
+			wantSetSnapshotCalls: 2,
+		},
+		{
+			name:                 "duplicate multi-zone events",
+			updates:              [][]GRPCApplication{{testMultiZoneGRPCApplication("app", false)}, {testMultiZoneGRPCApplication("app", false)}, {testMultiZoneGRPCApplication("app", false)}},
+			wantSetSnapshotCalls: 1,
+		},
+		{
+			name:                 "multi-zone endpoints in a different order",
+			updates:              [][]GRPCApplication{{testMultiZoneGRPCApplication("app", false)}, {testMultiZoneGRPCApplication("app", true)}},
+			wantSetSnapshotCalls: 1,
+		},
+		{
+			name:                 "multi-zone endpoint removed",
+			updates:              [][]GRPCApplication{{testMultiZoneGRPCApplication("app", false)}, {app}},
+			wantSetSnapshotCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, delegate := newTestSnapshotCache(t)
+			for _, apps := range tt.updates {
+				if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", apps); err != nil {
+					t.Fatalf("UpdateResources(): %v", err)
+				}
+			}
+			if delegate.setSnapshotCalls != tt.wantSetSnapshotCalls {
+				t.Errorf("SetSnapshot() calls = %d, want %d", delegate.setSnapshotCalls, tt.wantSetSnapshotCalls)
+			}
+		})
+	}
+}
+
+func TestCreateDeltaWatchSendsOnlyChangedEndpoints(t *testing.T) {
+	c, _ := newTestSnapshotCache(t)
+	update := func(apps ...GRPCApplication) {
+		t.Helper()
+		if err := c.UpdateResources(context.Background(), logr.Discard(), "kubecontext", "default", apps); err != nil {
+			t.Fatalf("UpdateResources(): %v", err)
+		}
+	}
+	receive := func(responses chan cachev3.DeltaResponse) cachev3.DeltaResponse {
+		t.Helper()
+		select {
+		case response := <-responses:
+			return response
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for delta response")
+			return nil
+		}
+	}
+	state := stream.NewStreamState(true, map[string]string{})
+	request := &cachev3.DeltaRequest{
+		Node:    testNode,
+		TypeUrl: resource.EndpointType,
+	}
+
+	update(testGRPCApplication("app-a", 3), testGRPCApplication("app-b", 3))
+	responses := make(chan cachev3.DeltaResponse, 1)
+	// The watch responds immediately, as the snapshot exists, and does not return a cancel function.
+	if cancel := c.CreateDeltaWatch(request, state, responses); cancel != nil {
+		cancel()
+	}
+	response := receive(responses)
+	if got, want := deltaResourceNames(t, response), []string{"app-a", "app-b"}; !slices.Equal(got, want) {
+		t.Fatalf("initial delta response resources = %v, want %v", got, want)
+	}
+	state.SetResourceVersions(response.GetNextVersionMap())
+
+	responses = make(chan cachev3.DeltaResponse, 1)
+	if cancel := c.CreateDeltaWatch(request, state, responses); cancel != nil {
+		defer cancel()
+	}
+	update(testGRPCApplication("app-a", 4), testGRPCApplication("app-b", 3))
+	response = receive(responses)
+	if got, want := deltaResourceNames(t, response), []string{"app-a"}; !slices.Equal(got, want) {
+		t.Errorf("delta response resources after adding an endpoint = %v, want %v", got, want)
+	}
+	deltaDiscoveryResponse, err := response.GetDeltaDiscoveryResponse()
+	if err != nil {
+		t.Fatalf("GetDeltaDiscoveryResponse(): %v", err)
+	}
+	if removed := deltaDiscoveryResponse.GetRemovedResources(); len(removed) > 0 {
+		t.Errorf("delta response removed resources = %v, want none", removed)
+	}
+}
+
+// deltaResourceNames returns the sorted names of the resources in the delta response.
+func deltaResourceNames(t *testing.T, response cachev3.DeltaResponse) []string {
+	t.Helper()
+	deltaDiscoveryResponse, err := response.GetDeltaDiscoveryResponse()
+	if err != nil {
+		t.Fatalf("GetDeltaDiscoveryResponse(): %v", err)
+	}
+	names := make([]string, 0, len(deltaDiscoveryResponse.GetResources()))
+	for _, r := range deltaDiscoveryResponse.GetResources() {
+		names = append(names, r.GetName())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func BenchmarkSnapshotEqual(b *testing.B) {
+	apps := []GRPCApplication{testGRPCApplication("app", 1000)}
+	previous := buildTestSnapshot(b, "1", apps)
+	snapshot := buildTestSnapshot(b, "2", apps)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !SnapshotEqual(previous, snapshot) {
+			b.Fatal("SnapshotEqual() = false, want true")
+		}
+	}
+}
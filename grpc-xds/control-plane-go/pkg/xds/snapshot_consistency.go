@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+
+	"github.com/googlecloudplatform/solutions-workshops/grpc-xds/control-plane-go/pkg/metrics"
+)
+
+var errInconsistentSnapshot = errors.New("inconsistent xDS resource snapshot")
+
+// validateSnapshot returns an error that lists the violations, if resources in the snapshot
+// reference resources that are not in the snapshot, and records the inconsistency in metrics.
+func validateSnapshot(nodeHash string, snapshot *cachev3.Snapshot) error {
+	violations := snapshotInconsistencies(snapshot)
+	if len(violations) == 0 {
+		return nil
+	}
+	metrics.XDSSnapshotInconsistent()
+	return fmt.Errorf("%w for nodeHash=%s: %s", errInconsistentSnapshot, nodeHash, strings.Join(violations, "; "))
+}
+
+// snapshotInconsistencies returns the references to RDS, CDS, and EDS resources that are
+// missing from the snapshot, sorted.
+//
+// This is similar to `Snapshot.Consistent()`, but it also checks the clusters referenced by
+// route configurations, and it returns all violations instead of the first one. Unlike
+// `Consistent()`, it allows resources that no other resource references, e.g., the server
+// listener route configuration when server listeners use inline route configurations.
+func snapshotInconsistencies(snapshot *cachev3.Snapshot) []string {
+	references := cachev3.GetAllResourceReferences(snapshot.Resources)
+	references[resource.ClusterType] = routeConfigurationClusterReferences(snapshot)
+	var violations []string
+	for _, typeURL := range []resource.Type{resource.RouteType, resource.ClusterType, resource.EndpointType} {
+		resources := snapshot.GetResources(typeURL)
+		for name := range references[typeURL] {
+			if _, exists := resources[name]; !exists {
+				violations = append(violations, fmt.Sprintf("missing %s resource %s", typeURL, name))
+			}
+		}
+	}
+	slices.Sort(violations)
+	return violations
+}
+
+// routeConfigurationClusterReferences returns the names of the clusters referenced by
+// routes in the route configurations of the snapshot.
+func routeConfigurationClusterReferences(snapshot *cachev3.Snapshot) map[string]bool {
+	clusterNames := map[string]bool{}
+	for _, res := range snapshot.GetResources(resource.RouteType) {
+		routeConfiguration, ok := res.(*routev3.RouteConfiguration)
+		if !ok {
+			continue
+		}
+		for _, virtualHost := range routeConfiguration.GetVirtualHosts() {
+			for _, route := range virtualHost.GetRoutes() {
+				if clusterName := route.GetRoute().GetCluster(); clusterName != "" {
+					clusterNames[clusterName] = true
+				}
+				for _, clusterWeight := range route.GetRoute().GetWeightedClusters().GetClusters() {
+					clusterNames[clusterWeight.GetName()] = true
+				}
+			}
+		}
+	}
+	return clusterNames
+}
+
+// ValidateInitialSnapshot builds a snapshot with the resources from all namespaces from the current
+// configuration, e.g., from the informer caches on startup before any xDS client connects,
+// and returns an error if the snapshot is inconsistent. It does not set the snapshot.
+func (c *SnapshotCache) ValidateInitialSnapshot(logger logr.Logger) error {
+	logger.V(2).Info("Validating the consistency of the initial xDS resource snapshot")
+	snapshot, _, err := c.buildSnapshot(dryRunNodeHash, c.appsCache.GetAll(), nil)
+	if err != nil {
+		return err
+	}
+	return validateSnapshot(dryRunNodeHash, snapshot)
+}
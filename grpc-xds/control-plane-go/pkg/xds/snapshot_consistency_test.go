@@ -0,0 +1,137 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+func TestSnapshotInconsistencies(t *testing.T) {
+	edsCluster := func(name string) *clusterv3.Cluster {
+		return &clusterv3.Cluster{
+			Name:                 name,
+			ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+			EdsClusterConfig: &clusterv3.Cluster_EdsClusterConfig{
+				EdsConfig: &corev3.ConfigSource{
+					ConfigSourceSpecifier: &corev3.ConfigSource_Ads{Ads: &corev3.AggregatedConfigSource{}},
+				},
+			},
+		}
+	}
+	routeConfiguration := &routev3.RouteConfiguration{
+		Name: "greeter",
+		VirtualHosts: []*routev3.VirtualHost{{
+			Name:    "greeter",
+			Domains: []string{"*"},
+			Routes: []*routev3.Route{
+				{
+					Match: &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: "/canary"}},
+					Action: &routev3.Route_Route{Route: &routev3.RouteAction{
+						ClusterSpecifier: &routev3.RouteAction_WeightedClusters{
+							WeightedClusters: &routev3.WeightedCluster{
+								Clusters: []*routev3.WeightedCluster_ClusterWeight{{Name: "greeter"}, {Name: "greeter-canary"}},
+							},
+						},
+					}},
+				},
+				{
+					Match: &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: ""}},
+					Action: &routev3.Route_Route{Route: &routev3.RouteAction{
+						ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: "greeter"},
+					}},
+				},
+			},
+		}},
+	}
+	tests := []struct {
+		name      string
+		resources map[resource.Type][]types.Resource
+		want      []string
+	}{
+		{
+			name: "consistent",
+			resources: map[resource.Type][]types.Resource{
+				resource.RouteType:    {routeConfiguration},
+				resource.ClusterType:  {edsCluster("greeter"), edsCluster("greeter-canary")},
+				resource.EndpointType: {&endpointv3.ClusterLoadAssignment{ClusterName: "greeter"}, &endpointv3.ClusterLoadAssignment{ClusterName: "greeter-canary"}},
+			},
+			want: nil,
+		},
+		{
+			name: "unreferenced route configuration is allowed",
+			resources: map[resource.Type][]types.Resource{
+				resource.RouteType: {routeConfiguration, &routev3.RouteConfiguration{Name: "unreferenced"}},
+				resource.ClusterType: {
+					edsCluster("greeter"),
+					edsCluster("greeter-canary"),
+				},
+				resource.EndpointType: {&endpointv3.ClusterLoadAssignment{ClusterName: "greeter"}, &endpointv3.ClusterLoadAssignment{ClusterName: "greeter-canary"}},
+			},
+			want: nil,
+		},
+		{
+			name: "missing weighted cluster and its endpoints",
+			resources: map[resource.Type][]types.Resource{
+				resource.RouteType:    {routeConfiguration},
+				resource.ClusterType:  {edsCluster("greeter")},
+				resource.EndpointType: {&endpointv3.ClusterLoadAssignment{ClusterName: "greeter"}},
+			},
+			want: []string{"missing " + resource.ClusterType + " resource greeter-canary"},
+		},
+		{
+			name: "missing endpoints",
+			resources: map[resource.Type][]types.Resource{
+				resource.RouteType:   {routeConfiguration},
+				resource.ClusterType: {edsCluster("greeter"), edsCluster("greeter-canary")},
+			},
+			want: []string{
+				"missing " + resource.EndpointType + " resource greeter",
+				"missing " + resource.EndpointType + " resource greeter-canary",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, err := cachev3.NewSnapshot("1", tt.resources)
+			if err != nil {
+				t.Fatalf("NewSnapshot(): %v", err)
+			}
+			if got := snapshotInconsistencies(snapshot); !slices.Equal(got, tt.want) {
+				t.Errorf("snapshotInconsistencies() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	snapshot := buildTestSnapshot(t, "1", []GRPCApplication{testGRPCApplication("greeter", 1)})
+	if err := validateSnapshot(testZone, snapshot); err != nil {
+		t.Errorf("validateSnapshot() error = %v, want nil", err)
+	}
+	delete(snapshot.Resources[types.Endpoint].Items, "greeter")
+	if err := validateSnapshot(testZone, snapshot); !errors.Is(err, errInconsistentSnapshot) {
+		t.Errorf("validateSnapshot() without endpoints error = %v, want %v", err, errInconsistentSnapshot)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var errPersistedSnapshotResource = errors.New("invalid resource in persisted snapshots")
+
+// persistedResourceTypes are the resource types of persisted snapshots.
+// SDS resources are not persisted, as they contain private keys.
+var persistedResourceTypes = []resource.Type{
+	resource.ListenerType,
+	resource.RouteType,
+	resource.ClusterType,
+	resource.EndpointType,
+}
+
+// persistedSnapshots is the JSON representation of the snapshots of all node hashes,
+// see `ExportSnapshots()` and `RestoreSnapshots()`.
+type persistedSnapshots struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Snapshots contains the resources by node hash and type URL, in protobuf JSON format.
+	Snapshots map[string]map[string][]json.RawMessage `json:"snapshots"`
+}
+
+// SetSnapshotListener sets a function that is called after each successful snapshot update,
+// e.g., to persist the snapshots. The function must not block.
+func (c *SnapshotCache) SetSnapshotListener(listener func()) {
+	c.snapshotListener.Store(&listener)
+}
+
+// ExportSnapshots returns the current snapshots of all node hashes as JSON, without SDS resources.
+func (c *SnapshotCache) ExportSnapshots() ([]byte, error) {
+	persisted := persistedSnapshots{
+		Timestamp: time.Now().UTC(),
+		Snapshots: map[string]map[string][]json.RawMessage{},
+	}
+	for _, nodeHash := range c.delegate.GetStatusKeys() {
+		snapshot, err := c.delegate.GetSnapshot(nodeHash)
+		if err != nil {
+			continue
+		}
+		resourcesByType := map[string][]json.RawMessage{}
+		for _, typeURL := range persistedResourceTypes {
+			resources := snapshot.GetResources(typeURL)
+			names := make([]string, 0, len(resources))
+			for name := range resources {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				resourceJSONBytes, err := protojson.Marshal(resources[name])
+				if err != nil {
+					return nil, fmt.Errorf("could not marshal resource type=%s name=%s for nodeHash=%s to JSON: %w", typeURL, name, nodeHash, err)
+				}
+				resourcesByType[typeURL] = append(resourcesByType[typeURL], resourceJSONBytes)
+			}
+		}
+		persisted.Snapshots[nodeHash] = resourcesByType
+	}
+	return json.Marshal(persisted)
+}
+
+// RestoreSnapshots sets the snapshots from the JSON created by `ExportSnapshots()`, if it is not
+// older than `maxAge`, so that xDS clients can receive resources before the informer caches sync.
+// Returns true if the snapshots were restored.
+//
+// Until `ReconcileNow()` is called, updates from informers change the cached configuration,
+// but not the restored snapshots, so that xDS clients do not receive partial configuration
+// from informers that have not synced yet.
+//
+// Call this method before starting the xDS server and informers.
+func (c *SnapshotCache) RestoreSnapshots(logger logr.Logger, data []byte, maxAge time.Duration) (bool, error) {
+	var persisted persistedSnapshots
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return false, fmt.Errorf("could not unmarshal persisted snapshots: %w", err)
+	}
+	if age := time.Since(persisted.Timestamp); age > maxAge {
+		logger.V(2).Info("Not restoring persisted xDS resource snapshots, as they are too old", "timestamp", persisted.Timestamp, "maxAge", maxAge)
+		return false, nil
+	}
+	snapshots := make(map[string]*cachev3.Snapshot, len(persisted.Snapshots))
+	for nodeHash, resourcesByType := range persisted.Snapshots {
+		snapshot := &cachev3.Snapshot{}
+		for _, typeURL := range append(slices.Clone(persistedResourceTypes), resource.SecretType) {
+			resources, err := unmarshalPersistedResources(typeURL, resourcesByType[typeURL])
+			if err != nil {
+				return false, fmt.Errorf("could not restore snapshot for nodeHash=%s: %w", nodeHash, err)
+			}
+			snapshot.Resources[cachev3.GetResponseType(typeURL)] = cachev3.NewResources(c.versions.versionFor(nil, typeURL, resources), resources)
+		}
+		snapshots[nodeHash] = snapshot
+	}
+	c.restoredMu.Lock()
+	defer c.restoredMu.Unlock()
+	for nodeHash, snapshot := range snapshots {
+		if err := c.delegate.SetSnapshot(c.ctx, nodeHash, snapshot); err != nil {
+			return false, fmt.Errorf("could not set restored xDS resource snapshot for nodeHash=%s: %w", nodeHash, err)
+		}
+		c.restoredNodeHashes = append(c.restoredNodeHashes, nodeHash)
+	}
+	c.restored.Store(true)
+	logger.V(2).Info("Restored persisted xDS resource snapshots", "timestamp", persisted.Timestamp, "nodeHashes", c.restoredNodeHashes)
+	return true, nil
+}
+
+// supersedeRestoredSnapshots ends the use of restored snapshots, see `RestoreSnapshots()`.
+// Restored snapshots of node hashes without xDS clients are removed, so that xDS clients that
+// connect later receive snapshots built from the informer caches. Snapshots of node hashes
+// with xDS clients are replaced by the caller.
+func (c *SnapshotCache) supersedeRestoredSnapshots(logger logr.Logger) {
+	if !c.restored.Swap(false) {
+		return
+	}
+	c.restoredMu.Lock()
+	defer c.restoredMu.Unlock()
+	statusKeys := c.delegate.GetStatusKeys()
+	for _, nodeHash := range c.restoredNodeHashes {
+		if !slices.Contains(statusKeys, nodeHash) {
+			c.delegate.ClearSnapshot(nodeHash)
+		}
+	}
+	logger.V(2).Info("Superseding restored xDS resource snapshots", "nodeHashes", c.restoredNodeHashes)
+	c.restoredNodeHashes = nil
+}
+
+func unmarshalPersistedResources(typeURL resource.Type, resourcesJSON []json.RawMessage) ([]types.Resource, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown type=%s: %w", errPersistedSnapshotResource, typeURL, err)
+	}
+	resources := make([]types.Resource, 0, len(resourcesJSON))
+	for _, resourceJSON := range resourcesJSON {
+		message := messageType.New().Interface()
+		if err := protojson.Unmarshal(resourceJSON, message); err != nil {
+			return nil, fmt.Errorf("%w: type=%s: %w", errPersistedSnapshotResource, typeURL, err)
+		}
+		resources = append(resources, message)
+	}
+	return resources, nil
+}
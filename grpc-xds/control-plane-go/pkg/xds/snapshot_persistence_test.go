@@ -0,0 +1,113 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+)
+
+func TestExportAndRestoreSnapshots(t *testing.T) {
+	source, _ := newTestSnapshotCache(t)
+	statusKeys := source.delegate.GetStatusKeys()
+	if len(statusKeys) != 1 {
+		t.Fatalf("GetStatusKeys() = %v, want one node hash", statusKeys)
+	}
+	nodeHash := statusKeys[0]
+	snapshot := buildTestSnapshot(t, "1", []GRPCApplication{testGRPCApplication("app", 3)})
+	if err := source.delegate.SetSnapshot(context.Background(), nodeHash, snapshot); err != nil {
+		t.Fatalf("SetSnapshot() error = %v", err)
+	}
+	data, err := source.ExportSnapshots()
+	if err != nil {
+		t.Fatalf("ExportSnapshots() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	target := NewSnapshotCache(ctx, false, ZoneHash{}, FixedLocalityPriority{}, &Features{}, "xds.example.com")
+	restored, err := target.RestoreSnapshots(logr.Discard(), data, time.Minute)
+	if err != nil {
+		t.Fatalf("RestoreSnapshots() error = %v", err)
+	}
+	if !restored {
+		t.Fatal("RestoreSnapshots() = false, want true")
+	}
+	got, err := target.delegate.GetSnapshot(nodeHash)
+	if err != nil {
+		t.Fatalf("GetSnapshot() error = %v", err)
+	}
+	if !SnapshotEqual(got, snapshot) {
+		t.Error("restored snapshot differs from exported snapshot")
+	}
+
+	target.supersedeRestoredSnapshots(logr.Discard())
+	if _, err := target.delegate.GetSnapshot(nodeHash); err == nil {
+		t.Error("GetSnapshot() after superseding returned a snapshot for a node hash without xDS clients")
+	}
+}
+
+func TestRestoreSnapshots(t *testing.T) {
+	tests := []struct {
+		name         string
+		persisted    persistedSnapshots
+		wantRestored bool
+		wantErr      error
+	}{
+		{
+			name:         "recent",
+			persisted:    persistedSnapshots{Timestamp: time.Now(), Snapshots: map[string]map[string][]json.RawMessage{"node-hash": {}}},
+			wantRestored: true,
+		},
+		{
+			name:      "too old",
+			persisted: persistedSnapshots{Timestamp: time.Now().Add(-time.Hour), Snapshots: map[string]map[string][]json.RawMessage{"node-hash": {}}},
+		},
+		{
+			name: "invalid resource",
+			persisted: persistedSnapshots{
+				Timestamp: time.Now(),
+				Snapshots: map[string]map[string][]json.RawMessage{
+					"node-hash": {resource.ClusterType: {json.RawMessage(`{"unknownField":true}`)}},
+				},
+			},
+			wantErr: errPersistedSnapshotResource,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.persisted)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			c := NewSnapshotCache(ctx, false, ZoneHash{}, FixedLocalityPriority{}, &Features{}, "xds.example.com")
+			restored, err := c.RestoreSnapshots(logr.Discard(), data, time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RestoreSnapshots() error = %v, want %v", err, tt.wantErr)
+			}
+			if restored != tt.wantRestored {
+				t.Errorf("RestoreSnapshots() = %t, want %t", restored, tt.wantRestored)
+			}
+		})
+	}
+}
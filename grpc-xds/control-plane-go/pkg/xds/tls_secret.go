@@ -0,0 +1,132 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+)
+
+// tlsSecretCACertificatesSuffix is appended to the SDS resource name of a TLS Secret
+// for the SDS resource with the CA certificates, as in Istio.
+const tlsSecretCACertificatesSuffix = "-cacert"
+
+// TLSSecret is the configuration from a Kubernetes Secret of type `kubernetes.io/tls`.
+type TLSSecret struct {
+	Namespace        string
+	Name             string
+	CertificateChain []byte
+	PrivateKey       []byte
+	// CACertificates is optional, from the `ca.crt` key of the Secret.
+	CACertificates []byte
+}
+
+// SDSName returns the SDS resource name of the certificate chain and private key, `<namespace>/<name>`.
+func (s TLSSecret) SDSName() string {
+	return s.Namespace + "/" + s.Name
+}
+
+func (s TLSSecret) Compare(t TLSSecret) int {
+	if s.Namespace != t.Namespace {
+		return strings.Compare(s.Namespace, t.Namespace)
+	}
+	if s.Name != t.Name {
+		return strings.Compare(s.Name, t.Name)
+	}
+	if c := bytes.Compare(s.CertificateChain, t.CertificateChain); c != 0 {
+		return c
+	}
+	if c := bytes.Compare(s.PrivateKey, t.PrivateKey); c != 0 {
+		return c
+	}
+	return bytes.Compare(s.CACertificates, t.CACertificates)
+}
+
+// createSecrets returns SDS resources for the TLS Secret, one with the certificate chain and
+// private key, and one with the CA certificates, if the Secret contains CA certificates.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/transport_sockets/tls/v3/secret.proto
+func createSecrets(s TLSSecret) []*tlsv3.Secret {
+	secrets := []*tlsv3.Secret{
+		{
+			Name: s.SDSName(),
+			Type: &tlsv3.Secret_TlsCertificate{
+				TlsCertificate: &tlsv3.TlsCertificate{
+					CertificateChain: &corev3.DataSource{
+						Specifier: &corev3.DataSource_InlineBytes{
+							InlineBytes: s.CertificateChain,
+						},
+					},
+					PrivateKey: &corev3.DataSource{
+						Specifier: &corev3.DataSource_InlineBytes{
+							InlineBytes: s.PrivateKey,
+						},
+					},
+				},
+			},
+		},
+	}
+	if len(s.CACertificates) > 0 {
+		secrets = append(secrets, &tlsv3.Secret{
+			Name: s.SDSName() + tlsSecretCACertificatesSuffix,
+			Type: &tlsv3.Secret_ValidationContext{
+				ValidationContext: &tlsv3.CertificateValidationContext{
+					TrustedCa: &corev3.DataSource{
+						Specifier: &corev3.DataSource_InlineBytes{
+							InlineBytes: s.CACertificates,
+						},
+					},
+				},
+			},
+		})
+	}
+	return secrets
+}
+
+// useTLSSecret replaces the certificate provider instances in the TLS context with SDS
+// configurations for the TLS Secret with the provided SDS name. The other validation
+// settings, e.g., subject alternative name matchers, stay in the default validation context.
+func useTLSSecret(commonTLSContext *tlsv3.CommonTlsContext, sdsName string) {
+	if commonTLSContext.GetTlsCertificateProviderInstance() != nil {
+		commonTLSContext.TlsCertificateProviderInstance = nil
+		commonTLSContext.TlsCertificateSdsSecretConfigs = []*tlsv3.SdsSecretConfig{
+			createSDSSecretConfig(sdsName),
+		}
+	}
+	if validationContext := commonTLSContext.GetValidationContext(); validationContext != nil {
+		validationContext.CaCertificateProviderInstance = nil
+		commonTLSContext.ValidationContextType = &tlsv3.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &tlsv3.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext:         validationContext,
+				ValidationContextSdsSecretConfig: createSDSSecretConfig(sdsName + tlsSecretCACertificatesSuffix),
+			},
+		}
+	}
+}
+
+// createSDSSecretConfig returns a reference to an SDS resource on the ADS stream.
+func createSDSSecretConfig(name string) *tlsv3.SdsSecretConfig {
+	return &tlsv3.SdsSecretConfig{
+		Name: name,
+		SdsConfig: &corev3.ConfigSource{
+			ConfigSourceSpecifier: &corev3.ConfigSource_Ads{
+				Ads: &corev3.AggregatedConfigSource{},
+			},
+			ResourceApiVersion: corev3.ApiVersion_V3,
+		},
+	}
+}
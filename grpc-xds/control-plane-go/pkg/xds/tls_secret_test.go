@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCreateSecrets(t *testing.T) {
+	tests := []struct {
+		name      string
+		tlsSecret TLSSecret
+		wantNames []string
+	}{
+		{
+			name: "without CA certificates",
+			tlsSecret: TLSSecret{
+				Namespace:        "xds",
+				Name:             "control-plane-tls",
+				CertificateChain: []byte("cert"),
+				PrivateKey:       []byte("key"),
+			},
+			wantNames: []string{"xds/control-plane-tls"},
+		},
+		{
+			name: "with CA certificates",
+			tlsSecret: TLSSecret{
+				Namespace:        "xds",
+				Name:             "control-plane-tls",
+				CertificateChain: []byte("cert"),
+				PrivateKey:       []byte("key"),
+				CACertificates:   []byte("ca"),
+			},
+			wantNames: []string{"xds/control-plane-tls", "xds/control-plane-tls-cacert"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := createSecrets(tt.tlsSecret)
+			if len(secrets) != len(tt.wantNames) {
+				t.Fatalf("createSecrets() returned %d secrets, want %d", len(secrets), len(tt.wantNames))
+			}
+			for i, secret := range secrets {
+				if secret.GetName() != tt.wantNames[i] {
+					t.Errorf("secret %d name = %s, want %s", i, secret.GetName(), tt.wantNames[i])
+				}
+			}
+			tlsCertificate := secrets[0].GetTlsCertificate()
+			if string(tlsCertificate.GetCertificateChain().GetInlineBytes()) != "cert" || string(tlsCertificate.GetPrivateKey().GetInlineBytes()) != "key" {
+				t.Errorf("secret 0 tlsCertificate = %v, want inline certificate chain and private key", tlsCertificate)
+			}
+			if len(secrets) > 1 && string(secrets[1].GetValidationContext().GetTrustedCa().GetInlineBytes()) != "ca" {
+				t.Errorf("secret 1 validationContext = %v, want inline trusted CA", secrets[1].GetValidationContext())
+			}
+		})
+	}
+}
+
+func TestUseTLSSecret(t *testing.T) {
+	sanMatcher := &tlsv3.SubjectAltNameMatcher{
+		SanType: tlsv3.SubjectAltNameMatcher_URI,
+		Matcher: &matcherv3.StringMatcher{
+			MatchPattern: &matcherv3.StringMatcher_Exact{Exact: "spiffe://example.com/ns/default/sa/client"},
+		},
+	}
+	commonTLSContext := &tlsv3.CommonTlsContext{
+		TlsCertificateProviderInstance: &tlsv3.CertificateProviderPluginInstance{InstanceName: "google_cloud_private_spiffe"},
+		ValidationContextType: &tlsv3.CommonTlsContext_ValidationContext{
+			ValidationContext: &tlsv3.CertificateValidationContext{
+				CaCertificateProviderInstance: &tlsv3.CertificateProviderPluginInstance{InstanceName: "google_cloud_private_spiffe"},
+				MatchTypedSubjectAltNames:     []*tlsv3.SubjectAltNameMatcher{sanMatcher},
+			},
+		},
+	}
+	useTLSSecret(commonTLSContext, "xds/control-plane-tls")
+	want := &tlsv3.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{createSDSSecretConfig("xds/control-plane-tls")},
+		ValidationContextType: &tlsv3.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &tlsv3.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext: &tlsv3.CertificateValidationContext{
+					MatchTypedSubjectAltNames: []*tlsv3.SubjectAltNameMatcher{sanMatcher},
+				},
+				ValidationContextSdsSecretConfig: createSDSSecretConfig("xds/control-plane-tls-cacert"),
+			},
+		},
+	}
+	if !proto.Equal(commonTLSContext, want) {
+		t.Errorf("useTLSSecret() commonTlsContext = %v, want %v", commonTLSContext, want)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	trafficSplitTotalWeight = 100
+)
+
+var (
+	errInvalidTrafficSplit        = errors.New("invalid traffic split")
+	errTrafficSplitWeightSum      = errors.New("traffic split weights must sum to 100")
+	errUnknownTrafficSplitService = errors.New("unknown service in traffic split")
+)
+
+// ClusterWeight is the percentage of traffic to send to a cluster.
+type ClusterWeight struct {
+	ClusterName string
+	Weight      uint32
+}
+
+func (w ClusterWeight) Compare(v ClusterWeight) int {
+	if w.ClusterName != v.ClusterName {
+		return strings.Compare(w.ClusterName, v.ClusterName)
+	}
+	return cmp.Compare(w.Weight, v.Weight)
+}
+
+// TrafficSplitFromAnnotations parses the `xds.example.com/traffic-split` annotation, e.g., `v1=90,v2=10`,
+// where `v1` and `v2` are names of Kubernetes Services in the same namespace.
+// Weights must sum to 100, and the Service names must be in `knownServiceNames`.
+// Returns nil if the annotation is not present.
+func TrafficSplitFromAnnotations(annotations map[string]string, knownServiceNames []string) ([]ClusterWeight, error) {
+	value, exists := annotations[trafficSplitAnnotation]
+	if !exists || strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var trafficSplit []ClusterWeight
+	var totalWeight uint64
+	for _, entry := range strings.Split(value, ",") {
+		serviceName, weightStr, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || serviceName == "" {
+			return nil, fmt.Errorf("%w: expected <service>=<weight>, got %q in %s=%q", errInvalidTrafficSplit, entry, trafficSplitAnnotation, value)
+		}
+		if !slices.Contains(knownServiceNames, serviceName) {
+			return nil, fmt.Errorf("%w: service=%s in %s=%q", errUnknownTrafficSplitService, serviceName, trafficSplitAnnotation, value)
+		}
+		if slices.ContainsFunc(trafficSplit, func(w ClusterWeight) bool { return w.ClusterName == serviceName }) {
+			return nil, fmt.Errorf("%w: service=%s listed more than once in %s=%q", errInvalidTrafficSplit, serviceName, trafficSplitAnnotation, value)
+		}
+		weight, err := strconv.ParseUint(weightStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not parse weight for service=%s in %s=%q: %w", errInvalidTrafficSplit, serviceName, trafficSplitAnnotation, value, err)
+		}
+		totalWeight += weight
+		trafficSplit = append(trafficSplit, ClusterWeight{
+			ClusterName: serviceName,
+			Weight:      uint32(weight),
+		})
+	}
+	if totalWeight != trafficSplitTotalWeight {
+		return nil, fmt.Errorf("%w: got %d in %s=%q", errTrafficSplitWeightSum, totalWeight, trafficSplitAnnotation, value)
+	}
+	slices.SortFunc(trafficSplit, func(a ClusterWeight, b ClusterWeight) int {
+		return a.Compare(b)
+	})
+	return trafficSplit, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestTrafficSplitFromAnnotations(t *testing.T) {
+	knownServiceNames := []string{"greeter-v1", "greeter-v2", "greeter-v3"}
+	tests := []struct {
+		name    string
+		value   *string
+		want    []ClusterWeight
+		wantErr error
+	}{
+		{
+			name:  "no annotation",
+			value: nil,
+			want:  nil,
+		},
+		{
+			name:  "empty annotation",
+			value: ptr(" "),
+			want:  nil,
+		},
+		{
+			name:  "sorted by service name",
+			value: ptr("greeter-v2=10, greeter-v1=90"),
+			want: []ClusterWeight{
+				{ClusterName: "greeter-v1", Weight: 90},
+				{ClusterName: "greeter-v2", Weight: 10},
+			},
+		},
+		{
+			name:  "single service",
+			value: ptr("greeter-v3=100"),
+			want:  []ClusterWeight{{ClusterName: "greeter-v3", Weight: 100}},
+		},
+		{
+			name:    "weights do not sum to 100",
+			value:   ptr("greeter-v1=90,greeter-v2=20"),
+			wantErr: errTrafficSplitWeightSum,
+		},
+		{
+			name:    "unknown service",
+			value:   ptr("greeter-v1=50,other=50"),
+			wantErr: errUnknownTrafficSplitService,
+		},
+		{
+			name:    "duplicate service",
+			value:   ptr("greeter-v1=50,greeter-v1=50"),
+			wantErr: errInvalidTrafficSplit,
+		},
+		{
+			name:    "missing weight",
+			value:   ptr("greeter-v1"),
+			wantErr: errInvalidTrafficSplit,
+		},
+		{
+			name:    "negative weight",
+			value:   ptr("greeter-v1=110,greeter-v2=-10"),
+			wantErr: errInvalidTrafficSplit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.value != nil {
+				annotations[trafficSplitAnnotation] = *tt.value
+			}
+			got, err := TrafficSplitFromAnnotations(annotations, knownServiceNames)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TrafficSplitFromAnnotations() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("TrafficSplitFromAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateWeightedClusters(t *testing.T) {
+	trafficSplit := []ClusterWeight{
+		{ClusterName: "greeter-v1", Weight: 70},
+		{ClusterName: "greeter-v2", Weight: 30},
+	}
+	tests := []struct {
+		name         string
+		trafficSplit []ClusterWeight
+		want         *routev3.RouteAction_WeightedClusters
+	}{
+		{
+			name:         "70/30",
+			trafficSplit: trafficSplit,
+			want: &routev3.RouteAction_WeightedClusters{
+				WeightedClusters: &routev3.WeightedCluster{
+					Clusters: []*routev3.WeightedCluster_ClusterWeight{
+						{Name: "greeter-v1", Weight: wrapperspb.UInt32(70)},
+						{Name: "greeter-v2", Weight: wrapperspb.UInt32(30)},
+					},
+					RuntimeKeyPrefix: "routing.traffic_split.greeter",
+				},
+			},
+		},
+		{
+			name:         "70/30 with xdstp cluster names",
+			trafficSplit: xdstpTrafficSplit("xds.example.com", trafficSplit),
+			want: &routev3.RouteAction_WeightedClusters{
+				WeightedClusters: &routev3.WeightedCluster{
+					Clusters: []*routev3.WeightedCluster_ClusterWeight{
+						{Name: "xdstp://xds.example.com/envoy.config.cluster.v3.Cluster/greeter-v1", Weight: wrapperspb.UInt32(70)},
+						{Name: "xdstp://xds.example.com/envoy.config.cluster.v3.Cluster/greeter-v2", Weight: wrapperspb.UInt32(30)},
+					},
+					RuntimeKeyPrefix: "routing.traffic_split.greeter",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createWeightedClusters("greeter", tt.trafficSplit); !proto.Equal(got.WeightedClusters, tt.want.WeightedClusters) {
+				t.Errorf("createWeightedClusters() = %v, want %v", got.WeightedClusters, tt.want.WeightedClusters)
+			}
+		})
+	}
+}
+
+func TestXDSTPTrafficSplitWithoutTrafficSplit(t *testing.T) {
+	if got := xdstpTrafficSplit("xds.example.com", nil); got != nil {
+		t.Errorf("xdstpTrafficSplit(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	quicv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/quic/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	upstreamhttpv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Values of the upstream protocol annotation.
+const (
+	UpstreamProtocolHTTP1 = "http1"
+	UpstreamProtocolHTTP2 = "http2"
+	UpstreamProtocolHTTP3 = "http3"
+	// UpstreamProtocolAuto uses the protocol of the downstream connection.
+	UpstreamProtocolAuto = "auto"
+
+	envoyTransportSocketsQUICName = "envoy.transport_sockets.quic"
+	alpnProtocolHTTP3             = "h3"
+)
+
+var errUnknownUpstreamProtocol = errors.New("unknown upstream protocol")
+
+// UpstreamProtocolFromAnnotations reads the protocol of upstream connections from Service annotations.
+// Returns the empty string if the annotation is not present, and an error for unknown protocols,
+// so that the caller can keep the previous valid protocol.
+func UpstreamProtocolFromAnnotations(annotations map[string]string) (string, error) {
+	value, exists := annotations[upstreamProtocolAnnotation]
+	if !exists {
+		return "", nil
+	}
+	protocol := strings.ToLower(strings.TrimSpace(value))
+	switch protocol {
+	case UpstreamProtocolHTTP1, UpstreamProtocolHTTP2, UpstreamProtocolHTTP3, UpstreamProtocolAuto:
+		return protocol, nil
+	default:
+		return "", fmt.Errorf("%w: %s=%s, must be one of %s, %s, %s, or %s", errUnknownUpstreamProtocol, upstreamProtocolAnnotation, value,
+			UpstreamProtocolHTTP1, UpstreamProtocolHTTP2, UpstreamProtocolHTTP3, UpstreamProtocolAuto)
+	}
+}
+
+// applyUpstreamProtocol configures the protocol of upstream connections of the cluster, using the
+// `HttpProtocolOptions` extension instead of the deprecated `protocol_selection` field of the cluster.
+// The HTTP/2 protocol options apply to the `http2` and `auto` protocols, and without a protocol, they
+// imply `http2`. Without a protocol and options, the cluster is unchanged.
+// gRPC clients always use HTTP/2 and ignore these options.
+// [Reference]: https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/upstreams/http/v3/http_protocol_options.proto
+func applyUpstreamProtocol(cluster *clusterv3.Cluster, protocol string, http2Options HTTP2ProtocolOptions) error {
+	if protocol == "" && http2Options == (HTTP2ProtocolOptions{}) {
+		return nil
+	}
+	httpProtocolOptions := &upstreamhttpv3.HttpProtocolOptions{}
+	switch protocol {
+	case UpstreamProtocolHTTP1:
+		httpProtocolOptions.UpstreamProtocolOptions = &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig_HttpProtocolOptions{
+					HttpProtocolOptions: &corev3.Http1ProtocolOptions{},
+				},
+			},
+		}
+	case UpstreamProtocolHTTP3:
+		httpProtocolOptions.UpstreamProtocolOptions = &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig_Http3ProtocolOptions{
+					Http3ProtocolOptions: &corev3.Http3ProtocolOptions{},
+				},
+			},
+		}
+		if err := applyQUICTransportSocket(cluster); err != nil {
+			return err
+		}
+	case UpstreamProtocolAuto:
+		httpProtocolOptions.UpstreamProtocolOptions = &upstreamhttpv3.HttpProtocolOptions_UseDownstreamProtocolConfig{
+			UseDownstreamProtocolConfig: &upstreamhttpv3.HttpProtocolOptions_UseDownstreamHttpConfig{
+				HttpProtocolOptions:  &corev3.Http1ProtocolOptions{},
+				Http2ProtocolOptions: createHTTP2ProtocolOptions(http2Options),
+			},
+		}
+	default:
+		httpProtocolOptions.UpstreamProtocolOptions = &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &upstreamhttpv3.HttpProtocolOptions_ExplicitHttpConfig_Http2ProtocolOptions{
+					Http2ProtocolOptions: createHTTP2ProtocolOptions(http2Options),
+				},
+			},
+		}
+	}
+	typedConfig, err := anypb.New(httpProtocolOptions)
+	if err != nil {
+		return fmt.Errorf("could not marshall HttpProtocolOptions +%v into Any instance: %w", httpProtocolOptions, err)
+	}
+	if cluster.TypedExtensionProtocolOptions == nil {
+		cluster.TypedExtensionProtocolOptions = map[string]*anypb.Any{}
+	}
+	cluster.TypedExtensionProtocolOptions[envoyUpstreamsHTTPProtocolOptionsName] = typedConfig
+	return nil
+}
+
+// applyQUICTransportSocket replaces the transport socket of the cluster with a QUIC transport socket,
+// as HTTP/3 requires QUIC. The QUIC transport socket wraps the existing upstream TLS context,
+// if the cluster has one, and the ALPN protocol is `h3`.
+func applyQUICTransportSocket(cluster *clusterv3.Cluster) error {
+	upstreamTLSContext := &tlsv3.UpstreamTlsContext{}
+	if cluster.TransportSocket != nil && cluster.TransportSocket.GetTypedConfig() != nil {
+		if err := cluster.TransportSocket.GetTypedConfig().UnmarshalTo(upstreamTLSContext); err != nil {
+			return fmt.Errorf("could not unmarshall UpstreamTlsContext of cluster %s for the QUIC transport socket: %w", cluster.Name, err)
+		}
+	}
+	if upstreamTLSContext.CommonTlsContext == nil {
+		upstreamTLSContext.CommonTlsContext = &tlsv3.CommonTlsContext{}
+	}
+	upstreamTLSContext.CommonTlsContext.AlpnProtocols = []string{alpnProtocolHTTP3}
+	quicUpstreamTransport := &quicv3.QuicUpstreamTransport{
+		UpstreamTlsContext: upstreamTLSContext,
+	}
+	typedConfig, err := anypb.New(quicUpstreamTransport)
+	if err != nil {
+		return fmt.Errorf("could not marshall QuicUpstreamTransport +%v into Any instance: %w", quicUpstreamTransport, err)
+	}
+	cluster.TransportSocket = &corev3.TransportSocket{
+		Name: envoyTransportSocketsQUICName,
+		ConfigType: &corev3.TransportSocket_TypedConfig{
+			TypedConfig: typedConfig,
+		},
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	quicv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/quic/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	upstreamhttpv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestUpstreamProtocolFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantErr     error
+	}{
+		{name: "no annotation", annotations: nil, want: ""},
+		{name: "http1", annotations: map[string]string{upstreamProtocolAnnotation: "http1"}, want: UpstreamProtocolHTTP1},
+		{name: "case and whitespace", annotations: map[string]string{upstreamProtocolAnnotation: " HTTP3 "}, want: UpstreamProtocolHTTP3},
+		{name: "auto", annotations: map[string]string{upstreamProtocolAnnotation: "auto"}, want: UpstreamProtocolAuto},
+		{name: "unknown", annotations: map[string]string{upstreamProtocolAnnotation: "spdy"}, wantErr: errUnknownUpstreamProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpstreamProtocolFromAnnotations(tt.annotations)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpstreamProtocolFromAnnotations() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UpstreamProtocolFromAnnotations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUpstreamProtocol(t *testing.T) {
+	tests := []struct {
+		name         string
+		protocol     string
+		http2Options HTTP2ProtocolOptions
+		wantOptions  bool
+		check        func(t *testing.T, options *upstreamhttpv3.HttpProtocolOptions)
+	}{
+		{
+			name:        "no protocol and no options",
+			wantOptions: false,
+		},
+		{
+			name:         "HTTP/2 options imply http2",
+			http2Options: HTTP2ProtocolOptions{MaxConcurrentStreams: 100},
+			wantOptions:  true,
+			check: func(t *testing.T, options *upstreamhttpv3.HttpProtocolOptions) {
+				got := options.GetExplicitHttpConfig().GetHttp2ProtocolOptions().GetMaxConcurrentStreams().GetValue()
+				if got != 100 {
+					t.Errorf("maxConcurrentStreams = %d, want 100", got)
+				}
+			},
+		},
+		{
+			name:        "http1",
+			protocol:    UpstreamProtocolHTTP1,
+			wantOptions: true,
+			check: func(t *testing.T, options *upstreamhttpv3.HttpProtocolOptions) {
+				if options.GetExplicitHttpConfig().GetHttpProtocolOptions() == nil {
+					t.Errorf("options = %v, want explicit HTTP/1.1 config", options)
+				}
+			},
+		},
+		{
+			name:        "http3",
+			protocol:    UpstreamProtocolHTTP3,
+			wantOptions: true,
+			check: func(t *testing.T, options *upstreamhttpv3.HttpProtocolOptions) {
+				if options.GetExplicitHttpConfig().GetHttp3ProtocolOptions() == nil {
+					t.Errorf("options = %v, want explicit HTTP/3 config", options)
+				}
+			},
+		},
+		{
+			name:         "auto",
+			protocol:     UpstreamProtocolAuto,
+			http2Options: HTTP2ProtocolOptions{MaxConcurrentStreams: 100},
+			wantOptions:  true,
+			check: func(t *testing.T, options *upstreamhttpv3.HttpProtocolOptions) {
+				config := options.GetUseDownstreamProtocolConfig()
+				if config.GetHttpProtocolOptions() == nil || config.GetHttp2ProtocolOptions().GetMaxConcurrentStreams().GetValue() != 100 {
+					t.Errorf("options = %v, want downstream protocol config with HTTP/2 options", options)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &clusterv3.Cluster{Name: "cluster"}
+			if err := applyUpstreamProtocol(cluster, tt.protocol, tt.http2Options); err != nil {
+				t.Fatalf("applyUpstreamProtocol() error = %v", err)
+			}
+			typedConfig, exists := cluster.GetTypedExtensionProtocolOptions()[envoyUpstreamsHTTPProtocolOptionsName]
+			if exists != tt.wantOptions {
+				t.Fatalf("HttpProtocolOptions present = %t, want %t", exists, tt.wantOptions)
+			}
+			if !exists {
+				return
+			}
+			var options upstreamhttpv3.HttpProtocolOptions
+			if err := typedConfig.UnmarshalTo(&options); err != nil {
+				t.Fatalf("could not unmarshal HttpProtocolOptions: %v", err)
+			}
+			tt.check(t, &options)
+		})
+	}
+}
+
+func TestApplyQUICTransportSocket(t *testing.T) {
+	upstreamTLSContext, err := anypb.New(&tlsv3.UpstreamTlsContext{Sni: "app.example.com"})
+	if err != nil {
+		t.Fatalf("anypb.New() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		cluster *clusterv3.Cluster
+		wantSNI string
+	}{
+		{
+			name:    "plaintext",
+			cluster: &clusterv3.Cluster{Name: "cluster"},
+		},
+		{
+			name: "wraps upstream TLS context",
+			cluster: &clusterv3.Cluster{
+				Name: "cluster",
+				TransportSocket: &corev3.TransportSocket{
+					Name:       envoyTransportSocketsTLSName,
+					ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: upstreamTLSContext},
+				},
+			},
+			wantSNI: "app.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := applyQUICTransportSocket(tt.cluster); err != nil {
+				t.Fatalf("applyQUICTransportSocket() error = %v", err)
+			}
+			if tt.cluster.GetTransportSocket().GetName() != envoyTransportSocketsQUICName {
+				t.Errorf("transport socket name = %s, want %s", tt.cluster.GetTransportSocket().GetName(), envoyTransportSocketsQUICName)
+			}
+			var quicTransport quicv3.QuicUpstreamTransport
+			if err := tt.cluster.GetTransportSocket().GetTypedConfig().UnmarshalTo(&quicTransport); err != nil {
+				t.Fatalf("could not unmarshal QuicUpstreamTransport: %v", err)
+			}
+			tlsContext := quicTransport.GetUpstreamTlsContext()
+			if tlsContext.GetSni() != tt.wantSNI {
+				t.Errorf("SNI = %q, want %q", tlsContext.GetSni(), tt.wantSNI)
+			}
+			if alpn := tlsContext.GetCommonTlsContext().GetAlpnProtocols(); !slices.Equal(alpn, []string{alpnProtocolHTTP3}) {
+				t.Errorf("ALPN protocols = %v, want [%s]", alpn, alpnProtocolHTTP3)
+			}
+		})
+	}
+}